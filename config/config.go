@@ -5,6 +5,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -72,13 +73,43 @@ type Config struct {
 	InsonBaseURL  string
 	InsonLogin    string
 	InsonPassword string
+	OsagoDisabledProviders []string // OSAGO_DISABLED_PROVIDERS — страховые, отключенные в calculate/create (через запятую)
+	OsagoPaymentTTL        time.Duration // OSAGO_PAYMENT_TTL — сколько ждать оплату, после этого заказ expired
+	OsagoReminderDays      []int         // OSAGO_REMINDER_DAYS — за сколько дней до окончания полиса напоминать (через запятую)
+	OsagoRenewalURL        string        // OSAGO_RENEWAL_URL — страница фронта, куда ведет ссылка продления (?renewal=<token>)
+	OsagoTariffTolerance   float64       // OSAGO_TARIFF_DEVIATION_PERCENT — допустимое отклонение котировки от регулируемого тарифа, %
+	// Кэш котировок OSAGO/KASKO: QUOTE_CACHE_TTL для всех, QUOTE_CACHE_TTL_<PROVIDER> — для отдельной страховой (0 — не кэшировать)
+	QuoteCacheTTL          time.Duration
+	QuoteCacheProviderTTLs map[string]time.Duration
+	// Circuit breaker интеграций со страховыми (OSAGO, KASKO, travel, accident)
+	ProviderBreakerErrorPercent int           // PROVIDER_BREAKER_ERROR_PERCENT — доля ошибок за окно, при которой провайдер отключается
+	ProviderBreakerMinCalls     int           // PROVIDER_BREAKER_MIN_CALLS — минимум вызовов в окне для решения
+	ProviderBreakerWindow       time.Duration // PROVIDER_BREAKER_WINDOW — скользящее окно статистики
+	ProviderBreakerCooldown     time.Duration // PROVIDER_BREAKER_COOLDOWN — сколько провайдер отключен до пробного запроса
 	// Translation API settings (бесплатный API, без токенов)
 	TranslationAPIURL string // URL для LibreTranslate (опционально, по умолчанию используется публичный)
+	// Порядок бэкендов перевода (fallback): libretranslate, mymemory, llm, dictionary
+	TranslationBackends        []string
+	TranslationBackendTimeouts map[string]time.Duration // TRANSLATION_TIMEOUT_<BACKEND>, например TRANSLATION_TIMEOUT_LLM=20s
+	TranslationBackendRPM      map[string]int           // TRANSLATION_RPM_<BACKEND> — лимит запросов в минуту (0 = без лимита)
+	TranslationLLMURL          string                   // chat-completions endpoint (по умолчанию DeepSeek)
+	TranslationLLMAPIKey       string
+	TranslationLLMModel        string
+	TranslationDictionaryPath  string // JSON-словарь фраз для dictionary-бэкенда
 	// App Links / Universal Links (для Android App Links и iOS Associated Domains)
 	AndroidPackageName       string   // package_name для assetlinks.json (например com.kliro.app)
 	AndroidSHA256Fingerprints []string // SHA256 отпечатки сертификатов (через запятую в .env)
 	AppleTeamID             string   // Team ID для apple-app-site-association (например 9JA89Q95N)
 	AppleBundleID           string   // Bundle ID для iOS (например com.kliro.app)
+	// Заявки на банковские продукты (лиды)
+	LeadFallbackEmail   string        // куда слать заявки, если у банка нет партнерской настройки (пусто — не слать)
+	LeadDeliveryTimeout time.Duration // таймаут доставки (SMTP / webhook)
+	LeadMaxAttempts     int           // сколько раз пытаться доставить заявку
+	LeadTestWebhook     bool          // LEAD_TEST_WEBHOOK=true — включить заглушку /test/lead-webhook (только для разработки, логирует заявки)
+	// Ночная выгрузка обезличенной аналитики в файлы
+	AnalyticsExportDir           string // каталог выгрузок (EXPORT_DIR)
+	AnalyticsExportRetentionDays int    // сколько дней хранить выгрузки (0 — не удалять)
+	AnalyticsExportSalt          string // соль для псевдонимов пользователей (EXPORT_SALT, без нее выгрузка не запускается)
 }
 
 func LoadConfig() *Config {
@@ -86,6 +117,7 @@ func LoadConfig() *Config {
 	if err != nil {
 		log.Println("No .env file found, using environment variables")
 	}
+	translationBackends := getenvSliceOrDefault("TRANSLATION_BACKENDS", []string{"dictionary", "libretranslate", "mymemory"})
 	return &Config{
 		DBHost:           os.Getenv("DB_HOST"),
 		DBPort:           os.Getenv("DB_PORT"),
@@ -140,11 +172,36 @@ func LoadConfig() *Config {
 		InsonBaseURL:          getenvOrDefault("INSON_BASE_URL", "https://testapi-ersp.insonline.uz"),
 		InsonLogin:          os.Getenv("INSON_LOGIN"),
 		InsonPassword:       os.Getenv("INSON_PASSWORD"),
+		OsagoDisabledProviders: getenvSliceOrDefault("OSAGO_DISABLED_PROVIDERS", nil),
+		OsagoPaymentTTL:        getenvDurationOrDefault("OSAGO_PAYMENT_TTL", 24*time.Hour),
+		OsagoReminderDays:      getenvIntSliceOrDefault("OSAGO_REMINDER_DAYS", []int{30, 7, 1}),
+		OsagoRenewalURL:        getenvOrDefault("OSAGO_RENEWAL_URL", "https://kliro.uz/osago"),
+		OsagoTariffTolerance:   float64(getenvIntOrDefault("OSAGO_TARIFF_DEVIATION_PERCENT", 2)),
+		QuoteCacheTTL:          getenvDurationOrDefault("QUOTE_CACHE_TTL", 10*time.Minute),
+		QuoteCacheProviderTTLs: quoteCacheProviderTTLs(),
+		ProviderBreakerErrorPercent: getenvIntOrDefault("PROVIDER_BREAKER_ERROR_PERCENT", 50),
+		ProviderBreakerMinCalls:     getenvIntOrDefault("PROVIDER_BREAKER_MIN_CALLS", 10),
+		ProviderBreakerWindow:       getenvDurationOrDefault("PROVIDER_BREAKER_WINDOW", time.Minute),
+		ProviderBreakerCooldown:     getenvDurationOrDefault("PROVIDER_BREAKER_COOLDOWN", 30*time.Second),
 		TranslationAPIURL:   getenvOrDefault("TRANSLATION_API_URL", "https://libretranslate.com/translate"),
+		TranslationBackends: translationBackends,
+		TranslationBackendTimeouts: translationBackendTimeouts(translationBackends),
+		TranslationBackendRPM:      translationBackendRPM(translationBackends),
+		TranslationLLMURL:          getenvOrDefault("TRANSLATION_LLM_URL", "https://api.deepseek.com/chat/completions"),
+		TranslationLLMAPIKey:       os.Getenv("TRANSLATION_LLM_API_KEY"),
+		TranslationLLMModel:        getenvOrDefault("TRANSLATION_LLM_MODEL", "deepseek-chat"),
+		TranslationDictionaryPath:  getenvOrDefault("TRANSLATION_DICTIONARY_PATH", "staticDate/translationDictionary.json"),
 		AndroidPackageName:  getenvOrDefault("ANDROID_PACKAGE_NAME", "com.kliro.app"),
 		AndroidSHA256Fingerprints: getenvSliceOrDefault("ANDROID_SHA256_CERT_FINGERPRINTS", []string{"F7:34:EE:03:5C:83:AA:B7:EF:44:43:67:95:28:9B:D0:16:99:0F:E5:52:B8:0F:98:E5:12:76:F2:33:E2"}),
 		AppleTeamID:         os.Getenv("APPLE_TEAM_ID"),
 		AppleBundleID:       getenvOrDefault("APPLE_BUNDLE_ID", "com.kliro.app"),
+		LeadFallbackEmail:   os.Getenv("LEAD_FALLBACK_EMAIL"),
+		LeadDeliveryTimeout: getenvDurationOrDefault("LEAD_DELIVERY_TIMEOUT", 10*time.Second),
+		LeadMaxAttempts:     getenvIntOrDefault("LEAD_MAX_ATTEMPTS", 5),
+		LeadTestWebhook:     os.Getenv("LEAD_TEST_WEBHOOK") == "true",
+		AnalyticsExportDir:           getenvOrDefault("EXPORT_DIR", "./exports"),
+		AnalyticsExportRetentionDays: getenvIntOrDefault("EXPORT_RETENTION_DAYS", 90),
+		AnalyticsExportSalt:          os.Getenv("EXPORT_SALT"),
 	}
 }
 
@@ -184,3 +241,67 @@ func getenvSliceOrDefault(key string, def []string) []string {
 	}
 	return out
 }
+
+// getenvIntSliceOrDefault returns the comma-separated environment variable as ints (invalid items are skipped), or def if empty
+func getenvIntSliceOrDefault(key string, def []int) []int {
+	var out []int
+	for _, p := range getenvSliceOrDefault(key, nil) {
+		if n, err := strconv.Atoi(p); err == nil {
+			out = append(out, n)
+		}
+	}
+	if len(out) == 0 {
+		return def
+	}
+	return out
+}
+
+// getenvDurationOrDefault returns the environment variable parsed as time.Duration ("10s", "1500ms"), otherwise returns def
+func getenvDurationOrDefault(key string, def time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+	}
+	return def
+}
+
+// quoteCacheProviderTTLs reads QUOTE_CACHE_TTL_<PROVIDER> for every insurance provider that has it set
+func quoteCacheProviderTTLs() map[string]time.Duration {
+	out := map[string]time.Duration{}
+	for _, name := range []string{"neo", "apex", "euroasia", "gross", "trust", "inson"} {
+		key := "QUOTE_CACHE_TTL_" + strings.ToUpper(name)
+		if os.Getenv(key) != "" {
+			out[name] = getenvDurationOrDefault(key, 0)
+		}
+	}
+	return out
+}
+
+// translationBackendTimeouts reads TRANSLATION_TIMEOUT_<BACKEND> for every configured backend
+func translationBackendTimeouts(backends []string) map[string]time.Duration {
+	defaults := map[string]time.Duration{
+		"libretranslate": 10 * time.Second,
+		"mymemory":       10 * time.Second,
+		"llm":            20 * time.Second,
+	}
+	out := make(map[string]time.Duration, len(backends))
+	for _, name := range backends {
+		out[name] = getenvDurationOrDefault("TRANSLATION_TIMEOUT_"+strings.ToUpper(name), defaults[name])
+	}
+	return out
+}
+
+// translationBackendRPM reads TRANSLATION_RPM_<BACKEND> for every configured backend
+func translationBackendRPM(backends []string) map[string]int {
+	defaults := map[string]int{
+		"libretranslate": 60,
+		"mymemory":       30,
+		"llm":            60,
+	}
+	out := make(map[string]int, len(backends))
+	for _, name := range backends {
+		out[name] = getenvIntOrDefault("TRANSLATION_RPM_"+strings.ToUpper(name), defaults[name])
+	}
+	return out
+}
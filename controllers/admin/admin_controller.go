@@ -108,6 +108,8 @@ func (ac *AdminController) DeleteUser(c *gin.Context) {
 		tx.Unscoped().Where("user_id = ?", userID).Delete(&models.HotelSearchHistory{})
 		tx.Unscoped().Where("user_id = ?", userID).Delete(&models.InsuranceSearchHistory{})
 		tx.Where("user_id = ?", userID).Delete(&models.OsagoOrder{})
+		tx.Where("user_id = ?", userID).Delete(&models.OsagoExternalPolicy{})
+		tx.Where("user_id = ?", userID).Delete(&models.OsagoRenewalReminder{})
 		if err := tx.Unscoped().Delete(&user).Error; err != nil {
 			return err
 		}
@@ -420,6 +422,20 @@ func (ac *AdminController) GetSystemInfo(c *gin.Context) {
 	})
 }
 
+// GetTranslationMetrics возвращает метрики сервиса переводов: какой бэкенд сколько фраз перевел
+func (ac *AdminController) GetTranslationMetrics(c *gin.Context) {
+	ts := utils.GetTranslationService()
+	if ts == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": "Сервис переводов не инициализирован"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"result":  ts.Metrics(),
+		"success": true,
+	})
+}
+
 // StartParsing запускает парсинг для конкретного сервиса
 func (ac *AdminController) StartParsing(c *gin.Context) {
 	service := c.Param("service")
@@ -0,0 +1,244 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"kliro/models"
+	editorialServices "kliro/services/editorial"
+	"kliro/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ProductOverrideRequest запрос на создание/изменение редакционной правки.
+// Продукт задается product_key либо тройкой direction + bank_name + product_name.
+type ProductOverrideRequest struct {
+	ProductKey    string             `json:"product_key"`
+	Direction     string             `json:"direction"`
+	BankName      string             `json:"bank_name"`
+	ProductName   string             `json:"product_name"`
+	Hidden        *bool              `json:"hidden"`
+	Pinned        *bool              `json:"pinned"`
+	PinPosition   *int               `json:"pin_position"`
+	Corrections   *map[string]string `json:"corrections"`
+	EditorialNote *string            `json:"editorial_note"`
+}
+
+// GetProductOverrides список редакционных правок (фильтры ?direction=, ?bank=)
+func (ac *AdminController) GetProductOverrides(c *gin.Context) {
+	query := ac.db.Model(&models.ProductOverride{})
+	if direction := c.Query("direction"); direction != "" {
+		query = query.Where("direction = ?", strings.ToLower(direction))
+	}
+	if bank := c.Query("bank"); bank != "" {
+		query = query.Where("bank_name ILIKE ?", "%"+bank+"%")
+	}
+
+	var overrides []models.ProductOverride
+	if err := query.Order("updated_at DESC").Find(&overrides).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Ошибка при получении правок"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"result": overrides, "success": true})
+}
+
+// GetCorrectableFields возвращает поля, которые можно исправлять, по направлениям
+func (ac *AdminController) GetCorrectableFields(c *gin.Context) {
+	result := make(map[string][]string)
+	for _, direction := range []string{"deposit", "microcredit", "autocredit", "mortgage", "card", "credit", "transfer"} {
+		result[direction] = editorialServices.CorrectableFields(direction)
+	}
+	c.JSON(http.StatusOK, gin.H{"result": result, "success": true})
+}
+
+// UpsertProductOverride создает или изменяет правку продукта (с записью в журнал)
+func (ac *AdminController) UpsertProductOverride(c *gin.Context) {
+	var req ProductOverrideRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "invalid request"})
+		return
+	}
+
+	key := strings.TrimSpace(req.ProductKey)
+	direction := strings.ToLower(strings.TrimSpace(req.Direction))
+	if key == "" {
+		if direction == "" || strings.TrimSpace(req.ProductName) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Укажите product_key или direction, bank_name и product_name"})
+			return
+		}
+		key = utils.ProductKey(direction, req.BankName, req.ProductName)
+	} else if i := strings.Index(key, ":"); i > 0 {
+		direction = key[:i]
+	}
+	if !editorialServices.ValidDirection(direction) {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Неизвестное направление"})
+		return
+	}
+
+	var override models.ProductOverride
+	err := ac.db.Where("product_key = ?", key).First(&override).Error
+	isNew := err == gorm.ErrRecordNotFound
+	if err != nil && !isNew {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Ошибка при получении правки"})
+		return
+	}
+	before := overrideSnapshot(override, isNew)
+
+	override.ProductKey = key
+	override.Direction = direction
+	if req.BankName != "" {
+		override.BankName = strings.TrimSpace(req.BankName)
+	}
+	if req.ProductName != "" {
+		override.ProductName = strings.TrimSpace(req.ProductName)
+	}
+	if req.Hidden != nil {
+		override.Hidden = *req.Hidden
+	}
+	if req.Pinned != nil {
+		override.Pinned = *req.Pinned
+	}
+	if req.PinPosition != nil {
+		override.PinPosition = *req.PinPosition
+	}
+	if override.PinPosition < 1 {
+		override.PinPosition = 1
+	}
+	if req.EditorialNote != nil {
+		override.EditorialNote = strings.TrimSpace(*req.EditorialNote)
+	}
+	if req.Corrections != nil {
+		allowed := make(map[string]bool)
+		for _, f := range editorialServices.CorrectableFields(direction) {
+			allowed[f] = true
+		}
+		for field := range *req.Corrections {
+			if !allowed[field] {
+				c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Поле нельзя исправить: " + field})
+				return
+			}
+		}
+		override.Corrections = ""
+		if len(*req.Corrections) > 0 {
+			data, _ := json.Marshal(*req.Corrections)
+			override.Corrections = string(data)
+		}
+	}
+	userID := currentAdminID(c)
+	override.UpdatedBy = userID
+
+	action := "update"
+	if isNew {
+		action = "create"
+	}
+	err = ac.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&override).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.ProductOverrideAudit{
+			OverrideID: override.ID,
+			ProductKey: override.ProductKey,
+			Action:     action,
+			Before:     before,
+			After:      overrideSnapshot(override, false),
+			UserID:     userID,
+		}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Не удалось сохранить правку"})
+		return
+	}
+	editorialServices.DefaultStore().Invalidate()
+
+	c.JSON(http.StatusOK, gin.H{"result": override, "success": true})
+}
+
+// DeleteProductOverride удаляет правку (продукт возвращается к данным парсера)
+func (ac *AdminController) DeleteProductOverride(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Неверный ID правки"})
+		return
+	}
+
+	var override models.ProductOverride
+	if err := ac.db.First(&override, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Правка не найдена"})
+		return
+	}
+
+	err = ac.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&override).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.ProductOverrideAudit{
+			OverrideID: override.ID,
+			ProductKey: override.ProductKey,
+			Action:     "delete",
+			Before:     overrideSnapshot(override, false),
+			UserID:     currentAdminID(c),
+		}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Не удалось удалить правку"})
+		return
+	}
+	editorialServices.DefaultStore().Invalidate()
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// GetProductOverrideAudit журнал изменений правок (фильтры ?product_key=, ?override_id=)
+func (ac *AdminController) GetProductOverrideAudit(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if limit < 1 || limit > 500 {
+		limit = 100
+	}
+
+	query := ac.db.Model(&models.ProductOverrideAudit{})
+	if key := c.Query("product_key"); key != "" {
+		query = query.Where("product_key = ?", key)
+	}
+	if overrideID := c.Query("override_id"); overrideID != "" {
+		query = query.Where("override_id = ?", overrideID)
+	}
+
+	var entries []models.ProductOverrideAudit
+	if err := query.Order("created_at DESC, id DESC").Limit(limit).Find(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Ошибка при получении журнала"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"result": entries, "success": true})
+}
+
+// overrideSnapshot - JSON состояния правки для журнала (пусто для еще не созданной)
+func overrideSnapshot(o models.ProductOverride, empty bool) string {
+	if empty {
+		return ""
+	}
+	data, _ := json.Marshal(gin.H{
+		"hidden":         o.Hidden,
+		"pinned":         o.Pinned,
+		"pin_position":   o.PinPosition,
+		"corrections":    o.Corrections,
+		"editorial_note": o.EditorialNote,
+		"bank_name":      o.BankName,
+		"product_name":   o.ProductName,
+	})
+	return string(data)
+}
+
+// currentAdminID - ID пользователя из JWT, если он есть в контексте
+func currentAdminID(c *gin.Context) *uint {
+	if userID := c.GetInt("user_id"); userID > 0 {
+		uid := uint(userID)
+		return &uid
+	}
+	return nil
+}
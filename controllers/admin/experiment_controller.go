@@ -0,0 +1,162 @@
+package admin
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"kliro/models"
+	experimentServices "kliro/services/experiments"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExperimentRequest запрос на создание/изменение эксперимента
+type ExperimentRequest struct {
+	Key            string     `json:"key"`
+	Name           *string    `json:"name"`
+	Description    *string    `json:"description"`
+	Status         *string    `json:"status"`
+	Variants       *string    `json:"variants"`
+	TrafficPercent *int       `json:"traffic_percent"`
+	StartsAt       *time.Time `json:"starts_at"`
+	EndsAt         *time.Time `json:"ends_at"`
+}
+
+// GetExperiments список экспериментов
+func (ac *AdminController) GetExperiments(c *gin.Context) {
+	query := ac.db.Model(&models.Experiment{})
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", strings.ToLower(status))
+	}
+	var experiments []models.Experiment
+	if err := query.Order("created_at DESC").Find(&experiments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Ошибка при получении экспериментов"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": experiments, "success": true})
+}
+
+// CreateExperiment создает эксперимент (по умолчанию в статусе draft)
+func (ac *AdminController) CreateExperiment(c *gin.Context) {
+	var req ExperimentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Неверный формат запроса"})
+		return
+	}
+	key := strings.ToLower(strings.TrimSpace(req.Key))
+	if key == "" || len(key) > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Укажите key"})
+		return
+	}
+	var count int64
+	ac.db.Model(&models.Experiment{}).Where("key = ?", key).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "Эксперимент с таким key уже существует"})
+		return
+	}
+
+	exp := models.Experiment{Key: key, Status: experimentServices.StatusDraft, TrafficPercent: 100}
+	if msg := applyExperimentRequest(&exp, req); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": msg})
+		return
+	}
+	exp.UpdatedBy = currentAdminID(c)
+
+	if err := ac.db.Create(&exp).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Не удалось создать эксперимент"})
+		return
+	}
+	experimentServices.DefaultStore().Invalidate()
+
+	c.JSON(http.StatusCreated, gin.H{"result": exp, "success": true})
+}
+
+// UpdateExperiment изменяет эксперимент; key не меняется, т.к. от него зависит распределение
+func (ac *AdminController) UpdateExperiment(c *gin.Context) {
+	var exp models.Experiment
+	if err := ac.db.First(&exp, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Эксперимент не найден"})
+		return
+	}
+	var req ExperimentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Неверный формат запроса"})
+		return
+	}
+	if msg := applyExperimentRequest(&exp, req); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": msg})
+		return
+	}
+	exp.UpdatedBy = currentAdminID(c)
+
+	if err := ac.db.Save(&exp).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Не удалось сохранить эксперимент"})
+		return
+	}
+	experimentServices.DefaultStore().Invalidate()
+
+	c.JSON(http.StatusOK, gin.H{"result": exp, "success": true})
+}
+
+// applyExperimentRequest переносит поля запроса в эксперимент и проверяет их; возвращает текст ошибки
+func applyExperimentRequest(exp *models.Experiment, req ExperimentRequest) string {
+	if req.Name != nil {
+		exp.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Description != nil {
+		exp.Description = strings.TrimSpace(*req.Description)
+	}
+	if req.Status != nil {
+		exp.Status = strings.ToLower(strings.TrimSpace(*req.Status))
+	}
+	if req.Variants != nil {
+		exp.Variants = strings.TrimSpace(*req.Variants)
+	}
+	if req.TrafficPercent != nil {
+		exp.TrafficPercent = *req.TrafficPercent
+	}
+	if req.StartsAt != nil {
+		exp.StartsAt = req.StartsAt
+	}
+	if req.EndsAt != nil {
+		exp.EndsAt = req.EndsAt
+	}
+
+	if !experimentServices.ValidStatuses[exp.Status] {
+		return "status должен быть draft, running, paused или completed"
+	}
+	if _, err := experimentServices.ParseVariants(exp.Variants); err != nil {
+		return err.Error()
+	}
+	if exp.TrafficPercent < 0 || exp.TrafficPercent > 100 {
+		return "traffic_percent должен быть от 0 до 100"
+	}
+	if exp.StartsAt != nil && exp.EndsAt != nil && !exp.EndsAt.After(*exp.StartsAt) {
+		return "ends_at должен быть позже starts_at"
+	}
+	return ""
+}
+
+// GetExperimentResults итоги эксперимента по вариантам (?goal= — конкретная цель)
+func (ac *AdminController) GetExperimentResults(c *gin.Context) {
+	var exp models.Experiment
+	if err := ac.db.Where("key = ?", c.Param("key")).First(&exp).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Эксперимент не найден"})
+		return
+	}
+	goal := strings.ToLower(c.Query("goal"))
+	results, err := experimentServices.Results(ac.db, exp.Key, goal)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Ошибка при расчете итогов"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"result": gin.H{
+			"experiment": exp,
+			"goal":       goal,
+			"variants":   results,
+		},
+		"success": true,
+	})
+}
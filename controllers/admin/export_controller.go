@@ -0,0 +1,67 @@
+package admin
+
+import (
+	"net/http"
+	"path/filepath"
+	"time"
+
+	exportServices "kliro/services/exports"
+	"kliro/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetExports список ночных выгрузок аналитики (манифесты, новые первыми)
+func (ac *AdminController) GetExports(c *gin.Context) {
+	manifests, err := exportServices.DefaultExporter().Manifests()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Ошибка при чтении выгрузок"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": manifests, "success": true})
+}
+
+// GetExportManifest манифест выгрузки за день
+func (ac *AdminController) GetExportManifest(c *gin.Context) {
+	m, err := exportServices.DefaultExporter().Manifest(c.Param("date"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Выгрузка не найдена"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": m, "success": true})
+}
+
+// DownloadExport скачивание файла набора данных из выгрузки за день
+func (ac *AdminController) DownloadExport(c *gin.Context) {
+	path, err := exportServices.DefaultExporter().FilePath(c.Param("date"), c.Param("dataset"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Файл выгрузки не найден"})
+		return
+	}
+	c.FileAttachment(path, c.Param("date")+"-"+filepath.Base(path))
+}
+
+// RunExport запускает выгрузку за день вручную (?date=YYYY-MM-DD, по умолчанию вчера); выполняется в фоне
+func (ac *AdminController) RunExport(c *gin.Context) {
+	if !exportServices.DefaultExporter().Configured() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": exportServices.ErrNoSalt.Error()})
+		return
+	}
+	now := utils.UzbekTime()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, -1)
+	if v := c.Query("date"); v != "" {
+		t, err := exportServices.ParseDate(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+		day = t
+	}
+
+	go func() {
+		if _, err := exportServices.DefaultExporter().Run(day); err != nil {
+			utils.LogError(err, "admin: run export")
+		}
+	}()
+	c.JSON(http.StatusAccepted, gin.H{"result": gin.H{"date": day.Format("2006-01-02")}, "success": true})
+}
@@ -0,0 +1,34 @@
+package admin
+
+import (
+	"net/http"
+	"strings"
+
+	funnelServices "kliro/services/funnel"
+	"kliro/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetFunnel воронка страхового сценария (osago, kasko, travel) за период ?from=&to= (YYYY-MM-DD, по умолчанию 30 дней):
+// доля прохождения шагов, разрез по страховым и по дням, медиана времени между шагами
+func (ac *AdminController) GetFunnel(c *gin.Context) {
+	flow := strings.ToLower(c.Param("flow"))
+	if _, ok := funnelServices.Steps[flow]; !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "flow должен быть osago, kasko или travel"})
+		return
+	}
+
+	from, to, errMsg := utils.ParseDateRange("from", c.Query("from"), "to", c.Query("to"), 0)
+	if errMsg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": errMsg})
+		return
+	}
+
+	report, err := funnelServices.BuildReport(ac.db, flow, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Ошибка при построении воронки"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": report, "success": true})
+}
@@ -0,0 +1,265 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"kliro/models"
+	leadServices "kliro/services/leads"
+	"kliro/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PartnerRequest запрос на создание/изменение банка-партнера
+type PartnerRequest struct {
+	BankName       string  `json:"bank_name"`
+	LeadChannel    *string `json:"lead_channel"`
+	LeadEmail      *string `json:"lead_email"`
+	LeadWebhookURL *string `json:"lead_webhook_url"`
+	LeadWebhookKey *string `json:"lead_webhook_key"`
+	LeadDirections *string `json:"lead_directions"`
+	ReportEmail    *string `json:"report_email"`
+	IsActive       *bool   `json:"is_active"`
+}
+
+// GetLeads список заявок (фильтры ?status=, ?direction=, ?bank=, ?partner_id=, ?from=, ?to=, page/size)
+func (ac *AdminController) GetLeads(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "0"))
+	size, _ := strconv.Atoi(c.DefaultQuery("size", "20"))
+	if page < 0 {
+		page = 0
+	}
+	if size < 1 || size > 100 {
+		size = 20
+	}
+
+	query := ac.db.Model(&models.Lead{})
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if direction := c.Query("direction"); direction != "" {
+		query = query.Where("direction = ?", strings.ToLower(direction))
+	}
+	if bank := c.Query("bank"); bank != "" {
+		query = query.Where("bank_name ILIKE ?", "%"+bank+"%")
+	}
+	if partnerID := c.Query("partner_id"); partnerID != "" {
+		query = query.Where("partner_id = ?", partnerID)
+	}
+	if from, err := time.Parse("2006-01-02", c.Query("from")); err == nil {
+		query = query.Where("created_at >= ?", from)
+	}
+	if to, err := time.Parse("2006-01-02", c.Query("to")); err == nil {
+		query = query.Where("created_at < ?", to.AddDate(0, 0, 1))
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var leads []models.Lead
+	if err := query.Order("created_at DESC").Offset(page * size).Limit(size).Find(&leads).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Ошибка при получении заявок"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"result": gin.H{"content": leads, "totalElements": total, "number": page, "size": size}, "success": true})
+}
+
+// GetLead заявка с историей статусов
+func (ac *AdminController) GetLead(c *gin.Context) {
+	var lead models.Lead
+	if err := ac.db.First(&lead, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Заявка не найдена"})
+		return
+	}
+	var history []models.LeadStatusHistory
+	ac.db.Where("lead_id = ?", lead.ID).Order("created_at ASC, id ASC").Find(&history)
+
+	c.JSON(http.StatusOK, gin.H{"result": gin.H{"lead": lead, "history": history}, "success": true})
+}
+
+// UpdateLeadStatus меняет статус заявки
+func (ac *AdminController) UpdateLeadStatus(c *gin.Context) {
+	var req struct {
+		Status  string `json:"status" binding:"required"`
+		Comment string `json:"comment"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "invalid request"})
+		return
+	}
+	if !leadServices.ValidStatuses[req.Status] {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Неверный статус"})
+		return
+	}
+
+	var lead models.Lead
+	if err := ac.db.First(&lead, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Заявка не найдена"})
+		return
+	}
+	if err := leadServices.DefaultService().ChangeStatus(&lead, req.Status, "admin", currentAdminID(c), req.Comment); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Не удалось обновить статус"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"result": lead, "success": true})
+}
+
+// ResendLead повторно отправляет заявку банку (синхронно, с результатом доставки)
+func (ac *AdminController) ResendLead(c *gin.Context) {
+	var lead models.Lead
+	if err := ac.db.First(&lead, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Заявка не найдена"})
+		return
+	}
+
+	// Партнер мог появиться после создания заявки
+	if partner := leadServices.DefaultService().FindPartner(lead.BankName, lead.Direction); partner != nil {
+		lead.PartnerID = &partner.ID
+		ac.db.Model(&lead).Update("partner_id", partner.ID)
+	}
+
+	if err := leadServices.DefaultService().Forward(&lead); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"result": lead, "success": false, "error": "Доставка не удалась: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"result": lead, "success": true})
+}
+
+// GetPartners список банков-партнеров
+func (ac *AdminController) GetPartners(c *gin.Context) {
+	var partners []models.BankPartner
+	if err := ac.db.Order("bank_name ASC").Find(&partners).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Ошибка при получении партнеров"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": partners, "success": true})
+}
+
+// CreatePartner создает банка-партнера; токен доступа возвращается только в этом ответе
+func (ac *AdminController) CreatePartner(c *gin.Context) {
+	var req PartnerRequest
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.BankName) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Укажите bank_name"})
+		return
+	}
+
+	partner := models.BankPartner{BankName: strings.TrimSpace(req.BankName), LeadChannel: "email", IsActive: true}
+	partner.BankSlug = utils.BankSlug(partner.BankName)
+	if errMsg := applyPartnerRequest(&partner, req); errMsg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": errMsg})
+		return
+	}
+
+	token, hash, err := utils.GenerateAPIToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Не удалось создать токен"})
+		return
+	}
+	partner.APITokenHash = hash
+
+	if err := ac.db.Create(&partner).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Партнер для этого банка уже существует"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"result": gin.H{"partner": partner, "api_token": token}, "success": true})
+}
+
+// UpdatePartner изменяет настройки доставки заявок партнера
+func (ac *AdminController) UpdatePartner(c *gin.Context) {
+	var partner models.BankPartner
+	if err := ac.db.First(&partner, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Партнер не найден"})
+		return
+	}
+
+	var req PartnerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "invalid request"})
+		return
+	}
+	if strings.TrimSpace(req.BankName) != "" {
+		partner.BankName = strings.TrimSpace(req.BankName)
+		partner.BankSlug = utils.BankSlug(partner.BankName)
+	}
+	if errMsg := applyPartnerRequest(&partner, req); errMsg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": errMsg})
+		return
+	}
+
+	if err := ac.db.Save(&partner).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Не удалось обновить партнера"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"result": partner, "success": true})
+}
+
+// RotatePartnerToken выпускает партнеру новый токен (старый перестает работать)
+func (ac *AdminController) RotatePartnerToken(c *gin.Context) {
+	var partner models.BankPartner
+	if err := ac.db.First(&partner, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Партнер не найден"})
+		return
+	}
+
+	token, hash, err := utils.GenerateAPIToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Не удалось создать токен"})
+		return
+	}
+	if err := ac.db.Model(&partner).Update("api_token_hash", hash).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Не удалось обновить токен"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"result": gin.H{"partner_id": partner.ID, "api_token": token}, "success": true})
+}
+
+// applyPartnerRequest переносит настройки доставки в партнера и проверяет их (возвращает текст ошибки)
+func applyPartnerRequest(p *models.BankPartner, req PartnerRequest) string {
+	if req.LeadChannel != nil {
+		p.LeadChannel = strings.ToLower(strings.TrimSpace(*req.LeadChannel))
+	}
+	if req.LeadEmail != nil {
+		p.LeadEmail = strings.TrimSpace(*req.LeadEmail)
+	}
+	if req.LeadWebhookURL != nil {
+		p.LeadWebhookURL = strings.TrimSpace(*req.LeadWebhookURL)
+	}
+	if req.LeadWebhookKey != nil {
+		p.LeadWebhookKey = *req.LeadWebhookKey
+	}
+	if req.LeadDirections != nil {
+		p.LeadDirections = strings.ToLower(strings.ReplaceAll(*req.LeadDirections, " ", ""))
+	}
+	if req.ReportEmail != nil {
+		p.ReportEmail = strings.TrimSpace(*req.ReportEmail)
+	}
+	if req.IsActive != nil {
+		p.IsActive = *req.IsActive
+	}
+
+	switch p.LeadChannel {
+	case "email":
+		if p.LeadEmail == "" {
+			return "Для канала email укажите lead_email"
+		}
+	case "webhook":
+		if !strings.HasPrefix(p.LeadWebhookURL, "http://") && !strings.HasPrefix(p.LeadWebhookURL, "https://") {
+			return "Для канала webhook укажите lead_webhook_url (http/https)"
+		}
+	default:
+		return "lead_channel должен быть email или webhook"
+	}
+	if p.BankSlug == "" {
+		return "Неверное название банка"
+	}
+	return ""
+}
@@ -0,0 +1,82 @@
+package admin
+
+import (
+	"net/http"
+	"strings"
+
+	"kliro/models"
+	osagoServices "kliro/services/osago"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OsagoTariffRequest запрос на создание/изменение коэффициента тарифа ОСАГО
+type OsagoTariffRequest struct {
+	Factor string   `json:"factor" binding:"required"` // base | region | vehicle_type | period | driver_restriction
+	Key    string   `json:"key" binding:"required"`
+	Value  *float64 `json:"value" binding:"required"`
+	Label  *string  `json:"label"`
+}
+
+// GetOsagoTariff коэффициенты регулируемого тарифа ОСАГО (фильтр ?factor=)
+func (ac *AdminController) GetOsagoTariff(c *gin.Context) {
+	query := ac.db.Model(&models.OsagoTariffCoefficient{})
+	if factor := c.Query("factor"); factor != "" {
+		query = query.Where("factor = ?", strings.ToLower(factor))
+	}
+	var coefficients []models.OsagoTariffCoefficient
+	if err := query.Order("factor ASC, key ASC").Find(&coefficients).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Ошибка при получении тарифа ОСАГО"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": coefficients, "success": true})
+}
+
+// UpsertOsagoTariff создает или изменяет коэффициент (по factor и key); действует на следующий расчет
+func (ac *AdminController) UpsertOsagoTariff(c *gin.Context) {
+	var req OsagoTariffRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Укажите factor, key и value"})
+		return
+	}
+	factor := strings.ToLower(strings.TrimSpace(req.Factor))
+	key := strings.ToLower(strings.TrimSpace(req.Key))
+	if !osagoServices.TariffFactors[factor] {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "factor должен быть base, region, vehicle_type, period или driver_restriction"})
+		return
+	}
+	if *req.Value <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "value должен быть больше 0"})
+		return
+	}
+
+	var coefficient models.OsagoTariffCoefficient
+	if err := ac.db.Where("factor = ? AND key = ?", factor, key).First(&coefficient).Error; err != nil {
+		coefficient = models.OsagoTariffCoefficient{Factor: factor, Key: key}
+	}
+	coefficient.Value = *req.Value
+	if req.Label != nil {
+		coefficient.Label = strings.TrimSpace(*req.Label)
+	}
+	coefficient.UpdatedBy = currentAdminID(c)
+
+	if err := ac.db.Save(&coefficient).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Не удалось сохранить коэффициент"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": coefficient, "success": true})
+}
+
+// DeleteOsagoTariff удаляет коэффициент; расчет с этим значением фактора станет недоступен
+func (ac *AdminController) DeleteOsagoTariff(c *gin.Context) {
+	result := ac.db.Delete(&models.OsagoTariffCoefficient{}, c.Param("id"))
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Не удалось удалить коэффициент"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Коэффициент не найден"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
@@ -0,0 +1,75 @@
+package admin
+
+import (
+	"fmt"
+	"net/http"
+
+	"kliro/models"
+	partnerServices "kliro/services/partners"
+	"kliro/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetPartnerReport отчет банка-партнера: показы, клики и заявки (?from=&to=, ?format=csv — выгрузка)
+func (ac *AdminController) GetPartnerReport(c *gin.Context) {
+	var partner models.BankPartner
+	if err := ac.db.First(&partner, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Партнер не найден"})
+		return
+	}
+	from, to, errMsg := utils.ParseDateRange("from", c.Query("from"), "to", c.Query("to"), 0)
+	if errMsg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": errMsg})
+		return
+	}
+
+	report, err := partnerServices.BuildReport(ac.db, partner, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Ошибка при построении отчета"})
+		return
+	}
+	if c.Query("format") == "csv" {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="kliro-report-%s-%s-%s.csv"`, partner.BankSlug, report.DateFrom, report.DateTo))
+		if err := partnerServices.WriteCSV(c.Writer, report); err != nil {
+			utils.LogError(err, "admin partner report: write csv")
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": report, "success": true})
+}
+
+// SendPartnerReport отправляет партнеру отчет за месяц вручную (?month=YYYY-MM, по умолчанию прошлый месяц)
+func (ac *AdminController) SendPartnerReport(c *gin.Context) {
+	var partner models.BankPartner
+	if err := ac.db.First(&partner, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Партнер не найден"})
+		return
+	}
+	if partner.ReportEmail == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "У партнера не указан report_email"})
+		return
+	}
+	month := c.DefaultQuery("month", partnerServices.PreviousMonth())
+	if _, _, err := partnerServices.MonthRange(month); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if err := partnerServices.SendMonthlyReport(ac.db, partner, month); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"success": false, "error": "Не удалось отправить отчет: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": gin.H{"partner_id": partner.ID, "month": month, "email": partner.ReportEmail}, "success": true})
+}
+
+// GetPartnerReportDeliveries журнал отправки ежемесячных отчетов партнеру
+func (ac *AdminController) GetPartnerReportDeliveries(c *gin.Context) {
+	var deliveries []models.PartnerReportDelivery
+	if err := ac.db.Where("partner_id = ?", c.Param("id")).Order("period DESC").Find(&deliveries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Ошибка при получении журнала отправки"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": deliveries, "success": true})
+}
@@ -0,0 +1,20 @@
+package admin
+
+import (
+	"net/http"
+
+	healthServices "kliro/services/providerhealth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetProvidersHealth состояние интеграций со страховыми (OSAGO, KASKO, travel, accident): circuit breaker
+// (closed/open/half_open), ошибки в текущем окне, p95 задержки и последние ошибки по каждой операции
+func (ac *AdminController) GetProvidersHealth(c *gin.Context) {
+	report, err := healthServices.Report()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Ошибка при чтении статистики провайдеров"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": report, "success": true})
+}
@@ -0,0 +1,47 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	quoteServices "kliro/services/quotes"
+	"kliro/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetQuoteReport конкурентность цен страховых: кто самый дешевый и как часто, средний разрыв цен,
+// доля ошибок и какую страховую выбрал пользователь.
+// Фильтры: ?product=osago|kasko, ?from=&to=, ?product_type=, ?period_id=, ?vehicle_type=, ?region=
+func (ac *AdminController) GetQuoteReport(c *gin.Context) {
+	product := strings.ToLower(c.DefaultQuery("product", quoteServices.ProductOsago))
+	if product != quoteServices.ProductOsago && product != quoteServices.ProductKasko {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "product должен быть osago или kasko"})
+		return
+	}
+	from, to, errMsg := utils.ParseDateRange("from", c.Query("from"), "to", c.Query("to"), 0)
+	if errMsg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": errMsg})
+		return
+	}
+
+	loc := utils.GetUzbekLocation()
+	filter := quoteServices.Filter{
+		Product:     product,
+		ProductType: c.Query("product_type"),
+		From:        time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc),
+		To:          time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, loc).AddDate(0, 0, 1),
+		VehicleType: c.Query("vehicle_type"),
+		Region:      c.Query("region"),
+	}
+	filter.PeriodID, _ = strconv.Atoi(c.Query("period_id"))
+
+	report, err := quoteServices.BuildReport(ac.db, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Ошибка при построении отчета"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": gin.H{"product": product, "from": from.Format("2006-01-02"), "to": to.Format("2006-01-02"), "report": report}, "success": true})
+}
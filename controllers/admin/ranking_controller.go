@@ -0,0 +1,230 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"kliro/models"
+	rankingServices "kliro/services/ranking"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UpdateRankingConfigRequest запрос на изменение настройки ранжирования направления
+type UpdateRankingConfigRequest struct {
+	Strategy       string `json:"strategy" binding:"required"`
+	TieBreaker     string `json:"tie_breaker" binding:"required"`
+	DiversifyBanks bool   `json:"diversify_banks"`
+}
+
+// PlacementRequest запрос на создание/изменение спонсорского размещения
+type PlacementRequest struct {
+	Direction    string     `json:"direction"`
+	BankName     string     `json:"bank_name"`
+	ProductMatch *string    `json:"product_match"`
+	Position     *int       `json:"position"`
+	Label        *string    `json:"label"`
+	StartsAt     *time.Time `json:"starts_at"`
+	EndsAt       *time.Time `json:"ends_at"`
+	IsActive     *bool      `json:"is_active"`
+}
+
+// GetRankingConfigs возвращает настройки ранжирования по всем направлениям (с учетом значений по умолчанию)
+func (ac *AdminController) GetRankingConfigs(c *gin.Context) {
+	var stored []models.RankingConfig
+	if err := ac.db.Find(&stored).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Ошибка при получении настроек"})
+		return
+	}
+
+	byDirection := make(map[string]models.RankingConfig, len(rankingServices.DefaultConfigs))
+	for direction, cfg := range rankingServices.DefaultConfigs {
+		byDirection[direction] = cfg
+	}
+	for _, cfg := range stored {
+		byDirection[cfg.Direction] = cfg
+	}
+
+	c.JSON(http.StatusOK, gin.H{"result": byDirection, "success": true})
+}
+
+// UpdateRankingConfig создает или изменяет настройку ранжирования направления
+func (ac *AdminController) UpdateRankingConfig(c *gin.Context) {
+	direction := strings.ToLower(c.Param("direction"))
+	if _, ok := rankingServices.DefaultConfigs[direction]; !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Неизвестное направление"})
+		return
+	}
+
+	var req UpdateRankingConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "invalid request"})
+		return
+	}
+	if !rankingServices.ValidStrategies[req.Strategy] {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Неверная стратегия сортировки"})
+		return
+	}
+	if !rankingServices.ValidTieBreakers[req.TieBreaker] {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Неверное правило разрешения равенства"})
+		return
+	}
+
+	var cfg models.RankingConfig
+	ac.db.Where("direction = ?", direction).First(&cfg)
+	cfg.Direction = direction
+	cfg.Strategy = req.Strategy
+	cfg.TieBreaker = req.TieBreaker
+	cfg.DiversifyBanks = req.DiversifyBanks
+	cfg.UpdatedBy = currentAdminID(c)
+
+	if err := ac.db.Save(&cfg).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Не удалось сохранить настройку"})
+		return
+	}
+	rankingServices.DefaultEngine().Invalidate()
+
+	c.JSON(http.StatusOK, gin.H{"result": cfg, "success": true})
+}
+
+// GetSponsoredPlacements список спонсорских размещений (фильтр ?direction=, ?active=true)
+func (ac *AdminController) GetSponsoredPlacements(c *gin.Context) {
+	query := ac.db.Model(&models.SponsoredPlacement{})
+	if direction := c.Query("direction"); direction != "" {
+		query = query.Where("direction = ?", strings.ToLower(direction))
+	}
+	if c.Query("active") == "true" {
+		now := time.Now()
+		query = query.Where("is_active = ? AND starts_at <= ? AND (ends_at IS NULL OR ends_at > ?)", true, now, now)
+	}
+
+	var placements []models.SponsoredPlacement
+	if err := query.Order("direction ASC, position ASC, id ASC").Find(&placements).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Ошибка при получении размещений"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"result": placements, "success": true})
+}
+
+// CreateSponsoredPlacement создает спонсорское размещение
+func (ac *AdminController) CreateSponsoredPlacement(c *gin.Context) {
+	var req PlacementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "invalid request"})
+		return
+	}
+
+	placement := models.SponsoredPlacement{Position: 1, Label: "promoted", StartsAt: time.Now(), IsActive: true}
+	if errMsg := applyPlacementRequest(&placement, req); errMsg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": errMsg})
+		return
+	}
+	placement.CreatedBy = currentAdminID(c)
+
+	if err := ac.db.Create(&placement).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Не удалось создать размещение"})
+		return
+	}
+	rankingServices.DefaultEngine().Invalidate()
+
+	c.JSON(http.StatusOK, gin.H{"result": placement, "success": true})
+}
+
+// UpdateSponsoredPlacement изменяет спонсорское размещение
+func (ac *AdminController) UpdateSponsoredPlacement(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Неверный ID размещения"})
+		return
+	}
+
+	var placement models.SponsoredPlacement
+	if err := ac.db.First(&placement, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Размещение не найдено"})
+		return
+	}
+
+	var req PlacementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "invalid request"})
+		return
+	}
+	if errMsg := applyPlacementRequest(&placement, req); errMsg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": errMsg})
+		return
+	}
+
+	if err := ac.db.Save(&placement).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Не удалось обновить размещение"})
+		return
+	}
+	rankingServices.DefaultEngine().Invalidate()
+
+	c.JSON(http.StatusOK, gin.H{"result": placement, "success": true})
+}
+
+// DeleteSponsoredPlacement удаляет спонсорское размещение
+func (ac *AdminController) DeleteSponsoredPlacement(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Неверный ID размещения"})
+		return
+	}
+
+	result := ac.db.Delete(&models.SponsoredPlacement{}, uint(id))
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Не удалось удалить размещение"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Размещение не найдено"})
+		return
+	}
+	rankingServices.DefaultEngine().Invalidate()
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// applyPlacementRequest переносит поля запроса в размещение и проверяет их (возвращает текст ошибки)
+func applyPlacementRequest(p *models.SponsoredPlacement, req PlacementRequest) string {
+	if req.Direction != "" {
+		p.Direction = strings.ToLower(req.Direction)
+	}
+	if _, ok := rankingServices.DefaultConfigs[p.Direction]; !ok {
+		return "Неизвестное направление"
+	}
+	if req.BankName != "" {
+		p.BankName = strings.TrimSpace(req.BankName)
+	}
+	if p.BankName == "" {
+		return "Укажите bank_name"
+	}
+	if req.ProductMatch != nil {
+		p.ProductMatch = strings.TrimSpace(*req.ProductMatch)
+	}
+	if req.Position != nil {
+		p.Position = *req.Position
+	}
+	if p.Position < 1 {
+		return "Позиция должна быть не меньше 1"
+	}
+	if req.Label != nil && strings.TrimSpace(*req.Label) != "" {
+		p.Label = strings.TrimSpace(*req.Label)
+	}
+	if req.StartsAt != nil {
+		p.StartsAt = *req.StartsAt
+	}
+	if req.EndsAt != nil {
+		p.EndsAt = req.EndsAt
+	}
+	if p.EndsAt != nil && !p.EndsAt.After(p.StartsAt) {
+		return "Дата окончания должна быть позже даты начала"
+	}
+	if req.IsActive != nil {
+		p.IsActive = *req.IsActive
+	}
+	return ""
+}
@@ -0,0 +1,130 @@
+package admin
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"kliro/models"
+	redirectServices "kliro/services/redirect"
+	"kliro/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RedirectConfigRequest запрос на создание/изменение партнерской ссылки банка
+type RedirectConfigRequest struct {
+	BankName    string  `json:"bank_name" binding:"required"`
+	DefaultURL  *string `json:"default_url"`
+	UTMSource   *string `json:"utm_source"`
+	UTMMedium   *string `json:"utm_medium"`
+	UTMCampaign *string `json:"utm_campaign"`
+	ExtraParams *string `json:"extra_params"`
+	IsActive    *bool   `json:"is_active"`
+}
+
+// GetRedirectConfigs список партнерских ссылок и UTM-меток банков
+func (ac *AdminController) GetRedirectConfigs(c *gin.Context) {
+	var configs []models.BankRedirectConfig
+	if err := ac.db.Order("bank_name ASC").Find(&configs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Ошибка при получении настроек ссылок"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": configs, "success": true})
+}
+
+// UpsertRedirectConfig создает или изменяет настройку банка (по bank_name)
+func (ac *AdminController) UpsertRedirectConfig(c *gin.Context) {
+	var req RedirectConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Укажите bank_name"})
+		return
+	}
+	slug := utils.BankSlug(req.BankName)
+	if slug == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Неверное название банка"})
+		return
+	}
+
+	var cfg models.BankRedirectConfig
+	if err := ac.db.Where("bank_slug = ?", slug).First(&cfg).Error; err != nil {
+		cfg = models.BankRedirectConfig{BankSlug: slug, IsActive: true}
+	}
+	cfg.BankName = strings.TrimSpace(req.BankName)
+
+	if req.DefaultURL != nil {
+		cfg.DefaultURL = strings.TrimSpace(*req.DefaultURL)
+	}
+	if req.UTMSource != nil {
+		cfg.UTMSource = strings.TrimSpace(*req.UTMSource)
+	}
+	if req.UTMMedium != nil {
+		cfg.UTMMedium = strings.TrimSpace(*req.UTMMedium)
+	}
+	if req.UTMCampaign != nil {
+		cfg.UTMCampaign = strings.TrimSpace(*req.UTMCampaign)
+	}
+	if req.ExtraParams != nil {
+		cfg.ExtraParams = strings.TrimPrefix(strings.TrimSpace(*req.ExtraParams), "?")
+	}
+	if req.IsActive != nil {
+		cfg.IsActive = *req.IsActive
+	}
+
+	if cfg.DefaultURL != "" && !strings.HasPrefix(cfg.DefaultURL, "http://") && !strings.HasPrefix(cfg.DefaultURL, "https://") {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "default_url должен начинаться с http:// или https://"})
+		return
+	}
+	if _, err := url.ParseQuery(cfg.ExtraParams); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "extra_params должен быть в формате key=value&key2=value2"})
+		return
+	}
+
+	if err := ac.db.Save(&cfg).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Не удалось сохранить настройку"})
+		return
+	}
+	redirectServices.DefaultResolver().Invalidate()
+
+	c.JSON(http.StatusOK, gin.H{"result": cfg, "success": true})
+}
+
+// DeleteRedirectConfig удаляет настройку банка
+func (ac *AdminController) DeleteRedirectConfig(c *gin.Context) {
+	result := ac.db.Delete(&models.BankRedirectConfig{}, c.Param("id"))
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Не удалось удалить настройку"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Настройка не найдена"})
+		return
+	}
+	redirectServices.DefaultResolver().Invalidate()
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// GetOutboundClicks последние переходы на сайты банков (фильтры ?direction=, ?bank=, ?limit=)
+func (ac *AdminController) GetOutboundClicks(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if limit < 1 || limit > 1000 {
+		limit = 100
+	}
+
+	query := ac.db.Model(&models.OutboundClick{})
+	if direction := c.Query("direction"); direction != "" {
+		query = query.Where("direction = ?", strings.ToLower(direction))
+	}
+	if bank := c.Query("bank"); bank != "" {
+		query = query.Where("bank_name ILIKE ?", "%"+bank+"%")
+	}
+
+	var clicks []models.OutboundClick
+	if err := query.Order("created_at DESC").Limit(limit).Find(&clicks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Ошибка при получении переходов"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": clicks, "success": true})
+}
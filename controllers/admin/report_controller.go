@@ -0,0 +1,178 @@
+package admin
+
+import (
+	"net/http"
+	"strings"
+
+	"kliro/models"
+	reportServices "kliro/services/reports"
+	"kliro/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReportScheduleRequest запрос на создание/изменение расписания сводки
+type ReportScheduleRequest struct {
+	Name       *string `json:"name"`
+	Kind       *string `json:"kind"`
+	Recipients *string `json:"recipients"`
+	SendHour   *int    `json:"send_hour"`
+	Weekday    *int    `json:"weekday"`
+	Sections   *string `json:"sections"`
+	IsActive   *bool   `json:"is_active"`
+}
+
+// GetReportSchedules список расписаний email-сводок
+func (ac *AdminController) GetReportSchedules(c *gin.Context) {
+	var schedules []models.ReportSchedule
+	if err := ac.db.Order("id ASC").Find(&schedules).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Ошибка при получении расписаний"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": schedules, "success": true})
+}
+
+// CreateReportSchedule создает расписание сводки
+func (ac *AdminController) CreateReportSchedule(c *gin.Context) {
+	var req ReportScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Неверный формат запроса"})
+		return
+	}
+	schedule := models.ReportSchedule{Kind: reportServices.KindDaily, SendHour: 9, Weekday: 1, IsActive: true}
+	if msg := applyReportScheduleRequest(&schedule, req); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": msg})
+		return
+	}
+	schedule.UpdatedBy = currentAdminID(c)
+
+	if err := ac.db.Create(&schedule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Не удалось создать расписание"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"result": schedule, "success": true})
+}
+
+// UpdateReportSchedule изменяет расписание сводки
+func (ac *AdminController) UpdateReportSchedule(c *gin.Context) {
+	var schedule models.ReportSchedule
+	if err := ac.db.First(&schedule, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Расписание не найдено"})
+		return
+	}
+	var req ReportScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Неверный формат запроса"})
+		return
+	}
+	if msg := applyReportScheduleRequest(&schedule, req); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": msg})
+		return
+	}
+	schedule.UpdatedBy = currentAdminID(c)
+
+	if err := ac.db.Save(&schedule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Не удалось сохранить расписание"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": schedule, "success": true})
+}
+
+// DeleteReportSchedule удаляет расписание сводки
+func (ac *AdminController) DeleteReportSchedule(c *gin.Context) {
+	result := ac.db.Delete(&models.ReportSchedule{}, c.Param("id"))
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Не удалось удалить расписание"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Расписание не найдено"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// SendReportSchedule отправляет сводку по расписанию прямо сейчас
+func (ac *AdminController) SendReportSchedule(c *gin.Context) {
+	var schedule models.ReportSchedule
+	if err := ac.db.First(&schedule, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Расписание не найдено"})
+		return
+	}
+	if err := reportServices.Send(ac.db, &schedule); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"success": false, "error": "Не удалось отправить сводку: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": schedule, "success": true})
+}
+
+// GetReportSummary предпросмотр сводки (?kind=daily|weekly, ?sections=, ?format=html)
+func (ac *AdminController) GetReportSummary(c *gin.Context) {
+	kind := c.DefaultQuery("kind", reportServices.KindDaily)
+	if kind != reportServices.KindDaily && kind != reportServices.KindWeekly {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "kind должен быть daily или weekly"})
+		return
+	}
+	from, to := reportServices.Period(kind, utils.UzbekTime())
+	summary, err := reportServices.Build(ac.db, kind, from, to, reportServices.ParseSections(c.Query("sections")))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Ошибка при построении сводки"})
+		return
+	}
+
+	if c.Query("format") == "html" {
+		body, err := reportServices.RenderHTML(summary)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Ошибка при построении сводки"})
+			return
+		}
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(body))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": summary, "success": true})
+}
+
+// applyReportScheduleRequest переносит поля запроса в расписание и проверяет их; возвращает текст ошибки
+func applyReportScheduleRequest(s *models.ReportSchedule, req ReportScheduleRequest) string {
+	if req.Name != nil {
+		s.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Kind != nil {
+		s.Kind = strings.ToLower(strings.TrimSpace(*req.Kind))
+	}
+	if req.Recipients != nil {
+		s.Recipients = strings.TrimSpace(*req.Recipients)
+	}
+	if req.SendHour != nil {
+		s.SendHour = *req.SendHour
+	}
+	if req.Weekday != nil {
+		s.Weekday = *req.Weekday
+	}
+	if req.Sections != nil {
+		s.Sections = strings.ToLower(strings.ReplaceAll(*req.Sections, " ", ""))
+	}
+	if req.IsActive != nil {
+		s.IsActive = *req.IsActive
+	}
+
+	if s.Name == "" {
+		return "Укажите name"
+	}
+	if s.Kind != reportServices.KindDaily && s.Kind != reportServices.KindWeekly {
+		return "kind должен быть daily или weekly"
+	}
+	if _, err := reportServices.ParseRecipients(s.Recipients); err != nil {
+		return err.Error()
+	}
+	if s.SendHour < 0 || s.SendHour > 23 {
+		return "send_hour должен быть от 0 до 23"
+	}
+	if s.Weekday < 0 || s.Weekday > 6 {
+		return "weekday должен быть от 0 (воскресенье) до 6 (суббота)"
+	}
+	if s.Sections != "" && len(reportServices.ParseSections(s.Sections)) == 0 {
+		return "sections: users, orders, payments, insurance, clicks, parsers"
+	}
+	return ""
+}
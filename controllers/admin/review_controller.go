@@ -0,0 +1,75 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"kliro/models"
+	reviewServices "kliro/services/reviews"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetReviewQueue очередь модерации отзывов (?status=pending по умолчанию, ?target_type=, page/size)
+func (ac *AdminController) GetReviewQueue(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "0"))
+	size, _ := strconv.Atoi(c.DefaultQuery("size", "20"))
+	if page < 0 {
+		page = 0
+	}
+	if size < 1 || size > 100 {
+		size = 20
+	}
+
+	query := ac.db.Model(&models.Review{}).Where("status = ?", c.DefaultQuery("status", reviewServices.StatusPending))
+	if targetType := c.Query("target_type"); targetType != "" {
+		query = query.Where("target_type = ?", targetType)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var reviews []models.Review
+	// Старые отзывы первыми — чтобы очередь разбиралась по порядку
+	if err := query.Order("updated_at ASC, id ASC").Offset(page * size).Limit(size).Find(&reviews).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Ошибка при получении отзывов"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"result": gin.H{"content": reviews, "totalElements": total, "number": page, "size": size}, "success": true})
+}
+
+// ModerateReview одобряет или отклоняет отзыв
+func (ac *AdminController) ModerateReview(c *gin.Context) {
+	var req struct {
+		Status string `json:"status" binding:"required"`
+		Note   string `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "invalid request"})
+		return
+	}
+	if req.Status != reviewServices.StatusApproved && req.Status != reviewServices.StatusRejected && req.Status != reviewServices.StatusPending {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Статус должен быть approved, rejected или pending"})
+		return
+	}
+
+	var review models.Review
+	if err := ac.db.First(&review, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Отзыв не найден"})
+		return
+	}
+
+	now := time.Now()
+	review.Status = req.Status
+	review.ModerationNote = req.Note
+	review.ModeratedBy = currentAdminID(c)
+	review.ModeratedAt = &now
+	if err := ac.db.Save(&review).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Не удалось обновить отзыв"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"result": review, "success": true})
+}
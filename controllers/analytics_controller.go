@@ -3,6 +3,7 @@ package controllers
 import (
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -10,6 +11,7 @@ import (
 	"gorm.io/gorm"
 
 	"kliro/models"
+	clickServices "kliro/services/clicks"
 	"kliro/utils"
 )
 
@@ -33,6 +35,9 @@ type TrackClickRequest struct {
 	Key       string `json:"key" binding:"required"`
 	Direction string `json:"direction" binding:"required"`
 	URL       string `json:"url" binding:"required"`
+	BankName  string `json:"bank_name"` // необязательно: иначе банк берется из ключа продукта
+	Lang      string `json:"lang"`
+	Platform  string `json:"platform"`
 }
 
 func (ac *AnalyticsController) TrackClick(c *gin.Context) {
@@ -51,72 +56,107 @@ func (ac *AnalyticsController) TrackClick(c *gin.Context) {
 	}
 
 	db := utils.GetDB()
+	productClick, err := incrementProductClick(db, req.Key, req.Direction, req.URL)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	event := models.ClickEvent{
+		Key:       req.Key,
+		Direction: strings.ToLower(req.Direction),
+		BankSlug:  utils.BankSlug(req.BankName),
+		Platform:  requestPlatform(c),
+		Lang:      requestLang(c),
+		Source:    clickServices.SourceTrack,
+	}
+	if lang := strings.ToLower(req.Lang); lang == "uz" || lang == "ru" || lang == "en" {
+		event.Lang = lang
+	}
+	if platform := strings.ToLower(req.Platform); platform == "ios" || platform == "android" || platform == "web" {
+		event.Platform = platform
+	}
+	if userID := uint(c.GetInt("user_id")); userID > 0 {
+		event.UserID = &userID
+	}
+	clickServices.Record(db, event)
 
+	c.JSON(http.StatusOK, gin.H{
+		"result": gin.H{
+			"key":         productClick.Key,
+			"direction":   productClick.Direction,
+			"click_count": productClick.ClickCount,
+			"message":     "Click tracked successfully",
+		},
+	})
+}
+
+// incrementProductClick увеличивает счетчик переходов по продукту (создает запись при первом клике)
+func incrementProductClick(db *gorm.DB, key, direction, url string) (models.ProductClick, error) {
 	var productClick models.ProductClick
-	result := db.Where("key = ? AND direction = ?", req.Key, req.Direction).First(&productClick)
+	result := db.Where("key = ? AND direction = ?", key, direction).First(&productClick)
 
 	if result.Error == gorm.ErrRecordNotFound {
 		productClick = models.ProductClick{
-			Key:        req.Key,
-			Direction:  req.Direction,
-			URL:        req.URL,
+			Key:        key,
+			Direction:  direction,
+			URL:        url,
 			ClickCount: 1,
 		}
 		if err := db.Create(&productClick).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create click record"})
-			return
+			return productClick, fmt.Errorf("failed to create click record")
 		}
 	} else if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
-		return
+		return productClick, fmt.Errorf("database error")
 	} else {
 		productClick.ClickCount++
-		productClick.URL = req.URL
+		productClick.URL = url
 		if err := db.Save(&productClick).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update click count"})
-			return
+			return productClick, fmt.Errorf("failed to update click count")
 		}
 	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"result": gin.H{
-			"key":         productClick.Key,
-			"direction":   productClick.Direction,
-			"click_count": productClick.ClickCount,
-			"message":     "Click tracked successfully",
-		},
-	})
+	return productClick, nil
 }
 
-func (ac *AnalyticsController) GetAllClicks(c *gin.Context) {
-	db := utils.GetDB()
+// analyticsMaxDays - максимальная длина периода для дневных отчетов (для почасовых - 31 день)
+const analyticsMaxDays = 366
 
-	var clicks []models.ProductClick
-	if err := db.Order("click_count DESC").Find(&clicks).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch clicks"})
-		return
+// clickFilter разбирает общие параметры отчетов: date_from, date_to (YYYY-MM-DD, по умолчанию последние 30 дней),
+// direction (из пути или query), key и bank
+func clickFilter(c *gin.Context) (clickServices.Filter, string) {
+	var f clickServices.Filter
+
+	var errMsg string
+	if f.From, f.To, errMsg = utils.ParseDateRange("date_from", c.Query("date_from"), "date_to", c.Query("date_to"), analyticsMaxDays); errMsg != "" {
+		return f, errMsg
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"result": clicks,
-	})
+	direction := strings.ToLower(c.Param("direction"))
+	if direction == "" {
+		direction = strings.ToLower(c.Query("direction"))
+	}
+	if direction != "" && !validDirections[direction] {
+		validDirs := []string{"deposit", "card", "credit", "mortgage", "microcredit", "autocredit", "transfer"}
+		return f, fmt.Sprintf("invalid direction. Valid directions are: %s", strings.Join(validDirs, ", "))
+	}
+	f.Direction = direction
+	f.Key = c.Query("key")
+	if bank := c.Query("bank"); bank != "" {
+		f.BankSlug = utils.BankSlug(bank)
+	}
+	return f, ""
 }
 
-func (ac *AnalyticsController) GetClicksByDirection(c *gin.Context) {
-	direction := c.Param("direction")
-
-	if !validDirections[strings.ToLower(direction)] {
-		validDirs := []string{"deposit", "card", "credit", "mortgage", "microcredit", "autocredit", "transfer"}
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": fmt.Sprintf("invalid direction. Valid directions are: %s", strings.Join(validDirs, ", ")),
-		})
+// GET /analytics/bank/clicks — клики по продуктам за период (?date_from, ?date_to, ?direction, ?bank)
+func (ac *AnalyticsController) GetAllClicks(c *gin.Context) {
+	f, errMsg := clickFilter(c)
+	if errMsg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
 		return
 	}
 
-	db := utils.GetDB()
-
-	var clicks []models.ProductClick
-	if err := db.Where("direction = ?", direction).Order("click_count DESC").Find(&clicks).Error; err != nil {
+	clicks, err := clickServices.Totals(utils.GetDB(), f, 0)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch clicks"})
 		return
 	}
@@ -126,54 +166,66 @@ func (ac *AnalyticsController) GetClicksByDirection(c *gin.Context) {
 	})
 }
 
-func (ac *AnalyticsController) GetClicksByDirectionAndDate(c *gin.Context) {
-	direction := c.Param("direction")
-	dateFrom := c.Query("date_from")
-	dateTo := c.Query("date_to")
-	key := c.Query("key")
+// GET /analytics/bank/clicks/:direction — клики по продуктам направления за период
+func (ac *AnalyticsController) GetClicksByDirection(c *gin.Context) {
+	ac.GetAllClicks(c)
+}
 
-	if dateFrom == "" || dateTo == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "date_from and date_to are required in format YYYY-MM-DD"})
+// GET /analytics/bank/clicks/:direction/by-date — клики по дням (или ?period=hour — по часам) за период,
+// с фильтрами ?key= и ?bank=
+func (ac *AnalyticsController) GetClicksByDirectionAndDate(c *gin.Context) {
+	f, errMsg := clickFilter(c)
+	if errMsg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
 		return
 	}
 
-	if !validDirections[strings.ToLower(direction)] {
-		validDirs := []string{"deposit", "card", "credit", "mortgage", "microcredit", "autocredit", "transfer"}
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": fmt.Sprintf("invalid direction. Valid directions are: %s", strings.Join(validDirs, ", ")),
-		})
-		return
+	period := clickServices.PeriodDay
+	if c.Query("period") == clickServices.PeriodHour {
+		if f.To.Sub(f.From) > 30*24*time.Hour {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "hourly range must not exceed 31 days"})
+			return
+		}
+		period = clickServices.PeriodHour
 	}
 
-	fromTime, err := time.Parse("2006-01-02", dateFrom)
+	series, err := clickServices.Series(utils.GetDB(), f, period)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "date_from must be YYYY-MM-DD"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch clicks"})
 		return
 	}
 
-	toTime, err := time.Parse("2006-01-02", dateTo)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "date_to must be YYYY-MM-DD"})
-		return
+	total := 0
+	for _, p := range series {
+		total += p.Clicks
 	}
 
-	if toTime.Before(fromTime) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "date_to must be >= date_from"})
+	c.JSON(http.StatusOK, gin.H{
+		"result": gin.H{
+			"date_from": f.From.Format("2006-01-02"),
+			"date_to":   f.To.Format("2006-01-02"),
+			"period":    period,
+			"total":     total,
+			"series":    series,
+		},
+	})
+}
+
+// GET /analytics/bank/top-clicks — самые кликаемые продукты за период (?limit=, по умолчанию 10)
+func (ac *AnalyticsController) GetTopClicks(c *gin.Context) {
+	f, errMsg := clickFilter(c)
+	if errMsg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
 		return
 	}
-
-	toTime = toTime.Add(23*time.Hour + 59*time.Minute + 59*time.Second)
-
-	db := utils.GetDB()
-
-	var clicks []models.ProductClick
-	query := db.Where("direction = ? AND created_at BETWEEN ? AND ?", direction, fromTime, toTime)
-	if key != "" {
-		query = query.Where("key = ?", key)
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if limit < 1 || limit > 100 {
+		limit = 10
 	}
 
-	if err := query.Order("created_at DESC").Find(&clicks).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch clicks"})
+	clicks, err := clickServices.Totals(utils.GetDB(), f, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch top clicks"})
 		return
 	}
 
@@ -182,38 +234,59 @@ func (ac *AnalyticsController) GetClicksByDirectionAndDate(c *gin.Context) {
 	})
 }
 
-func (ac *AnalyticsController) GetTopClicks(c *gin.Context) {
-	db := utils.GetDB()
+type DirectionStats struct {
+	Direction   string `json:"direction"`
+	TotalClicks int    `json:"total_clicks"`
+}
 
-	var clicks []models.ProductClick
-	if err := db.Order("click_count DESC").Limit(10).Find(&clicks).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch top clicks"})
+// GET /analytics/bank/stats-by-direction — клики по направлениям за период
+func (ac *AnalyticsController) GetStatsByDirection(c *gin.Context) {
+	f, errMsg := clickFilter(c)
+	if errMsg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
+
+	totals, err := clickServices.TotalsBy(utils.GetDB(), f, "direction")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch stats"})
 		return
 	}
 
+	stats := make([]DirectionStats, 0, len(totals))
+	for _, t := range totals {
+		stats = append(stats, DirectionStats{Direction: t.Name, TotalClicks: t.TotalClicks})
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"result": clicks,
+		"result": stats,
 	})
 }
 
-type DirectionStats struct {
-	Direction   string `json:"direction"`
+type BankStats struct {
+	BankSlug    string `json:"bank_slug"`
 	TotalClicks int    `json:"total_clicks"`
 }
 
-func (ac *AnalyticsController) GetStatsByDirection(c *gin.Context) {
-	db := utils.GetDB()
+// GET /analytics/bank/stats-by-bank — клики по банкам за период (?direction=)
+func (ac *AnalyticsController) GetStatsByBank(c *gin.Context) {
+	f, errMsg := clickFilter(c)
+	if errMsg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
 
-	var stats []DirectionStats
-	if err := db.Model(&models.ProductClick{}).
-		Select("direction, SUM(click_count) as total_clicks").
-		Group("direction").
-		Order("total_clicks DESC").
-		Find(&stats).Error; err != nil {
+	totals, err := clickServices.TotalsBy(utils.GetDB(), f, "bank_slug")
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch stats"})
 		return
 	}
 
+	stats := make([]BankStats, 0, len(totals))
+	for _, t := range totals {
+		stats = append(stats, BankStats{BankSlug: t.Name, TotalClicks: t.TotalClicks})
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"result": stats,
 	})
@@ -2,8 +2,12 @@ package bank
 
 import (
 	"kliro/models"
+	editorialServices "kliro/services/editorial"
+	partnerServices "kliro/services/partners"
+	rankingServices "kliro/services/ranking"
+	redirectServices "kliro/services/redirect"
+	reviewServices "kliro/services/reviews"
 	"kliro/utils"
-	"math/rand"
 	"net/http"
 	"sort"
 	"strconv"
@@ -48,16 +52,14 @@ type TranslatedAutocreditResponseByPagination struct {
 	Empty            bool                          `json:"empty"`
 }
 
-// GetNewAutocredits получает новые автокредиты с пагинацией (порядок каждый раз случайный).
+// GetNewAutocredits получает новые автокредиты с пагинацией (порядок задает движок ранжирования).
 func (ac *AutocreditController) GetNewAutocredits(c *gin.Context) {
 	ac.getAutocreditsWithPagination(c, "new_autocredit", true)
 }
 
 // getAutocreditsWithPagination общая функция для получения автокредитов с пагинацией.
-// shuffleOrder: при true порядок случайный каждый раз.
-func (ac *AutocreditController) getAutocreditsWithPagination(c *gin.Context, tableName string, shuffleOrder bool) {
-	db := utils.GetDB()
-
+// ranked: при true и без явного sort порядок задает движок ранжирования.
+func (ac *AutocreditController) getAutocreditsWithPagination(c *gin.Context, tableName string, ranked bool) {
 	// Параметры пагинации
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "0"))
 	size, _ := strconv.Atoi(c.DefaultQuery("size", "10"))
@@ -71,6 +73,91 @@ func (ac *AutocreditController) getAutocreditsWithPagination(c *gin.Context, tab
 		size = 10
 	}
 
+	list, err := ac.listAutocredits(c, tableName, ranked)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при получении данных"})
+		return
+	}
+	filtered, overrides, ranking, useRanking := list.Items, list.Overrides, list.Ranking, list.Ranked
+
+	// Итоги и пагинация
+	totalElements := int64(len(filtered))
+	totalPages := int((totalElements + int64(size) - 1) / int64(size))
+	if totalPages > 0 {
+		lastPageOffset := (totalPages - 1) * size
+		if lastPageOffset >= len(filtered) {
+			totalPages = totalPages - 1
+		}
+	}
+	offset := page * size
+	end := offset + size
+	if offset > len(filtered) {
+		offset = len(filtered)
+	}
+	if end > len(filtered) {
+		end = len(filtered)
+	}
+	pageItems := filtered[offset:end]
+
+	// Применяем переводы к каждому элементу
+	translator := utils.GetMicrocreditTranslator()
+	// Оценки по одобренным отзывам — одним запросом на страницу
+	keys := productKeys(pageItems, autocreditKey)
+	ratings := reviewServices.ProductAggregates(keys)
+	// Показы продуктов в выдаче — для отчетов банкам-партнерам
+	partnerServices.RecordImpressions("autocredit", keys)
+	redirects := redirectServices.DefaultResolver()
+	translatedContent := make([]utils.TranslatedAutocredit, 0, len(pageItems))
+	
+	for _, item := range pageItems {
+		translated := translator.TranslateAutocredit(
+			item.BankName,
+			item.Description,
+			item.Rate,
+			item.Term,
+			item.Amount,
+			item.Channel,
+		)
+		// Заполняем остальные поля
+		translated.ID = item.ID
+		translated.CreatedAt = item.CreatedAt.Format("2006-01-02T15:04:05.000000Z")
+		translated.ProductKey = autocreditKey(item)
+		translated.Promoted, translated.PromotionLabel = ranking.PromotionByKey(translated.ProductKey)
+		translated.Pinned = overrides.Pinned(translated.ProductKey)
+		translated.EditorialNote = overrides.Note(translated.ProductKey)
+		translated.Rating, translated.ReviewCount = ratings.Get(translated.ProductKey)
+		translated.RedirectURL = redirects.Link("autocredit", translated.ProductKey, item.BankName, "")
+		translatedContent = append(translatedContent, translated)
+	}
+
+	sortObj := Sort{Direction: strings.ToUpper(sortDir), NullHandling: "NATIVE", Ascending: strings.ToLower(sortDir) == "asc", Property: sortBy, IgnoreCase: false}
+	response := TranslatedAutocreditResponseByPagination{
+		TotalPages:       totalPages,
+		TotalElements:    totalElements,
+		First:            page == 0,
+		Last:             page >= totalPages-1,
+		Size:             size,
+		Content:          translatedContent,
+		Number:           page,
+		Sort:             []Sort{sortObj},
+		NumberOfElements: len(pageItems),
+		Pageable:         Pageable{Offset: offset, Sort: []Sort{sortObj}, Paged: true, PageNumber: page, PageSize: size, Unpaged: false},
+		Empty:            len(pageItems) == 0,
+	}
+
+	resp := gin.H{"result": response, "success": true}
+	if useRanking {
+		resp["ranking"] = ranking.Meta
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// listAutocredits возвращает отфильтрованный и отсортированный список без пагинации — общий для выдачи и экспорта.
+func (ac *AutocreditController) listAutocredits(c *gin.Context, tableName string, ranked bool) (productList[models.Autocredit], error) {
+	db := utils.GetDB()
+	sortBy := c.DefaultQuery("sort", "bank_name")
+	sortDir := c.DefaultQuery("direction", "asc")
+
 	// Фильтры (строки)
 	bank := c.Query("bank")
 	search := c.Query("search")
@@ -150,10 +237,13 @@ func (ac *AutocreditController) getAutocreditsWithPagination(c *gin.Context, tab
 	// Грузим кандидатов, применяем числовые фильтры в памяти
 	var all []models.Autocredit
 	if err := baseQ.Find(&all).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при получении данных"})
-		return
+		return productList[models.Autocredit]{}, err
 	}
 
+	// Редакционные правки: исправленные поля и скрытые продукты (до фильтров)
+	overrides := editorialServices.DefaultStore().ForDirection("autocredit")
+	all = editorialServices.Apply(overrides, all, autocreditKey)
+
 	filtered := make([]models.Autocredit, 0, len(all))
 	for _, a := range all {
 		if useRateFrom {
@@ -177,13 +267,16 @@ func (ac *AutocreditController) getAutocreditsWithPagination(c *gin.Context, tab
 		filtered = append(filtered, a)
 	}
 
-	// Случайный порядок для /autocredits/new
-	if shuffleOrder && len(filtered) > 1 {
-		rand.Shuffle(len(filtered), func(i, j int) { filtered[i], filtered[j] = filtered[j], filtered[i] })
+	// Ранжирование для /autocredits/new (детерминированно, seed и спонсорские места)
+	useRanking := ranked && c.Query("sort") == ""
+	var ranking rankingServices.Ranked[models.Autocredit]
+	if useRanking {
+		ranking = rankingServices.Rank(rankingServices.DefaultEngine(), "autocredit", c.Query("seed"), filtered, autocreditFeatures, rankingOverride(c, "autocredit"))
+		filtered = ranking.Items
 	}
 
-	// Сортировка ДО пагинации (не применяем при shuffleOrder)
-	if !shuffleOrder {
+	// Сортировка ДО пагинации (не применяем при ранжировании)
+	if !useRanking {
 		if useRateFrom {
 			sort.SliceStable(filtered, func(i, j int) bool {
 				rateI := utils.ExtractFirstFloat(filtered[i].Rate)
@@ -200,58 +293,8 @@ func (ac *AutocreditController) getAutocreditsWithPagination(c *gin.Context, tab
 		}
 	}
 
-	// Итоги и пагинация
-	totalElements := int64(len(filtered))
-	totalPages := int((totalElements + int64(size) - 1) / int64(size))
-	if totalPages > 0 {
-		lastPageOffset := (totalPages - 1) * size
-		if lastPageOffset >= len(filtered) {
-			totalPages = totalPages - 1
-		}
-	}
-	offset := page * size
-	end := offset + size
-	if offset > len(filtered) {
-		offset = len(filtered)
-	}
-	if end > len(filtered) {
-		end = len(filtered)
-	}
-	pageItems := filtered[offset:end]
-
-	// Применяем переводы к каждому элементу
-	translator := utils.GetMicrocreditTranslator()
-	translatedContent := make([]utils.TranslatedAutocredit, 0, len(pageItems))
-	
-	for _, item := range pageItems {
-		translated := translator.TranslateAutocredit(
-			item.BankName,
-			item.Description,
-			item.Rate,
-			item.Term,
-			item.Amount,
-			item.Channel,
-		)
-		// Заполняем остальные поля
-		translated.ID = item.ID
-		translated.CreatedAt = item.CreatedAt.Format("2006-01-02T15:04:05.000000Z")
-		translatedContent = append(translatedContent, translated)
-	}
-
-	sortObj := Sort{Direction: strings.ToUpper(sortDir), NullHandling: "NATIVE", Ascending: strings.ToLower(sortDir) == "asc", Property: sortBy, IgnoreCase: false}
-	response := TranslatedAutocreditResponseByPagination{
-		TotalPages:       totalPages,
-		TotalElements:    totalElements,
-		First:            page == 0,
-		Last:             page >= totalPages-1,
-		Size:             size,
-		Content:          translatedContent,
-		Number:           page,
-		Sort:             []Sort{sortObj},
-		NumberOfElements: len(pageItems),
-		Pageable:         Pageable{Offset: offset, Sort: []Sort{sortObj}, Paged: true, PageNumber: page, PageSize: size, Unpaged: false},
-		Empty:            len(pageItems) == 0,
-	}
+	// Закрепленные редакцией продукты — в начало списка
+	filtered = editorialServices.Pin(overrides, filtered, autocreditKey)
 
-	c.JSON(http.StatusOK, gin.H{"result": response, "success": true})
+	return productList[models.Autocredit]{Items: filtered, Overrides: overrides, Ranking: ranking, Ranked: useRanking}, nil
 }
@@ -0,0 +1,388 @@
+package bank
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"kliro/models"
+	"kliro/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bankProfileCacheTTL - страховочный TTL кэша профиля (основная инвалидация — по версии данных парсеров)
+const bankProfileCacheTTL = 24 * time.Hour
+
+// BankProfileInfo - справочная информация о банке
+type BankProfileInfo struct {
+	Slug        string `json:"slug"`
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	LogoPath    string `json:"logo_path"`
+	IsActive    bool   `json:"is_active"`
+}
+
+// BankCurrencyQuote - котировка валюты банка
+type BankCurrencyQuote struct {
+	Currency  string   `json:"currency"`
+	BuyRate   float64  `json:"buy_rate"`
+	SellRate  *float64 `json:"sell_rate"`
+	Spread    *float64 `json:"spread"`
+	UpdatedAt string   `json:"updated_at"`
+}
+
+// BankProfileStats - сводная статистика по продуктам банка
+type BankProfileStats struct {
+	ProductCounts           map[string]int     `json:"product_counts"`
+	BestDepositRate         *float64           `json:"best_deposit_rate"`
+	CheapestMicrocreditRate *float64           `json:"cheapest_microcredit_rate"`
+	CheapestAutocreditRate  *float64           `json:"cheapest_autocredit_rate"`
+	CheapestMortgageRate    *float64           `json:"cheapest_mortgage_rate"`
+	CheapestCreditCardRate  *float64           `json:"cheapest_credit_card_rate"`
+	CheapestCreditRate      *float64           `json:"cheapest_credit_rate"`
+	CurrencySpreads         map[string]float64 `json:"currency_spreads"`
+}
+
+// BankProfileClicks - клики по продуктам банка из product_clicks
+type BankProfileClicks struct {
+	Total       int            `json:"total"`
+	ByDirection map[string]int `json:"by_direction"`
+}
+
+// BankProfileResponse - агрегированный профиль банка
+type BankProfileResponse struct {
+	Bank         BankProfileInfo               `json:"bank"`
+	BankNames    []string                      `json:"bank_names"`
+	Deposits     []utils.TranslatedDeposit     `json:"deposits"`
+	Microcredits []utils.TranslatedMicrocredit `json:"microcredits"`
+	Autocredits  []utils.TranslatedAutocredit  `json:"autocredits"`
+	Mortgages    []utils.TranslatedMicrocredit `json:"mortgages"`
+	Cards        []utils.TranslatedCard        `json:"cards"`
+	CreditCards  []utils.TranslatedCreditCard  `json:"credit_cards"`
+	Currencies   []BankCurrencyQuote           `json:"currencies"`
+	Stats        BankProfileStats              `json:"stats"`
+	Clicks       BankProfileClicks             `json:"clicks"`
+	DataVersion  int64                         `json:"data_version"`
+	GeneratedAt  string                        `json:"generated_at"`
+}
+
+// bankProfileTables - таблицы с продуктами банков, из которых собирается профиль
+var bankProfileTables = []string{
+	"new_deposit",
+	"new_microcredit",
+	"new_autocredit",
+	"new_mortgage",
+	"new_card",
+	"new_credit_card",
+	"new_currency",
+}
+
+// GetBankProfile - GET /bank/banks/:slug/profile — всё, что предлагает банк, одним ответом.
+// Кэшируется в Redis до следующего парсинга (ключ включает версию данных парсеров).
+func (bc *BankController) GetBankProfile(c *gin.Context) {
+	slug := strings.TrimSpace(c.Param("slug"))
+	if slug == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"result": nil, "success": false, "error": "slug банка не указан"})
+		return
+	}
+
+	version := utils.BankDataVersion()
+	cacheKey := fmt.Sprintf("bank_profile:v%d:%s", version, strings.ToLower(slug))
+	rdb := utils.GetRedis()
+	if rdb != nil {
+		if cached, err := rdb.Get(context.Background(), cacheKey).Bytes(); err == nil {
+			var profile BankProfileResponse
+			if json.Unmarshal(cached, &profile) == nil {
+				c.JSON(http.StatusOK, gin.H{"result": profile, "success": true, "cached": true})
+				return
+			}
+		}
+	}
+
+	bankNames, err := bc.findBankNamesBySlug(slug)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"result": nil, "success": false, "error": "Ошибка при поиске банка"})
+		return
+	}
+	if len(bankNames) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"result": nil, "success": false, "error": "Банк не найден"})
+		return
+	}
+
+	profile, err := bc.buildBankProfile(slug, bankNames)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"result": nil, "success": false, "error": "Ошибка при получении данных банка"})
+		return
+	}
+	profile.DataVersion = version
+
+	if rdb != nil {
+		if data, err := json.Marshal(profile); err == nil {
+			rdb.Set(context.Background(), cacheKey, data, bankProfileCacheTTL)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"result": profile, "success": true, "cached": false})
+}
+
+// findBankNamesBySlug возвращает все варианты написания bank_name во всех new_* таблицах, совпадающие со slug
+func (bc *BankController) findBankNamesBySlug(slug string) ([]string, error) {
+	seen := make(map[string]bool)
+	var names []string
+	for _, table := range bankProfileTables {
+		var tableNames []string
+		if err := bc.db.Table(table).Distinct("bank_name").Pluck("bank_name", &tableNames).Error; err != nil {
+			return nil, err
+		}
+		for _, name := range tableNames {
+			if name == "" || seen[name] {
+				continue
+			}
+			if utils.BankSlugMatches(name, slug) {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// buildBankProfile собирает продукты банка из всех new_* таблиц, статистику и клики
+func (bc *BankController) buildBankProfile(slug string, bankNames []string) (*BankProfileResponse, error) {
+	var deposits []models.Deposit
+	if err := bc.db.Table("new_deposit").Where("bank_name IN ?", bankNames).Find(&deposits).Error; err != nil {
+		return nil, err
+	}
+	var microcredits []models.Microcredit
+	if err := bc.db.Table("new_microcredit").Where("bank_name IN ?", bankNames).Find(&microcredits).Error; err != nil {
+		return nil, err
+	}
+	var autocredits []models.Autocredit
+	if err := bc.db.Table("new_autocredit").Where("bank_name IN ?", bankNames).Find(&autocredits).Error; err != nil {
+		return nil, err
+	}
+	var mortgages []models.Mortgage
+	if err := bc.db.Table("new_mortgage").Where("bank_name IN ?", bankNames).Find(&mortgages).Error; err != nil {
+		return nil, err
+	}
+	var cards []models.Card
+	if err := bc.db.Table("new_card").Where("bank_name IN ?", bankNames).Find(&cards).Error; err != nil {
+		return nil, err
+	}
+	var creditCards []models.CreditCard
+	if err := bc.db.Table("new_credit_card").Where("bank_name IN ?", bankNames).Find(&creditCards).Error; err != nil {
+		return nil, err
+	}
+	var currencies []models.Currency
+	if err := bc.db.Table("new_currency").Where("bank_name IN ?", bankNames).Order("currency").Find(&currencies).Error; err != nil {
+		return nil, err
+	}
+
+	profile := &BankProfileResponse{
+		Bank:         bc.bankDirectoryInfo(slug, bankNames[0]),
+		BankNames:    bankNames,
+		Deposits:     make([]utils.TranslatedDeposit, 0, len(deposits)),
+		Microcredits: make([]utils.TranslatedMicrocredit, 0, len(microcredits)),
+		Autocredits:  make([]utils.TranslatedAutocredit, 0, len(autocredits)),
+		Mortgages:    make([]utils.TranslatedMicrocredit, 0, len(mortgages)),
+		Cards:        make([]utils.TranslatedCard, 0, len(cards)),
+		CreditCards:  make([]utils.TranslatedCreditCard, 0, len(creditCards)),
+		Currencies:   make([]BankCurrencyQuote, 0, len(currencies)),
+		GeneratedAt:  utils.UzbekTime().Format("2006-01-02 15:04:05"),
+	}
+
+	stats := BankProfileStats{
+		ProductCounts: map[string]int{
+			"deposits":     len(deposits),
+			"microcredits": len(microcredits),
+			"autocredits":  len(autocredits),
+			"mortgages":    len(mortgages),
+			"cards":        len(cards),
+			"credit_cards": len(creditCards),
+			"currencies":   len(currencies),
+		},
+		CurrencySpreads: make(map[string]float64),
+	}
+
+	depositTranslator := utils.GetDepositTranslator()
+	for _, item := range deposits {
+		translated := depositTranslator.TranslateDeposit(item.BankName, item.Title, item.Rate, item.TermYears, item.MinAmount)
+		translated.ID = item.ID
+		translated.CreatedAt = item.CreatedAt.Format("2006-01-02T15:04:05.000000Z")
+		profile.Deposits = append(profile.Deposits, translated)
+		stats.BestDepositRate = maxRate(stats.BestDepositRate, utils.ExtractFirstFloat(item.Rate))
+	}
+
+	creditTranslator := utils.GetMicrocreditTranslator()
+	for _, item := range microcredits {
+		translated := creditTranslator.TranslateMicrocredit(item.BankName, item.Description, item.Rate, item.Term, item.Amount, item.Channel)
+		translated.ID = item.ID
+		translated.URL = item.URL
+		translated.CreatedAt = item.CreatedAt.Format("2006-01-02T15:04:05.000000Z")
+		profile.Microcredits = append(profile.Microcredits, translated)
+		stats.CheapestMicrocreditRate = minRate(stats.CheapestMicrocreditRate, utils.ExtractFirstFloat(item.Rate))
+	}
+	for _, item := range autocredits {
+		translated := creditTranslator.TranslateAutocredit(item.BankName, item.Description, item.Rate, item.Term, item.Amount, item.Channel)
+		translated.ID = item.ID
+		translated.CreatedAt = item.CreatedAt.Format("2006-01-02T15:04:05.000000Z")
+		profile.Autocredits = append(profile.Autocredits, translated)
+		stats.CheapestAutocreditRate = minRate(stats.CheapestAutocreditRate, utils.ExtractFirstFloat(item.Rate))
+	}
+	for _, item := range mortgages {
+		translated := creditTranslator.TranslateMicrocredit(item.BankName, item.Description, item.Rate, item.Term, item.Amount, item.Channel)
+		translated.ID = item.ID
+		translated.URL = "" // У mortgage нет URL
+		translated.CreatedAt = item.CreatedAt.Format("2006-01-02T15:04:05.000000Z")
+		profile.Mortgages = append(profile.Mortgages, translated)
+		stats.CheapestMortgageRate = minRate(stats.CheapestMortgageRate, utils.ExtractFirstFloat(item.Rate))
+	}
+
+	cardTranslator := utils.GetCardTranslator()
+	for _, item := range cards {
+		translated := cardTranslator.TranslateCard(item.BankName, item.Title, item.Currency, item.System, item.OpeningType)
+		translated.ID = item.ID
+		translated.CreatedAt = item.CreatedAt.Format("2006-01-02T15:04:05.000000Z")
+		profile.Cards = append(profile.Cards, translated)
+	}
+	for _, item := range creditCards {
+		translated := cardTranslator.TranslateCreditCard(item.BankName, item.Title, item.Rate, item.Term, item.Amount)
+		translated.ID = item.ID
+		translated.CreatedAt = item.CreatedAt.Format("2006-01-02T15:04:05.000000Z")
+		profile.CreditCards = append(profile.CreditCards, translated)
+		stats.CheapestCreditCardRate = minRate(stats.CheapestCreditCardRate, utils.ExtractFirstFloat(item.Rate))
+	}
+
+	// Самый дешевый кредит среди всех кредитных продуктов
+	for _, r := range []*float64{stats.CheapestMicrocreditRate, stats.CheapestAutocreditRate, stats.CheapestMortgageRate, stats.CheapestCreditCardRate} {
+		if r != nil {
+			stats.CheapestCreditRate = minRate(stats.CheapestCreditRate, *r)
+		}
+	}
+
+	uzLoc, _ := time.LoadLocation("Asia/Tashkent")
+	for _, item := range currencies {
+		quote := BankCurrencyQuote{
+			Currency:  item.Currency,
+			BuyRate:   item.BuyRate,
+			SellRate:  item.SellRate,
+			UpdatedAt: item.UpdatedAt.In(uzLoc).Format("2006-01-02 15:04:05"),
+		}
+		if item.SellRate != nil && *item.SellRate > 0 && item.BuyRate > 0 {
+			spread := math.Round((*item.SellRate-item.BuyRate)*100) / 100
+			quote.Spread = &spread
+			stats.CurrencySpreads[item.Currency] = spread
+		}
+		profile.Currencies = append(profile.Currencies, quote)
+	}
+	profile.Stats = stats
+
+	clicks, err := bc.bankClicks(bankNames)
+	if err != nil {
+		return nil, err
+	}
+	profile.Clicks = clicks
+
+	return profile, nil
+}
+
+// bankDirectoryInfo берет данные из справочника bank_references, а если записи нет — строит базовую информацию
+func (bc *BankController) bankDirectoryInfo(slug, bankName string) BankProfileInfo {
+	normalizedName := utils.GetBankNormalizer().NormalizeBankName(bankName)
+	info := BankProfileInfo{
+		Slug:        utils.BankSlug(bankName),
+		Name:        normalizedName,
+		DisplayName: normalizedName,
+		IsActive:    true,
+	}
+
+	var reference struct {
+		Name        string
+		DisplayName string
+		LogoPath    string
+		IsActive    bool
+	}
+	err := bc.db.Table("bank_references").
+		Select("name, display_name, logo_path, is_active").
+		Where("name = ? OR aliases LIKE ?", normalizedName, "%"+bankName+"%").
+		First(&reference).Error
+	if err == nil {
+		info.Name = reference.Name
+		if reference.DisplayName != "" {
+			info.DisplayName = reference.DisplayName
+		}
+		info.LogoPath = reference.LogoPath
+		info.IsActive = reference.IsActive
+	}
+	return info
+}
+
+// bankClicks суммирует клики из product_clicks по продуктам банка. Ключи там произвольные (их присылает
+// приложение), поэтому совпадение — по названию банка в ключе, а для ключей вида "direction:bank-slug:hash" —
+// еще и по slug банка
+func (bc *BankController) bankClicks(bankNames []string) (BankProfileClicks, error) {
+	result := BankProfileClicks{ByDirection: make(map[string]int)}
+
+	conditions := make([]string, 0, len(bankNames)+1)
+	args := make([]interface{}, 0, len(bankNames)+1)
+	slugs := make([]string, 0, len(bankNames))
+	for _, name := range bankNames {
+		conditions = append(conditions, "key ILIKE ?")
+		args = append(args, "%"+name+"%")
+		if slug := utils.BankSlug(name); slug != "" {
+			slugs = append(slugs, slug)
+		}
+	}
+	if len(slugs) > 0 {
+		conditions = append(conditions, "split_part(key, ':', 2) IN ?")
+		args = append(args, slugs)
+	}
+	if len(conditions) == 0 {
+		return result, nil
+	}
+
+	var rows []struct {
+		Direction   string
+		TotalClicks int
+	}
+	if err := bc.db.Model(&models.ProductClick{}).
+		Select("direction, SUM(click_count) as total_clicks").
+		Where(strings.Join(conditions, " OR "), args...).
+		Group("direction").
+		Find(&rows).Error; err != nil {
+		return result, err
+	}
+	for _, r := range rows {
+		result.ByDirection[r.Direction] = r.TotalClicks
+		result.Total += r.TotalClicks
+	}
+	return result, nil
+}
+
+// maxRate / minRate обновляют экстремум, игнорируя нераспознанные (<=0) ставки
+func maxRate(current *float64, v float64) *float64 {
+	if v <= 0 {
+		return current
+	}
+	if current == nil || v > *current {
+		return &v
+	}
+	return current
+}
+
+func minRate(current *float64, v float64) *float64 {
+	if v <= 0 {
+		return current
+	}
+	if current == nil || v < *current {
+		return &v
+	}
+	return current
+}
@@ -2,13 +2,18 @@ package bank
 
 import (
 	"kliro/models"
+	editorialServices "kliro/services/editorial"
+	partnerServices "kliro/services/partners"
+	rankingServices "kliro/services/ranking"
+	redirectServices "kliro/services/redirect"
+	reviewServices "kliro/services/reviews"
 	"kliro/utils"
-	"math/rand"
 	"net/http"
 	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type CardController struct{}
@@ -62,22 +67,153 @@ type TranslatedCreditCardResponseByPagination struct {
 	Empty            bool                       `json:"empty"`
 }
 
-// GetNewCards godoc — порядок каждый раз случайный.
+// GetNewCards godoc — порядок задает движок ранжирования.
 func (cc *CardController) GetNewCards(c *gin.Context) {
 	cc.getCardsWithPagination(c, "new_card", true)
 }
 
 // getCardsWithPagination общая функция для получения карт с пагинацией и фильтрацией.
-// shuffleOrder: при true загружаем все, перемешиваем, пагинируем в памяти.
-func (cc *CardController) getCardsWithPagination(c *gin.Context, tableName string, shuffleOrder bool) {
-	db := utils.GetDB()
-
+// ranked: при true и без явного sort загружаем все, ранжируем, пагинируем в памяти.
+func (cc *CardController) getCardsWithPagination(c *gin.Context, tableName string, ranked bool) {
 	// Параметры пагинации
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "0"))
 	size, _ := strconv.Atoi(c.DefaultQuery("size", "10"))
 	sortBy := c.DefaultQuery("sort", "bank_name")
 	sortDir := c.DefaultQuery("direction", "asc")
 
+	// Валидация параметров
+	if page < 0 {
+		page = 0
+	}
+	if size < 1 || size > 100 {
+		size = 10
+	}
+
+	// Правки, закрепление и ранжирование применяются ко всему отфильтрованному списку, пагинация — в памяти:
+	// скрытые продукты не уменьшают страницу и не попадают в totalElements
+	list, err := cc.listCards(c, tableName, ranked)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при получении данных"})
+		return
+	}
+	allCards := list.Items
+	totalElements := int64(len(allCards))
+	totalPages := int((totalElements + int64(size) - 1) / int64(size))
+	offset := page * size
+
+	// Проверка на пустой результат
+	if totalElements == 0 {
+		sortObj := Sort{
+			Direction:    strings.ToUpper(sortDir),
+			NullHandling: "NATIVE",
+			Ascending:    strings.ToLower(sortDir) == "asc",
+			Property:     sortBy,
+			IgnoreCase:   false,
+		}
+		response := TranslatedCardResponseByPagination{
+			TotalPages:       0,
+			TotalElements:    0,
+			First:            true,
+			Last:             true,
+			Size:             size,
+			Content:          []utils.TranslatedCard{},
+			Number:           page,
+			Sort:             []Sort{sortObj},
+			NumberOfElements: 0,
+			Pageable: Pageable{
+				Offset:     offset,
+				Sort:       []Sort{sortObj},
+				Paged:      true,
+				PageNumber: page,
+				PageSize:   size,
+				Unpaged:    false,
+			},
+			Empty: true,
+		}
+		c.JSON(http.StatusOK, gin.H{"result": response, "success": true})
+		return
+	}
+
+	overrides := list.Overrides
+	ranking := list.Ranking
+	useRanking := list.Ranked
+	end := offset + size
+	if end > len(allCards) {
+		end = len(allCards)
+	}
+	if offset > len(allCards) {
+		offset = len(allCards)
+	}
+	cards := allCards[offset:end]
+
+	// Применяем переводы к каждому элементу (uz/ru/en/oz)
+	translator := utils.GetCardTranslator()
+	translatedContent := make([]utils.TranslatedCard, 0, len(cards))
+	// Оценки по одобренным отзывам — одним запросом на страницу
+	keys := productKeys(cards, cardKey)
+	ratings := reviewServices.ProductAggregates(keys)
+	// Показы продуктов в выдаче — для отчетов банкам-партнерам
+	partnerServices.RecordImpressions("card", keys)
+	redirects := redirectServices.DefaultResolver()
+	for _, item := range cards {
+		translated := translator.TranslateCard(
+			item.BankName,
+			item.Title,
+			item.Currency,
+			item.System,
+			item.OpeningType,
+		)
+		translated.ID = item.ID
+		translated.CreatedAt = item.CreatedAt.Format("2006-01-02T15:04:05.000000Z")
+		translated.ProductKey = cardKey(item)
+		translated.Promoted, translated.PromotionLabel = ranking.PromotionByKey(translated.ProductKey)
+		translated.Pinned = overrides.Pinned(translated.ProductKey)
+		translated.EditorialNote = overrides.Note(translated.ProductKey)
+		translated.Rating, translated.ReviewCount = ratings.Get(translated.ProductKey)
+		translated.RedirectURL = redirects.Link("card", translated.ProductKey, item.BankName, "")
+		translatedContent = append(translatedContent, translated)
+	}
+
+	// Создание объекта сортировки
+	sortObj := Sort{
+		Direction:    strings.ToUpper(sortDir),
+		NullHandling: "NATIVE",
+		Ascending:    strings.ToLower(sortDir) == "asc",
+		Property:     sortBy,
+		IgnoreCase:   false,
+	}
+
+	// Формирование ответа с переводами
+	response := TranslatedCardResponseByPagination{
+		TotalPages:       totalPages,
+		TotalElements:    totalElements,
+		First:            page == 0,
+		Last:             page >= totalPages-1,
+		Size:             size,
+		Content:          translatedContent,
+		Number:           page,
+		Sort:             []Sort{sortObj},
+		NumberOfElements: len(translatedContent),
+		Pageable: Pageable{
+			Offset:     offset,
+			Sort:       []Sort{sortObj},
+			Paged:      true,
+			PageNumber: page,
+			PageSize:   size,
+			Unpaged:    false,
+		},
+		Empty: len(translatedContent) == 0,
+	}
+
+	resp := gin.H{"result": response, "success": true}
+	if useRanking {
+		resp["ranking"] = ranking.Meta
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// cardsQuery строит запрос карт с фильтрами выдачи (currency, system, bank/search, opening).
+func cardsQuery(c *gin.Context, tableName string) *gorm.DB {
 	// Параметры фильтрации
 	currency := c.Query("currency")
 	system := c.Query("system")
@@ -137,19 +273,7 @@ func (cc *CardController) getCardsWithPagination(c *gin.Context, tableName strin
 		}
 	}
 
-	// Валидация параметров
-	if page < 0 {
-		page = 0
-	}
-	if size < 1 || size > 100 {
-		size = 10
-	}
-
-	// Подсчет общего количества записей с фильтрацией
-	var totalElements int64
-	query := db.Table(tableName)
-
-	// Применение фильтров
+	query := utils.GetDB().Table(tableName)
 	if len(currencySynonyms) > 0 {
 		for i, syn := range currencySynonyms {
 			pattern := "%" + syn + "%"
@@ -175,81 +299,11 @@ func (cc *CardController) getCardsWithPagination(c *gin.Context, tableName strin
 	} else if opening == "all" {
 		query = query.Where("opening_type ILIKE '%Bank%'").Where("opening_type ILIKE '%Onlayn%'")
 	}
+	return query
+}
 
-	query.Count(&totalElements)
-
-	// Вычисление пагинации
-	totalPages := int((totalElements + int64(size) - 1) / int64(size))
-	// Проверяем, есть ли данные на последней странице
-	if totalPages > 0 {
-		lastPageOffset := (totalPages - 1) * size
-		var lastPageCount int64
-		lastPageQuery := db.Table(tableName)
-		// Применяем те же фильтры для проверки последней страницы
-		if len(currencySynonyms) > 0 {
-			for i, syn := range currencySynonyms {
-				pattern := "%" + syn + "%"
-				if i == 0 {
-					lastPageQuery = lastPageQuery.Where("currency ILIKE ?", pattern)
-				} else {
-					lastPageQuery = lastPageQuery.Or("currency ILIKE ?", pattern)
-				}
-			}
-		}
-		if system != "" {
-			lastPageQuery = lastPageQuery.Where("system ILIKE ?", "%"+system+"%")
-		}
-		if bankFilter != "" {
-			lastPageQuery = lastPageQuery.Where("bank_name ILIKE ?", "%"+bankFilter+"%")
-		}
-		if opening == "bank" {
-			lastPageQuery = lastPageQuery.Where("opening_type ILIKE '%Bank%'").Where("opening_type NOT ILIKE '%Onlayn%'")
-		} else if opening == "online" || opening == "onlayn" {
-			lastPageQuery = lastPageQuery.Where("opening_type ILIKE '%Onlayn%'").Where("opening_type NOT ILIKE '%Bank%'")
-		} else if opening == "all" {
-			lastPageQuery = lastPageQuery.Where("opening_type ILIKE '%Bank%'").Where("opening_type ILIKE '%Onlayn%'")
-		}
-		lastPageQuery.Offset(lastPageOffset).Limit(size).Count(&lastPageCount)
-		if lastPageCount == 0 {
-			totalPages = totalPages - 1
-		}
-	}
-	offset := page * size
-
-	// Проверка на пустой результат
-	if totalElements == 0 {
-		sortObj := Sort{
-			Direction:    strings.ToUpper(sortDir),
-			NullHandling: "NATIVE",
-			Ascending:    strings.ToLower(sortDir) == "asc",
-			Property:     sortBy,
-			IgnoreCase:   false,
-		}
-		response := TranslatedCardResponseByPagination{
-			TotalPages:       0,
-			TotalElements:    0,
-			First:            true,
-			Last:             true,
-			Size:             size,
-			Content:          []utils.TranslatedCard{},
-			Number:           page,
-			Sort:             []Sort{sortObj},
-			NumberOfElements: 0,
-			Pageable: Pageable{
-				Offset:     offset,
-				Sort:       []Sort{sortObj},
-				Paged:      true,
-				PageNumber: page,
-				PageSize:   size,
-				Unpaged:    false,
-			},
-			Empty: true,
-		}
-		c.JSON(http.StatusOK, gin.H{"result": response, "success": true})
-		return
-	}
-
-	// Создание сортировки
+// cardsOrder возвращает ORDER BY для явной сортировки карт (sort/direction).
+func cardsOrder(sortBy, sortDir string) string {
 	sortDirection := "ASC"
 	if strings.ToLower(sortDir) == "desc" {
 		sortDirection = "DESC"
@@ -270,135 +324,44 @@ func (cc *CardController) getCardsWithPagination(c *gin.Context, tableName strin
 		sortField = "bank_name"
 	}
 
-	// Выполнение запроса с пагинацией, сортировкой и фильтрацией
-	var cards []models.Card
-	query = db.Table(tableName)
-	if len(currencySynonyms) > 0 {
-		for i, syn := range currencySynonyms {
-			pattern := "%" + syn + "%"
-			if i == 0 {
-				query = query.Where("currency ILIKE ?", pattern)
-			} else {
-				query = query.Or("currency ILIKE ?", pattern)
-			}
-		}
-	}
-	if system != "" {
-		query = query.Where("system ILIKE ?", "%"+system+"%")
-	}
-	if search != "" {
-		query = query.Where("bank_name ILIKE ?", "%"+search+"%")
-	} else if bankFilter != "" {
-		query = query.Where("bank_name ILIKE ?", "%"+bankFilter+"%")
-	}
-	if opening == "bank" {
-		query = query.Where("opening_type ILIKE '%Bank%'").Where("opening_type NOT ILIKE '%Onlayn%'")
-	} else if opening == "online" || opening == "onlayn" {
-		query = query.Where("opening_type ILIKE '%Onlayn%'").Where("opening_type NOT ILIKE '%Bank%'")
-	} else if opening == "all" {
-		query = query.Where("opening_type ILIKE '%Bank%'").Where("opening_type ILIKE '%Onlayn%'")
+	if sortField == "created_at" {
+		return sortField + " " + sortDirection
 	}
+	return sortField + " COLLATE \"C\" " + sortDirection
+}
 
-	if shuffleOrder {
-		var allCards []models.Card
-		if err := query.Find(&allCards).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при получении данных"})
-			return
-		}
-		if len(allCards) > 1 {
-			rand.Shuffle(len(allCards), func(i, j int) { allCards[i], allCards[j] = allCards[j], allCards[i] })
-		}
-		totalElements = int64(len(allCards))
-		totalPages = int((totalElements + int64(size) - 1) / int64(size))
-		if totalPages > 0 && (totalPages-1)*size >= len(allCards) {
-			totalPages--
-		}
-		end := offset + size
-		if end > len(allCards) {
-			end = len(allCards)
-		}
-		if offset > len(allCards) {
-			offset = len(allCards)
-		}
-		cards = allCards[offset:end]
-	} else {
-		if sortField == "bank_name" || sortField == "title" || sortField == "currency" || sortField == "system" || sortField == "opening_type" {
-			if sortDirection == "ASC" {
-				query = query.Order(sortField + " COLLATE \"C\" ASC")
-			} else {
-				query = query.Order(sortField + " COLLATE \"C\" DESC")
-			}
-		} else {
-			query = query.Order(sortField + " " + sortDirection)
-		}
-		query = query.Offset(offset).Limit(size)
-		if err := query.Find(&cards).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при получении данных"})
-			return
-		}
-	}
+// listCards возвращает отфильтрованный и отсортированный список без пагинации — общий для выдачи и экспорта.
+func (cc *CardController) listCards(c *gin.Context, tableName string, ranked bool) (productList[models.Card], error) {
+	overrides := editorialServices.DefaultStore().ForDirection("card")
+	useRanking := ranked && c.Query("sort") == ""
 
-	// Применяем переводы к каждому элементу (uz/ru/en/oz)
-	translator := utils.GetCardTranslator()
-	translatedContent := make([]utils.TranslatedCard, 0, len(cards))
-	for _, item := range cards {
-		translated := translator.TranslateCard(
-			item.BankName,
-			item.Title,
-			item.Currency,
-			item.System,
-			item.OpeningType,
-		)
-		translated.ID = item.ID
-		translated.CreatedAt = item.CreatedAt.Format("2006-01-02T15:04:05.000000Z")
-		translatedContent = append(translatedContent, translated)
+	query := cardsQuery(c, tableName)
+	if !useRanking {
+		query = query.Order(cardsOrder(c.DefaultQuery("sort", "bank_name"), c.DefaultQuery("direction", "asc")))
 	}
-
-	// Создание объекта сортировки
-	sortObj := Sort{
-		Direction:    strings.ToUpper(sortDir),
-		NullHandling: "NATIVE",
-		Ascending:    strings.ToLower(sortDir) == "asc",
-		Property:     sortBy,
-		IgnoreCase:   false,
+	var cards []models.Card
+	if err := query.Find(&cards).Error; err != nil {
+		return productList[models.Card]{}, err
 	}
+	cards = editorialServices.Apply(overrides, cards, cardKey)
 
-	// Формирование ответа с переводами
-	response := TranslatedCardResponseByPagination{
-		TotalPages:       totalPages,
-		TotalElements:    totalElements,
-		First:            page == 0,
-		Last:             page >= totalPages-1,
-		Size:             size,
-		Content:          translatedContent,
-		Number:           page,
-		Sort:             []Sort{sortObj},
-		NumberOfElements: len(translatedContent),
-		Pageable: Pageable{
-			Offset:     offset,
-			Sort:       []Sort{sortObj},
-			Paged:      true,
-			PageNumber: page,
-			PageSize:   size,
-			Unpaged:    false,
-		},
-		Empty: len(translatedContent) == 0,
+	var ranking rankingServices.Ranked[models.Card]
+	if useRanking {
+		ranking = rankingServices.Rank(rankingServices.DefaultEngine(), "card", c.Query("seed"), cards, cardFeatures, rankingOverride(c, "card"))
+		cards = ranking.Items
 	}
-
-	c.JSON(http.StatusOK, gin.H{"result": response, "success": true})
+	cards = editorialServices.Pin(overrides, cards, cardKey)
+	return productList[models.Card]{Items: cards, Overrides: overrides, Ranking: ranking, Ranked: useRanking}, nil
 }
 
-// GetNewCreditCards возвращает кредитные карты с пагинацией (порядок каждый раз случайный).
+// GetNewCreditCards возвращает кредитные карты с пагинацией (порядок задает движок ранжирования).
 func (cc *CardController) GetNewCreditCards(c *gin.Context) {
 	cc.getCreditCardsWithPagination(c, "new_credit_card", true)
 }
 
-func (cc *CardController) getCreditCardsWithPagination(c *gin.Context, tableName string, shuffleOrder bool) {
-	db := utils.GetDB()
-
+func (cc *CardController) getCreditCardsWithPagination(c *gin.Context, tableName string, ranked bool) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "0"))
 	size, _ := strconv.Atoi(c.DefaultQuery("size", "10"))
-	search := c.Query("search")
 
 	if page < 0 {
 		page = 0
@@ -408,47 +371,34 @@ func (cc *CardController) getCreditCardsWithPagination(c *gin.Context, tableName
 	}
 	offset := page * size
 
-	query := db.Table(tableName)
-	if search != "" {
-		query = query.Where("bank_name ILIKE ?", "%"+search+"%")
+	// Правки и закрепление применяются ко всему списку, пагинация — в памяти (как и при ранжировании)
+	list, err := cc.listCreditCards(c, tableName, ranked)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при получении данных"})
+		return
 	}
-
-	var total int64
-	query.Count(&total)
-
-	var items []models.CreditCard
-	dataQuery := db.Table(tableName)
-	if search != "" {
-		dataQuery = dataQuery.Where("bank_name ILIKE ?", "%"+search+"%")
+	overrides := list.Overrides
+	ranking := list.Ranking
+	items := list.Items
+	total := int64(len(items))
+	end := offset + size
+	if end > len(items) {
+		end = len(items)
 	}
-
-	if shuffleOrder {
-		if err := dataQuery.Find(&items).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при получении данных"})
-			return
-		}
-		if len(items) > 1 {
-			rand.Shuffle(len(items), func(i, j int) { items[i], items[j] = items[j], items[i] })
-		}
-		total = int64(len(items))
-		end := offset + size
-		if end > len(items) {
-			end = len(items)
-		}
-		if offset > len(items) {
-			offset = len(items)
-		}
-		items = items[offset:end]
-	} else {
-		if err := dataQuery.Order("created_at DESC").Offset(offset).Limit(size).Find(&items).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при получении данных"})
-			return
-		}
+	if offset > len(items) {
+		offset = len(items)
 	}
+	items = items[offset:end]
 
 	// Применяем переводы к каждому элементу (uz/ru/en/oz)
 	translator := utils.GetCardTranslator()
 	translatedContent := make([]utils.TranslatedCreditCard, 0, len(items))
+	// Оценки по одобренным отзывам — одним запросом на страницу
+	keys := productKeys(items, creditCardKey)
+	ratings := reviewServices.ProductAggregates(keys)
+	// Показы продуктов в выдаче — для отчетов банкам-партнерам
+	partnerServices.RecordImpressions("credit", keys)
+	redirects := redirectServices.DefaultResolver()
 	for _, item := range items {
 		translated := translator.TranslateCreditCard(
 			item.BankName,
@@ -459,6 +409,12 @@ func (cc *CardController) getCreditCardsWithPagination(c *gin.Context, tableName
 		)
 		translated.ID = item.ID
 		translated.CreatedAt = item.CreatedAt.Format("2006-01-02T15:04:05.000000Z")
+		translated.ProductKey = creditCardKey(item)
+		translated.Promoted, translated.PromotionLabel = ranking.PromotionByKey(translated.ProductKey)
+		translated.Pinned = overrides.Pinned(translated.ProductKey)
+		translated.EditorialNote = overrides.Note(translated.ProductKey)
+		translated.Rating, translated.ReviewCount = ratings.Get(translated.ProductKey)
+		translated.RedirectURL = redirects.Link("credit", translated.ProductKey, item.BankName, "")
 		translatedContent = append(translatedContent, translated)
 	}
 
@@ -477,5 +433,41 @@ func (cc *CardController) getCreditCardsWithPagination(c *gin.Context, tableName
 		Content:          translatedContent,
 	}
 
-	c.JSON(http.StatusOK, gin.H{"result": response, "success": true})
+	resp := gin.H{"result": response, "success": true}
+	if ranked {
+		resp["ranking"] = ranking.Meta
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// creditCardsQuery строит запрос кредитных карт с фильтрами выдачи.
+func creditCardsQuery(c *gin.Context, tableName string) *gorm.DB {
+	query := utils.GetDB().Table(tableName)
+	if search := c.Query("search"); search != "" {
+		query = query.Where("bank_name ILIKE ?", "%"+search+"%")
+	}
+	return query
+}
+
+// listCreditCards возвращает отфильтрованный и отсортированный список без пагинации — общий для выдачи и экспорта.
+func (cc *CardController) listCreditCards(c *gin.Context, tableName string, ranked bool) (productList[models.CreditCard], error) {
+	overrides := editorialServices.DefaultStore().ForDirection("credit")
+
+	query := creditCardsQuery(c, tableName)
+	if !ranked {
+		query = query.Order("created_at DESC")
+	}
+	var items []models.CreditCard
+	if err := query.Find(&items).Error; err != nil {
+		return productList[models.CreditCard]{}, err
+	}
+	items = editorialServices.Apply(overrides, items, creditCardKey)
+
+	var ranking rankingServices.Ranked[models.CreditCard]
+	if ranked {
+		ranking = rankingServices.Rank(rankingServices.DefaultEngine(), "credit", c.Query("seed"), items, creditCardFeatures, rankingOverride(c, "credit"))
+		items = ranking.Items
+	}
+	items = editorialServices.Pin(overrides, items, creditCardKey)
+	return productList[models.CreditCard]{Items: items, Overrides: overrides, Ranking: ranking, Ranked: ranked}, nil
 }
@@ -2,8 +2,12 @@ package bank
 
 import (
 	"kliro/models"
+	editorialServices "kliro/services/editorial"
+	partnerServices "kliro/services/partners"
+	rankingServices "kliro/services/ranking"
+	redirectServices "kliro/services/redirect"
+	reviewServices "kliro/services/reviews"
 	"kliro/utils"
-	"math/rand"
 	"net/http"
 	"regexp"
 	"sort"
@@ -49,16 +53,14 @@ type TranslatedDepositResponseByPagination struct {
 	Empty            bool                   `json:"empty"`
 }
 
-// GetNewDeposits godoc — вклады: сначала самые высокие проценты, внутри одной ставки порядок случайный.
+// GetNewDeposits godoc — вклады: порядок задает движок ранжирования (по умолчанию сначала самые высокие проценты).
 func (dc *DepositController) GetNewDeposits(c *gin.Context) {
 	dc.getDepositsWithPagination(c, "new_deposit", true)
 }
 
 // getDepositsWithPagination общая функция для получения вкладов с пагинацией.
-// shuffleHighRateFirst: для new — сортировка по убыванию ставки, внутри одной ставки случайный порядок.
-func (dc *DepositController) getDepositsWithPagination(c *gin.Context, tableName string, shuffleHighRateFirst bool) {
-	db := utils.GetDB()
-
+// ranked: для new без явного sort порядок задает движок ранжирования.
+func (dc *DepositController) getDepositsWithPagination(c *gin.Context, tableName string, ranked bool) {
 	// Параметры пагинации
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "0"))
 	size, _ := strconv.Atoi(c.DefaultQuery("size", "10"))
@@ -73,6 +75,80 @@ func (dc *DepositController) getDepositsWithPagination(c *gin.Context, tableName
 		size = 10
 	}
 
+	list, err := dc.listDeposits(c, tableName, ranked)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при получении данных"})
+		return
+	}
+	filtered, overrides, ranking, useRanking := list.Items, list.Overrides, list.Ranking, list.Ranked
+
+	// Пагинация в памяти
+	offset := page * size
+	end := offset + size
+	if offset > len(filtered) {
+		offset = len(filtered)
+	}
+	if end > len(filtered) {
+		end = len(filtered)
+	}
+	pageItems := filtered[offset:end]
+
+	// Применяем переводы к каждому элементу (uz/ru/en/oz)
+	translator := utils.GetDepositTranslator()
+	// Оценки по одобренным отзывам — одним запросом на страницу
+	keys := productKeys(pageItems, depositKey)
+	ratings := reviewServices.ProductAggregates(keys)
+	// Показы продуктов в выдаче — для отчетов банкам-партнерам
+	partnerServices.RecordImpressions("deposit", keys)
+	redirects := redirectServices.DefaultResolver()
+	translatedContent := make([]utils.TranslatedDeposit, 0, len(pageItems))
+	for _, item := range pageItems {
+		translated := translator.TranslateDeposit(
+			item.BankName,
+			item.Title,
+			item.Rate,
+			item.TermYears,
+			item.MinAmount,
+		)
+		translated.ID = item.ID
+		translated.CreatedAt = item.CreatedAt.Format("2006-01-02T15:04:05.000000Z")
+		translated.ProductKey = depositKey(item)
+		translated.Promoted, translated.PromotionLabel = ranking.PromotionByKey(translated.ProductKey)
+		translated.Pinned = overrides.Pinned(translated.ProductKey)
+		translated.EditorialNote = overrides.Note(translated.ProductKey)
+		translated.Rating, translated.ReviewCount = ratings.Get(translated.ProductKey)
+		translated.RedirectURL = redirects.Link("deposit", translated.ProductKey, item.BankName, "")
+		translatedContent = append(translatedContent, translated)
+	}
+
+	sortObj := Sort{Direction: strings.ToUpper(sortDir), NullHandling: "NATIVE", Ascending: strings.ToLower(sortDir) == "asc", Property: sortBy, IgnoreCase: false}
+	response := TranslatedDepositResponseByPagination{
+		TotalPages:       int((int64(len(filtered)) + int64(size) - 1) / int64(size)),
+		TotalElements:    int64(len(filtered)),
+		First:            page == 0,
+		Last:             (page+1)*size >= len(filtered) && len(filtered) > 0,
+		Size:             size,
+		Content:          translatedContent,
+		Number:           page,
+		Sort:             []Sort{sortObj},
+		NumberOfElements: len(translatedContent),
+		Pageable:         Pageable{Offset: offset, Sort: []Sort{sortObj}, Paged: true, PageNumber: page, PageSize: size, Unpaged: false},
+		Empty:            len(translatedContent) == 0,
+	}
+
+	resp := gin.H{"result": response, "success": true}
+	if useRanking {
+		resp["ranking"] = ranking.Meta
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// listDeposits возвращает отфильтрованный и отсортированный список без пагинации — общий для выдачи и экспорта.
+func (dc *DepositController) listDeposits(c *gin.Context, tableName string, ranked bool) (productList[models.Deposit], error) {
+	db := utils.GetDB()
+	sortBy := c.DefaultQuery("sort", "bank_name")
+	sortDir := c.DefaultQuery("direction", "asc")
+
 	// Фильтры
 	bank := c.Query("bank")
 	search := c.Query("search")
@@ -148,10 +224,13 @@ func (dc *DepositController) getDepositsWithPagination(c *gin.Context, tableName
 	// Загружаем кандидатов
 	var all []models.Deposit
 	if err := baseQ.Find(&all).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при получении данных"})
-		return
+		return productList[models.Deposit]{}, err
 	}
 
+	// Редакционные правки: исправленные поля и скрытые продукты (до фильтров)
+	overrides := editorialServices.DefaultStore().ForDirection("deposit")
+	all = editorialServices.Apply(overrides, all, depositKey)
+
 	// Числовые фильтры в памяти
 	rateFrom := utils.ParseFloatSafe(rateFromStr)
 	termFrom := utils.ParseIntSafe(termFromStr)
@@ -184,27 +263,13 @@ func (dc *DepositController) getDepositsWithPagination(c *gin.Context, tableName
 		filtered = append(filtered, d)
 	}
 
-	// Для /deposits/new: сначала самые высокие проценты, внутри одной ставки — случайный порядок
-	if shuffleHighRateFirst && len(filtered) > 0 {
-		// Сортируем по ставке по убыванию
-		sort.SliceStable(filtered, func(i, j int) bool {
-			rateI := utils.ExtractFirstFloat(filtered[i].Rate)
-			rateJ := utils.ExtractFirstFloat(filtered[j].Rate)
-			return rateI > rateJ
-		})
-		// Группы по одинаковой ставке перемешиваем
-		i := 0
-		for i < len(filtered) {
-			ri := utils.ExtractFirstFloat(filtered[i].Rate)
-			j := i + 1
-			for j < len(filtered) && utils.ExtractFirstFloat(filtered[j].Rate) == ri {
-				j++
-			}
-			if j-i > 1 {
-				rand.Shuffle(j-i, func(a, b int) { filtered[i+a], filtered[i+b] = filtered[i+b], filtered[i+a] })
-			}
-			i = j
-		}
+	// Для /deposits/new без явного sort: движок ранжирования (по умолчанию — сначала самые высокие проценты,
+	// внутри одной ставки — детерминированный порядок, seed для пагинации, спонсорские места)
+	useRanking := ranked && c.Query("sort") == ""
+	var ranking rankingServices.Ranked[models.Deposit]
+	if useRanking {
+		ranking = rankingServices.Rank(rankingServices.DefaultEngine(), "deposit", c.Query("seed"), filtered, depositFeatures, rankingOverride(c, "deposit"))
+		filtered = ranking.Items
 	} else {
 		// Обычная сортировка
 		if rateFromStr != "" {
@@ -223,49 +288,10 @@ func (dc *DepositController) getDepositsWithPagination(c *gin.Context, tableName
 		}
 	}
 
-	// Пагинация в памяти
-	offset := page * size
-	end := offset + size
-	if offset > len(filtered) {
-		offset = len(filtered)
-	}
-	if end > len(filtered) {
-		end = len(filtered)
-	}
-	pageItems := filtered[offset:end]
-
-	// Применяем переводы к каждому элементу (uz/ru/en/oz)
-	translator := utils.GetDepositTranslator()
-	translatedContent := make([]utils.TranslatedDeposit, 0, len(pageItems))
-	for _, item := range pageItems {
-		translated := translator.TranslateDeposit(
-			item.BankName,
-			item.Title,
-			item.Rate,
-			item.TermYears,
-			item.MinAmount,
-		)
-		translated.ID = item.ID
-		translated.CreatedAt = item.CreatedAt.Format("2006-01-02T15:04:05.000000Z")
-		translatedContent = append(translatedContent, translated)
-	}
-
-	sortObj := Sort{Direction: strings.ToUpper(sortDir), NullHandling: "NATIVE", Ascending: strings.ToLower(sortDir) == "asc", Property: sortBy, IgnoreCase: false}
-	response := TranslatedDepositResponseByPagination{
-		TotalPages:       int((int64(len(filtered)) + int64(size) - 1) / int64(size)),
-		TotalElements:    int64(len(filtered)),
-		First:            page == 0,
-		Last:             (page+1)*size >= len(filtered) && len(filtered) > 0,
-		Size:             size,
-		Content:          translatedContent,
-		Number:           page,
-		Sort:             []Sort{sortObj},
-		NumberOfElements: len(translatedContent),
-		Pageable:         Pageable{Offset: offset, Sort: []Sort{sortObj}, Paged: true, PageNumber: page, PageSize: size, Unpaged: false},
-		Empty:            len(translatedContent) == 0,
-	}
+	// Закрепленные редакцией продукты — в начало списка
+	filtered = editorialServices.Pin(overrides, filtered, depositKey)
 
-	c.JSON(http.StatusOK, gin.H{"result": response, "success": true})
+	return productList[models.Deposit]{Items: filtered, Overrides: overrides, Ranking: ranking, Ranked: useRanking}, nil
 }
 
 var _ = regexp.MustCompile // keep import if not used elsewhere
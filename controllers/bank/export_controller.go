@@ -0,0 +1,283 @@
+package bank
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"kliro/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ExportController выгружает списки продуктов в CSV/XLSX: тот же отфильтрованный и отсортированный набор,
+// что и выдача /new, без пагинации. Набор, как и в /new, собирается в памяти (ранжирование, закрепление
+// и числовые фильтры по строковым полям требуют всего списка); потоково пишется только файл — строки
+// переводятся и уходят клиенту по одной. Размер набора ограничен exportMaxRows.
+type ExportController struct {
+	db           *gorm.DB
+	microcredits *MicrocreditController
+	autocredits  *AutocreditController
+	transfers    *TransferController
+	deposits     *DepositController
+	cards        *CardController
+}
+
+func NewExportController(db *gorm.DB) *ExportController {
+	return &ExportController{
+		db:           db,
+		microcredits: NewMicrocreditController(),
+		autocredits:  NewAutocreditController(),
+		transfers:    NewTransferController(),
+		deposits:     NewDepositController(),
+		cards:        NewCardController(),
+	}
+}
+
+// exportMaxRows - максимум строк в одной выгрузке; больше — просим сузить фильтры
+const exportMaxRows = 10000
+
+// exportTable - выгружаемая таблица: колонки и построчная сборка (перевод делается при записи строки)
+type exportTable struct {
+	columns []string
+	rows    int
+	row     func(i int) []string
+}
+
+// exportHeaders - заголовки колонок на uz/ru/en/oz
+var exportHeaders = map[string]map[string]string{
+	"bank":         {"uz": "Bank", "ru": "Банк", "en": "Bank", "oz": "Банк"},
+	"title":        {"uz": "Nomi", "ru": "Название", "en": "Title", "oz": "Номи"},
+	"description":  {"uz": "Tavsif", "ru": "Описание", "en": "Description", "oz": "Тавсиф"},
+	"rate":         {"uz": "Foiz stavkasi", "ru": "Ставка", "en": "Rate", "oz": "Фоиз ставкаси"},
+	"term":         {"uz": "Muddat", "ru": "Срок", "en": "Term", "oz": "Муддат"},
+	"amount":       {"uz": "Summa", "ru": "Сумма", "en": "Amount", "oz": "Сумма"},
+	"min_amount":   {"uz": "Minimal summa", "ru": "Минимальная сумма", "en": "Minimum amount", "oz": "Минимал сумма"},
+	"channel":      {"uz": "Rasmiylashtirish", "ru": "Оформление", "en": "Application", "oz": "Расмийлаштириш"},
+	"currency":     {"uz": "Valyuta", "ru": "Валюта", "en": "Currency", "oz": "Валюта"},
+	"system":       {"uz": "To'lov tizimi", "ru": "Платежная система", "en": "Payment system", "oz": "Тўлов тизими"},
+	"opening_type": {"uz": "Ochish usuli", "ru": "Способ открытия", "en": "Opening", "oz": "Очиш усули"},
+	"app_name":     {"uz": "Ilova", "ru": "Приложение", "en": "App", "oz": "Илова"},
+	"commission":   {"uz": "Komissiya", "ru": "Комиссия", "en": "Commission", "oz": "Комиссия"},
+	"limit":        {"uz": "Limit", "ru": "Лимит", "en": "Limit", "oz": "Лимит"},
+	"url":          {"uz": "Havola", "ru": "Ссылка", "en": "Link", "oz": "Ҳавола"},
+}
+
+// exportLang нормализует ?lang= (по умолчанию uz)
+func exportLang(lang string) string {
+	switch l := strings.ToLower(strings.TrimSpace(lang)); l {
+	case "ru", "en", "oz":
+		return l
+	default:
+		return "uz"
+	}
+}
+
+// pickLang выбирает переведенные поля нужного языка
+func pickLang[T any](lang string, uz, ru, en, oz T) T {
+	switch lang {
+	case "ru":
+		return ru
+	case "en":
+		return en
+	case "oz":
+		return oz
+	default:
+		return uz
+	}
+}
+
+// Export отдает GET /bank/{category}/export?format=csv|xlsx&lang=uz|ru|en|oz.
+// Фильтры и сортировка — те же параметры, что у /bank/{category}/new.
+func (ec *ExportController) Export(category string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		format := strings.ToLower(c.DefaultQuery("format", "csv"))
+		if format != "csv" && format != "xlsx" {
+			c.JSON(http.StatusBadRequest, gin.H{"result": nil, "success": false, "error": "format должен быть csv или xlsx"})
+			return
+		}
+		lang := exportLang(c.Query("lang"))
+
+		if ok, msg := utils.CanExport(utils.GetRedis(), c.ClientIP()); !ok {
+			c.Header("Retry-After", "60")
+			c.JSON(http.StatusTooManyRequests, gin.H{"result": nil, "success": false, "error": msg})
+			return
+		}
+
+		table, err := ec.table(c, category, lang)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"result": nil, "success": false, "error": "Ошибка при получении данных"})
+			return
+		}
+		if table.rows > exportMaxRows {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"result": nil, "success": false, "error": fmt.Sprintf("Слишком много строк для выгрузки (больше %d), уточните фильтры", exportMaxRows)})
+			return
+		}
+
+		filename := fmt.Sprintf("%s-%s.%s", category, utils.UzbekTime().Format("2006-01-02"), format)
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+		c.Header("Cache-Control", "no-store")
+
+		var w utils.TableWriter
+		if format == "xlsx" {
+			c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+			c.Status(http.StatusOK)
+			w, err = utils.NewXLSXTableWriter(c.Writer, category)
+		} else {
+			c.Header("Content-Type", "text/csv; charset=utf-8")
+			c.Status(http.StatusOK)
+			w, err = utils.NewCSVTableWriter(c.Writer)
+		}
+		if err != nil {
+			utils.LogError(err, "bank export "+category)
+			return
+		}
+
+		// Ответ уже начат — ошибки записи (обрыв соединения) только логируем
+		if err := writeExportTable(w, table, lang); err != nil {
+			utils.LogError(err, "bank export "+category)
+		}
+	}
+}
+
+// writeExportTable пишет заголовок и строки, закрывает writer
+func writeExportTable(w utils.TableWriter, table exportTable, lang string) error {
+	header := make([]string, 0, len(table.columns))
+	for _, col := range table.columns {
+		header = append(header, exportHeaders[col][lang])
+	}
+	if err := w.WriteRow(header); err != nil {
+		return err
+	}
+	for i := 0; i < table.rows; i++ {
+		if err := w.WriteRow(table.row(i)); err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
+
+// table собирает выгрузку направления через общие list*-функции выдачи: список загружается целиком,
+// строки файла собираются лениво при записи
+func (ec *ExportController) table(c *gin.Context, category, lang string) (exportTable, error) {
+	switch category {
+	case "microcredits":
+		list, err := ec.microcredits.listMicrocredits(c, "new_microcredit", true)
+		if err != nil {
+			return exportTable{}, err
+		}
+		translator := utils.GetMicrocreditTranslator()
+		return exportTable{
+			columns: []string{"bank", "description", "rate", "term", "amount", "channel", "url"},
+			rows:    len(list.Items),
+			row: func(i int) []string {
+				item := list.Items[i]
+				t := translator.TranslateMicrocredit(item.BankName, item.Description, item.Rate, item.Term, item.Amount, item.Channel)
+				d := pickLang(lang, t.Uz, t.Ru, t.En, t.Oz)
+				return []string{item.BankName, d.Description, d.Rate, d.Term, d.Amount, d.Channel, item.URL}
+			},
+		}, nil
+	case "autocredits":
+		list, err := ec.autocredits.listAutocredits(c, "new_autocredit", true)
+		if err != nil {
+			return exportTable{}, err
+		}
+		translator := utils.GetMicrocreditTranslator()
+		return exportTable{
+			columns: []string{"bank", "description", "rate", "term", "amount", "channel"},
+			rows:    len(list.Items),
+			row: func(i int) []string {
+				item := list.Items[i]
+				t := translator.TranslateAutocredit(item.BankName, item.Description, item.Rate, item.Term, item.Amount, item.Channel)
+				d := pickLang(lang, t.Uz, t.Ru, t.En, t.Oz)
+				return []string{item.BankName, d.Description, d.Rate, d.Term, d.Amount, d.Channel}
+			},
+		}, nil
+	case "mortgages":
+		list, err := listMortgages(c, ec.db, "new_mortgage", true)
+		if err != nil {
+			return exportTable{}, err
+		}
+		translator := utils.GetMicrocreditTranslator()
+		return exportTable{
+			columns: []string{"bank", "description", "rate", "term", "amount", "channel"},
+			rows:    len(list.Items),
+			row: func(i int) []string {
+				item := list.Items[i]
+				t := translator.TranslateMicrocredit(item.BankName, item.Description, item.Rate, item.Term, item.Amount, item.Channel)
+				d := pickLang(lang, t.Uz, t.Ru, t.En, t.Oz)
+				return []string{item.BankName, d.Description, d.Rate, d.Term, d.Amount, d.Channel}
+			},
+		}, nil
+	case "deposits":
+		list, err := ec.deposits.listDeposits(c, "new_deposit", true)
+		if err != nil {
+			return exportTable{}, err
+		}
+		translator := utils.GetDepositTranslator()
+		return exportTable{
+			columns: []string{"bank", "title", "rate", "term", "min_amount"},
+			rows:    len(list.Items),
+			row: func(i int) []string {
+				item := list.Items[i]
+				t := translator.TranslateDeposit(item.BankName, item.Title, item.Rate, item.TermYears, item.MinAmount)
+				d := pickLang(lang, t.Uz, t.Ru, t.En, t.Oz)
+				return []string{item.BankName, d.Title, d.Rate, d.Term, d.MinAmount}
+			},
+		}, nil
+	case "transfers":
+		list, err := ec.transfers.listTransfers(c, "new_transfer", true)
+		if err != nil {
+			return exportTable{}, err
+		}
+		translator := utils.GetTransferTranslator()
+		return exportTable{
+			columns: []string{"app_name", "commission", "limit"},
+			rows:    len(list.Items),
+			row: func(i int) []string {
+				item := list.Items[i]
+				t := translator.TranslateTransfer(item.AppName, item.Commission, item.LimitUZ)
+				d := pickLang(lang, t.Uz, t.Ru, t.En, t.Oz)
+				limit := ""
+				if d.Limit != nil {
+					limit = *d.Limit
+				}
+				return []string{d.AppName, d.Commission, limit}
+			},
+		}, nil
+	case "cards":
+		list, err := ec.cards.listCards(c, "new_card", true)
+		if err != nil {
+			return exportTable{}, err
+		}
+		translator := utils.GetCardTranslator()
+		return exportTable{
+			columns: []string{"bank", "title", "currency", "system", "opening_type"},
+			rows:    len(list.Items),
+			row: func(i int) []string {
+				item := list.Items[i]
+				t := translator.TranslateCard(item.BankName, item.Title, item.Currency, item.System, item.OpeningType)
+				d := pickLang(lang, t.Uz, t.Ru, t.En, t.Oz)
+				return []string{item.BankName, d.Title, d.Currency, d.System, d.OpeningType}
+			},
+		}, nil
+	case "credit-cards":
+		list, err := ec.cards.listCreditCards(c, "new_credit_card", true)
+		if err != nil {
+			return exportTable{}, err
+		}
+		translator := utils.GetCardTranslator()
+		return exportTable{
+			columns: []string{"bank", "title", "rate", "term", "amount"},
+			rows:    len(list.Items),
+			row: func(i int) []string {
+				item := list.Items[i]
+				t := translator.TranslateCreditCard(item.BankName, item.Title, item.Rate, item.Term, item.Amount)
+				d := pickLang(lang, t.Uz, t.Ru, t.En, t.Oz)
+				return []string{item.BankName, d.Title, d.Rate, d.Term, d.Amount}
+			},
+		}, nil
+	}
+	return exportTable{}, fmt.Errorf("unknown export category %q", category)
+}
@@ -2,8 +2,12 @@ package bank
 
 import (
 	"kliro/models"
+	editorialServices "kliro/services/editorial"
+	partnerServices "kliro/services/partners"
+	rankingServices "kliro/services/ranking"
+	redirectServices "kliro/services/redirect"
+	reviewServices "kliro/services/reviews"
 	"kliro/utils"
-	"math/rand"
 	"net/http"
 	"sort"
 	"strconv"
@@ -72,44 +76,9 @@ func (mc *MicrocreditController) GetNewMicrocredits(c *gin.Context) {
 	mc.getMicrocreditsWithPagination(c, "new_microcredit", true)
 }
 
-// shuffleFilteredByBank переставляет элементы так, чтобы один банк не шёл подряд (round-robin по банкам, порядок банков случайный).
-func shuffleFilteredByBank(filtered []models.Microcredit) []models.Microcredit {
-	if len(filtered) <= 1 {
-		return filtered
-	}
-	byBank := make(map[string][]models.Microcredit)
-	for _, m := range filtered {
-		byBank[m.BankName] = append(byBank[m.BankName], m)
-	}
-	banks := make([]string, 0, len(byBank))
-	for b := range byBank {
-		banks = append(banks, b)
-	}
-	rand.Shuffle(len(banks), func(i, j int) { banks[i], banks[j] = banks[j], banks[i] })
-	result := make([]models.Microcredit, 0, len(filtered))
-	indices := make(map[string]int)
-	for len(result) < len(filtered) {
-		added := false
-		for _, b := range banks {
-			if indices[b] >= len(byBank[b]) {
-				continue
-			}
-			result = append(result, byBank[b][indices[b]])
-			indices[b]++
-			added = true
-		}
-		if !added {
-			break
-		}
-	}
-	return result
-}
-
 // getMicrocreditsWithPagination общая функция для получения микрофинансов с пагинацией.
-// shuffleByBank: для new_microcredit — перемешивать так, чтобы один банк не шёл подряд.
-func (mc *MicrocreditController) getMicrocreditsWithPagination(c *gin.Context, tableName string, shuffleByBank bool) {
-	db := utils.GetDB()
-
+// ranked: для new_microcredit без явного sort — порядок задает движок ранжирования.
+func (mc *MicrocreditController) getMicrocreditsWithPagination(c *gin.Context, tableName string, ranked bool) {
 	// Пагинация и сортировка
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "0"))
 	size, _ := strconv.Atoi(c.DefaultQuery("size", "10"))
@@ -122,6 +91,94 @@ func (mc *MicrocreditController) getMicrocreditsWithPagination(c *gin.Context, t
 		size = 10
 	}
 
+	list, err := mc.listMicrocredits(c, tableName, ranked)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при получении данных"})
+		return
+	}
+	filtered, overrides, ranking, useRanking := list.Items, list.Overrides, list.Ranking, list.Ranked
+
+	// total после числовых фильтров
+	totalElements := int64(len(filtered))
+	totalPages := int((totalElements + int64(size) - 1) / int64(size))
+	if totalPages > 0 {
+		lastPageOffset := (totalPages - 1) * size
+		if lastPageOffset >= len(filtered) {
+			totalPages = totalPages - 1
+		}
+	}
+
+	// Пагинация в памяти
+	offset := page * size
+	end := offset + size
+	if offset > len(filtered) {
+		offset = len(filtered)
+	}
+	if end > len(filtered) {
+		end = len(filtered)
+	}
+	pageItems := filtered[offset:end]
+
+	// Применяем переводы к каждому элементу
+	translator := utils.GetMicrocreditTranslator()
+	// Оценки по одобренным отзывам — одним запросом на страницу
+	keys := productKeys(pageItems, microcreditKey)
+	ratings := reviewServices.ProductAggregates(keys)
+	// Показы продуктов в выдаче — для отчетов банкам-партнерам
+	partnerServices.RecordImpressions("microcredit", keys)
+	redirects := redirectServices.DefaultResolver()
+	translatedContent := make([]utils.TranslatedMicrocredit, 0, len(pageItems))
+	
+	for _, item := range pageItems {
+		translated := translator.TranslateMicrocredit(
+			item.BankName,
+			item.Description,
+			item.Rate,
+			item.Term,
+			item.Amount,
+			item.Channel,
+		)
+		// Заполняем остальные поля
+		translated.ID = item.ID
+		translated.URL = item.URL
+		translated.CreatedAt = item.CreatedAt.Format("2006-01-02T15:04:05.000000Z")
+		translated.ProductKey = microcreditKey(item)
+		translated.Promoted, translated.PromotionLabel = ranking.PromotionByKey(translated.ProductKey)
+		translated.Pinned = overrides.Pinned(translated.ProductKey)
+		translated.EditorialNote = overrides.Note(translated.ProductKey)
+		translated.Rating, translated.ReviewCount = ratings.Get(translated.ProductKey)
+		translated.RedirectURL = redirects.Link("microcredit", translated.ProductKey, item.BankName, item.URL)
+		translatedContent = append(translatedContent, translated)
+	}
+
+	sortObj := Sort{Direction: strings.ToUpper(sortDir), NullHandling: "NATIVE", Ascending: strings.ToLower(sortDir) == "asc", Property: sortBy, IgnoreCase: false}
+	response := TranslatedResponseByPagination{
+		TotalPages:       totalPages,
+		TotalElements:    totalElements,
+		First:            page == 0,
+		Last:             page >= totalPages-1,
+		Size:             size,
+		Content:          translatedContent,
+		Number:           page,
+		Sort:             []Sort{sortObj},
+		NumberOfElements: len(pageItems),
+		Pageable:         Pageable{Offset: offset, Sort: []Sort{sortObj}, Paged: true, PageNumber: page, PageSize: size, Unpaged: false},
+		Empty:            len(pageItems) == 0,
+	}
+
+	resp := gin.H{"result": response, "success": true}
+	if useRanking {
+		resp["ranking"] = ranking.Meta
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// listMicrocredits возвращает отфильтрованный и отсортированный список без пагинации — общий для выдачи и экспорта.
+func (mc *MicrocreditController) listMicrocredits(c *gin.Context, tableName string, ranked bool) (productList[models.Microcredit], error) {
+	db := utils.GetDB()
+	sortBy := c.DefaultQuery("sort", "bank_name")
+	sortDir := c.DefaultQuery("direction", "asc")
+
 	// Фильтры (строковые)
 	bank := c.Query("bank")
 	search := c.Query("search")
@@ -201,10 +258,13 @@ func (mc *MicrocreditController) getMicrocreditsWithPagination(c *gin.Context, t
 	// Загружаем кандидатов (без пагинации), затем применяем числовые фильтры в памяти
 	var all []models.Microcredit
 	if err := baseQ.Find(&all).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при получении данных"})
-		return
+		return productList[models.Microcredit]{}, err
 	}
 
+	// Редакционные правки: исправленные поля и скрытые продукты (до фильтров)
+	overrides := editorialServices.DefaultStore().ForDirection("microcredit")
+	all = editorialServices.Apply(overrides, all, microcreditKey)
+
 	filtered := make([]models.Microcredit, 0, len(all))
 	for _, m := range all {
 		// rate_from: сравниваем с минимальным значением в строке ставки
@@ -231,13 +291,17 @@ func (mc *MicrocreditController) getMicrocreditsWithPagination(c *gin.Context, t
 		filtered = append(filtered, m)
 	}
 
-	// Для /microcredits/new — случайный порядок и round-robin по банкам, чтобы один банк не шёл подряд
-	if shuffleByBank && len(filtered) > 1 {
-		filtered = shuffleFilteredByBank(filtered)
+	// Для /microcredits/new без явного sort — детерминированный порядок из ranking_configs,
+	// seed для стабильной пагинации и спонсорские места
+	useRanking := ranked && c.Query("sort") == ""
+	var ranking rankingServices.Ranked[models.Microcredit]
+	if useRanking {
+		ranking = rankingServices.Rank(rankingServices.DefaultEngine(), "microcredit", c.Query("seed"), filtered, microcreditFeatures, rankingOverride(c, "microcredit"))
+		filtered = ranking.Items
 	}
 
-	// Сортировка ДО пагинации (не применяем, если включён shuffle по банкам — чтобы не сгруппировать один банк подряд)
-	if !shuffleByBank {
+	// Сортировка ДО пагинации (только если не работает ранжирование)
+	if !useRanking {
 		if rateFromStr != "" {
 			sort.SliceStable(filtered, func(i, j int) bool {
 				rateI := utils.ExtractFirstFloat(filtered[i].Rate)
@@ -254,61 +318,8 @@ func (mc *MicrocreditController) getMicrocreditsWithPagination(c *gin.Context, t
 		}
 	}
 
-	// total после числовых фильтров
-	totalElements := int64(len(filtered))
-	totalPages := int((totalElements + int64(size) - 1) / int64(size))
-	if totalPages > 0 {
-		lastPageOffset := (totalPages - 1) * size
-		if lastPageOffset >= len(filtered) {
-			totalPages = totalPages - 1
-		}
-	}
-
-	// Пагинация в памяти
-	offset := page * size
-	end := offset + size
-	if offset > len(filtered) {
-		offset = len(filtered)
-	}
-	if end > len(filtered) {
-		end = len(filtered)
-	}
-	pageItems := filtered[offset:end]
-
-	// Применяем переводы к каждому элементу
-	translator := utils.GetMicrocreditTranslator()
-	translatedContent := make([]utils.TranslatedMicrocredit, 0, len(pageItems))
-	
-	for _, item := range pageItems {
-		translated := translator.TranslateMicrocredit(
-			item.BankName,
-			item.Description,
-			item.Rate,
-			item.Term,
-			item.Amount,
-			item.Channel,
-		)
-		// Заполняем остальные поля
-		translated.ID = item.ID
-		translated.URL = item.URL
-		translated.CreatedAt = item.CreatedAt.Format("2006-01-02T15:04:05.000000Z")
-		translatedContent = append(translatedContent, translated)
-	}
-
-	sortObj := Sort{Direction: strings.ToUpper(sortDir), NullHandling: "NATIVE", Ascending: strings.ToLower(sortDir) == "asc", Property: sortBy, IgnoreCase: false}
-	response := TranslatedResponseByPagination{
-		TotalPages:       totalPages,
-		TotalElements:    totalElements,
-		First:            page == 0,
-		Last:             page >= totalPages-1,
-		Size:             size,
-		Content:          translatedContent,
-		Number:           page,
-		Sort:             []Sort{sortObj},
-		NumberOfElements: len(pageItems),
-		Pageable:         Pageable{Offset: offset, Sort: []Sort{sortObj}, Paged: true, PageNumber: page, PageSize: size, Unpaged: false},
-		Empty:            len(pageItems) == 0,
-	}
+	// Закрепленные редакцией продукты — в начало списка
+	filtered = editorialServices.Pin(overrides, filtered, microcreditKey)
 
-	c.JSON(http.StatusOK, gin.H{"result": response, "success": true})
+	return productList[models.Microcredit]{Items: filtered, Overrides: overrides, Ranking: ranking, Ranked: useRanking}, nil
 }
@@ -3,8 +3,12 @@ package bank
 import (
 	"kliro/models"
 	bankServices "kliro/services/bank"
+	editorialServices "kliro/services/editorial"
+	partnerServices "kliro/services/partners"
+	rankingServices "kliro/services/ranking"
+	redirectServices "kliro/services/redirect"
+	reviewServices "kliro/services/reviews"
 	"kliro/utils"
-	"math/rand"
 	"net/http"
 	"sort"
 	"strconv"
@@ -22,7 +26,7 @@ func NewMortgageController(db *gorm.DB) *MortgageController {
 	return &MortgageController{db: db}
 }
 
-// GetNewMortgages получает новые ипотечные кредиты с пагинацией (порядок каждый раз случайный).
+// GetNewMortgages получает новые ипотечные кредиты с пагинацией (порядок задает движок ранжирования).
 func (mc *MortgageController) GetNewMortgages(c *gin.Context) {
 	getMortgagesWithPagination(c, mc.db, "new_mortgage", true)
 }
@@ -67,12 +71,11 @@ func (mc *MortgageController) ParseMortgage(c *gin.Context) {
 	})
 }
 
-func getMortgagesWithPagination(c *gin.Context, db *gorm.DB, tableName string, shuffleOrder bool) {
+// ranked: при true и без явного sortBy порядок задает движок ранжирования
+func getMortgagesWithPagination(c *gin.Context, db *gorm.DB, tableName string, ranked bool) {
 	// Параметры пагинации (1-based)
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-	sortBy := c.DefaultQuery("sortBy", "created_at")
-	sortOrder := c.DefaultQuery("sortOrder", "desc")
 	if page < 1 {
 		page = 1
 	}
@@ -80,6 +83,91 @@ func getMortgagesWithPagination(c *gin.Context, db *gorm.DB, tableName string, s
 		limit = 10
 	}
 
+	list, err := listMortgages(c, db, tableName, ranked)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"result": nil, "success": false, "error": "DB error"})
+		return
+	}
+	filtered, overrides, ranking, useRanking := list.Items, list.Overrides, list.Ranking, list.Ranked
+
+	// Пагинация в памяти
+	offset := (page - 1) * limit
+	end := offset + limit
+	if offset > len(filtered) {
+		offset = len(filtered)
+	}
+	if end > len(filtered) {
+		end = len(filtered)
+	}
+	pageItems := filtered[offset:end]
+
+	// Переводим данные через API (как у microcredit и autocredit)
+	translator := utils.GetMicrocreditTranslator()
+	// Оценки по одобренным отзывам — одним запросом на страницу
+	keys := productKeys(pageItems, mortgageKey)
+	ratings := reviewServices.ProductAggregates(keys)
+	// Показы продуктов в выдаче — для отчетов банкам-партнерам
+	partnerServices.RecordImpressions("mortgage", keys)
+	redirects := redirectServices.DefaultResolver()
+	translatedContent := make([]utils.TranslatedMicrocredit, 0, len(pageItems))
+	
+	for _, item := range pageItems {
+		translated := translator.TranslateMicrocredit(
+			item.BankName,
+			item.Description,
+			item.Rate,
+			item.Term,
+			item.Amount,
+			item.Channel,
+		)
+		translated.ID = item.ID
+		translated.URL = "" // У mortgage нет URL
+		translated.CreatedAt = item.CreatedAt.Format("2006-01-02T15:04:05.000000Z")
+		translated.ProductKey = mortgageKey(item)
+		translated.Promoted, translated.PromotionLabel = ranking.PromotionByKey(translated.ProductKey)
+		translated.Pinned = overrides.Pinned(translated.ProductKey)
+		translated.EditorialNote = overrides.Note(translated.ProductKey)
+		translated.Rating, translated.ReviewCount = ratings.Get(translated.ProductKey)
+		translated.RedirectURL = redirects.Link("mortgage", translated.ProductKey, item.BankName, "")
+		translatedContent = append(translatedContent, translated)
+	}
+
+	// Подсчитываем total и totalPages
+	total := int64(len(filtered))
+	totalPages := (total + int64(limit) - 1) / int64(limit)
+	if totalPages > 0 {
+		lastPageOffset := int((totalPages - 1) * int64(limit))
+		if lastPageOffset >= len(filtered) {
+			totalPages = totalPages - 1
+		}
+	}
+
+	response := gin.H{
+		"result": gin.H{
+			"totalPages":       totalPages,
+			"totalElements":    total,
+			"first":            page == 1,
+			"last":             page >= int(totalPages),
+			"size":             limit,
+			"content":          translatedContent,
+			"number":           page - 1, // 0-based
+			"numberOfElements": len(translatedContent),
+			"empty":            len(translatedContent) == 0,
+		},
+		"success": true,
+	}
+	if useRanking {
+		response["ranking"] = ranking.Meta
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// listMortgages возвращает отфильтрованный и отсортированный список без пагинации — общий для выдачи и экспорта.
+func listMortgages(c *gin.Context, db *gorm.DB, tableName string, ranked bool) (productList[models.Mortgage], error) {
+	sortBy := c.DefaultQuery("sortBy", "created_at")
+	sortOrder := c.DefaultQuery("sortOrder", "desc")
+
 	// Фильтры
 	bank := c.Query("bank")
 	search := c.Query("search")
@@ -133,10 +221,13 @@ func getMortgagesWithPagination(c *gin.Context, db *gorm.DB, tableName string, s
 	// Загружаем кандидатов
 	var all []models.Mortgage
 	if err := baseQ.Find(&all).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"result": nil, "success": false, "error": "DB error"})
-		return
+		return productList[models.Mortgage]{}, err
 	}
 
+	// Редакционные правки: исправленные поля и скрытые продукты (до фильтров)
+	overrides := editorialServices.DefaultStore().ForDirection("mortgage")
+	all = editorialServices.Apply(overrides, all, mortgageKey)
+
 	// Числовые фильтры в памяти
 	rateFrom := utils.ParseFloatSafe(rateFromStr)
 	termFrom := utils.ParseIntSafe(termFromStr)
@@ -165,13 +256,16 @@ func getMortgagesWithPagination(c *gin.Context, db *gorm.DB, tableName string, s
 		filtered = append(filtered, m)
 	}
 
-	// Случайный порядок для /mortgages/new
-	if shuffleOrder && len(filtered) > 1 {
-		rand.Shuffle(len(filtered), func(i, j int) { filtered[i], filtered[j] = filtered[j], filtered[i] })
+	// Ранжирование для /mortgages/new (детерминированно, seed и спонсорские места)
+	useRanking := ranked && c.Query("sortBy") == ""
+	var ranking rankingServices.Ranked[models.Mortgage]
+	if useRanking {
+		ranking = rankingServices.Rank(rankingServices.DefaultEngine(), "mortgage", c.Query("seed"), filtered, mortgageFeatures, rankingOverride(c, "mortgage"))
+		filtered = ranking.Items
 	}
 
-	// Сортировка ДО пагинации (не применяем при shuffleOrder)
-	if !shuffleOrder {
+	// Сортировка ДО пагинации (не применяем при ранжировании)
+	if !useRanking {
 		if rateFromStr != "" {
 			sort.SliceStable(filtered, func(i, j int) bool {
 				rateI := utils.ExtractFirstFloat(filtered[i].Rate)
@@ -188,60 +282,8 @@ func getMortgagesWithPagination(c *gin.Context, db *gorm.DB, tableName string, s
 		}
 	}
 
-	// Пагинация в памяти
-	offset := (page - 1) * limit
-	end := offset + limit
-	if offset > len(filtered) {
-		offset = len(filtered)
-	}
-	if end > len(filtered) {
-		end = len(filtered)
-	}
-	pageItems := filtered[offset:end]
+	// Закрепленные редакцией продукты — в начало списка
+	filtered = editorialServices.Pin(overrides, filtered, mortgageKey)
 
-	// Переводим данные через API (как у microcredit и autocredit)
-	translator := utils.GetMicrocreditTranslator()
-	translatedContent := make([]utils.TranslatedMicrocredit, 0, len(pageItems))
-	
-	for _, item := range pageItems {
-		translated := translator.TranslateMicrocredit(
-			item.BankName,
-			item.Description,
-			item.Rate,
-			item.Term,
-			item.Amount,
-			item.Channel,
-		)
-		translated.ID = item.ID
-		translated.URL = "" // У mortgage нет URL
-		translated.CreatedAt = item.CreatedAt.Format("2006-01-02T15:04:05.000000Z")
-		translatedContent = append(translatedContent, translated)
-	}
-
-	// Подсчитываем total и totalPages
-	total := int64(len(filtered))
-	totalPages := (total + int64(limit) - 1) / int64(limit)
-	if totalPages > 0 {
-		lastPageOffset := int((totalPages - 1) * int64(limit))
-		if lastPageOffset >= len(filtered) {
-			totalPages = totalPages - 1
-		}
-	}
-
-	response := gin.H{
-		"result": gin.H{
-			"totalPages":       totalPages,
-			"totalElements":    total,
-			"first":            page == 1,
-			"last":             page >= int(totalPages),
-			"size":             limit,
-			"content":          translatedContent,
-			"number":           page - 1, // 0-based
-			"numberOfElements": len(translatedContent),
-			"empty":            len(translatedContent) == 0,
-		},
-		"success": true,
-	}
-
-	c.JSON(http.StatusOK, response)
+	return productList[models.Mortgage]{Items: filtered, Overrides: overrides, Ranking: ranking, Ranked: useRanking}, nil
 }
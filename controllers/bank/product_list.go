@@ -0,0 +1,15 @@
+package bank
+
+import (
+	editorialServices "kliro/services/editorial"
+	rankingServices "kliro/services/ranking"
+)
+
+// productList — отфильтрованный и отсортированный список продуктов направления до пагинации.
+// Один и тот же набор отдают выдача (/new) и экспорт (/export).
+type productList[T any] struct {
+	Items     []T
+	Overrides editorialServices.Set
+	Ranking   rankingServices.Ranked[T]
+	Ranked    bool
+}
@@ -0,0 +1,108 @@
+package bank
+
+import (
+	"regexp"
+
+	"kliro/models"
+	experimentServices "kliro/services/experiments"
+	rankingServices "kliro/services/ranking"
+	"kliro/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Признаки продуктов для движка ранжирования (ставка, свежесть, стабильный ключ)
+
+var hasDigitRe = regexp.MustCompile(`\d`)
+
+// Ключи продуктов (utils.ProductKey) — по ним работают ранжирование и редакционные правки
+
+func microcreditKey(m models.Microcredit) string {
+	return utils.ProductKey("microcredit", m.BankName, m.Description)
+}
+
+func autocreditKey(a models.Autocredit) string {
+	return utils.ProductKey("autocredit", a.BankName, a.Description)
+}
+
+func mortgageKey(m models.Mortgage) string {
+	return utils.ProductKey("mortgage", m.BankName, m.Description)
+}
+
+func depositKey(d models.Deposit) string {
+	return utils.ProductKey("deposit", d.BankName, d.Title)
+}
+
+func cardKey(cd models.Card) string {
+	return utils.ProductKey("card", cd.BankName, cd.Title)
+}
+
+func creditCardKey(cc models.CreditCard) string {
+	return utils.ProductKey("credit", cc.BankName, cc.Title)
+}
+
+func transferKey(t models.Transfer) string {
+	return utils.ProductKey("transfer", t.AppName, t.AppName)
+}
+
+func microcreditFeatures(m models.Microcredit) rankingServices.Features {
+	rate := utils.ExtractFirstFloat(m.Rate)
+	return rankingServices.Features{Key: microcreditKey(m), BankName: m.BankName, Title: m.Description, Rate: rate, HasRate: rate > 0, CreatedAt: m.CreatedAt}
+}
+
+func autocreditFeatures(a models.Autocredit) rankingServices.Features {
+	rate := utils.ExtractFirstFloat(a.Rate)
+	return rankingServices.Features{Key: autocreditKey(a), BankName: a.BankName, Title: a.Description, Rate: rate, HasRate: rate > 0, CreatedAt: a.CreatedAt}
+}
+
+func mortgageFeatures(m models.Mortgage) rankingServices.Features {
+	rate := utils.ExtractFirstFloat(m.Rate)
+	return rankingServices.Features{Key: mortgageKey(m), BankName: m.BankName, Title: m.Description, Rate: rate, HasRate: rate > 0, CreatedAt: m.CreatedAt}
+}
+
+func depositFeatures(d models.Deposit) rankingServices.Features {
+	rate := utils.ExtractFirstFloat(d.Rate)
+	return rankingServices.Features{Key: depositKey(d), BankName: d.BankName, Title: d.Title, Rate: rate, HasRate: rate > 0, CreatedAt: d.CreatedAt}
+}
+
+func cardFeatures(cd models.Card) rankingServices.Features {
+	return rankingServices.Features{Key: cardKey(cd), BankName: cd.BankName, Title: cd.Title, CreatedAt: cd.CreatedAt}
+}
+
+func creditCardFeatures(cc models.CreditCard) rankingServices.Features {
+	rate := utils.ExtractFirstFloat(cc.Rate)
+	return rankingServices.Features{Key: creditCardKey(cc), BankName: cc.BankName, Title: cc.Title, Rate: rate, HasRate: rate > 0, CreatedAt: cc.CreatedAt}
+}
+
+// transferFeatures: у переводов нет банка — роль банка играет приложение, ставка — комиссия (0% — валидное значение)
+func transferFeatures(t models.Transfer) rankingServices.Features {
+	return rankingServices.Features{Key: transferKey(t), BankName: t.AppName, Title: t.AppName, Rate: utils.ExtractFirstFloat(t.Commission), HasRate: hasDigitRe.MatchString(t.Commission), CreatedAt: t.CreatedAt}
+}
+
+// productKeys - ключи продуктов страницы (для пакетной загрузки оценок)
+func productKeys[T any](items []T, keyOf func(T) string) []string {
+	keys := make([]string, len(items))
+	for i, it := range items {
+		keys[i] = keyOf(it)
+	}
+	return keys
+}
+
+// rankingOverride - порядок списка из A/B эксперимента "ranking_<direction>", если он запущен.
+// Config варианта: {"strategy": "...", "tie_breaker": "...", "diversify_banks": true, "sponsored": false}
+func rankingOverride(c *gin.Context, direction string) rankingServices.Override {
+	a, ok := experimentServices.FromContext(c).Variant("ranking_" + direction)
+	if !ok {
+		return rankingServices.Override{}
+	}
+	o := rankingServices.Override{Experiment: a.Experiment + ":" + a.Variant}
+	o.Strategy, _ = a.Config["strategy"].(string)
+	o.TieBreaker, _ = a.Config["tie_breaker"].(string)
+	if v, ok := a.Config["diversify_banks"].(bool); ok {
+		o.DiversifyBanks = &v
+	}
+	if v, ok := a.Config["sponsored"].(bool); ok && !v {
+		o.DisableSponsored = true
+	}
+	return o
+}
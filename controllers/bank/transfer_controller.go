@@ -3,8 +3,12 @@ package bank
 import (
 	"kliro/models"
 	bankServices "kliro/services/bank"
+	editorialServices "kliro/services/editorial"
+	partnerServices "kliro/services/partners"
+	rankingServices "kliro/services/ranking"
+	redirectServices "kliro/services/redirect"
+	reviewServices "kliro/services/reviews"
 	"kliro/utils"
-	"math/rand"
 	"net/http"
 	"sort"
 	"strconv"
@@ -49,7 +53,7 @@ type TranslatedTransferResponseByPagination struct {
 	Empty            bool                        `json:"empty"`
 }
 
-// GetNewTransfers получает новые переводы с пагинацией (порядок каждый раз случайный).
+// GetNewTransfers получает новые переводы с пагинацией (порядок задает движок ранжирования).
 func (tc *TransferController) GetNewTransfers(c *gin.Context) {
 	tc.getTransfersWithPagination(c, "new_transfer", true)
 }
@@ -95,17 +99,13 @@ func (tc *TransferController) ParseTransfer(c *gin.Context) {
 }
 
 // getTransfersWithPagination общая функция для получения переводов с пагинацией.
-// shuffleOrder: при true порядок записей случайный каждый раз.
-func (tc *TransferController) getTransfersWithPagination(c *gin.Context, tableName string, shuffleOrder bool) {
-	db := utils.GetDB()
-
+// ranked: при true и без явного sort порядок задает движок ранжирования.
+func (tc *TransferController) getTransfersWithPagination(c *gin.Context, tableName string, ranked bool) {
 	// Параметры пагинации
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "0"))
 	size, _ := strconv.Atoi(c.DefaultQuery("size", "10"))
 	sortBy := c.DefaultQuery("sort", "app_name")
 	sortDir := c.DefaultQuery("direction", "asc")
-	search := c.Query("search")
-	commissionFromStr := c.Query("commission_from")
 
 	// Валидация параметров
 	if page < 0 {
@@ -115,83 +115,12 @@ func (tc *TransferController) getTransfersWithPagination(c *gin.Context, tableNa
 		size = 10
 	}
 
-	// Парсим commission_from
-	commissionFrom := utils.ParseFloatSafe(commissionFromStr)
-
-	// Загружаем все данные из БД
-	var all []models.Transfer
-	baseQuery := db.Table(tableName)
-	if search != "" {
-		baseQuery = baseQuery.Where("app_name ILIKE ?", "%"+search+"%")
-	}
-
-	if err := baseQuery.Find(&all).Error; err != nil {
+	list, err := tc.listTransfers(c, tableName, ranked)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при получении данных"})
 		return
 	}
-
-	// Фильтрация по commission_from
-	// commission_from показывает записи с комиссией >= указанного значения
-	filtered := make([]models.Transfer, 0, len(all))
-	for _, t := range all {
-		if commissionFromStr != "" {
-			commissionValue := utils.ExtractFirstFloat(t.Commission)
-			if commissionValue < commissionFrom {
-				continue
-			}
-		}
-		filtered = append(filtered, t)
-	}
-
-	// Для /transfers/new: сначала приложения с 0% комиссией (внутри случайно), затем остальные (внутри случайно)
-	if shuffleOrder && len(filtered) > 0 {
-		zeroComm := make([]models.Transfer, 0)
-		other := make([]models.Transfer, 0)
-		for _, t := range filtered {
-			if utils.ExtractFirstFloat(t.Commission) == 0 {
-				zeroComm = append(zeroComm, t)
-			} else {
-				other = append(other, t)
-			}
-		}
-		rand.Shuffle(len(zeroComm), func(i, j int) { zeroComm[i], zeroComm[j] = zeroComm[j], zeroComm[i] })
-		rand.Shuffle(len(other), func(i, j int) { other[i], other[j] = other[j], other[i] })
-		filtered = append(zeroComm, other...)
-	}
-
-	// Сортировка ДО пагинации (не применяем при shuffleOrder)
-	if !shuffleOrder && commissionFromStr != "" {
-		// Автоматическая сортировка по комиссии от большего к меньшему при наличии фильтра
-		// чтобы сначала показывались записи с комиссией ближе к указанному значению
-		sort.SliceStable(filtered, func(i, j int) bool {
-			commI := utils.ExtractFirstFloat(filtered[i].Commission)
-			commJ := utils.ExtractFirstFloat(filtered[j].Commission)
-			return commI > commJ
-		})
-	} else if !shuffleOrder && strings.EqualFold(sortBy, "commission") {
-		sort.SliceStable(filtered, func(i, j int) bool {
-			commI := utils.ExtractFirstFloat(filtered[i].Commission)
-			commJ := utils.ExtractFirstFloat(filtered[j].Commission)
-			if strings.ToLower(sortDir) == "desc" {
-				return commI > commJ
-			}
-			return commI < commJ
-		})
-	} else if !shuffleOrder && strings.EqualFold(sortBy, "app_name") {
-		sort.SliceStable(filtered, func(i, j int) bool {
-			if strings.ToLower(sortDir) == "desc" {
-				return filtered[i].AppName > filtered[j].AppName
-			}
-			return filtered[i].AppName < filtered[j].AppName
-		})
-	} else if !shuffleOrder && strings.EqualFold(sortBy, "created_at") {
-		sort.SliceStable(filtered, func(i, j int) bool {
-			if strings.ToLower(sortDir) == "desc" {
-				return filtered[i].CreatedAt.After(filtered[j].CreatedAt)
-			}
-			return filtered[i].CreatedAt.Before(filtered[j].CreatedAt)
-		})
-	}
+	filtered, overrides, ranking, useRanking := list.Items, list.Overrides, list.Ranking, list.Ranked
 
 	// Подсчет общего количества после фильтрации
 	totalElements := int64(len(filtered))
@@ -236,6 +165,12 @@ func (tc *TransferController) getTransfersWithPagination(c *gin.Context, tableNa
 
 	// Переводим данные через API
 	translator := utils.GetTransferTranslator()
+	// Оценки по одобренным отзывам — одним запросом на страницу
+	keys := productKeys(pageItems, transferKey)
+	ratings := reviewServices.ProductAggregates(keys)
+	// Показы продуктов в выдаче — для отчетов банкам-партнерам
+	partnerServices.RecordImpressions("transfer", keys)
+	redirects := redirectServices.DefaultResolver()
 	translatedContent := make([]utils.TranslatedTransfer, 0, len(pageItems))
 
 	for _, item := range pageItems {
@@ -246,6 +181,12 @@ func (tc *TransferController) getTransfersWithPagination(c *gin.Context, tableNa
 		)
 		translated.ID = item.ID
 		translated.CreatedAt = item.CreatedAt.Format("2006-01-02T15:04:05.000000Z")
+		translated.ProductKey = transferKey(item)
+		translated.Promoted, translated.PromotionLabel = ranking.PromotionByKey(translated.ProductKey)
+		translated.Pinned = overrides.Pinned(translated.ProductKey)
+		translated.EditorialNote = overrides.Note(translated.ProductKey)
+		translated.Rating, translated.ReviewCount = ratings.Get(translated.ProductKey)
+		translated.RedirectURL = redirects.Link("transfer", translated.ProductKey, item.AppName, "")
 		translatedContent = append(translatedContent, translated)
 	}
 
@@ -280,5 +221,97 @@ func (tc *TransferController) getTransfersWithPagination(c *gin.Context, tableNa
 		Empty: len(translatedContent) == 0,
 	}
 
-	c.JSON(http.StatusOK, gin.H{"result": response, "success": true})
+	resp := gin.H{"result": response, "success": true}
+	if useRanking {
+		resp["ranking"] = ranking.Meta
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// listTransfers возвращает отфильтрованный и отсортированный список без пагинации — общий для выдачи и экспорта.
+func (tc *TransferController) listTransfers(c *gin.Context, tableName string, ranked bool) (productList[models.Transfer], error) {
+	db := utils.GetDB()
+	sortBy := c.DefaultQuery("sort", "app_name")
+	sortDir := c.DefaultQuery("direction", "asc")
+	search := c.Query("search")
+	commissionFromStr := c.Query("commission_from")
+
+	// Парсим commission_from
+	commissionFrom := utils.ParseFloatSafe(commissionFromStr)
+
+	// Загружаем все данные из БД
+	var all []models.Transfer
+	baseQuery := db.Table(tableName)
+	if search != "" {
+		baseQuery = baseQuery.Where("app_name ILIKE ?", "%"+search+"%")
+	}
+
+	if err := baseQuery.Find(&all).Error; err != nil {
+		return productList[models.Transfer]{}, err
+	}
+
+	// Редакционные правки: исправленные поля и скрытые продукты (до фильтров)
+	overrides := editorialServices.DefaultStore().ForDirection("transfer")
+	all = editorialServices.Apply(overrides, all, transferKey)
+
+	// Фильтрация по commission_from
+	// commission_from показывает записи с комиссией >= указанного значения
+	filtered := make([]models.Transfer, 0, len(all))
+	for _, t := range all {
+		if commissionFromStr != "" {
+			commissionValue := utils.ExtractFirstFloat(t.Commission)
+			if commissionValue < commissionFrom {
+				continue
+			}
+		}
+		filtered = append(filtered, t)
+	}
+
+	// Для /transfers/new без явного sort: движок ранжирования (по умолчанию — по возрастанию комиссии,
+	// 0% первыми; seed для пагинации, спонсорские места)
+	useRanking := ranked && c.Query("sort") == ""
+	var ranking rankingServices.Ranked[models.Transfer]
+	if useRanking {
+		ranking = rankingServices.Rank(rankingServices.DefaultEngine(), "transfer", c.Query("seed"), filtered, transferFeatures, rankingOverride(c, "transfer"))
+		filtered = ranking.Items
+	}
+
+	// Сортировка ДО пагинации (не применяем при ранжировании)
+	if !useRanking && commissionFromStr != "" {
+		// Автоматическая сортировка по комиссии от большего к меньшему при наличии фильтра
+		// чтобы сначала показывались записи с комиссией ближе к указанному значению
+		sort.SliceStable(filtered, func(i, j int) bool {
+			commI := utils.ExtractFirstFloat(filtered[i].Commission)
+			commJ := utils.ExtractFirstFloat(filtered[j].Commission)
+			return commI > commJ
+		})
+	} else if !useRanking && strings.EqualFold(sortBy, "commission") {
+		sort.SliceStable(filtered, func(i, j int) bool {
+			commI := utils.ExtractFirstFloat(filtered[i].Commission)
+			commJ := utils.ExtractFirstFloat(filtered[j].Commission)
+			if strings.ToLower(sortDir) == "desc" {
+				return commI > commJ
+			}
+			return commI < commJ
+		})
+	} else if !useRanking && strings.EqualFold(sortBy, "app_name") {
+		sort.SliceStable(filtered, func(i, j int) bool {
+			if strings.ToLower(sortDir) == "desc" {
+				return filtered[i].AppName > filtered[j].AppName
+			}
+			return filtered[i].AppName < filtered[j].AppName
+		})
+	} else if !useRanking && strings.EqualFold(sortBy, "created_at") {
+		sort.SliceStable(filtered, func(i, j int) bool {
+			if strings.ToLower(sortDir) == "desc" {
+				return filtered[i].CreatedAt.After(filtered[j].CreatedAt)
+			}
+			return filtered[i].CreatedAt.Before(filtered[j].CreatedAt)
+		})
+	}
+
+	// Закрепленные редакцией продукты — в начало списка
+	filtered = editorialServices.Pin(overrides, filtered, transferKey)
+
+	return productList[models.Transfer]{Items: filtered, Overrides: overrides, Ranking: ranking, Ranked: useRanking}, nil
 }
@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+
+	experimentServices "kliro/services/experiments"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ExperimentController struct{}
+
+func NewExperimentController() *ExperimentController {
+	return &ExperimentController{}
+}
+
+// GET /experiments/assignments — варианты пользователя во всех запущенных экспериментах.
+// Субъект: авторизованный пользователь, иначе X-Device-ID (или ?device_id=). Показ не записывается —
+// приложение сообщает о нем через POST /experiments/events, когда вариант действительно увидели.
+func (ec *ExperimentController) Assignments(c *gin.Context) {
+	session := experimentServices.FromContext(c)
+	subject := session.Subject()
+	if subject == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"result": nil, "success": false, "error": "Передайте X-Device-ID или авторизуйтесь"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"result": gin.H{
+			"subject":     subject,
+			"assignments": experimentServices.DefaultStore().AssignAll(subject),
+		},
+		"success": true,
+	})
+}
+
+// ExperimentEventRequest - событие эксперимента от приложения
+type ExperimentEventRequest struct {
+	Experiment string  `json:"experiment"` // для exposure — обязательно
+	Event      string  `json:"event" binding:"required"`
+	Goal       string  `json:"goal"` // для conversion — обязательно
+	Value      float64 `json:"value"`
+}
+
+// POST /experiments/events — показ варианта (exposure) или целевое действие (conversion).
+// Вариант определяется сервером по субъекту, значение из приложения не принимается.
+func (ec *ExperimentController) Event(c *gin.Context) {
+	var req ExperimentEventRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"result": nil, "success": false, "error": "invalid request"})
+		return
+	}
+	session := experimentServices.FromContext(c)
+	if session.Subject() == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"result": nil, "success": false, "error": "Передайте X-Device-ID или авторизуйтесь"})
+		return
+	}
+
+	switch strings.ToLower(req.Event) {
+	case experimentServices.EventExposure:
+		a, ok := session.Variant(req.Experiment)
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"result": nil, "success": false, "error": "Эксперимент не найден или пользователь в нем не участвует"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"result": a, "success": true})
+	case experimentServices.EventConversion:
+		goal := strings.ToLower(strings.TrimSpace(req.Goal))
+		if goal == "" || len(goal) > 50 {
+			c.JSON(http.StatusBadRequest, gin.H{"result": nil, "success": false, "error": "Укажите goal"})
+			return
+		}
+		session.Convert(goal, req.Value)
+		c.JSON(http.StatusOK, gin.H{"result": nil, "success": true})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"result": nil, "success": false, "error": "event должен быть exposure или conversion"})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -15,18 +16,23 @@ import (
 	"github.com/google/uuid"
 
 	"kliro/config"
+	funnelServices "kliro/services/funnel"
+	healthServices "kliro/services/providerhealth"
+	quoteServices "kliro/services/quotes"
 	"kliro/utils"
 )
 
 type KaskoAllController struct {
-	cfg *config.Config
-	cl  *http.Client
+	cfg        *config.Config
+	cl         *http.Client
+	quoteCache *quoteServices.Cache
 }
 
 func NewKaskoAllController(cfg *config.Config) *KaskoAllController {
 	return &KaskoAllController{
-		cfg: cfg,
-		cl:  &http.Client{Timeout: 30 * time.Second},
+		cfg:        cfg,
+		cl:         &http.Client{Timeout: 30 * time.Second},
+		quoteCache: quoteServices.NewCache(cfg),
 	}
 }
 
@@ -43,6 +49,7 @@ func (kc *KaskoAllController) Start(c *gin.Context) {
 
 	// keep minimal session payload; client will update it via calculate
 	kc.saveSession(sessionID, map[string]interface{}{"created_at": time.Now().Format(time.RFC3339)})
+	funnelServices.Track(c, funnelServices.FlowKasko, "start", sessionID, "", true, "")
 
 	c.JSON(http.StatusOK, KaskoStartResponse{
 		SessionID:    sessionID,
@@ -158,6 +165,12 @@ type KaskoCalculateResponse struct {
 	ProductType KaskoProductType `json:"product_type"`
 	Results  []ProviderResult  `json:"results"`
 	Errors   []string          `json:"errors,omitempty"`
+	Meta     *KaskoResponseMeta `json:"meta,omitempty"`
+}
+
+// KaskoResponseMeta - метаданные расчета: hit/miss кэша котировок по каждой страховой
+type KaskoResponseMeta struct {
+	Cache *quoteServices.CacheMeta `json:"cache"`
 }
 
 // -------- handlers ----------
@@ -194,37 +207,107 @@ func (kc *KaskoAllController) Calculate(c *gin.Context) {
 
 	results := make([]ProviderResult, 0)
 
+	// Кэш котировок по параметрам расчета (?no_cache=1 — опросить страховые заново)
+	cacheMeta := quoteServices.NewCacheMeta(quoteServices.CacheKey(quoteServices.ProductKasko, kaskoCacheInputs(req, lang)), quoteCacheBypass(c))
+	calc := func(provider string, fn func() ProviderResult) ProviderResult {
+		var r ProviderResult
+		if kc.quoteCache.Get(quoteServices.ProductKasko, provider, cacheMeta, &r) {
+			return r
+		}
+		err := healthServices.Do(provider, "kasko.calculate", func() error {
+			r = fn()
+			if len(r.Offers) == 0 && len(r.Errors) > 0 {
+				return errors.New(strings.Join(r.Errors, "; "))
+			}
+			return nil
+		})
+		if errors.Is(err, healthServices.ErrCircuitOpen) {
+			return ProviderResult{Provider: provider, Errors: []string{err.Error()}}
+		}
+		if len(r.Offers) > 0 && len(r.Errors) == 0 {
+			kc.quoteCache.Set(quoteServices.ProductKasko, provider, cacheMeta, r)
+		}
+		return r
+	}
+
 	switch req.ProductType {
 	case ProductMini:
 		if allowProvider("neo") {
-			results = append(results, kc.calcNeoMini(lang))
+			results = append(results, calc("neo", func() ProviderResult { return kc.calcNeoMini(lang) }))
 		}
 	case ProductEuro:
 		if allowProvider("euroasia") {
-			results = append(results, kc.calcEuroAsia(lang, req.Risks))
+			results = append(results, calc("euroasia", func() ProviderResult { return kc.calcEuroAsia(lang, req.Risks) }))
 		}
 	case ProductFull:
 		if allowProvider("neo") {
-			results = append(results, kc.calcNeoFull(lang, req))
+			results = append(results, calc("neo", func() ProviderResult { return kc.calcNeoFull(lang, req) }))
 		}
 		if allowProvider("gross") {
-			results = append(results, kc.calcGross(lang, req))
+			results = append(results, calc("gross", func() ProviderResult { return kc.calcGross(lang, req) }))
 		}
 		if allowProvider("trust") {
-			results = append(results, kc.calcTrust(lang, req))
+			results = append(results, calc("trust", func() ProviderResult { return kc.calcTrust(lang, req) }))
 		}
 	default:
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product_type", "details": string(req.ProductType)})
 		return
 	}
 
+	// Шаг воронки: результат по каждой страховой и по шагу в целом
+	hasOffers := false
+	for _, r := range results {
+		funnelServices.Track(c, funnelServices.FlowKasko, "calculate", req.SessionID, r.Provider, len(r.Offers) > 0, strings.Join(r.Errors, "; "))
+		hasOffers = hasOffers || len(r.Offers) > 0
+	}
+	funnelServices.Track(c, funnelServices.FlowKasko, "calculate", req.SessionID, "", hasOffers, "")
+
+	// Котировки для аналитики цен: самая низкая цена каждой страховой
+	quoteResults := make([]quoteServices.Result, 0, len(results))
+	for _, r := range results {
+		qr := quoteServices.Result{Provider: r.Provider, Premium: cheapestOffer(r.Offers)}
+		if qr.Premium <= 0 {
+			qr.Error = strings.Join(r.Errors, "; ")
+			if qr.Error == "" && len(r.MissingFields) > 0 {
+				qr.Error = "missing fields: " + strings.Join(r.MissingFields, ", ")
+			}
+		}
+		quoteResults = append(quoteResults, qr)
+	}
+	quoteServices.Record(quoteServices.ProductKasko, req.SessionID, quoteServices.Inputs{
+		ProductType: string(req.ProductType),
+		VehicleType: strings.TrimSpace(req.Vehicle.BrandName + " " + req.Vehicle.ModelName),
+		PeriodID:    req.Days,
+		VehicleYear: req.Vehicle.Year,
+	}, quoteResults)
+
 	c.JSON(http.StatusOK, KaskoCalculateResponse{
 		SessionID: req.SessionID,
 		ProductType: req.ProductType,
 		Results:  results,
+		Meta:     &KaskoResponseMeta{Cache: cacheMeta},
 	})
 }
 
+// kaskoCacheInputs - параметры расчета KASKO без сессии и фильтра страховых: ключ кэша котировок
+func kaskoCacheInputs(req KaskoCalculateRequest, lang string) KaskoCalculateRequest {
+	req.SessionID = ""
+	req.Providers = nil
+	req.Language = lang
+	return req
+}
+
+// cheapestOffer самая низкая премия среди предложений страховой (0 — цен нет)
+func cheapestOffer(offers []Offer) int64 {
+	var min int64
+	for _, o := range offers {
+		if o.Premium != nil && o.Premium.Amount > 0 && (min == 0 || o.Premium.Amount < min) {
+			min = o.Premium.Amount
+		}
+	}
+	return min
+}
+
 // -------- lookups (provider adapters, v1 minimal) ----------
 
 func (kc *KaskoAllController) LookupsProviders(c *gin.Context) {
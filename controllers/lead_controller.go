@@ -0,0 +1,242 @@
+package controllers
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"kliro/models"
+	experimentServices "kliro/services/experiments"
+	leadServices "kliro/services/leads"
+	"kliro/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type LeadController struct {
+	db *gorm.DB
+}
+
+func NewLeadController() *LeadController {
+	return &LeadController{db: utils.GetDB()}
+}
+
+// CreateLeadRequest - заявка пользователя на продукт банка
+type CreateLeadRequest struct {
+	Direction   string                 `json:"direction" binding:"required"`
+	ProductKey  string                 `json:"product_key"`
+	BankName    string                 `json:"bank_name" binding:"required"`
+	ProductName string                 `json:"product_name"`
+	FullName    string                 `json:"full_name" binding:"required"`
+	Phone       string                 `json:"phone" binding:"required"`
+	Email       string                 `json:"email"`
+	Amount      int64                  `json:"amount"`
+	TermMonths  int                    `json:"term_months"`
+	Params      map[string]interface{} `json:"params"`
+	Lang        string                 `json:"lang"`
+	Consent     bool                   `json:"consent"`
+	ConsentText string                 `json:"consent_text"`
+}
+
+var leadPhoneRe = regexp.MustCompile(`^\+?\d{9,15}$`)
+
+// leadDuplicateWindow - повторная заявка с тем же телефоном на тот же продукт в этом окне не создается
+const leadDuplicateWindow = 10 * time.Minute
+
+// POST /leads (авторизация необязательна)
+func (lc *LeadController) Create(c *gin.Context) {
+	var req CreateLeadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"result": nil, "success": false, "error": "invalid request"})
+		return
+	}
+
+	direction := strings.ToLower(strings.TrimSpace(req.Direction))
+	if !leadServices.Directions[direction] {
+		c.JSON(http.StatusBadRequest, gin.H{"result": nil, "success": false, "error": "direction должен быть: microcredit, mortgage, autocredit или deposit"})
+		return
+	}
+	if !req.Consent {
+		c.JSON(http.StatusBadRequest, gin.H{"result": nil, "success": false, "error": "Необходимо согласие на обработку персональных данных"})
+		return
+	}
+	phone := strings.NewReplacer(" ", "", "-", "", "(", "", ")", "").Replace(req.Phone)
+	if !leadPhoneRe.MatchString(phone) {
+		c.JSON(http.StatusBadRequest, gin.H{"result": nil, "success": false, "error": "Неверный номер телефона"})
+		return
+	}
+	if req.Amount < 0 || req.TermMonths < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"result": nil, "success": false, "error": "Сумма и срок не могут быть отрицательными"})
+		return
+	}
+
+	productKey := strings.TrimSpace(req.ProductKey)
+	if productKey == "" && req.ProductName != "" {
+		productKey = utils.ProductKey(direction, req.BankName, req.ProductName)
+	}
+
+	// Защита от двойной отправки формы
+	var existing models.Lead
+	err := lc.db.Where("phone = ? AND direction = ? AND bank_name = ? AND product_key = ? AND created_at > ?",
+		phone, direction, strings.TrimSpace(req.BankName), productKey, time.Now().Add(-leadDuplicateWindow)).
+		First(&existing).Error
+	if err == nil {
+		// Заявку целиком отдаем только ее владельцу: по номеру телефона нельзя прочитать чужие данные
+		if userID := uint(c.GetInt("user_id")); userID > 0 && existing.UserID != nil && *existing.UserID == userID {
+			c.JSON(http.StatusOK, gin.H{"result": existing, "success": true, "duplicate": true})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"result": gin.H{"id": existing.ID, "duplicate": true}, "success": true, "duplicate": true})
+		return
+	}
+
+	if ok, msg := utils.CanSubmitLead(utils.GetRedis(), c.ClientIP(), phone); !ok {
+		c.Header("Retry-After", "3600")
+		c.JSON(http.StatusTooManyRequests, gin.H{"result": nil, "success": false, "error": msg})
+		return
+	}
+
+	lead := models.Lead{
+		Direction:   direction,
+		ProductKey:  productKey,
+		BankName:    strings.TrimSpace(req.BankName),
+		ProductName: strings.TrimSpace(req.ProductName),
+		FullName:    strings.TrimSpace(req.FullName),
+		Phone:       phone,
+		Email:       strings.TrimSpace(req.Email),
+		Amount:      req.Amount,
+		TermMonths:  req.TermMonths,
+		Lang:        strings.ToLower(req.Lang),
+		Consent:     true,
+		ConsentText: req.ConsentText,
+		ConsentAt:   time.Now(),
+		ConsentIP:   c.ClientIP(),
+	}
+	if len(req.Params) > 0 {
+		data, _ := json.Marshal(req.Params)
+		lead.Params = string(data)
+	}
+	if userID := uint(c.GetInt("user_id")); userID > 0 {
+		lead.UserID = &userID
+	}
+
+	if err := leadServices.DefaultService().Create(&lead); err != nil {
+		utils.LogError(err, "Create lead")
+		c.JSON(http.StatusInternalServerError, gin.H{"result": nil, "success": false, "error": "Не удалось сохранить заявку"})
+		return
+	}
+	experimentServices.FromContext(c).Convert("lead", float64(req.Amount))
+
+	c.JSON(http.StatusOK, gin.H{"result": lead, "success": true})
+}
+
+// GET /user/leads - заявки текущего пользователя
+func (lc *LeadController) ListMine(c *gin.Context) {
+	userID := uint(c.GetInt("user_id"))
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"result": nil, "success": false, "error": "Пользователь не авторизован"})
+		return
+	}
+
+	var leads []models.Lead
+	if err := lc.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&leads).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"result": nil, "success": false, "error": "Ошибка при получении заявок"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"result": leads, "success": true})
+}
+
+// GET /partner/leads - заявки банка-партнера (фильтры ?status=, ?direction=, ?from=, ?to=, page/size)
+func (lc *LeadController) PartnerList(c *gin.Context) {
+	partnerID := c.GetInt("partner_id")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "0"))
+	size, _ := strconv.Atoi(c.DefaultQuery("size", "20"))
+	if page < 0 {
+		page = 0
+	}
+	if size < 1 || size > 100 {
+		size = 20
+	}
+
+	query := lc.db.Model(&models.Lead{}).Where("partner_id = ?", partnerID)
+	query = applyLeadFilters(c, query)
+
+	var total int64
+	query.Count(&total)
+
+	var leads []models.Lead
+	if err := query.Order("created_at DESC").Offset(page * size).Limit(size).Find(&leads).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"result": nil, "success": false, "error": "Ошибка при получении заявок"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"result": gin.H{"content": leads, "totalElements": total, "number": page, "size": size}, "success": true})
+}
+
+// PUT /partner/leads/:id/status - банк-партнер обновляет статус своей заявки
+func (lc *LeadController) PartnerUpdateStatus(c *gin.Context) {
+	partnerID := c.GetInt("partner_id")
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"result": nil, "success": false, "error": "Неверный ID заявки"})
+		return
+	}
+
+	var req struct {
+		Status  string `json:"status" binding:"required"`
+		Comment string `json:"comment"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"result": nil, "success": false, "error": "invalid request"})
+		return
+	}
+	if !leadServices.PartnerStatuses[req.Status] {
+		c.JSON(http.StatusBadRequest, gin.H{"result": nil, "success": false, "error": "Статус должен быть: contacted, approved, rejected или closed"})
+		return
+	}
+
+	var lead models.Lead
+	if err := lc.db.Where("id = ? AND partner_id = ?", uint(id), partnerID).First(&lead).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"result": nil, "success": false, "error": "Заявка не найдена"})
+		return
+	}
+
+	pid := uint(partnerID)
+	if err := leadServices.DefaultService().ChangeStatus(&lead, req.Status, "partner", &pid, req.Comment); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"result": nil, "success": false, "error": "Не удалось обновить статус"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"result": lead, "success": true})
+}
+
+// applyLeadFilters - общие фильтры списков заявок
+func applyLeadFilters(c *gin.Context, query *gorm.DB) *gorm.DB {
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if direction := c.Query("direction"); direction != "" {
+		query = query.Where("direction = ?", strings.ToLower(direction))
+	}
+	if from, err := time.Parse("2006-01-02", c.Query("from")); err == nil {
+		query = query.Where("created_at >= ?", from)
+	}
+	if to, err := time.Parse("2006-01-02", c.Query("to")); err == nil {
+		query = query.Where("created_at < ?", to.AddDate(0, 0, 1))
+	}
+	return query
+}
+
+// TestLeadWebhook - локальная заглушка банка для проверки webhook-доставки заявок
+func TestLeadWebhook(c *gin.Context) {
+	body, _ := io.ReadAll(c.Request.Body)
+	log.Printf("[LEADS] test webhook received (signature=%s): %s", c.GetHeader("X-Kliro-Signature"), string(body))
+	c.JSON(http.StatusOK, gin.H{"received": true})
+}
@@ -0,0 +1,68 @@
+package osagoCreate
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"kliro/models"
+	osagoServices "kliro/services/osago"
+	osagoProviders "kliro/services/osago/providers"
+	"kliro/utils"
+)
+
+// recordOrder сохраняет попытку create в osago_orders: провайдер, телефон и данные ТС/владельца для продления, премия,
+// идентификаторы и платежные ссылки провайдера, ключ идемпотентности. Пользователь — из токена, если он передан.
+func (oc *OsagoCreateController) recordOrder(c *gin.Context, session *osagoProviders.Session, req *osagoProviders.CreateRequest, result interface{}, errs []string, idemKey, reqHash string) *models.OsagoOrder {
+	periodID, amount := req.PeriodID, req.AmountUZS
+	if snap := session.CalculateSnapshot; snap != nil {
+		if snap.PeriodID >= 1 && snap.PeriodID <= 3 {
+			periodID = snap.PeriodID
+		}
+		if snap.Premiums != nil && snap.Premiums[req.Provider] > 0 {
+			amount = snap.Premiums[req.Provider]
+		}
+	}
+	if amount <= 0 {
+		// Trust возвращает премию в ответе create
+		amount, _ = strconv.Atoi(osagoProviders.ExtractString(result, "insurance_premium"))
+	}
+
+	begin := osagoProviders.ToYYYYMMDD(req.StartDate)
+	if begin == "" {
+		begin = utils.UzbekTime().Format("2006-01-02")
+	}
+	end := ""
+	switch periodID {
+	case 1:
+		end = osagoProviders.AddMonthsThenSubDays(begin, 12, 1)
+	case 2:
+		end = osagoProviders.AddMonthsThenSubDays(begin, 6, 1)
+	case 3:
+		end = osagoProviders.AddDays(begin, 19)
+	}
+
+	var createErr error
+	if len(errs) > 0 {
+		createErr = errors.New(strings.Join(errs, "; "))
+	}
+
+	return osagoServices.LogAttempt(utils.GetDB(), osagoServices.Attempt{
+		UserID:    uint(c.GetInt("user_id")),
+		Provider:  req.Provider,
+		SessionID: req.SessionID,
+		Inputs:    osagoServices.NewOrderInputs(req, session),
+		AmountUZS: int64(amount),
+		PeriodID:  periodID,
+		GosNumber: strings.TrimSpace(strings.ToUpper(osagoProviders.ExtractString(session.Vehicle, "data", "license_plate"))),
+		BeginDate: begin,
+		EndDate:   end,
+		Response:  result,
+		Err:       createErr,
+
+		IdempotencyKey: idemKey,
+		RequestHash:    reqHash,
+	})
+}
@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"kliro/config"
+	"kliro/models"
+	osagoServices "kliro/services/osago"
+	osagoProviders "kliro/services/osago/providers"
+	"kliro/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// OsagoOrderController - полисы ОСАГО пользователя (попытки оформления через /osago-all/create)
+type OsagoOrderController struct {
+	db        *gorm.DB
+	providers *osagoProviders.Registry
+}
+
+func NewOsagoOrderController() *OsagoOrderController {
+	return &OsagoOrderController{db: utils.GetDB(), providers: osagoServices.NewRegistry(config.LoadConfig())}
+}
+
+// GET /user/osago/policies?page=1&limit=20&status=created|paid|issued|failed|expired
+func (oc *OsagoOrderController) List(c *gin.Context) {
+	userID := uint(c.GetInt("user_id"))
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"result": nil, "success": false, "error": "Пользователь не авторизован"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	offset := (page - 1) * limit
+
+	query := oc.db.Model(&models.OsagoOrder{}).Where("user_id = ?", userID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"result": nil, "success": false, "error": "Ошибка подсчета полисов"})
+		return
+	}
+
+	var orders []models.OsagoOrder
+	if err := query.Order("created_at DESC").Offset(offset).Limit(limit).Find(&orders).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"result": nil, "success": false, "error": "Ошибка получения полисов"})
+		return
+	}
+
+	totalPages := int((total + int64(limit) - 1) / int64(limit))
+
+	c.JSON(http.StatusOK, gin.H{"result": gin.H{
+		"totalPages":       totalPages,
+		"totalElements":    total,
+		"first":            page == 1,
+		"last":             page >= totalPages && totalPages != 0,
+		"size":             limit,
+		"content":          orders,
+		"number":           page - 1,
+		"numberOfElements": len(orders),
+		"empty":            len(orders) == 0,
+		"pageable": gin.H{
+			"offset":     offset,
+			"pageNumber": page - 1,
+			"pageSize":   limit,
+			"paged":      true,
+			"unpaged":    false,
+		},
+	}, "success": true})
+}
+
+// GET /user/osago/policies/:id
+func (oc *OsagoOrderController) Get(c *gin.Context) {
+	userID := uint(c.GetInt("user_id"))
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"result": nil, "success": false, "error": "Пользователь не авторизован"})
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"result": nil, "success": false, "error": "invalid id"})
+		return
+	}
+
+	var order models.OsagoOrder
+	if err := oc.db.Where("id = ? AND user_id = ?", id, userID).First(&order).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"result": nil, "success": false, "error": "Не найдено"})
+		return
+	}
+	oc.db.Where("order_id = ?", order.ID).Order("created_at, id").Find(&order.History)
+
+	c.JSON(http.StatusOK, gin.H{"result": order, "success": true})
+}
+
+// POST /user/osago/policies/:id/confirm-paid - пользователь подтверждает оплату заказа страховой без API статуса
+func (oc *OsagoOrderController) ConfirmPaid(c *gin.Context) {
+	userID := uint(c.GetInt("user_id"))
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"result": nil, "success": false, "error": "Пользователь не авторизован"})
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"result": nil, "success": false, "error": "invalid id"})
+		return
+	}
+
+	order, err := osagoServices.ConfirmPaidByUser(oc.db, oc.providers, userID, uint(id))
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"result": nil, "success": false, "error": "Не найдено"})
+		return
+	case errors.Is(err, osagoServices.ErrConfirmPaidNotAllowed):
+		c.JSON(http.StatusConflict, gin.H{"result": nil, "success": false, "error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"result": nil, "success": false, "error": "Ошибка сохранения"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": order, "success": true})
+}
@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"kliro/config"
+	"kliro/models"
+	osagoServices "kliro/services/osago"
+	"kliro/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// OsagoRenewalController - напоминания о продлении ОСАГО: настройка рассылки, внешние полисы пользователя
+// и ссылка продления из напоминания
+type OsagoRenewalController struct {
+	db  *gorm.DB
+	cfg *config.Config
+}
+
+func NewOsagoRenewalController(cfg *config.Config) *OsagoRenewalController {
+	return &OsagoRenewalController{db: utils.GetDB(), cfg: cfg}
+}
+
+// ExternalPolicyRequest - полис ОСАГО, купленный не через Kliro
+type ExternalPolicyRequest struct {
+	Insurer            string `json:"insurer"`
+	PolicyNumber       string `json:"policy_number"`
+	LicensePlate       string `json:"license_plate" binding:"required"`
+	TechPassportSeries string `json:"tech_passport_series"`
+	TechPassportNumber string `json:"tech_passport_number"`
+	PassportSeries     string `json:"passport_series"`
+	PassportNumber     string `json:"passport_number"`
+	Birthdate          string `json:"birthdate"` // YYYY-MM-DD
+	Inn                string `json:"inn"`
+	EndDate            string `json:"end_date" binding:"required"` // YYYY-MM-DD или DD.MM.YYYY
+}
+
+// GET /user/osago/reminders
+func (rc *OsagoRenewalController) GetSettings(c *gin.Context) {
+	userID := uint(c.GetInt("user_id"))
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"result": nil, "success": false, "error": "Пользователь не авторизован"})
+		return
+	}
+	var user models.User
+	if err := rc.db.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"result": nil, "success": false, "error": "Пользователь не найден"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": gin.H{"enabled": !user.OsagoRemindersOptOut, "days": rc.cfg.OsagoReminderDays}, "success": true})
+}
+
+// PUT /user/osago/reminders {"enabled": false}
+func (rc *OsagoRenewalController) UpdateSettings(c *gin.Context) {
+	userID := uint(c.GetInt("user_id"))
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"result": nil, "success": false, "error": "Пользователь не авторизован"})
+		return
+	}
+	var req struct {
+		Enabled *bool `json:"enabled" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"result": nil, "success": false, "error": "enabled обязателен"})
+		return
+	}
+	if err := osagoServices.SetRemindersOptOut(rc.db, userID, !*req.Enabled); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"result": nil, "success": false, "error": "Ошибка сохранения"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": gin.H{"enabled": *req.Enabled}, "success": true})
+}
+
+// GET /user/osago/external-policies
+func (rc *OsagoRenewalController) ListExternal(c *gin.Context) {
+	userID := uint(c.GetInt("user_id"))
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"result": nil, "success": false, "error": "Пользователь не авторизован"})
+		return
+	}
+	var policies []models.OsagoExternalPolicy
+	if err := rc.db.Where("user_id = ?", userID).Order("end_date").Find(&policies).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"result": nil, "success": false, "error": "Ошибка получения полисов"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": policies, "success": true})
+}
+
+// POST /user/osago/external-policies
+func (rc *OsagoRenewalController) CreateExternal(c *gin.Context) {
+	userID := uint(c.GetInt("user_id"))
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"result": nil, "success": false, "error": "Пользователь не авторизован"})
+		return
+	}
+	var req ExternalPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"result": nil, "success": false, "error": "license_plate и end_date обязательны"})
+		return
+	}
+	endDate, err := osagoServices.ParseDate(req.EndDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"result": nil, "success": false, "error": "неверная дата окончания"})
+		return
+	}
+	birthdate := ""
+	if strings.TrimSpace(req.Birthdate) != "" {
+		if birthdate, err = osagoServices.ParseDate(req.Birthdate); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"result": nil, "success": false, "error": "неверная дата рождения"})
+			return
+		}
+	}
+
+	policy := models.OsagoExternalPolicy{
+		UserID:             userID,
+		Insurer:            strings.TrimSpace(req.Insurer),
+		PolicyNumber:       strings.TrimSpace(req.PolicyNumber),
+		LicensePlate:       osagoServices.NormalizePlate(req.LicensePlate),
+		TechPassportSeries: strings.ToUpper(strings.TrimSpace(req.TechPassportSeries)),
+		TechPassportNumber: strings.TrimSpace(req.TechPassportNumber),
+		PassportSeries:     strings.ToUpper(strings.TrimSpace(req.PassportSeries)),
+		PassportNumber:     strings.TrimSpace(req.PassportNumber),
+		Birthdate:          birthdate,
+		Inn:                strings.TrimSpace(req.Inn),
+		EndDate:            endDate,
+	}
+	if err := rc.db.Create(&policy).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"result": nil, "success": false, "error": "Ошибка сохранения полиса"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"result": policy, "success": true})
+}
+
+// DELETE /user/osago/external-policies/:id
+func (rc *OsagoRenewalController) DeleteExternal(c *gin.Context) {
+	userID := uint(c.GetInt("user_id"))
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"result": nil, "success": false, "error": "Пользователь не авторизован"})
+		return
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"result": nil, "success": false, "error": "invalid id"})
+		return
+	}
+	res := rc.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.OsagoExternalPolicy{})
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"result": nil, "success": false, "error": "Ошибка удаления"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"result": nil, "success": false, "error": "Не найдено"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": gin.H{"deleted": true}, "success": true})
+}
+
+// Renewal - GET /osago-all/renewal/:token: тело для /osago-all/find из ссылки в напоминании
+func (rc *OsagoRenewalController) Renewal(c *gin.Context) {
+	reminder, err := osagoServices.ResolveRenewalLink(rc.db, c.Param("token"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", []byte(reminder.Prefill))
+}
+
+// Unsubscribe - POST /osago-all/renewal/:token/unsubscribe: отказ от напоминаний по ссылке, без авторизации
+func (rc *OsagoRenewalController) Unsubscribe(c *gin.Context) {
+	reminder, err := osagoServices.ResolveRenewalLink(rc.db, c.Param("token"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err := osagoServices.SetRemindersOptOut(rc.db, reminder.UserID, true); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка сохранения"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"unsubscribed": true})
+}
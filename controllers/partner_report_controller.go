@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+
+	"kliro/models"
+	partnerServices "kliro/services/partners"
+	"kliro/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type PartnerReportController struct {
+	db *gorm.DB
+}
+
+func NewPartnerReportController() *PartnerReportController {
+	return &PartnerReportController{db: utils.GetDB()}
+}
+
+// GET /partner/reports — показы, клики и заявки своего банка по продуктам и дням (?from=&to=, по умолчанию последние 30 дней)
+func (pc *PartnerReportController) Get(c *gin.Context) {
+	report, ok := pc.build(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": report, "success": true})
+}
+
+// GET /partner/reports/export — тот же отчет в CSV
+func (pc *PartnerReportController) Export(c *gin.Context) {
+	report, ok := pc.build(c)
+	if !ok {
+		return
+	}
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="kliro-report-%s-%s.csv"`, report.DateFrom, report.DateTo))
+	if err := partnerServices.WriteCSV(c.Writer, report); err != nil {
+		utils.LogError(err, "partner report: write csv")
+	}
+}
+
+// build строит отчет партнера из контекста (partner_id выставляет PartnerAuthMiddleware)
+func (pc *PartnerReportController) build(c *gin.Context) (*partnerServices.Report, bool) {
+	var partner models.BankPartner
+	if err := pc.db.First(&partner, c.GetInt("partner_id")).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"result": nil, "success": false, "error": "Партнер не найден"})
+		return nil, false
+	}
+	from, to, errMsg := utils.ParseDateRange("from", c.Query("from"), "to", c.Query("to"), 366)
+	if errMsg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"result": nil, "success": false, "error": errMsg})
+		return nil, false
+	}
+
+	report, err := partnerServices.BuildReport(pc.db, partner, from, to)
+	if err != nil {
+		utils.LogError(err, "partner report: build")
+		c.JSON(http.StatusInternalServerError, gin.H{"result": nil, "success": false, "error": "Ошибка при построении отчета"})
+		return nil, false
+	}
+	return report, true
+}
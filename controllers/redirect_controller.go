@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+
+	"kliro/models"
+	clickServices "kliro/services/clicks"
+	experimentServices "kliro/services/experiments"
+	redirectServices "kliro/services/redirect"
+	"kliro/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type RedirectController struct {
+	db *gorm.DB
+}
+
+func NewRedirectController() *RedirectController {
+	return &RedirectController{db: utils.GetDB()}
+}
+
+// GET /go/:token — переход на сайт банка по подписанной ссылке из списков /bank/*/new.
+// Записывает клик (пользователь, язык, платформа, referrer) и отвечает 302 на партнерский URL с UTM-метками.
+func (rc *RedirectController) Go(c *gin.Context) {
+	payload, err := redirectServices.Parse(c.Param("token"))
+	if err != nil || !validDirections[payload.Direction] {
+		c.JSON(http.StatusNotFound, gin.H{"result": nil, "success": false, "error": "Ссылка недействительна"})
+		return
+	}
+
+	target, err := redirectServices.DefaultResolver().Target(payload)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"result": nil, "success": false, "error": "Ссылка на сайт банка не найдена"})
+		return
+	}
+
+	click := models.OutboundClick{
+		ProductKey: payload.ProductKey,
+		Direction:  payload.Direction,
+		BankName:   payload.BankName,
+		TargetURL:  target,
+		Lang:       requestLang(c),
+		Platform:   requestPlatform(c),
+		Referrer:   c.Request.Referer(),
+	}
+	if userID := uint(c.GetInt("user_id")); userID > 0 {
+		click.UserID = &userID
+	}
+	if err := rc.db.Create(&click).Error; err != nil {
+		utils.LogError(err, "redirect: save outbound click")
+	}
+	if _, err := incrementProductClick(rc.db, payload.ProductKey, payload.Direction, target); err != nil {
+		utils.LogError(err, "redirect: increment product click")
+	}
+	clickServices.Record(rc.db, models.ClickEvent{
+		Key:       payload.ProductKey,
+		Direction: payload.Direction,
+		BankSlug:  utils.BankSlug(payload.BankName),
+		UserID:    click.UserID,
+		Platform:  click.Platform,
+		Lang:      click.Lang,
+		Source:    clickServices.SourceRedirect,
+	})
+	experimentServices.FromContext(c).Convert("outbound_click", 0)
+
+	c.Header("Cache-Control", "no-store")
+	c.Redirect(http.StatusFound, target)
+}
+
+// requestLang язык пользователя: ?lang=, иначе Accept-Language (uz, ru, en)
+func requestLang(c *gin.Context) string {
+	lang := strings.ToLower(c.Query("lang"))
+	if lang == "" {
+		lang = strings.ToLower(c.GetHeader("Accept-Language"))
+	}
+	if len(lang) > 2 {
+		lang = lang[:2]
+	}
+	switch lang {
+	case "uz", "ru", "en":
+		return lang
+	}
+	return ""
+}
+
+// requestPlatform платформа клиента: ?platform= или X-Platform, иначе по User-Agent
+func requestPlatform(c *gin.Context) string {
+	platform := strings.ToLower(c.Query("platform"))
+	if platform == "" {
+		platform = strings.ToLower(c.GetHeader("X-Platform"))
+	}
+	switch platform {
+	case "ios", "android", "web":
+		return platform
+	}
+
+	ua := strings.ToLower(c.Request.UserAgent())
+	switch {
+	case strings.Contains(ua, "android"):
+		return "android"
+	case strings.Contains(ua, "iphone"), strings.Contains(ua, "ipad"), strings.Contains(ua, "darwin"):
+		return "ios"
+	case ua != "":
+		return "web"
+	}
+	return ""
+}
@@ -0,0 +1,271 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"kliro/models"
+	reviewServices "kliro/services/reviews"
+	"kliro/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ReviewController struct {
+	db *gorm.DB
+}
+
+func NewReviewController() *ReviewController {
+	return &ReviewController{db: utils.GetDB()}
+}
+
+// ReviewRequest - создание/редактирование отзыва.
+// Для банка: target_type=bank и bank_name. Для продукта: target_type=product и product_key
+// (или direction + bank_name + product_name).
+type ReviewRequest struct {
+	TargetType  string `json:"target_type"`
+	ProductKey  string `json:"product_key"`
+	Direction   string `json:"direction"`
+	BankName    string `json:"bank_name"`
+	ProductName string `json:"product_name"`
+	Rating      int    `json:"rating"`
+	Title       string `json:"title"`
+	Body        string `json:"body"`
+}
+
+// resolveReviewTarget определяет объект отзыва (тип и ключ)
+func resolveReviewTarget(targetType, productKey, direction, bankName, productName string) (string, string, string) {
+	targetType = strings.ToLower(strings.TrimSpace(targetType))
+	switch targetType {
+	case reviewServices.TargetBank:
+		slug := utils.BankSlug(bankName)
+		if slug == "" {
+			return "", "", "Укажите bank_name"
+		}
+		return targetType, slug, ""
+	case reviewServices.TargetProduct:
+		key := strings.TrimSpace(productKey)
+		if key == "" {
+			if direction == "" || productName == "" {
+				return "", "", "Укажите product_key или direction, bank_name и product_name"
+			}
+			key = utils.ProductKey(direction, bankName, productName)
+		}
+		return targetType, key, ""
+	}
+	return "", "", "target_type должен быть bank или product"
+}
+
+// GET /reviews?target_type=&target_key= (или bank_name / product_key) — одобренные отзывы и сводная оценка
+func (rc *ReviewController) List(c *gin.Context) {
+	targetType := strings.ToLower(c.Query("target_type"))
+	targetKey := c.Query("target_key")
+	if targetKey == "" {
+		var errMsg string
+		targetType, targetKey, errMsg = resolveReviewTarget(targetType, c.Query("product_key"), strings.ToLower(c.Query("direction")), c.Query("bank_name"), c.Query("product_name"))
+		if errMsg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"result": nil, "success": false, "error": errMsg})
+			return
+		}
+	} else if targetType != reviewServices.TargetBank && targetType != reviewServices.TargetProduct {
+		c.JSON(http.StatusBadRequest, gin.H{"result": nil, "success": false, "error": "target_type должен быть bank или product"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "0"))
+	size, _ := strconv.Atoi(c.DefaultQuery("size", "10"))
+	if page < 0 {
+		page = 0
+	}
+	if size < 1 || size > 50 {
+		size = 10
+	}
+	order := "created_at DESC"
+	if c.Query("sort") == "helpful" {
+		order = "helpful_count DESC, created_at DESC"
+	}
+
+	query := rc.db.Model(&models.Review{}).Where("target_type = ? AND target_key = ? AND status = ?", targetType, targetKey, reviewServices.StatusApproved)
+	var total int64
+	query.Count(&total)
+
+	var reviews []models.Review
+	if err := query.Order(order).Offset(page * size).Limit(size).Find(&reviews).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"result": nil, "success": false, "error": "Ошибка при получении отзывов"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"result": gin.H{
+			"content":       reviewServices.PublicReviews(rc.db, reviews),
+			"totalElements": total,
+			"number":        page,
+			"size":          size,
+			"summary":       reviewServices.TargetAggregate(rc.db, targetType, targetKey),
+		},
+		"success": true,
+	})
+}
+
+// POST /user/reviews — один отзыв на пользователя и объект (повторный — 409, редактируйте через PUT)
+func (rc *ReviewController) Create(c *gin.Context) {
+	userID := uint(c.GetInt("user_id"))
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"result": nil, "success": false, "error": "Пользователь не авторизован"})
+		return
+	}
+
+	var req ReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"result": nil, "success": false, "error": "invalid request"})
+		return
+	}
+	targetType, targetKey, errMsg := resolveReviewTarget(req.TargetType, req.ProductKey, strings.ToLower(req.Direction), req.BankName, req.ProductName)
+	if errMsg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"result": nil, "success": false, "error": errMsg})
+		return
+	}
+	if req.Rating < 1 || req.Rating > 5 {
+		c.JSON(http.StatusBadRequest, gin.H{"result": nil, "success": false, "error": "Оценка должна быть от 1 до 5"})
+		return
+	}
+
+	var existing models.Review
+	if err := rc.db.Where("user_id = ? AND target_type = ? AND target_key = ?", userID, targetType, targetKey).First(&existing).Error; err == nil {
+		c.JSON(http.StatusConflict, gin.H{"result": existing, "success": false, "error": "Вы уже оставили отзыв, его можно отредактировать"})
+		return
+	}
+
+	review := models.Review{
+		UserID:      userID,
+		TargetType:  targetType,
+		TargetKey:   targetKey,
+		Direction:   strings.ToLower(strings.TrimSpace(req.Direction)),
+		BankName:    strings.TrimSpace(req.BankName),
+		ProductName: strings.TrimSpace(req.ProductName),
+		Rating:      req.Rating,
+		Title:       strings.TrimSpace(req.Title),
+		Body:        strings.TrimSpace(req.Body),
+		Status:      reviewServices.StatusPending,
+	}
+	if targetType == reviewServices.TargetProduct && review.Direction == "" {
+		if i := strings.Index(targetKey, ":"); i > 0 {
+			review.Direction = targetKey[:i]
+		}
+	}
+	if err := rc.db.Create(&review).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"result": nil, "success": false, "error": "Не удалось сохранить отзыв"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"result": review, "success": true})
+}
+
+// PUT /user/reviews/:id — редактирование своего отзыва (снова уходит на модерацию)
+func (rc *ReviewController) Update(c *gin.Context) {
+	userID := uint(c.GetInt("user_id"))
+	var review models.Review
+	if err := rc.db.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&review).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"result": nil, "success": false, "error": "Отзыв не найден"})
+		return
+	}
+
+	var req struct {
+		Rating *int    `json:"rating"`
+		Title  *string `json:"title"`
+		Body   *string `json:"body"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"result": nil, "success": false, "error": "invalid request"})
+		return
+	}
+	if req.Rating != nil {
+		if *req.Rating < 1 || *req.Rating > 5 {
+			c.JSON(http.StatusBadRequest, gin.H{"result": nil, "success": false, "error": "Оценка должна быть от 1 до 5"})
+			return
+		}
+		review.Rating = *req.Rating
+	}
+	if req.Title != nil {
+		review.Title = strings.TrimSpace(*req.Title)
+	}
+	if req.Body != nil {
+		review.Body = strings.TrimSpace(*req.Body)
+	}
+	review.Status = reviewServices.StatusPending
+	review.ModerationNote = ""
+
+	if err := rc.db.Save(&review).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"result": nil, "success": false, "error": "Не удалось обновить отзыв"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"result": review, "success": true})
+}
+
+// DELETE /user/reviews/:id
+func (rc *ReviewController) Delete(c *gin.Context) {
+	userID := uint(c.GetInt("user_id"))
+	result := rc.db.Where("id = ? AND user_id = ?", c.Param("id"), userID).Delete(&models.Review{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"result": nil, "success": false, "error": "Не удалось удалить отзыв"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"result": nil, "success": false, "error": "Отзыв не найден"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": nil, "success": true})
+}
+
+// GET /user/reviews — отзывы пользователя (все статусы)
+func (rc *ReviewController) ListMine(c *gin.Context) {
+	userID := uint(c.GetInt("user_id"))
+	var reviews []models.Review
+	if err := rc.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&reviews).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"result": nil, "success": false, "error": "Ошибка при получении отзывов"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": reviews, "success": true})
+}
+
+// POST /user/reviews/:id/helpful — отметить отзыв полезным; DELETE — снять отметку
+func (rc *ReviewController) Vote(c *gin.Context) {
+	userID := uint(c.GetInt("user_id"))
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"result": nil, "success": false, "error": "Неверный ID отзыва"})
+		return
+	}
+
+	var review models.Review
+	if err := rc.db.Where("id = ? AND status = ?", uint(id), reviewServices.StatusApproved).First(&review).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"result": nil, "success": false, "error": "Отзыв не найден"})
+		return
+	}
+	if review.UserID == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"result": nil, "success": false, "error": "Нельзя голосовать за свой отзыв"})
+		return
+	}
+
+	if c.Request.Method == http.MethodDelete {
+		rc.db.Where("review_id = ? AND user_id = ?", review.ID, userID).Delete(&models.ReviewVote{})
+	} else {
+		var count int64
+		rc.db.Model(&models.ReviewVote{}).Where("review_id = ? AND user_id = ?", review.ID, userID).Count(&count)
+		if count == 0 {
+			if err := rc.db.Create(&models.ReviewVote{ReviewID: review.ID, UserID: userID}).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"result": nil, "success": false, "error": "Не удалось сохранить голос"})
+				return
+			}
+		}
+	}
+
+	helpful, err := reviewServices.RecountHelpful(rc.db, review.ID)
+	if err != nil {
+		utils.LogError(err, "reviews: recount helpful")
+	}
+	c.JSON(http.StatusOK, gin.H{"result": gin.H{"review_id": review.ID, "helpful_count": helpful}, "success": true})
+}
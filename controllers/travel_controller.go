@@ -16,6 +16,9 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/go-redis/redis/v8"
 	"github.com/google/uuid"
+
+	funnelServices "kliro/services/funnel"
+	healthServices "kliro/services/providerhealth"
 )
 
 type ApexCountry struct {
@@ -209,6 +212,8 @@ func (tc *TravelController) SetTravelPurpose(c *gin.Context) {
 		return
 	}
 
+	funnelServices.Track(c, funnelServices.FlowTravel, "purpose", sessionID, "", true, "")
+
 	response := gin.H{
 		"result": gin.H{
 			"session_id":   sessionID,
@@ -254,6 +259,7 @@ func (tc *TravelController) SetTravelDetails(c *gin.Context) {
 	}
 
 	fmt.Printf("PARSED: SessionID=%s, StartDate=%s, EndDate=%s\n", req.SessionID, req.StartDate, req.EndDate)
+	defer funnelServices.TrackResponse(c, funnelServices.FlowTravel, "details", req.SessionID, "")
 	fmt.Printf("        TravelersBirthdates=%v\n", req.TravelersBirthdates)
 	fmt.Printf("        AnnualPolicy=%v, CovidProtection=%v, FamilyTravel=%v\n",
 		req.AnnualPolicy, req.CovidProtection, req.FamilyTravel)
@@ -417,6 +423,7 @@ func (tc *TravelController) CalculateTravel(c *gin.Context) {
 	}
 
 	fmt.Printf("PARSED: SessionID=%s\n", req.SessionID)
+	defer funnelServices.TrackResponse(c, funnelServices.FlowTravel, "calculate", req.SessionID, "")
 	fmt.Printf("        Risks: accident=%v, luggage=%v, cancel=%v, person_respon=%v, delay=%v\n",
 		req.Accident, req.Luggage, req.CancelTravel, req.PersonRespon, req.DelayTravel)
 
@@ -537,7 +544,7 @@ func (tc *TravelController) CalculateTravel(c *gin.Context) {
 			httpReq.Header.Set("Content-Type", "application/json")
 			httpReq.Header.Set("Authorization", authHeader)
 
-			client := &http.Client{Timeout: 30 * time.Second}
+			client := healthServices.Client("neo", "travel.calculate", 30*time.Second)
 			resp, err := client.Do(httpReq)
 			if err != nil {
 				resultChan <- ProviderResult{Provider: "neo", Error: err}
@@ -602,7 +609,7 @@ func (tc *TravelController) CalculateTravel(c *gin.Context) {
 			httpReq.Header.Set("Content-Type", "application/json")
 			httpReq.Header.Set("Authorization", authHeader)
 
-			client := &http.Client{Timeout: 30 * time.Second}
+			client := healthServices.Client("gross", "travel.calculate", 30*time.Second)
 			resp, err := client.Do(httpReq)
 			if err != nil {
 				resultChan <- ProviderResult{Provider: "gross", Error: err}
@@ -695,7 +702,7 @@ func (tc *TravelController) CalculateTravel(c *gin.Context) {
 			httpReq.Header.Set("Content-Type", "application/json")
 			httpReq.Header.Set("Authorization", authHeader)
 
-			client := &http.Client{Timeout: 30 * time.Second}
+			client := healthServices.Client("trust", "travel.calculate", 30*time.Second)
 			resp, err := client.Do(httpReq)
 			if err != nil {
 				resultChan <- ProviderResult{Provider: "trust", Error: err}
@@ -796,7 +803,7 @@ func (tc *TravelController) CalculateTravel(c *gin.Context) {
 				httpReq.Header.Set("Content-Type", "application/json")
 				httpReq.Header.Set("Authorization", authHeader)
 
-				client := &http.Client{Timeout: 30 * time.Second}
+				client := healthServices.Client("apex", "travel.calculate", 30*time.Second)
 				resp, err := client.Do(httpReq)
 				if err != nil {
 					fmt.Printf("Program %d: Failed to send request: %v\n", programID, err)
@@ -849,8 +856,10 @@ func (tc *TravelController) CalculateTravel(c *gin.Context) {
 		result := <-resultChan
 		if result.Error != nil {
 			results[result.Provider] = gin.H{"error": result.Error.Error()}
+			funnelServices.Track(c, funnelServices.FlowTravel, "calculate", req.SessionID, result.Provider, false, result.Error.Error())
 		} else {
 			results[result.Provider] = result.Data
+			funnelServices.Track(c, funnelServices.FlowTravel, "calculate", req.SessionID, result.Provider, true, "")
 		}
 	}
 
@@ -963,6 +972,7 @@ func (tc *TravelController) SaveTravel(c *gin.Context) {
 	fmt.Printf("  Sugurtalovchi: Type=%d, Passport=%s%s, Birthday=%s\n",
 		req.Sugurtalovchi.Type, req.Sugurtalovchi.PassportSeries, req.Sugurtalovchi.PassportNumber, req.Sugurtalovchi.Birthday)
 	fmt.Printf("  Travelers count: %d\n", len(req.Travelers))
+	defer funnelServices.TrackResponse(c, funnelServices.FlowTravel, "save", req.SessionID, req.Provider)
 
 	ctx := context.Background()
 	redisKey := "travel:session:" + req.SessionID
@@ -1101,7 +1111,7 @@ func (tc *TravelController) SaveTravel(c *gin.Context) {
 		httpReq.Header.Set("Content-Type", "application/json")
 		httpReq.Header.Set("Authorization", authHeader)
 
-		client := &http.Client{Timeout: 30 * time.Second}
+		client := healthServices.Client("neo", "travel.save", 30*time.Second)
 		resp, err := client.Do(httpReq)
 		if err != nil {
 			c.JSON(500, gin.H{"result": nil, "success": false, "error": "failed to send request to Neo"})
@@ -1235,7 +1245,7 @@ func (tc *TravelController) SaveTravel(c *gin.Context) {
 		httpReq.Header.Set("Content-Type", "application/json")
 		httpReq.Header.Set("Authorization", authHeader)
 
-		client := &http.Client{Timeout: 30 * time.Second}
+		client := healthServices.Client("trust", "travel.save", 30*time.Second)
 		resp, err := client.Do(httpReq)
 		if err != nil {
 			c.JSON(500, gin.H{"result": nil, "success": false, "error": "failed to send request to Trust"})
@@ -1397,7 +1407,7 @@ func (tc *TravelController) SaveTravel(c *gin.Context) {
 		httpReq.Header.Set("Content-Type", "application/json")
 		httpReq.Header.Set("Authorization", authHeader)
 
-		client := &http.Client{Timeout: 30 * time.Second}
+		client := healthServices.Client("apex", "travel.save", 30*time.Second)
 		resp, err := client.Do(httpReq)
 		if err != nil {
 			fmt.Printf("ERROR: Failed to send request: %v\n", err)
@@ -1465,6 +1475,7 @@ func (tc *TravelController) CheckTravel(c *gin.Context) {
 	}
 
 	fmt.Printf("PARSED: SessionID=%s\n", req.SessionID)
+	defer funnelServices.TrackResponse(c, funnelServices.FlowTravel, "check", req.SessionID, "")
 
 	ctx := context.Background()
 	redisKey := "travel:session:" + req.SessionID
@@ -1559,7 +1570,7 @@ func (tc *TravelController) CheckTravel(c *gin.Context) {
 		httpReq.Header.Set("Content-Type", "application/json")
 		httpReq.Header.Set("Authorization", authHeader)
 
-		client := &http.Client{Timeout: 30 * time.Second}
+		client := healthServices.Client("neo", "travel.check", 30*time.Second)
 		resp, err := client.Do(httpReq)
 		if err != nil {
 			fmt.Printf("ERROR: Failed to send request to Neo: %v\n", err)
@@ -1623,7 +1634,7 @@ func (tc *TravelController) CheckTravel(c *gin.Context) {
 		httpReq.Header.Set("Content-Type", "application/json")
 		httpReq.Header.Set("Authorization", authHeader)
 
-		client := &http.Client{Timeout: 30 * time.Second}
+		client := healthServices.Client("trust", "travel.check", 30*time.Second)
 		resp, err := client.Do(httpReq)
 		if err != nil {
 			c.JSON(500, gin.H{"result": nil, "success": false, "error": "failed to send request to Trust"})
@@ -1679,7 +1690,7 @@ func (tc *TravelController) CheckTravel(c *gin.Context) {
 		httpReq.Header.Set("Content-Type", "application/json")
 		httpReq.Header.Set("Authorization", authHeader)
 
-		client := &http.Client{Timeout: 30 * time.Second}
+		client := healthServices.Client("apex", "travel.check", 30*time.Second)
 		resp, err := client.Do(httpReq)
 		if err != nil {
 			c.JSON(500, gin.H{"result": nil, "success": false, "error": "failed to send request to Apex"})
@@ -1731,7 +1742,7 @@ func (tc *TravelController) GetCountries(c *gin.Context) {
 
 	httpReq.Header.Set("Authorization", authHeader)
 
-	client := &http.Client{Timeout: 30 * time.Second}
+	client := healthServices.Client("neo", "travel.countries", 30*time.Second)
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		c.JSON(500, gin.H{"result": nil, "success": false, "error": "failed to send request"})
@@ -1845,7 +1856,7 @@ func (tc *TravelController) GetTarifs(c *gin.Context) {
 		httpReq.Header.Set("Content-Type", "application/json")
 		httpReq.Header.Set("Authorization", trustAuthHeader)
 
-		client := &http.Client{Timeout: 30 * time.Second}
+		client := healthServices.Client("trust", "travel.tariffs", 30*time.Second)
 		resp, err := client.Do(httpReq)
 		if err != nil {
 			result["trust"] = map[string]interface{}{"error": "failed to send request"}
@@ -1892,7 +1903,7 @@ func (tc *TravelController) GetTarifs(c *gin.Context) {
 		httpReq.Header.Set("Content-Type", "application/json")
 		httpReq.Header.Set("Authorization", apexAuthHeader)
 
-		client := &http.Client{Timeout: 30 * time.Second}
+		client := healthServices.Client("apex", "travel.tariffs", 30*time.Second)
 		resp, err := client.Do(httpReq)
 		if err != nil {
 			continue
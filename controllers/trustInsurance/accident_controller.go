@@ -12,6 +12,7 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"kliro/config"
+	healthServices "kliro/services/providerhealth"
 )
 
 type AccidentController struct {
@@ -54,7 +55,7 @@ func (ac *AccidentController) GetTariffs(c *gin.Context) {
 
 	req.Header.Set("Authorization", ac.basicAuthHeader())
 
-	resp, err := ac.cl.Do(req)
+	resp, err := healthServices.Client("trust", "accident.tariffs", ac.cl.Timeout).Do(req)
 	if err != nil {
 		c.JSON(http.StatusBadGateway, gin.H{"error": "external api error", "details": err.Error()})
 		return
@@ -158,7 +159,7 @@ func (ac *AccidentController) GetRegions(c *gin.Context) {
 
 	req.Header.Set("Authorization", ac.basicAuthHeader())
 
-	resp, err := ac.cl.Do(req)
+	resp, err := healthServices.Client("trust", "accident.regions", ac.cl.Timeout).Do(req)
 	if err != nil {
 		c.JSON(http.StatusBadGateway, gin.H{"error": "external api error", "details": err.Error()})
 		return
@@ -238,7 +239,7 @@ func (ac *AccidentController) Create(c *gin.Context) {
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("Authorization", ac.basicAuthHeader())
 
-	resp, err := ac.cl.Do(httpReq)
+	resp, err := healthServices.Client("trust", "accident.create", ac.cl.Timeout).Do(httpReq)
 	if err != nil {
 		c.JSON(http.StatusBadGateway, gin.H{"error": "external api error", "details": err.Error()})
 		return
@@ -321,7 +322,7 @@ func (ac *AccidentController) CheckPayment(c *gin.Context) {
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("Authorization", ac.basicAuthHeader())
 
-	resp, err := ac.cl.Do(httpReq)
+	resp, err := healthServices.Client("trust", "accident.check", ac.cl.Timeout).Do(httpReq)
 	if err != nil {
 		c.JSON(http.StatusBadGateway, gin.H{"error": "external api error", "details": err.Error()})
 		return
@@ -250,5 +250,85 @@ func Migrate(db *gorm.DB) error {
 		return err
 	}
 
+	// Создаем таблицы ранжирования списков банковских продуктов и спонсорских размещений
+	if err := migrations.CreateRankingTables(db); err != nil {
+		return err
+	}
+
+	// Создаем таблицы редакционных правок продуктов (скрыть, закрепить, исправить поля) и журнал изменений
+	if err := migrations.CreateEditorialTables(db); err != nil {
+		return err
+	}
+
+	// Создаем таблицы заявок на банковские продукты (лиды), истории статусов и банков-партнеров
+	if err := migrations.CreateLeadTables(db); err != nil {
+		return err
+	}
+
+	// Создаем таблицы отзывов и оценок банков и продуктов
+	if err := migrations.CreateReviewTables(db); err != nil {
+		return err
+	}
+
+	// Создаем таблицы партнерских ссылок (UTM по банкам) и переходов через /go/:token
+	if err := migrations.CreateRedirectTables(db); err != nil {
+		return err
+	}
+
+	// Создаем журнал кликов (append-only) и часовые/дневные агрегаты для аналитики
+	if err := migrations.CreateClickEventTables(db); err != nil {
+		return err
+	}
+
+	// Создаем таблицу событий воронки страховых сценариев (OSAGO, KASKO, travel)
+	if err := migrations.CreateFunnelTables(db); err != nil {
+		return err
+	}
+
+	// Создаем таблицу котировок страховых (аналитика цен OSAGO/KASKO)
+	if err := migrations.CreateInsuranceQuoteTables(db); err != nil {
+		return err
+	}
+
+	// Создаем таблицы A/B экспериментов (варианты, показы и конверсии)
+	if err := migrations.CreateExperimentTables(db); err != nil {
+		return err
+	}
+
+	// Создаем дневные показы продуктов и журнал ежемесячных отчетов банкам-партнерам
+	if err := migrations.CreatePartnerReportTables(db); err != nil {
+		return err
+	}
+
+	// Создаем расписания email-сводок (ежедневные и еженедельные отчеты)
+	if err := migrations.CreateReportScheduleTables(db); err != nil {
+		return err
+	}
+
+	// Заказы ОСАГО: запись каждой попытки create (без обязательного пользователя, идентификаторы провайдеров)
+	if err := migrations.UpdateOsagoOrdersForAttempts(db); err != nil {
+		return err
+	}
+
+	// Заказы ОСАГО: опрос статуса у провайдеров и история переходов
+	if err := migrations.CreateOsagoOrderStatusTables(db); err != nil {
+		return err
+	}
+
+	// Напоминания о продлении ОСАГО: внешние полисы пользователей, журнал отправок, отказ от рассылки
+	if err := migrations.CreateOsagoRenewalTables(db); err != nil {
+		return err
+	}
+
+	// Локальный тариф ОСАГО: коэффициенты регулируемой формулы
+	if err := migrations.CreateOsagoTariffTables(db); err != nil {
+		return err
+	}
+
+	// Заказы ОСАГО: ключ идемпотентности create (повтор ответа вместо второй заявки у провайдера)
+	if err := migrations.AddOsagoOrderIdempotency(db); err != nil {
+		return err
+	}
+
 	return nil
 }
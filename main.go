@@ -18,6 +18,13 @@ import (
 	"kliro/database"
 	"kliro/routes"
 	bankServices "kliro/services/bank"
+	clickServices "kliro/services/clicks"
+	exportServices "kliro/services/exports"
+	leadServices "kliro/services/leads"
+	osagoServices "kliro/services/osago"
+	partnerServices "kliro/services/partners"
+	redirectServices "kliro/services/redirect"
+	reportServices "kliro/services/reports"
 	"kliro/utils"
 )
 
@@ -113,6 +120,32 @@ func main() {
 
 	log.Println("Bank services starting in background...")
 
+	// Повтор доставки заявок (лидов) банкам, которые не удалось отправить сразу
+	leadServices.StartLeadRetryCron(leadServices.DefaultService())
+
+	// Подписанные ссылки /go/ без ключа подписи не выдаются — сообщаем об этом сразу
+	redirectServices.CheckSecret()
+
+	// Часовые и дневные агрегаты кликов для аналитики
+	clickServices.StartClickRollupCron(db)
+
+	// Показы продуктов в выдаче и ежемесячные отчеты банкам-партнерам
+	partnerServices.StartImpressionFlushCron(db)
+	partnerServices.StartPartnerReportCron(db)
+
+	// Ежедневные и еженедельные email-сводки по расписаниям из админки
+	reportServices.StartReportCron(db)
+
+	// Ночная выгрузка обезличенной аналитики в файлы
+	exportServices.StartExportCron(exportServices.DefaultExporter())
+
+	// Опрос статусов оформленных полисов ОСАГО у страховых (оплата, выпуск, PDF)
+	osagoCfg := config.LoadConfig()
+	osagoServices.StartStatusCron(osagoServices.NewStatusTracker(db, osagoCfg, osagoServices.NewRegistry(osagoCfg)))
+
+	// Напоминания о продлении ОСАГО за OSAGO_REMINDER_DAYS дней до окончания полиса
+	osagoServices.StartRenewalCron(osagoServices.NewRenewalReminders(db, osagoCfg))
+
 	// Подключение к Redis
 	rdb := redis.NewClient(&redis.Options{
 		Addr:     getenvOr("REDIS_ADDR", fmt.Sprintf("%s:6379", os.Getenv("DB_HOST"))),
@@ -128,7 +161,8 @@ func main() {
 
 	// Инициализация сервиса переводов (бесплатный, без токенов)
 	cfg := config.LoadConfig()
-	translationService := utils.NewTranslationService(cfg.TranslationAPIURL)
+	translationService := utils.NewTranslationServiceFromConfig(cfg)
+	utils.SetTranslationService(translationService)
 	
 	// Устанавливаем сервис для микрокредитов
 	microcreditTranslator := utils.GetMicrocreditTranslator()
@@ -146,7 +180,7 @@ func main() {
 	cardTranslator := utils.GetCardTranslator()
 	cardTranslator.SetTranslationService(translationService)
 	
-	log.Println("Translation service initialized (free API, no tokens required)")
+	log.Printf("Translation service initialized (backends: %v)", translationService.Backends())
 
 	// Инициализация Google OAuth
 	controllers.InitGoogleOAuth()
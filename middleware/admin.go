@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"net/http"
+
+	"kliro/models"
+	"kliro/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminMiddleware пускает только пользователей с ролью admin; ставится после JWTAuthMiddleware.
+// Роль проверяется по базе, а не по токену, чтобы снятие роли действовало сразу.
+func AdminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == "OPTIONS" {
+			c.Next()
+			return
+		}
+		var user models.User
+		if err := utils.GetDB().Select("id", "role").First(&user, c.GetInt("user_id")).Error; err != nil || user.Role != "admin" {
+			c.JSON(http.StatusForbidden, gin.H{"success": false, "error": "Доступ только для администраторов"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
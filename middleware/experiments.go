@@ -0,0 +1,16 @@
+package middleware
+
+import (
+	experimentServices "kliro/services/experiments"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExperimentsMiddleware кладет в контекст сессию A/B экспериментов.
+// Контроллеры получают вариант через experimentServices.FromContext(c).Variant("<key>") — показ записывается автоматически.
+func ExperimentsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		experimentServices.FromContext(c)
+		c.Next()
+	}
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"os"
+	"strings"
+
+	"kliro/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OptionalJWTMiddleware выставляет user_id, если передан валидный токен, но не требует авторизации
+func OptionalJWTMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		if !strings.HasPrefix(header, "Bearer ") {
+			c.Next()
+			return
+		}
+		token := strings.TrimPrefix(header, "Bearer ")
+
+		if rdb := utils.GetRedis(); rdb != nil {
+			if _, err := rdb.Get(utils.RedisCtx(), "blacklist:"+token).Result(); err == nil {
+				c.Next()
+				return
+			}
+		}
+
+		claims, err := utils.ParseJWT(token, os.Getenv("JWT_SECRET"))
+		if err == nil {
+			if userID, ok := claims["user_id"].(float64); ok {
+				c.Set("user_id", int(userID))
+			}
+		}
+		c.Next()
+	}
+}
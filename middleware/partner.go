@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"kliro/models"
+	"kliro/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PartnerAuthMiddleware пускает банк-партнера по токену (X-Partner-Token или Authorization: Partner <token>)
+// и выставляет partner_id / partner_bank для ограничения данных своим банком
+func PartnerAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == "OPTIONS" {
+			c.Next()
+			return
+		}
+		token := c.GetHeader("X-Partner-Token")
+		if token == "" {
+			token = strings.TrimPrefix(c.GetHeader("Authorization"), "Partner ")
+		}
+		if token == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": "Missing partner token"})
+			c.Abort()
+			return
+		}
+
+		var partner models.BankPartner
+		err := utils.GetDB().Where("api_token_hash = ? AND is_active = ?", utils.HashAPIToken(token), true).First(&partner).Error
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": "Invalid partner token"})
+			c.Abort()
+			return
+		}
+
+		c.Set("partner_id", int(partner.ID))
+		c.Set("partner_bank", partner.BankName)
+		c.Set("partner_slug", partner.BankSlug)
+		c.Next()
+	}
+}
@@ -0,0 +1,12 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// AddOsagoOrderIdempotency добавляет в osago_orders ключ идемпотентности create и хэш тела запроса
+func AddOsagoOrderIdempotency(db *gorm.DB) error {
+	return db.Exec(`
+		ALTER TABLE osago_orders ADD COLUMN IF NOT EXISTS idempotency_key VARCHAR(150);
+		ALTER TABLE osago_orders ADD COLUMN IF NOT EXISTS request_hash VARCHAR(64);
+		CREATE INDEX IF NOT EXISTS idx_osago_orders_idempotency_key ON osago_orders(idempotency_key);
+	`).Error
+}
@@ -0,0 +1,38 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// CreateClickEventTables создает журнал кликов и таблицу часовых/дневных агрегатов
+func CreateClickEventTables(db *gorm.DB) error {
+	return db.Exec(`
+		CREATE TABLE IF NOT EXISTS click_events (
+			id BIGSERIAL PRIMARY KEY,
+			key VARCHAR(255) NOT NULL,
+			direction VARCHAR(100) NOT NULL,
+			bank_slug VARCHAR(255),
+			user_id INTEGER,
+			platform VARCHAR(20),
+			lang VARCHAR(5),
+			source VARCHAR(20),
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_click_events_created_at ON click_events(created_at);
+
+		CREATE TABLE IF NOT EXISTS click_rollups (
+			id BIGSERIAL PRIMARY KEY,
+			period VARCHAR(10) NOT NULL,
+			bucket_start TIMESTAMP NOT NULL,
+			key VARCHAR(255) NOT NULL,
+			direction VARCHAR(100) NOT NULL,
+			bank_slug VARCHAR(255),
+			clicks INTEGER NOT NULL DEFAULT 0,
+			unique_users INTEGER NOT NULL DEFAULT 0,
+			updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_click_rollups_bucket ON click_rollups(period, bucket_start, key, direction);
+		CREATE INDEX IF NOT EXISTS idx_click_rollups_direction ON click_rollups(period, direction, bucket_start);
+		CREATE INDEX IF NOT EXISTS idx_click_rollups_bank ON click_rollups(period, bank_slug, bucket_start);
+	`).Error
+}
@@ -0,0 +1,41 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// CreateEditorialTables создает таблицы редакционных правок продуктов и журнала их изменений
+func CreateEditorialTables(db *gorm.DB) error {
+	return db.Exec(`
+		CREATE TABLE IF NOT EXISTS product_overrides (
+			id SERIAL PRIMARY KEY,
+			product_key VARCHAR(255) NOT NULL,
+			direction VARCHAR(50) NOT NULL,
+			bank_name VARCHAR(255),
+			product_name TEXT,
+			hidden BOOLEAN DEFAULT FALSE,
+			pinned BOOLEAN DEFAULT FALSE,
+			pin_position INTEGER DEFAULT 1,
+			corrections TEXT,
+			editorial_note TEXT,
+			updated_by INTEGER,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+			updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_product_overrides_key ON product_overrides(product_key);
+		CREATE INDEX IF NOT EXISTS idx_product_overrides_direction ON product_overrides(direction);
+
+		CREATE TABLE IF NOT EXISTS product_override_audits (
+			id SERIAL PRIMARY KEY,
+			override_id INTEGER,
+			product_key VARCHAR(255),
+			action VARCHAR(20),
+			before TEXT,
+			after TEXT,
+			user_id INTEGER,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_product_override_audits_override ON product_override_audits(override_id);
+		CREATE INDEX IF NOT EXISTS idx_product_override_audits_key ON product_override_audits(product_key);
+	`).Error
+}
@@ -0,0 +1,39 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// CreateExperimentTables создает таблицы A/B экспериментов и их событий
+func CreateExperimentTables(db *gorm.DB) error {
+	return db.Exec(`
+		CREATE TABLE IF NOT EXISTS experiments (
+			id SERIAL PRIMARY KEY,
+			key VARCHAR(100) NOT NULL,
+			name VARCHAR(255),
+			description TEXT,
+			status VARCHAR(20) NOT NULL DEFAULT 'draft',
+			variants TEXT NOT NULL,
+			traffic_percent INTEGER NOT NULL DEFAULT 100,
+			starts_at TIMESTAMP WITH TIME ZONE,
+			ends_at TIMESTAMP WITH TIME ZONE,
+			updated_by INTEGER,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+			updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_experiments_key ON experiments(key);
+
+		CREATE TABLE IF NOT EXISTS experiment_events (
+			id BIGSERIAL PRIMARY KEY,
+			experiment_key VARCHAR(100) NOT NULL,
+			variant VARCHAR(50) NOT NULL,
+			subject_id VARCHAR(150) NOT NULL,
+			event VARCHAR(20) NOT NULL,
+			goal VARCHAR(50),
+			value DOUBLE PRECISION NOT NULL DEFAULT 0,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_experiment_events_key ON experiment_events(experiment_key, event, variant);
+		CREATE INDEX IF NOT EXISTS idx_experiment_events_subject ON experiment_events(experiment_key, subject_id);
+	`).Error
+}
@@ -0,0 +1,23 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// CreateFunnelTables создает таблицу событий шагов страховых сценариев
+func CreateFunnelTables(db *gorm.DB) error {
+	return db.Exec(`
+		CREATE TABLE IF NOT EXISTS funnel_events (
+			id BIGSERIAL PRIMARY KEY,
+			flow VARCHAR(20) NOT NULL,
+			step VARCHAR(30) NOT NULL,
+			session_id VARCHAR(64) NOT NULL,
+			provider VARCHAR(30),
+			success BOOLEAN NOT NULL DEFAULT FALSE,
+			error TEXT,
+			user_id INTEGER,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_funnel_events_flow_created ON funnel_events(flow, created_at);
+		CREATE INDEX IF NOT EXISTS idx_funnel_events_session ON funnel_events(session_id);
+	`).Error
+}
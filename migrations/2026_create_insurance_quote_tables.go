@@ -0,0 +1,35 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// CreateInsuranceQuoteTables создает таблицу котировок страховых из расчетов OSAGO/KASKO
+func CreateInsuranceQuoteTables(db *gorm.DB) error {
+	return db.Exec(`
+		CREATE TABLE IF NOT EXISTS insurance_quotes (
+			id BIGSERIAL PRIMARY KEY,
+			product VARCHAR(20) NOT NULL,
+			product_type VARCHAR(30),
+			batch_id VARCHAR(64) NOT NULL,
+			session_id VARCHAR(64) NOT NULL,
+			provider VARCHAR(30) NOT NULL,
+			success BOOLEAN NOT NULL DEFAULT FALSE,
+			premium BIGINT NOT NULL DEFAULT 0,
+			error TEXT,
+			rank INTEGER NOT NULL DEFAULT 0,
+			gap_to_cheapest BIGINT NOT NULL DEFAULT 0,
+			gap_percent DOUBLE PRECISION NOT NULL DEFAULT 0,
+			vehicle_type VARCHAR(100),
+			region VARCHAR(100),
+			owner_type VARCHAR(20),
+			period_id INTEGER,
+			driver_restriction BOOLEAN DEFAULT FALSE,
+			vehicle_year INTEGER,
+			picked BOOLEAN NOT NULL DEFAULT FALSE,
+			picked_at TIMESTAMP WITH TIME ZONE,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_insurance_quotes_product_created ON insurance_quotes(product, created_at);
+		CREATE INDEX IF NOT EXISTS idx_insurance_quotes_session ON insurance_quotes(session_id);
+	`).Error
+}
@@ -0,0 +1,74 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// CreateLeadTables создает таблицы заявок на банковские продукты, истории статусов и банков-партнеров
+func CreateLeadTables(db *gorm.DB) error {
+	return db.Exec(`
+		CREATE TABLE IF NOT EXISTS bank_partners (
+			id SERIAL PRIMARY KEY,
+			bank_name VARCHAR(255) NOT NULL,
+			bank_slug VARCHAR(255) NOT NULL,
+			api_token_hash VARCHAR(64),
+			lead_channel VARCHAR(20) DEFAULT 'email',
+			lead_email VARCHAR(255),
+			lead_webhook_url TEXT,
+			lead_webhook_key VARCHAR(255),
+			lead_directions VARCHAR(255),
+			is_active BOOLEAN DEFAULT TRUE,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+			updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_bank_partners_slug ON bank_partners(bank_slug);
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_bank_partners_token ON bank_partners(api_token_hash);
+
+		CREATE TABLE IF NOT EXISTS leads (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+			partner_id INTEGER REFERENCES bank_partners(id) ON DELETE SET NULL,
+			direction VARCHAR(50) NOT NULL,
+			product_key VARCHAR(255),
+			bank_name VARCHAR(255) NOT NULL,
+			product_name TEXT,
+			full_name VARCHAR(255) NOT NULL,
+			phone VARCHAR(50) NOT NULL,
+			email VARCHAR(255),
+			amount BIGINT DEFAULT 0,
+			term_months INTEGER DEFAULT 0,
+			params TEXT,
+			lang VARCHAR(5),
+			consent BOOLEAN NOT NULL,
+			consent_text TEXT,
+			consent_at TIMESTAMP WITH TIME ZONE,
+			consent_ip VARCHAR(64),
+			status VARCHAR(30) NOT NULL DEFAULT 'new',
+			delivery_channel VARCHAR(20),
+			delivery_attempts INTEGER DEFAULT 0,
+			delivery_error TEXT,
+			delivered_at TIMESTAMP WITH TIME ZONE,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+			updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_leads_user_id ON leads(user_id);
+		CREATE INDEX IF NOT EXISTS idx_leads_partner_id ON leads(partner_id);
+		CREATE INDEX IF NOT EXISTS idx_leads_direction ON leads(direction);
+		CREATE INDEX IF NOT EXISTS idx_leads_status ON leads(status);
+		CREATE INDEX IF NOT EXISTS idx_leads_product_key ON leads(product_key);
+		CREATE INDEX IF NOT EXISTS idx_leads_created_at ON leads(created_at);
+
+		CREATE TABLE IF NOT EXISTS lead_status_histories (
+			id SERIAL PRIMARY KEY,
+			lead_id INTEGER NOT NULL REFERENCES leads(id) ON DELETE CASCADE,
+			from_status VARCHAR(30),
+			to_status VARCHAR(30),
+			actor VARCHAR(20),
+			actor_id INTEGER,
+			comment TEXT,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_lead_status_histories_lead ON lead_status_histories(lead_id);
+	`).Error
+}
@@ -0,0 +1,27 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// CreateOsagoOrderStatusTables добавляет в osago_orders поля опроса статуса у провайдера
+// и создает историю переходов статуса (created -> paid -> issued / failed / expired)
+func CreateOsagoOrderStatusTables(db *gorm.DB) error {
+	return db.Exec(`
+		ALTER TABLE osago_orders ADD COLUMN IF NOT EXISTS status_checked_at TIMESTAMP WITH TIME ZONE;
+		ALTER TABLE osago_orders ADD COLUMN IF NOT EXISTS status_checks INTEGER NOT NULL DEFAULT 0;
+		ALTER TABLE osago_orders ADD COLUMN IF NOT EXISTS next_status_check_at TIMESTAMP WITH TIME ZONE;
+		ALTER TABLE osago_orders ADD COLUMN IF NOT EXISTS notified_at TIMESTAMP WITH TIME ZONE;
+
+		CREATE INDEX IF NOT EXISTS idx_osago_orders_next_status_check ON osago_orders(next_status_check_at)
+			WHERE next_status_check_at IS NOT NULL;
+
+		CREATE TABLE IF NOT EXISTS osago_order_status_histories (
+			id SERIAL PRIMARY KEY,
+			order_id INTEGER NOT NULL,
+			from_status VARCHAR(30),
+			to_status VARCHAR(30),
+			comment TEXT,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+		CREATE INDEX IF NOT EXISTS idx_osago_order_status_histories_order ON osago_order_status_histories(order_id);
+	`).Error
+}
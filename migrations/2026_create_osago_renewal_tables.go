@@ -0,0 +1,47 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// CreateOsagoRenewalTables создает внешние полисы пользователей, журнал напоминаний о продлении ОСАГО
+// и флаг отказа от напоминаний в users
+func CreateOsagoRenewalTables(db *gorm.DB) error {
+	return db.Exec(`
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS osago_reminders_opt_out BOOLEAN DEFAULT FALSE;
+
+		CREATE TABLE IF NOT EXISTS osago_external_policies (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL,
+			insurer VARCHAR(100),
+			policy_number VARCHAR(100),
+			license_plate VARCHAR(32),
+			tech_passport_series VARCHAR(10),
+			tech_passport_number VARCHAR(20),
+			passport_series VARCHAR(10),
+			passport_number VARCHAR(20),
+			birthdate VARCHAR(20),
+			inn VARCHAR(20),
+			end_date VARCHAR(20) NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+			updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+		CREATE INDEX IF NOT EXISTS idx_osago_external_policies_user ON osago_external_policies(user_id);
+		CREATE INDEX IF NOT EXISTS idx_osago_external_policies_end_date ON osago_external_policies(end_date);
+
+		CREATE TABLE IF NOT EXISTS osago_renewal_reminders (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER,
+			source VARCHAR(40) NOT NULL,
+			end_date VARCHAR(20) NOT NULL,
+			offset_days INTEGER NOT NULL,
+			token VARCHAR(64) NOT NULL,
+			prefill TEXT,
+			channel VARCHAR(10),
+			error TEXT,
+			sent_at TIMESTAMP WITH TIME ZONE,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+		CREATE UNIQUE INDEX IF NOT EXISTS uniq_osago_renewal_reminders_token ON osago_renewal_reminders(token);
+		CREATE UNIQUE INDEX IF NOT EXISTS uniq_osago_renewal_reminders_source ON osago_renewal_reminders(source, end_date, offset_days);
+		CREATE INDEX IF NOT EXISTS idx_osago_renewal_reminders_user ON osago_renewal_reminders(user_id);
+	`).Error
+}
@@ -0,0 +1,21 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// CreateOsagoTariffTables создает таблицу коэффициентов регулируемого тарифа ОСАГО. Таблица создается пустой:
+// коэффициенты вносятся в админке по действующему постановлению, до этого расчет тарифа и отметка отклонения отключены.
+func CreateOsagoTariffTables(db *gorm.DB) error {
+	return db.Exec(`
+		CREATE TABLE IF NOT EXISTS osago_tariff_coefficients (
+			id SERIAL PRIMARY KEY,
+			factor VARCHAR(30) NOT NULL,
+			key VARCHAR(30) NOT NULL,
+			value DOUBLE PRECISION NOT NULL,
+			label VARCHAR(100),
+			updated_by INTEGER,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+			updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_osago_tariff_factor_key ON osago_tariff_coefficients(factor, key);
+	`).Error
+}
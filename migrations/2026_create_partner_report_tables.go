@@ -0,0 +1,38 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// CreatePartnerReportTables создает дневные показы продуктов, журнал отправки отчетов партнерам
+// и добавляет партнерам адрес для ежемесячного отчета
+func CreatePartnerReportTables(db *gorm.DB) error {
+	return db.Exec(`
+		CREATE TABLE IF NOT EXISTS product_impressions (
+			id BIGSERIAL PRIMARY KEY,
+			day DATE NOT NULL,
+			key VARCHAR(255) NOT NULL,
+			direction VARCHAR(100) NOT NULL,
+			bank_slug VARCHAR(255),
+			impressions INTEGER NOT NULL DEFAULT 0,
+			updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_product_impressions_day ON product_impressions(day, key, direction);
+		CREATE INDEX IF NOT EXISTS idx_product_impressions_bank ON product_impressions(bank_slug, day);
+
+		CREATE TABLE IF NOT EXISTS partner_report_deliveries (
+			id SERIAL PRIMARY KEY,
+			partner_id INTEGER NOT NULL REFERENCES bank_partners(id) ON DELETE CASCADE,
+			period VARCHAR(7) NOT NULL,
+			email VARCHAR(255),
+			status VARCHAR(20) NOT NULL,
+			error TEXT,
+			sent_at TIMESTAMP WITH TIME ZONE,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+			updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_partner_report_deliveries_period ON partner_report_deliveries(partner_id, period);
+
+		ALTER TABLE bank_partners ADD COLUMN IF NOT EXISTS report_email VARCHAR(255);
+	`).Error
+}
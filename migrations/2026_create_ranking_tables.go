@@ -0,0 +1,39 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// CreateRankingTables создает таблицы настроек ранжирования и спонсорских размещений
+func CreateRankingTables(db *gorm.DB) error {
+	return db.Exec(`
+		CREATE TABLE IF NOT EXISTS ranking_configs (
+			id SERIAL PRIMARY KEY,
+			direction VARCHAR(50) NOT NULL,
+			strategy VARCHAR(30) NOT NULL,
+			tie_breaker VARCHAR(30) NOT NULL,
+			diversify_banks BOOLEAN DEFAULT FALSE,
+			updated_by INTEGER,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+			updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_ranking_configs_direction ON ranking_configs(direction);
+
+		CREATE TABLE IF NOT EXISTS sponsored_placements (
+			id SERIAL PRIMARY KEY,
+			direction VARCHAR(50) NOT NULL,
+			bank_name VARCHAR(255) NOT NULL,
+			product_match TEXT,
+			position INTEGER NOT NULL DEFAULT 1,
+			label VARCHAR(50) NOT NULL DEFAULT 'promoted',
+			starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			ends_at TIMESTAMP WITH TIME ZONE,
+			is_active BOOLEAN DEFAULT TRUE,
+			created_by INTEGER,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+			updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_sponsored_placements_direction ON sponsored_placements(direction);
+		CREATE INDEX IF NOT EXISTS idx_sponsored_placements_window ON sponsored_placements(starts_at, ends_at);
+	`).Error
+}
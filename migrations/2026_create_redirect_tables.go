@@ -0,0 +1,41 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// CreateRedirectTables создает таблицы настроек партнерских ссылок и переходов через /go/:token
+func CreateRedirectTables(db *gorm.DB) error {
+	return db.Exec(`
+		CREATE TABLE IF NOT EXISTS bank_redirect_configs (
+			id SERIAL PRIMARY KEY,
+			bank_name VARCHAR(255) NOT NULL,
+			bank_slug VARCHAR(255) NOT NULL,
+			default_url TEXT,
+			utm_source VARCHAR(100),
+			utm_medium VARCHAR(100),
+			utm_campaign VARCHAR(255),
+			extra_params TEXT,
+			is_active BOOLEAN DEFAULT TRUE,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+			updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_bank_redirect_configs_slug ON bank_redirect_configs(bank_slug);
+
+		CREATE TABLE IF NOT EXISTS outbound_clicks (
+			id SERIAL PRIMARY KEY,
+			product_key VARCHAR(255),
+			direction VARCHAR(50),
+			bank_name VARCHAR(255),
+			target_url TEXT,
+			user_id INTEGER,
+			lang VARCHAR(5),
+			platform VARCHAR(20),
+			referrer TEXT,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_outbound_clicks_product_key ON outbound_clicks(product_key);
+		CREATE INDEX IF NOT EXISTS idx_outbound_clicks_direction ON outbound_clicks(direction);
+		CREATE INDEX IF NOT EXISTS idx_outbound_clicks_created_at ON outbound_clicks(created_at);
+	`).Error
+}
@@ -0,0 +1,24 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// CreateReportScheduleTables создает таблицу расписаний email-сводок для руководства
+func CreateReportScheduleTables(db *gorm.DB) error {
+	return db.Exec(`
+		CREATE TABLE IF NOT EXISTS report_schedules (
+			id SERIAL PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			kind VARCHAR(10) NOT NULL,
+			recipients TEXT NOT NULL,
+			send_hour INTEGER NOT NULL DEFAULT 9,
+			weekday INTEGER NOT NULL DEFAULT 1,
+			sections VARCHAR(255),
+			is_active BOOLEAN DEFAULT TRUE,
+			last_sent_at TIMESTAMP WITH TIME ZONE,
+			last_error TEXT,
+			updated_by INTEGER,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+			updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+	`).Error
+}
@@ -0,0 +1,41 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// CreateReviewTables создает таблицы отзывов о банках и продуктах и голосов "полезно"
+func CreateReviewTables(db *gorm.DB) error {
+	return db.Exec(`
+		CREATE TABLE IF NOT EXISTS reviews (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			target_type VARCHAR(20) NOT NULL,
+			target_key VARCHAR(255) NOT NULL,
+			direction VARCHAR(50),
+			bank_name VARCHAR(255),
+			product_name TEXT,
+			rating INTEGER NOT NULL CHECK (rating BETWEEN 1 AND 5),
+			title VARCHAR(255),
+			body TEXT,
+			status VARCHAR(20) NOT NULL DEFAULT 'pending',
+			moderation_note TEXT,
+			moderated_by INTEGER,
+			moderated_at TIMESTAMP WITH TIME ZONE,
+			helpful_count INTEGER DEFAULT 0,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+			updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_reviews_user_target ON reviews(user_id, target_type, target_key);
+		CREATE INDEX IF NOT EXISTS idx_reviews_target ON reviews(target_type, target_key, status);
+		CREATE INDEX IF NOT EXISTS idx_reviews_status ON reviews(status);
+
+		CREATE TABLE IF NOT EXISTS review_votes (
+			id SERIAL PRIMARY KEY,
+			review_id INTEGER NOT NULL REFERENCES reviews(id) ON DELETE CASCADE,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_review_votes_review_user ON review_votes(review_id, user_id);
+	`).Error
+}
@@ -0,0 +1,27 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// UpdateOsagoOrdersForAttempts готовит osago_orders к записи каждой попытки create: заказ может быть без пользователя
+// и без числового external_order_id (идентификаторы провайдеров — строки), поэтому снимаем NOT NULL и уникальный индекс
+func UpdateOsagoOrdersForAttempts(db *gorm.DB) error {
+	return db.Exec(`
+		DROP INDEX IF EXISTS uniq_osago_user_extorder;
+		ALTER TABLE osago_orders ALTER COLUMN user_id DROP NOT NULL;
+		ALTER TABLE osago_orders ALTER COLUMN external_order_id SET DEFAULT 0;
+
+		ALTER TABLE osago_orders ADD COLUMN IF NOT EXISTS provider VARCHAR(20);
+		ALTER TABLE osago_orders ADD COLUMN IF NOT EXISTS session_id VARCHAR(64);
+		ALTER TABLE osago_orders ADD COLUMN IF NOT EXISTS inputs TEXT;
+		ALTER TABLE osago_orders ADD COLUMN IF NOT EXISTS provider_response TEXT;
+		ALTER TABLE osago_orders ADD COLUMN IF NOT EXISTS error TEXT;
+		ALTER TABLE osago_orders ADD COLUMN IF NOT EXISTS period_id INTEGER;
+		ALTER TABLE osago_orders ADD COLUMN IF NOT EXISTS provider_order_id VARCHAR(100);
+		ALTER TABLE osago_orders ADD COLUMN IF NOT EXISTS provider_policy_id VARCHAR(100);
+		ALTER TABLE osago_orders ADD COLUMN IF NOT EXISTS payment_url TEXT;
+		ALTER TABLE osago_orders ADD COLUMN IF NOT EXISTS payme_url TEXT;
+
+		CREATE INDEX IF NOT EXISTS idx_osago_orders_user_created ON osago_orders(user_id, created_at DESC);
+		CREATE INDEX IF NOT EXISTS idx_osago_orders_status ON osago_orders(status);
+	`).Error
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// ClickEvent - одно событие перехода по продукту (append-only, не изменяется)
+type ClickEvent struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Key       string    `json:"key" gorm:"type:varchar(255);not null"`
+	Direction string    `json:"direction" gorm:"type:varchar(100);not null"`
+	BankSlug  string    `json:"bank_slug" gorm:"type:varchar(255)"`
+	UserID    *uint     `json:"user_id"`
+	Platform  string    `json:"platform" gorm:"type:varchar(20)"`
+	Lang      string    `json:"lang" gorm:"type:varchar(5)"`
+	Source    string    `json:"source" gorm:"type:varchar(20)"` // track (POST /analytics/bank/track-click) или redirect (/go/:token)
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
+
+// ClickRollup - агрегат кликов за час или день (по времени Ташкента), пересчитывается cron'ом
+type ClickRollup struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Period      string    `json:"period" gorm:"type:varchar(10);not null"` // hour | day
+	BucketStart time.Time `json:"bucket_start" gorm:"type:timestamp;not null"`
+	Key         string    `json:"key" gorm:"type:varchar(255);not null"`
+	Direction   string    `json:"direction" gorm:"type:varchar(100);not null"`
+	BankSlug    string    `json:"bank_slug" gorm:"type:varchar(255)"`
+	Clicks      int       `json:"clicks"`
+	UniqueUsers int       `json:"unique_users"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// ProductOverride - редакционная правка продукта банка поверх данных парсера.
+// Привязана к стабильному ключу продукта (utils.ProductKey), поэтому переживает перепарсинг.
+type ProductOverride struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	ProductKey    string    `json:"product_key" gorm:"type:varchar(255);uniqueIndex;not null"`
+	Direction     string    `json:"direction" gorm:"type:varchar(50);index;not null"`
+	BankName      string    `json:"bank_name" gorm:"type:varchar(255)"`
+	ProductName   string    `json:"product_name" gorm:"type:text"`
+	Hidden        bool      `json:"hidden" gorm:"default:false"`
+	Pinned        bool      `json:"pinned" gorm:"default:false"`
+	PinPosition   int       `json:"pin_position" gorm:"default:1"`   // порядок среди закрепленных (1 — первый)
+	Corrections   string    `json:"corrections" gorm:"type:text"`    // JSON: {"rate": "24%", ...} по json-полям модели
+	EditorialNote string    `json:"editorial_note" gorm:"type:text"` // заметка редакции, отдается клиенту
+	UpdatedBy     *uint     `json:"updated_by"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// ProductOverrideAudit - журнал изменений редакционных правок (кто, что и когда поменял)
+type ProductOverrideAudit struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	OverrideID uint      `json:"override_id" gorm:"index"`
+	ProductKey string    `json:"product_key" gorm:"type:varchar(255);index"`
+	Action     string    `json:"action" gorm:"type:varchar(20)"` // create | update | delete
+	Before     string    `json:"before" gorm:"type:text"`        // JSON состояния до изменения
+	After      string    `json:"after" gorm:"type:text"`         // JSON состояния после изменения
+	UserID     *uint     `json:"user_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Experiment - A/B эксперимент: варианты с весами и долей трафика
+type Experiment struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	Key            string     `json:"key" gorm:"type:varchar(100);uniqueIndex;not null"` // например ranking_deposit
+	Name           string     `json:"name" gorm:"type:varchar(255)"`
+	Description    string     `json:"description" gorm:"type:text"`
+	Status         string     `json:"status" gorm:"type:varchar(20);not null;default:'draft'"` // draft | running | paused | completed
+	Variants       string     `json:"variants" gorm:"type:text;not null"`                      // JSON: [{"key":"control","weight":50,"config":{...}}]
+	TrafficPercent int        `json:"traffic_percent" gorm:"not null;default:100"`             // доля пользователей в эксперименте
+	StartsAt       *time.Time `json:"starts_at"`
+	EndsAt         *time.Time `json:"ends_at"`
+	UpdatedBy      *uint      `json:"updated_by"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// ExperimentEvent - показ варианта (exposure) или целевое действие (conversion)
+type ExperimentEvent struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	ExperimentKey string    `json:"experiment_key" gorm:"type:varchar(100);not null"`
+	Variant       string    `json:"variant" gorm:"type:varchar(50);not null"`
+	SubjectID     string    `json:"subject_id" gorm:"type:varchar(150);not null"` // user:<id> или device:<id>
+	Event         string    `json:"event" gorm:"type:varchar(20);not null"`       // exposure | conversion
+	Goal          string    `json:"goal" gorm:"type:varchar(50)"`                 // для conversion: lead, outbound_click, osago_create ...
+	Value         float64   `json:"value"`
+	CreatedAt     time.Time `json:"created_at"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// FunnelEvent - шаг пользователя в страховом сценарии (OSAGO, KASKO, travel), ключ — session_id из Redis
+type FunnelEvent struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Flow      string    `json:"flow" gorm:"type:varchar(20);not null"` // osago | kasko | travel
+	Step      string    `json:"step" gorm:"type:varchar(30);not null"` // find, calculate, create ...
+	SessionID string    `json:"session_id" gorm:"type:varchar(64);not null"`
+	Provider  string    `json:"provider" gorm:"type:varchar(30)"` // пусто — событие по шагу в целом
+	Success   bool      `json:"success"`
+	Error     string    `json:"error" gorm:"type:text"`
+	UserID    *uint     `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// InsuranceQuote - нормализованная котировка страховой из расчета OSAGO/KASKO (без персональных данных)
+type InsuranceQuote struct {
+	ID                uint       `json:"id" gorm:"primaryKey"`
+	Product           string     `json:"product" gorm:"type:varchar(20);not null"`    // osago | kasko
+	ProductType       string     `json:"product_type" gorm:"type:varchar(30)"`        // для KASKO: full_kasko | mini_kasko | euro_kasko
+	BatchID           string     `json:"batch_id" gorm:"type:varchar(64);not null"`   // один вызов Calculate
+	SessionID         string     `json:"session_id" gorm:"type:varchar(64);not null"` // связь с выбором пользователя на шаге create
+	Provider          string     `json:"provider" gorm:"type:varchar(30);not null"`
+	Success           bool       `json:"success"`
+	Premium           int64      `json:"premium"` // UZS, 0 — нет цены
+	Error             string     `json:"error" gorm:"type:text"`
+	Rank              int        `json:"rank"`            // 1 — самая низкая цена в расчете (0 — нет цены)
+	GapToCheapest     int64      `json:"gap_to_cheapest"` // насколько дороже самой низкой цены, UZS
+	GapPercent        float64    `json:"gap_percent"`
+	VehicleType       string     `json:"vehicle_type" gorm:"type:varchar(100)"`
+	Region            string     `json:"region" gorm:"type:varchar(100)"`
+	OwnerType         string     `json:"owner_type" gorm:"type:varchar(20)"` // person | organization
+	PeriodID          int        `json:"period_id"`                          // OSAGO: 1 — 12 мес, 2 — 6 мес, 3 — 20 дней; KASKO — дни
+	DriverRestriction bool       `json:"driver_restriction"`
+	VehicleYear       *int       `json:"vehicle_year"`
+	Picked            bool       `json:"picked"`
+	PickedAt          *time.Time `json:"picked_at"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
@@ -0,0 +1,61 @@
+package models
+
+import "time"
+
+// Lead - заявка пользователя на банковский продукт (микрокредит, ипотека, автокредит, вклад)
+type Lead struct {
+	ID               uint       `json:"id" gorm:"primaryKey"`
+	UserID           *uint      `json:"user_id" gorm:"index"`
+	PartnerID        *uint      `json:"partner_id" gorm:"index"`
+	Direction        string     `json:"direction" gorm:"type:varchar(50);index;not null"`
+	ProductKey       string     `json:"product_key" gorm:"type:varchar(255);index"`
+	BankName         string     `json:"bank_name" gorm:"type:varchar(255);not null"`
+	ProductName      string     `json:"product_name" gorm:"type:text"`
+	FullName         string     `json:"full_name" gorm:"type:varchar(255);not null"`
+	Phone            string     `json:"phone" gorm:"type:varchar(50);not null"`
+	Email            string     `json:"email" gorm:"type:varchar(255)"`
+	Amount           int64      `json:"amount"`
+	TermMonths       int        `json:"term_months"`
+	Params           string     `json:"params" gorm:"type:text"` // JSON желаемых параметров (доход, первоначальный взнос и т.п.)
+	Lang             string     `json:"lang" gorm:"type:varchar(5)"`
+	Consent          bool       `json:"consent" gorm:"not null"`
+	ConsentText      string     `json:"consent_text" gorm:"type:text"`
+	ConsentAt        time.Time  `json:"consent_at"`
+	ConsentIP        string     `json:"consent_ip" gorm:"type:varchar(64)"`
+	Status           string     `json:"status" gorm:"type:varchar(30);index;not null;default:'new'"`
+	DeliveryChannel  string     `json:"delivery_channel" gorm:"type:varchar(20)"` // email | webhook
+	DeliveryAttempts int        `json:"delivery_attempts" gorm:"default:0"`
+	DeliveryError    string     `json:"delivery_error" gorm:"type:text"`
+	DeliveredAt      *time.Time `json:"delivered_at"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// LeadStatusHistory - история смены статусов заявки (кто и когда)
+type LeadStatusHistory struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	LeadID     uint      `json:"lead_id" gorm:"index;not null"`
+	FromStatus string    `json:"from_status" gorm:"type:varchar(30)"`
+	ToStatus   string    `json:"to_status" gorm:"type:varchar(30)"`
+	Actor      string    `json:"actor" gorm:"type:varchar(20)"` // system | admin | partner
+	ActorID    *uint     `json:"actor_id"`
+	Comment    string    `json:"comment" gorm:"type:text"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// BankPartner - банк-партнер: куда доставлять заявки и доступ к своему кабинету по токену
+type BankPartner struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	BankName       string    `json:"bank_name" gorm:"type:varchar(255);not null"`
+	BankSlug       string    `json:"bank_slug" gorm:"type:varchar(255);uniqueIndex;not null"`
+	APITokenHash   string    `json:"-" gorm:"type:varchar(64);uniqueIndex"`
+	LeadChannel    string    `json:"lead_channel" gorm:"type:varchar(20);default:'email'"` // email | webhook
+	LeadEmail      string    `json:"lead_email" gorm:"type:varchar(255)"`
+	LeadWebhookURL string    `json:"lead_webhook_url" gorm:"type:text"`
+	LeadWebhookKey string    `json:"-" gorm:"type:varchar(255)"`               // секрет для подписи webhook (X-Kliro-Signature)
+	LeadDirections string    `json:"lead_directions" gorm:"type:varchar(255)"` // через запятую; пусто — все
+	ReportEmail    string    `json:"report_email" gorm:"type:varchar(255)"`    // куда слать ежемесячный отчет; пусто — не слать
+	IsActive       bool      `json:"is_active" gorm:"default:true"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
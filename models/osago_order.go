@@ -2,19 +2,47 @@ package models
 
 import "time"
 
-// OsagoOrder хранит заказы ОСАГО, связанные с пользователем
+// OsagoOrder хранит попытки оформления ОСАГО у провайдеров (create) и их статус, связанные с пользователем
 type OsagoOrder struct {
-	ID              uint   `gorm:"primaryKey"`
-	UserID          uint   `gorm:"index:uniq_osago_user_extorder,unique;not null"`
-	ExternalOrderID int64  `gorm:"index:uniq_osago_user_extorder,unique;not null"`
-	Status          string `gorm:"type:varchar(50)"`
-	AmountUZS       *int64
-	PolicyNumber    *string    `gorm:"type:varchar(100)"`
-	GosNumber       *string    `gorm:"type:varchar(32)"`
-	BeginDate       *string    `gorm:"type:varchar(20)"`
-	EndDate         *string    `gorm:"type:varchar(20)"`
-	PdfURL          *string    `gorm:"type:text"`
-	IssuedAt        *time.Time // дата оформления (при успешной оплате/активации)
-	CreatedAt       time.Time
-	UpdatedAt       time.Time
+	ID                uint       `json:"id" gorm:"primaryKey"`
+	UserID            *uint      `json:"user_id" gorm:"index"` // nil — оформление без авторизации
+	ExternalOrderID   int64      `json:"external_order_id" gorm:"not null;default:0"`
+	Provider          string     `json:"provider" gorm:"type:varchar(20);index"`
+	SessionID         string     `json:"session_id" gorm:"type:varchar(64);index"`
+	Inputs            string     `json:"-" gorm:"type:text"`             // JSON: телефон и данные ТС/владельца для уведомлений и продления (osago.OrderInputs)
+	ProviderResponse  string     `json:"-" gorm:"type:text"`             // JSON сырого ответа провайдера — для разбора
+	Status            string     `json:"status" gorm:"type:varchar(50)"` // created | paid | issued | failed | expired (см. services/osago)
+	Error             string     `json:"error,omitempty" gorm:"type:text"`
+	AmountUZS         *int64     `json:"amount_uzs"`
+	PeriodID          int        `json:"period_id"`
+	ProviderOrderID   string     `json:"provider_order_id" gorm:"type:varchar(100)"`
+	ProviderPolicyID  string     `json:"provider_policy_id" gorm:"type:varchar(100)"`
+	PaymentURL        string     `json:"payment_url" gorm:"type:text"` // Click (или единственная ссылка провайдера)
+	PaymeURL          string     `json:"payme_url" gorm:"type:text"`
+	PolicyNumber      *string    `json:"policy_number" gorm:"type:varchar(100)"`
+	GosNumber         *string    `json:"gos_number" gorm:"type:varchar(32)"`
+	BeginDate         *string    `json:"begin_date" gorm:"type:varchar(20)"`
+	EndDate           *string    `json:"end_date" gorm:"type:varchar(20)"`
+	PdfURL            *string    `json:"pdf_url" gorm:"type:text"`
+	IssuedAt          *time.Time `json:"issued_at"` // дата оформления (при успешной оплате/активации)
+	StatusCheckedAt   *time.Time `json:"status_checked_at"`
+	StatusChecks      int        `json:"-" gorm:"not null;default:0"`      // число опросов провайдера (для backoff)
+	NextStatusCheckAt *time.Time `json:"-"`                                // nil — опрос не нужен
+	NotifiedAt        *time.Time `json:"-"`                                // когда пользователю отправлено уведомление о выпуске
+	IdempotencyKey    string     `json:"-" gorm:"type:varchar(150);index"` // ключ повтора create (Idempotency-Key или сессия+провайдер)
+	RequestHash       string     `json:"-" gorm:"type:varchar(64)"`        // хэш тела create для явного Idempotency-Key
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+
+	History []OsagoOrderStatusHistory `json:"history,omitempty" gorm:"-"` // заполняется в детальном ответе
+}
+
+// OsagoOrderStatusHistory - переходы статуса заказа ОСАГО (create, опрос провайдера)
+type OsagoOrderStatusHistory struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	OrderID    uint      `json:"order_id" gorm:"index;not null"`
+	FromStatus string    `json:"from_status" gorm:"type:varchar(30)"`
+	ToStatus   string    `json:"to_status" gorm:"type:varchar(30)"`
+	Comment    string    `json:"comment" gorm:"type:text"`
+	CreatedAt  time.Time `json:"created_at"`
 }
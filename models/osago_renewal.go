@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// OsagoExternalPolicy - полис ОСАГО, купленный не через Kliro: пользователь вносит его сам, чтобы получить напоминание о продлении
+type OsagoExternalPolicy struct {
+	ID                 uint      `json:"id" gorm:"primaryKey"`
+	UserID             uint      `json:"user_id" gorm:"index;not null"`
+	Insurer            string    `json:"insurer" gorm:"type:varchar(100)"`
+	PolicyNumber       string    `json:"policy_number" gorm:"type:varchar(100)"`
+	LicensePlate       string    `json:"license_plate" gorm:"type:varchar(32)"`
+	TechPassportSeries string    `json:"tech_passport_series" gorm:"type:varchar(10)"`
+	TechPassportNumber string    `json:"tech_passport_number" gorm:"type:varchar(20)"`
+	PassportSeries     string    `json:"passport_series,omitempty" gorm:"type:varchar(10)"` // владелец-физлицо
+	PassportNumber     string    `json:"passport_number,omitempty" gorm:"type:varchar(20)"`
+	Birthdate          string    `json:"birthdate,omitempty" gorm:"type:varchar(20)"` // YYYY-MM-DD
+	Inn                string    `json:"inn,omitempty" gorm:"type:varchar(20)"`       // владелец-юрлицо
+	EndDate            string    `json:"end_date" gorm:"type:varchar(20);index"`      // YYYY-MM-DD
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+// OsagoRenewalReminder - отправленное напоминание о продлении (одно на полис, дату окончания и отступ)
+type OsagoRenewalReminder struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     uint       `json:"user_id" gorm:"index"`
+	Source     string     `json:"source" gorm:"type:varchar(40)"` // order:<id> | external:<id>
+	EndDate    string     `json:"end_date" gorm:"type:varchar(20)"`
+	OffsetDays int        `json:"offset_days"`
+	Token      string     `json:"-" gorm:"type:varchar(64);uniqueIndex"` // ключ ссылки для предзаполнения /osago-all/find
+	Prefill    string     `json:"-" gorm:"type:text"`                    // JSON тела /osago-all/find
+	Channel    string     `json:"channel" gorm:"type:varchar(10)"`       // email | sms
+	Error      string     `json:"error,omitempty" gorm:"type:text"`
+	SentAt     *time.Time `json:"sent_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
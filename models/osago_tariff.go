@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// OsagoTariffCoefficient - коэффициент регулируемого тарифа ОСАГО. Премия = базовая премия × коэффициенты
+// территории, типа ТС, периода и ограничения водителей; таблица редактируется в админке.
+type OsagoTariffCoefficient struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Factor    string    `json:"factor" gorm:"type:varchar(30);uniqueIndex:idx_osago_tariff_factor_key"` // base | region | vehicle_type | period | driver_restriction
+	Key       string    `json:"key" gorm:"type:varchar(30);uniqueIndex:idx_osago_tariff_factor_key"`    // external_id из Find, period_id, limited/unlimited; для base — default
+	Value     float64   `json:"value"`                                                                  // для base — премия в UZS, иначе множитель
+	Label     string    `json:"label" gorm:"type:varchar(100)"`
+	UpdatedBy *uint     `json:"updated_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// ProductImpression - сколько раз продукт попал в выдачу списков /bank/*/new за день (время Ташкента)
+type ProductImpression struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Day         time.Time `json:"day" gorm:"type:date;not null"`
+	Key         string    `json:"key" gorm:"type:varchar(255);not null"`
+	Direction   string    `json:"direction" gorm:"type:varchar(100);not null"`
+	BankSlug    string    `json:"bank_slug" gorm:"type:varchar(255)"`
+	Impressions int       `json:"impressions"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// PartnerReportDelivery - отправка ежемесячного отчета банку-партнеру (одна запись на партнера и месяц)
+type PartnerReportDelivery struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	PartnerID uint       `json:"partner_id" gorm:"not null"`
+	Period    string     `json:"period" gorm:"type:varchar(7);not null"` // YYYY-MM
+	Email     string     `json:"email" gorm:"type:varchar(255)"`
+	Status    string     `json:"status" gorm:"type:varchar(20);not null"` // sent | failed
+	Error     string     `json:"error" gorm:"type:text"`
+	SentAt    *time.Time `json:"sent_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
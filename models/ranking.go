@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// RankingConfig - настройки ранжирования списка продуктов одного направления (deposit, microcredit, ...)
+type RankingConfig struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	Direction      string    `json:"direction" gorm:"type:varchar(50);uniqueIndex;not null"`
+	Strategy       string    `json:"strategy" gorm:"type:varchar(30);not null"`    // rate_desc | rate_asc | freshness | bank_name
+	TieBreaker     string    `json:"tie_breaker" gorm:"type:varchar(30);not null"` // freshness | bank_name | seeded
+	DiversifyBanks bool      `json:"diversify_banks" gorm:"default:false"`         // не ставить один банк подряд
+	UpdatedBy      *uint     `json:"updated_by"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// SponsoredPlacement - оплаченное место продукта в списке (с пометкой "promoted") в заданном окне дат
+type SponsoredPlacement struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	Direction    string     `json:"direction" gorm:"type:varchar(50);index;not null"`
+	BankName     string     `json:"bank_name" gorm:"type:varchar(255);not null"`
+	ProductMatch string     `json:"product_match" gorm:"type:text"`     // подстрока названия продукта (пусто — первый продукт банка)
+	Position     int        `json:"position" gorm:"not null;default:1"` // 1-based позиция в списке
+	Label        string     `json:"label" gorm:"type:varchar(50);not null;default:'promoted'"`
+	StartsAt     time.Time  `json:"starts_at" gorm:"not null"`
+	EndsAt       *time.Time `json:"ends_at"` // nil — без даты окончания
+	IsActive     bool       `json:"is_active" gorm:"default:true"`
+	CreatedBy    *uint      `json:"created_by"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
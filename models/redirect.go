@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// BankRedirectConfig - партнерская ссылка банка и UTM-метки для /go/:token
+type BankRedirectConfig struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	BankName    string    `json:"bank_name" gorm:"type:varchar(255);not null"`
+	BankSlug    string    `json:"bank_slug" gorm:"type:varchar(255);uniqueIndex;not null"`
+	DefaultURL  string    `json:"default_url" gorm:"type:text"` // куда вести, если у продукта нет своей ссылки
+	UTMSource   string    `json:"utm_source" gorm:"type:varchar(100)"`
+	UTMMedium   string    `json:"utm_medium" gorm:"type:varchar(100)"`
+	UTMCampaign string    `json:"utm_campaign" gorm:"type:varchar(255)"` // {direction} заменяется на направление
+	ExtraParams string    `json:"extra_params" gorm:"type:text"`         // дополнительные параметры в виде query string
+	IsActive    bool      `json:"is_active" gorm:"default:true"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// OutboundClick - переход пользователя на сайт банка через /go/:token
+type OutboundClick struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	ProductKey string    `json:"product_key" gorm:"type:varchar(255);index"`
+	Direction  string    `json:"direction" gorm:"type:varchar(50);index"`
+	BankName   string    `json:"bank_name" gorm:"type:varchar(255)"`
+	TargetURL  string    `json:"target_url" gorm:"type:text"`
+	UserID     *uint     `json:"user_id" gorm:"index"`
+	Lang       string    `json:"lang" gorm:"type:varchar(5)"`
+	Platform   string    `json:"platform" gorm:"type:varchar(20)"`
+	Referrer   string    `json:"referrer" gorm:"type:text"`
+	CreatedAt  time.Time `json:"created_at" gorm:"index"`
+}
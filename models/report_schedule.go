@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// ReportSchedule - рассылка сводки (ежедневной или еженедельной) руководству по email
+type ReportSchedule struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	Name       string     `json:"name" gorm:"type:varchar(255);not null"`
+	Kind       string     `json:"kind" gorm:"type:varchar(10);not null"` // daily | weekly
+	Recipients string     `json:"recipients" gorm:"type:text;not null"`  // email через запятую
+	SendHour   int        `json:"send_hour" gorm:"not null;default:9"`   // час отправки по времени Ташкента
+	Weekday    int        `json:"weekday" gorm:"not null;default:1"`     // для weekly: 0 — воскресенье, 1 — понедельник ...
+	Sections   string     `json:"sections" gorm:"type:varchar(255)"`     // через запятую; пусто — все разделы
+	IsActive   bool       `json:"is_active" gorm:"default:true"`
+	LastSentAt *time.Time `json:"last_sent_at"`
+	LastError  string     `json:"last_error" gorm:"type:text"`
+	UpdatedBy  *uint      `json:"updated_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
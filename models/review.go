@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// Review - отзыв пользователя о банке или продукте банка (один отзыв на пользователя и объект)
+type Review struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	UserID         uint       `json:"user_id" gorm:"index;not null"`
+	TargetType     string     `json:"target_type" gorm:"type:varchar(20);not null"`       // bank | product
+	TargetKey      string     `json:"target_key" gorm:"type:varchar(255);index;not null"` // slug банка или utils.ProductKey
+	Direction      string     `json:"direction" gorm:"type:varchar(50)"`
+	BankName       string     `json:"bank_name" gorm:"type:varchar(255)"`
+	ProductName    string     `json:"product_name" gorm:"type:text"`
+	Rating         int        `json:"rating" gorm:"not null"` // 1..5
+	Title          string     `json:"title" gorm:"type:varchar(255)"`
+	Body           string     `json:"body" gorm:"type:text"`
+	Status         string     `json:"status" gorm:"type:varchar(20);index;not null;default:'pending'"` // pending | approved | rejected
+	ModerationNote string     `json:"moderation_note" gorm:"type:text"`
+	ModeratedBy    *uint      `json:"moderated_by"`
+	ModeratedAt    *time.Time `json:"moderated_at"`
+	HelpfulCount   int        `json:"helpful_count" gorm:"default:0"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// ReviewVote - голос "полезно" за отзыв (один на пользователя)
+type ReviewVote struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	ReviewID  uint      `json:"review_id" gorm:"index;not null"`
+	UserID    uint      `json:"user_id" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
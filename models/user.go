@@ -14,4 +14,6 @@ type User struct {
 	FirstName  *string
 	LastName   *string
 	GoogleID   *string
+
+	OsagoRemindersOptOut bool `gorm:"default:false"` // не присылать напоминания о продлении ОСАГО
 }
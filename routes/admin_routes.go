@@ -3,6 +3,7 @@ package routes
 import (
 	"fmt"
 	"kliro/controllers/admin"
+	"kliro/middleware"
 	"kliro/utils"
 
 	"github.com/gin-gonic/gin"
@@ -53,6 +54,86 @@ func SetupAdminRoutes(r *gin.Engine) {
 		// Системная информация
 		adminGroup.GET("/system-info", adminController.GetSystemInfo)
 
+		// Разделы, меняющие контент и данные: только админ по JWT, действия пишутся в аудит от его имени
+		staffGroup := adminGroup.Group("", middleware.JWTAuthMiddleware(), middleware.AdminMiddleware())
+		{
+			// Метрики сервиса переводов (какой бэкенд обслужил фразы)
+			staffGroup.GET("/translation/metrics", adminController.GetTranslationMetrics)
+
+			// Ранжирование списков /bank/*/new и спонсорские места
+			staffGroup.GET("/ranking/configs", adminController.GetRankingConfigs)
+			staffGroup.PUT("/ranking/configs/:direction", adminController.UpdateRankingConfig)
+			staffGroup.GET("/ranking/placements", adminController.GetSponsoredPlacements)
+			staffGroup.POST("/ranking/placements", adminController.CreateSponsoredPlacement)
+			staffGroup.PUT("/ranking/placements/:id", adminController.UpdateSponsoredPlacement)
+			staffGroup.DELETE("/ranking/placements/:id", adminController.DeleteSponsoredPlacement)
+
+			// Редакционные правки продуктов (скрыть, закрепить, исправить поля, заметка) и журнал изменений
+			staffGroup.GET("/editorial/overrides", adminController.GetProductOverrides)
+			staffGroup.PUT("/editorial/overrides", adminController.UpsertProductOverride)
+			staffGroup.DELETE("/editorial/overrides/:id", adminController.DeleteProductOverride)
+			staffGroup.GET("/editorial/fields", adminController.GetCorrectableFields)
+			staffGroup.GET("/editorial/audit", adminController.GetProductOverrideAudit)
+
+			// Заявки на банковские продукты (лиды) и банки-партнеры
+			staffGroup.GET("/leads", adminController.GetLeads)
+			staffGroup.GET("/leads/:id", adminController.GetLead)
+			staffGroup.PUT("/leads/:id/status", adminController.UpdateLeadStatus)
+			staffGroup.POST("/leads/:id/resend", adminController.ResendLead)
+			staffGroup.GET("/partners", adminController.GetPartners)
+			staffGroup.POST("/partners", adminController.CreatePartner)
+			staffGroup.PUT("/partners/:id", adminController.UpdatePartner)
+			staffGroup.POST("/partners/:id/rotate-token", adminController.RotatePartnerToken)
+			// Отчеты банкам-партнерам: показы, клики, заявки; ручная отправка месячного отчета
+			staffGroup.GET("/partners/:id/report", adminController.GetPartnerReport)
+			staffGroup.POST("/partners/:id/report/send", adminController.SendPartnerReport)
+			staffGroup.GET("/partners/:id/report/deliveries", adminController.GetPartnerReportDeliveries)
+
+			// Модерация отзывов
+			staffGroup.GET("/reviews", adminController.GetReviewQueue)
+			staffGroup.PUT("/reviews/:id/moderate", adminController.ModerateReview)
+
+			// Партнерские ссылки банков (UTM-метки для /go/:token) и журнал переходов
+			staffGroup.GET("/redirects", adminController.GetRedirectConfigs)
+			staffGroup.PUT("/redirects", adminController.UpsertRedirectConfig)
+			staffGroup.DELETE("/redirects/:id", adminController.DeleteRedirectConfig)
+			staffGroup.GET("/redirects/clicks", adminController.GetOutboundClicks)
+
+			// Воронки страховых сценариев (OSAGO, KASKO, travel)
+			staffGroup.GET("/funnels/:flow", adminController.GetFunnel)
+
+			// Котировки страховых: конкурентность цен OSAGO/KASKO
+			staffGroup.GET("/quotes/report", adminController.GetQuoteReport)
+
+			// Здоровье интеграций со страховыми: circuit breaker, ошибки, p95 задержки
+			staffGroup.GET("/providers/health", adminController.GetProvidersHealth)
+
+			// Регулируемый тариф ОСАГО: коэффициенты локального расчета премии
+			staffGroup.GET("/osago/tariff", adminController.GetOsagoTariff)
+			staffGroup.PUT("/osago/tariff", adminController.UpsertOsagoTariff)
+			staffGroup.DELETE("/osago/tariff/:id", adminController.DeleteOsagoTariff)
+
+			// A/B эксперименты: настройка и итоги по вариантам
+			staffGroup.GET("/experiments", adminController.GetExperiments)
+			staffGroup.POST("/experiments", adminController.CreateExperiment)
+			staffGroup.PUT("/experiments/:id", adminController.UpdateExperiment)
+			staffGroup.GET("/experiments/:key/results", adminController.GetExperimentResults)
+
+			// Email-сводки для руководства: расписания, ручная отправка, предпросмотр
+			staffGroup.GET("/reports/schedules", adminController.GetReportSchedules)
+			staffGroup.POST("/reports/schedules", adminController.CreateReportSchedule)
+			staffGroup.PUT("/reports/schedules/:id", adminController.UpdateReportSchedule)
+			staffGroup.DELETE("/reports/schedules/:id", adminController.DeleteReportSchedule)
+			staffGroup.POST("/reports/schedules/:id/send", adminController.SendReportSchedule)
+			staffGroup.GET("/reports/summary", adminController.GetReportSummary)
+
+			// Ночные выгрузки обезличенной аналитики (CSV) для аналитиков
+			staffGroup.GET("/exports", adminController.GetExports)
+			staffGroup.POST("/exports/run", adminController.RunExport)
+			staffGroup.GET("/exports/:date", adminController.GetExportManifest)
+			staffGroup.GET("/exports/:date/:dataset", adminController.DownloadExport)
+		}
+
 		// Пользователи (админка)
 		adminGroup.GET("/users", adminController.UsersList)
 		adminGroup.PUT("/users/:id", adminController.UpdateUser)
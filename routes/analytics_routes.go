@@ -2,6 +2,7 @@ package routes
 
 import (
 	"kliro/controllers"
+	"kliro/middleware"
 
 	"github.com/gin-gonic/gin"
 )
@@ -11,11 +12,12 @@ func SetupAnalyticsRoutes(r *gin.Engine) {
 
 	analyticsGroup := r.Group("/analytics/bank")
 	{
-		analyticsGroup.POST("/track-click", analyticsController.TrackClick)
+		analyticsGroup.POST("/track-click", middleware.OptionalJWTMiddleware(), analyticsController.TrackClick)
 		analyticsGroup.GET("/clicks", analyticsController.GetAllClicks)
 		analyticsGroup.GET("/clicks/:direction", analyticsController.GetClicksByDirection)
 		analyticsGroup.GET("/clicks/:direction/by-date", analyticsController.GetClicksByDirectionAndDate)
 		analyticsGroup.GET("/top-clicks", analyticsController.GetTopClicks)
 		analyticsGroup.GET("/stats-by-direction", analyticsController.GetStatsByDirection)
+		analyticsGroup.GET("/stats-by-bank", analyticsController.GetStatsByBank)
 	}
 }
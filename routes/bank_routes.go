@@ -2,6 +2,7 @@ package routes
 
 import (
 	bank "kliro/controllers/bank"
+	"kliro/middleware"
 	bankServices "kliro/services/bank"
 	"kliro/utils"
 
@@ -24,20 +25,29 @@ func SetupBankRoutes(router *gin.Engine) {
 	cardController := bank.NewCardController()
 	currencyController := bank.NewCurrencyController(currencyService)
 	bankController := bank.NewBankController(db)
+	exportController := bank.NewExportController(db)
 
 	// Bank group for all bank-related endpoints
 	bankGroup := router.Group("/bank")
 	{
-		// Data endpoints
-		bankGroup.GET("/microcredits/new", microcreditController.GetNewMicrocredits)
-		bankGroup.GET("/autocredits/new", autocreditController.GetNewAutocredits)
-		bankGroup.GET("/transfers/new", transferController.GetNewTransfers)
-		bankGroup.GET("/mortgages/new", mortgageController.GetNewMortgages)
-		bankGroup.GET("/deposits/new", depositController.GetNewDeposits)
-		bankGroup.GET("/cards/new", cardController.GetNewCards)
-		bankGroup.GET("/credit-cards/new", cardController.GetNewCreditCards)
+		// Data endpoints (OptionalJWT — ранжирование и эксперименты по user:ID, а не по устройству)
+		bankGroup.GET("/microcredits/new", middleware.OptionalJWTMiddleware(), microcreditController.GetNewMicrocredits)
+		bankGroup.GET("/autocredits/new", middleware.OptionalJWTMiddleware(), autocreditController.GetNewAutocredits)
+		bankGroup.GET("/transfers/new", middleware.OptionalJWTMiddleware(), transferController.GetNewTransfers)
+		bankGroup.GET("/mortgages/new", middleware.OptionalJWTMiddleware(), mortgageController.GetNewMortgages)
+		bankGroup.GET("/deposits/new", middleware.OptionalJWTMiddleware(), depositController.GetNewDeposits)
+		bankGroup.GET("/cards/new", middleware.OptionalJWTMiddleware(), cardController.GetNewCards)
+		bankGroup.GET("/credit-cards/new", middleware.OptionalJWTMiddleware(), cardController.GetNewCreditCards)
 		bankGroup.GET("/currencies/new", currencyController.GetLatestCurrencyRates)
 		bankGroup.GET("/currencies/by-date", currencyController.GetCurrencyRatesByDate)
 		bankGroup.GET("/search", bankController.SmartSearchAllCategories)
+
+		// Выгрузка списков в CSV/XLSX (?format=csv|xlsx&lang=uz|ru|en|oz + фильтры выдачи)
+		for _, category := range []string{"microcredits", "autocredits", "transfers", "mortgages", "deposits", "cards", "credit-cards"} {
+			bankGroup.GET("/"+category+"/export", exportController.Export(category))
+		}
+
+		// Профиль банка: все продукты, котировки, статистика и клики
+		bankGroup.GET("/banks/:slug/profile", bankController.GetBankProfile)
 	}
 }
@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"kliro/controllers"
+	"kliro/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupExperimentRoutes настраивает маршруты A/B экспериментов для приложений
+func SetupExperimentRoutes(r *gin.Engine) {
+	experimentController := controllers.NewExperimentController()
+
+	experimentsGroup := r.Group("/experiments", middleware.OptionalJWTMiddleware())
+	{
+		experimentsGroup.GET("/assignments", experimentController.Assignments)
+		experimentsGroup.POST("/events", experimentController.Event)
+	}
+}
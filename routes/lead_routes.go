@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"kliro/controllers"
+	"kliro/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupLeadRoutes настраивает маршруты заявок на банковские продукты (пользователь и банк-партнер)
+func SetupLeadRoutes(r *gin.Engine) {
+	leadController := controllers.NewLeadController()
+
+	r.POST("/leads", middleware.OptionalJWTMiddleware(), leadController.Create)
+	r.GET("/user/leads", middleware.JWTAuthMiddleware(), leadController.ListMine)
+
+	partnerGroup := r.Group("/partner", middleware.PartnerAuthMiddleware())
+	{
+		partnerGroup.GET("/leads", leadController.PartnerList)
+		partnerGroup.PUT("/leads/:id/status", leadController.PartnerUpdateStatus)
+	}
+}
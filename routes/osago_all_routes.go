@@ -4,20 +4,33 @@ import (
 	"kliro/config"
 	"kliro/controllers"
 	"kliro/controllers/osagoCreate"
+	"kliro/middleware"
+	osagoServices "kliro/services/osago"
 
 	"github.com/gin-gonic/gin"
 )
 
 func SetupOsagoAllRoutes(r *gin.Engine) {
 	cfg := config.LoadConfig()
-	osagoAllController := controllers.NewOsagoAllController(cfg)
-	osagoCreateController := osagoCreate.NewOsagoCreateController(cfg)
+	// Реестр страховых: calculate и create опрашивают только подключенных провайдеров
+	providers := osagoServices.NewRegistry(cfg)
+	osagoAllController := controllers.NewOsagoAllController(cfg, providers)
+	osagoCreateController := osagoCreate.NewOsagoCreateController(cfg, providers)
+	osagoRenewalController := controllers.NewOsagoRenewalController(cfg)
 
 	// OSAGO All API routes
 	osagoAllGroup := r.Group("/osago-all")
 	{
 		osagoAllGroup.POST("/find", osagoAllController.Find)
 		osagoAllGroup.POST("/calculate", osagoAllController.Calculate)
-		osagoAllGroup.POST("/create", osagoCreateController.Create)
+		// То же, но Server-Sent Events: предложение каждой страховой по мере ответа и итоговое событие
+		osagoAllGroup.POST("/calculate/stream", osagoAllController.CalculateStream)
+		// Ориентировочная премия по регулируемому тарифу (локально, без страховых)
+		osagoAllGroup.POST("/tariff", osagoAllController.Tariff)
+		// Токен необязателен: при наличии заказ привязывается к пользователю
+		osagoAllGroup.POST("/create", middleware.OptionalJWTMiddleware(), osagoCreateController.Create)
+		// Ссылка из напоминания о продлении: тело для /find и отказ от рассылки
+		osagoAllGroup.GET("/renewal/:token", osagoRenewalController.Renewal)
+		osagoAllGroup.POST("/renewal/:token/unsubscribe", osagoRenewalController.Unsubscribe)
 	}
 }
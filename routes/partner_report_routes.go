@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"kliro/controllers"
+	"kliro/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupPartnerReportRoutes настраивает отчеты для банков-партнеров (доступ только к данным своего банка)
+func SetupPartnerReportRoutes(r *gin.Engine) {
+	reportController := controllers.NewPartnerReportController()
+
+	partnerGroup := r.Group("/partner", middleware.PartnerAuthMiddleware())
+	{
+		partnerGroup.GET("/reports", reportController.Get)
+		partnerGroup.GET("/reports/export", reportController.Export)
+	}
+}
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"kliro/controllers"
+	"kliro/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupRedirectRoutes настраивает переход на сайт банка по подписанной ссылке
+func SetupRedirectRoutes(r *gin.Engine) {
+	redirectController := controllers.NewRedirectController()
+
+	r.GET("/go/:token", middleware.OptionalJWTMiddleware(), redirectController.Go)
+}
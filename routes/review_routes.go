@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"kliro/controllers"
+	"kliro/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupReviewRoutes настраивает маршруты отзывов о банках и продуктах
+func SetupReviewRoutes(r *gin.Engine) {
+	reviewController := controllers.NewReviewController()
+
+	r.GET("/reviews", reviewController.List)
+
+	userReviews := r.Group("/user/reviews", middleware.JWTAuthMiddleware())
+	{
+		userReviews.GET("", reviewController.ListMine)
+		userReviews.POST("", reviewController.Create)
+		userReviews.PUT("/:id", reviewController.Update)
+		userReviews.DELETE("/:id", reviewController.Delete)
+		userReviews.POST("/:id/helpful", reviewController.Vote)
+		userReviews.DELETE("/:id/helpful", reviewController.Vote)
+	}
+}
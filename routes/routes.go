@@ -34,11 +34,14 @@ func SetupRouter() *gin.Engine {
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:3000", "https://kliro.uz", "https://www.kliro.uz", "https://kliro-frontend.vercel.app"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Authorization", "Content-Type"},
+		AllowHeaders:     []string{"Origin", "Authorization", "Content-Type", "X-Partner-Token", "X-Platform", "X-Device-ID"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
 	}))
 
+	// A/B эксперименты: сессия вариантов в контексте запроса
+	r.Use(middleware.ExperimentsMiddleware())
+
 	// Здесь инициализируй зависимости (например, Redis)
 	// Для тестов можно использовать in-memory Redis или мок
 	// Пример с реальным Redis:
@@ -75,6 +78,21 @@ func SetupRouter() *gin.Engine {
 	// Analytics routes for tracking clicks
 	SetupAnalyticsRoutes(r)
 
+	// Lead routes (заявки на банковские продукты и кабинет банка-партнера)
+	SetupLeadRoutes(r)
+
+	// Review routes (отзывы и оценки банков и продуктов)
+	SetupReviewRoutes(r)
+
+	// Redirect routes (переход на сайт банка через /go/:token с UTM-метками)
+	SetupRedirectRoutes(r)
+
+	// Experiment routes (A/B эксперименты: варианты и события для приложений)
+	SetupExperimentRoutes(r)
+
+	// Partner report routes (отчеты банкам-партнерам: показы, клики, заявки)
+	SetupPartnerReportRoutes(r)
+
 	userGroup := r.Group("/user", middleware.JWTAuthMiddleware())
 	{
 		userGroup.GET("/profile", userProfileController.GetProfile)
@@ -103,6 +121,20 @@ func SetupRouter() *gin.Engine {
 		userGroup.PATCH("/favorites/:id", favoritesController.Patch)
 		userGroup.DELETE("/favorites/:id", favoritesController.Delete)
 
+		// OSAGO policies (заказы, оформленные через /osago-all/create)
+		osagoOrderController := controllers.NewOsagoOrderController()
+		userGroup.GET("/osago/policies", osagoOrderController.List)
+		userGroup.GET("/osago/policies/:id", osagoOrderController.Get)
+		userGroup.POST("/osago/policies/:id/confirm-paid", osagoOrderController.ConfirmPaid)
+
+		// Напоминания о продлении ОСАГО и полисы, купленные не через Kliro
+		osagoRenewalController := controllers.NewOsagoRenewalController(config.LoadConfig())
+		userGroup.GET("/osago/reminders", osagoRenewalController.GetSettings)
+		userGroup.PUT("/osago/reminders", osagoRenewalController.UpdateSettings)
+		userGroup.GET("/osago/external-policies", osagoRenewalController.ListExternal)
+		userGroup.POST("/osago/external-policies", osagoRenewalController.CreateExternal)
+		userGroup.DELETE("/osago/external-policies/:id", osagoRenewalController.DeleteExternal)
+
 		// Search History endpoints (Avia, Hotel, Insurance)
 		searchHistoryController := controllers.NewSearchHistoryController()
 		
@@ -164,6 +196,10 @@ func SetupRouter() *gin.Engine {
 	// Test routes for logging
 	r.GET("/test/error", controllers.TestError)
 	r.GET("/test/panic", controllers.TestPanic)
+	// Заглушка webhook банка логирует персональные данные заявок — только при LEAD_TEST_WEBHOOK=true
+	if config.LoadConfig().LeadTestWebhook {
+		r.POST("/test/lead-webhook", controllers.TestLeadWebhook)
+	}
 
 	return r
 }
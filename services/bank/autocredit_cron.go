@@ -60,6 +60,7 @@ func InitializeAutocreditData(db *gorm.DB) {
 	}
 
 	logger.Printf("Инициализация завершена - заполнены таблицы new_autocredit ")
+	utils.BumpBankDataVersion()
 }
 
 func StartAutocreditCron(db *gorm.DB) {
@@ -88,6 +89,7 @@ func StartAutocreditCron(db *gorm.DB) {
 		}
 
 		logger.Printf("Ежедневный парсинг autocredit завершен")
+		utils.BumpBankDataVersion()
 	})
 	c.Start()
 	log.Printf("[AUTOCREDIT CRON] Планировщик запущен. Парсинг автокредитов будет выполняться каждый день в 03:00 UTC")
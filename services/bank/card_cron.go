@@ -94,6 +94,7 @@ func InitializeCardData(db *gorm.DB) {
 	}
 
 	logger.Printf("Инициализация завершена - заполнена таблица new_card")
+	utils.BumpBankDataVersion()
 }
 
 // Инициализация данных кредитных карт
@@ -113,6 +114,7 @@ func InitializeCreditCardData(db *gorm.DB) {
 		}
 	}
 	logger.Printf("Инициализация завершена - заполнена таблица new_credit_card")
+	utils.BumpBankDataVersion()
 }
 
 func StartCardCron(db *gorm.DB) {
@@ -141,6 +143,7 @@ func StartCardCron(db *gorm.DB) {
 		}
 
 		logger.Printf("Ежедневный парсинг card завершен")
+		utils.BumpBankDataVersion()
 	})
 	c.Start()
 	log.Printf("[CARD CRON] Планировщик запущен. Парсинг карт будет выполняться каждый день в 03:15 UTC")
@@ -167,6 +170,7 @@ func StartCreditCardCron(db *gorm.DB) {
 			}
 		}
 		logger.Printf("Ежедневный парсинг credit_card завершен")
+		utils.BumpBankDataVersion()
 	})
 	c.Start()
 	log.Printf("[CREDIT CARD CRON] Планировщик запущен. Парсинг кредитных карт будет выполняться каждый день в 03:20 UTC")
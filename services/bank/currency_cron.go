@@ -154,6 +154,7 @@ func InitializeCurrencyData(db *gorm.DB) {
 			db.Table("new_currency").Create(currency)
 		}
 		logger.Printf("Инициализация завершена - заполнена таблица new_currency (Asia/Tashkent)")
+		utils.BumpBankDataVersion()
 	} else {
 		logger.Printf("Ошибка при парсинге валют")
 	}
@@ -181,6 +182,7 @@ func StartCurrencyCron(db *gorm.DB) {
 				db.Table("new_currency").Create(currency)
 			}
 			logger.Printf("Парсинг currency завершен - обновлено %d записей в new_currency (Asia/Tashkent)", len(currencies))
+			utils.BumpBankDataVersion()
 		} else {
 			logger.Printf("Ошибка при парсинге currency")
 		}
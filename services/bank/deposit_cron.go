@@ -65,6 +65,7 @@ func InitializeDepositData(db *gorm.DB) {
 	}
 
 	logger.Printf("Инициализация завершена - заполнена таблица new_deposit")
+	utils.BumpBankDataVersion()
 }
 
 func StartDepositCron(db *gorm.DB) {
@@ -93,6 +94,7 @@ func StartDepositCron(db *gorm.DB) {
 		}
 
 		logger.Printf("Ежедневный парсинг deposit завершен")
+		utils.BumpBankDataVersion()
 	})
 	c.Start()
 	log.Printf("[DEPOSIT CRON] Планировщик запущен. Парсинг вкладов будет выполняться каждый день в 02:00 UTC")
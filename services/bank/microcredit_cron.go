@@ -60,6 +60,7 @@ func InitializeMicrocreditData(db *gorm.DB) {
 	}
 
 	logger.Printf("Инициализация завершена - заполнена таблица new_microcredit")
+	utils.BumpBankDataVersion()
 }
 
 func StartMicrocreditCron(db *gorm.DB) {
@@ -87,6 +88,7 @@ func StartMicrocreditCron(db *gorm.DB) {
 		}
 
 		logger.Printf("Ежедневный парсинг microcredit завершен")
+		utils.BumpBankDataVersion()
 	})
 	c.Start()
 	log.Printf("[MICROCREDIT CRON] Планировщик запущен. Парсинг микрокредитов будет выполняться каждый день в 03:10 UTC")
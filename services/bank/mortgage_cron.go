@@ -59,6 +59,7 @@ func InitializeMortgageData(db *gorm.DB) {
 	}
 
 	logger.Printf("Инициализация завершена - заполнена таблица new_mortgage")
+	utils.BumpBankDataVersion()
 }
 
 func StartMortgageCron(db *gorm.DB) {
@@ -87,6 +88,7 @@ func StartMortgageCron(db *gorm.DB) {
 		}
 
 		logger.Printf("Ежедневный парсинг mortgage завершен")
+		utils.BumpBankDataVersion()
 	})
 	c.Start()
 	log.Printf("[MORTGAGE CRON] Планировщик запущен. Парсинг ипотеки будет выполняться каждый день в 03:05 UTC")
@@ -0,0 +1,218 @@
+package services
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"kliro/models"
+	"kliro/utils"
+
+	"github.com/robfig/cron/v3"
+	"gorm.io/gorm"
+)
+
+// Периоды агрегатов
+const (
+	PeriodHour = "hour"
+	PeriodDay  = "day"
+)
+
+// Источники кликов
+const (
+	SourceTrack    = "track"
+	SourceRedirect = "redirect"
+)
+
+// bucketLayout - формат границ бакетов (bucket_start хранится как время Ташкента без часового пояса)
+const bucketLayout = "2006-01-02 15:04:05"
+
+// Record добавляет событие клика в журнал (ошибка только логируется — клик не должен ломать запрос)
+func Record(db *gorm.DB, event models.ClickEvent) {
+	if event.BankSlug == "" {
+		event.BankSlug = BankSlugFromKey(event.Key)
+	}
+	if err := db.Create(&event).Error; err != nil {
+		utils.LogError(err, "clicks: record event")
+	}
+}
+
+// BankSlugFromKey достает slug банка из ключа продукта вида "deposit:kapital-bank:1a2b3c"
+func BankSlugFromKey(key string) string {
+	parts := strings.Split(key, ":")
+	if len(parts) == 3 && parts[1] != "-" {
+		return parts[1]
+	}
+	return ""
+}
+
+// Rollup пересчитывает агрегаты периода для бакетов, начинающихся в [from, to).
+// Бакеты считаются по времени Ташкента; повторный запуск безопасен (перезаписывает значения).
+func Rollup(db *gorm.DB, period string, from, to time.Time) error {
+	return db.Exec(`
+		INSERT INTO click_rollups (period, bucket_start, key, direction, bank_slug, clicks, unique_users, updated_at)
+		SELECT ?, date_trunc(?, created_at AT TIME ZONE 'Asia/Tashkent') AS bucket, key, direction,
+			COALESCE(MAX(bank_slug), ''), COUNT(*), COUNT(DISTINCT user_id), NOW()
+		FROM click_events
+		WHERE created_at >= ? AND created_at < ?
+		GROUP BY bucket, key, direction
+		ON CONFLICT (period, bucket_start, key, direction) DO UPDATE
+		SET clicks = EXCLUDED.clicks, unique_users = EXCLUDED.unique_users, bank_slug = EXCLUDED.bank_slug, updated_at = NOW()
+	`, period, period, from, to).Error
+}
+
+// RollupSince пересчитывает часовые и дневные агрегаты начиная с дня since до текущего момента
+func RollupSince(db *gorm.DB, since time.Time) {
+	loc := utils.GetUzbekLocation()
+	now := time.Now().In(loc)
+	since = since.In(loc)
+	dayStart := time.Date(since.Year(), since.Month(), since.Day(), 0, 0, 0, 0, loc)
+	end := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc).AddDate(0, 0, 1)
+
+	if err := Rollup(db, PeriodHour, dayStart, end); err != nil {
+		log.Printf("[CLICKS] hourly rollup failed: %v", err)
+	}
+	if err := Rollup(db, PeriodDay, dayStart, end); err != nil {
+		log.Printf("[CLICKS] daily rollup failed: %v", err)
+	}
+}
+
+// RollupRecent пересчитывает вчерашний и сегодняшний день (клики могли прийти на границе суток)
+func RollupRecent(db *gorm.DB) {
+	RollupSince(db, time.Now().AddDate(0, 0, -1))
+}
+
+// StartClickRollupCron догоняет пропущенные дни при старте и пересчитывает агрегаты каждые 15 минут
+func StartClickRollupCron(db *gorm.DB) {
+	go func() {
+		var last struct{ Bucket *time.Time }
+		db.Raw("SELECT MAX(bucket_start) AS bucket FROM click_rollups WHERE period = ?", PeriodDay).Scan(&last)
+		if last.Bucket != nil {
+			b := *last.Bucket
+			RollupSince(db, time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, utils.GetUzbekLocation()))
+			return
+		}
+		var first struct{ CreatedAt *time.Time }
+		db.Raw("SELECT MIN(created_at) AS created_at FROM click_events").Scan(&first)
+		if first.CreatedAt != nil {
+			RollupSince(db, *first.CreatedAt)
+		}
+	}()
+
+	c := cron.New()
+	c.AddFunc("*/15 * * * *", func() { RollupRecent(db) })
+	c.Start()
+	log.Printf("[CLICKS CRON] Планировщик запущен. Пересчет часовых и дневных агрегатов кликов каждые 15 минут")
+}
+
+// Filter - условия выборки из агрегатов; From/To - даты (включительно) по времени Ташкента
+type Filter struct {
+	From      time.Time
+	To        time.Time
+	Direction string
+	Key       string
+	BankSlug  string
+}
+
+func (f Filter) apply(db *gorm.DB, period string) *gorm.DB {
+	q := db.Model(&models.ClickRollup{}).
+		Where("period = ? AND bucket_start >= ? AND bucket_start < ?", period, f.From.Format(bucketLayout), f.To.AddDate(0, 0, 1).Format(bucketLayout))
+	if f.Direction != "" {
+		q = q.Where("direction = ?", f.Direction)
+	}
+	if f.Key != "" {
+		q = q.Where("key = ?", f.Key)
+	}
+	if f.BankSlug != "" {
+		q = q.Where("bank_slug = ?", f.BankSlug)
+	}
+	return q
+}
+
+// KeyTotal - клики по продукту за период
+type KeyTotal struct {
+	Key        string `json:"key"`
+	Direction  string `json:"direction"`
+	BankSlug   string `json:"bank_slug"`
+	ClickCount int    `json:"click_count"`
+}
+
+// Totals клики по продуктам за период (по убыванию); limit <= 0 — без ограничения
+func Totals(db *gorm.DB, f Filter, limit int) ([]KeyTotal, error) {
+	var rows []KeyTotal
+	q := f.apply(db, PeriodDay).
+		Select("key, direction, MAX(bank_slug) AS bank_slug, SUM(clicks) AS click_count").
+		Group("key, direction").
+		Order("click_count DESC, key ASC")
+	if limit > 0 {
+		q = q.Limit(limit)
+	}
+	err := q.Scan(&rows).Error
+	return rows, err
+}
+
+// GroupTotal - клики в разрезе направления или банка
+type GroupTotal struct {
+	Name        string `json:"name"`
+	TotalClicks int    `json:"total_clicks"`
+}
+
+// TotalsBy клики за период, сгруппированные по колонке direction или bank_slug
+func TotalsBy(db *gorm.DB, f Filter, column string) ([]GroupTotal, error) {
+	if column != "direction" && column != "bank_slug" {
+		column = "direction"
+	}
+	var rows []GroupTotal
+	err := f.apply(db, PeriodDay).
+		Select(column + " AS name, SUM(clicks) AS total_clicks").
+		Group(column).
+		Order("total_clicks DESC").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// Point - клики в одном бакете временного ряда
+type Point struct {
+	Bucket      string `json:"bucket"`
+	Clicks      int    `json:"clicks"`
+	UniqueUsers int    `json:"unique_users"`
+}
+
+// Series временной ряд кликов по дням или часам; пустые бакеты заполняются нулями.
+// unique_users суммируется по продуктам, поэтому это верхняя оценка.
+func Series(db *gorm.DB, f Filter, period string) ([]Point, error) {
+	var rows []struct {
+		BucketStart time.Time
+		Clicks      int
+		UniqueUsers int
+	}
+	if err := f.apply(db, period).
+		Select("bucket_start, SUM(clicks) AS clicks, SUM(unique_users) AS unique_users").
+		Group("bucket_start").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	layout, step := "2006-01-02", 24*time.Hour
+	if period == PeriodHour {
+		layout, step = "2006-01-02 15:00", time.Hour
+	}
+	byBucket := make(map[string]Point, len(rows))
+	for _, r := range rows {
+		key := r.BucketStart.Format(layout)
+		byBucket[key] = Point{Bucket: key, Clicks: r.Clicks, UniqueUsers: r.UniqueUsers}
+	}
+
+	from := time.Date(f.From.Year(), f.From.Month(), f.From.Day(), 0, 0, 0, 0, time.UTC)
+	to := time.Date(f.To.Year(), f.To.Month(), f.To.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, 1)
+	var points []Point
+	for t := from; t.Before(to); t = t.Add(step) {
+		key := t.Format(layout)
+		p, ok := byBucket[key]
+		if !ok {
+			p = Point{Bucket: key}
+		}
+		points = append(points, p)
+	}
+	return points, nil
+}
@@ -0,0 +1,228 @@
+package services
+
+import (
+	"encoding/json"
+	"log"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"kliro/models"
+	"kliro/utils"
+
+	"gorm.io/gorm"
+)
+
+// directionModels - модели направлений /bank/*/new (по ним проверяются исправляемые поля)
+var directionModels = map[string]interface{}{
+	"deposit":     models.Deposit{},
+	"microcredit": models.Microcredit{},
+	"autocredit":  models.Autocredit{},
+	"mortgage":    models.Mortgage{},
+	"card":        models.Card{},
+	"credit":      models.CreditCard{},
+	"transfer":    models.Transfer{},
+}
+
+// identityFields - поля, из которых строится ключ продукта: их исправление "оторвало" бы правку от продукта
+var identityFields = map[string]bool{"id": true, "created_at": true, "bank_name": true, "title": true, "description": true, "app_name": true}
+
+// ValidDirection - поддерживается ли направление
+func ValidDirection(direction string) bool {
+	_, ok := directionModels[direction]
+	return ok
+}
+
+// CorrectableFields возвращает json-поля модели направления, которые можно исправлять
+func CorrectableFields(direction string) []string {
+	sample, ok := directionModels[direction]
+	if !ok {
+		return nil
+	}
+	var fields []string
+	t := reflect.TypeOf(sample)
+	for i := 0; i < t.NumField(); i++ {
+		name := jsonName(t.Field(i))
+		if name == "" || identityFields[name] || !isStringField(t.Field(i).Type) {
+			continue
+		}
+		fields = append(fields, name)
+	}
+	return fields
+}
+
+// Set - правки одного направления по ключу продукта
+type Set map[string]models.ProductOverride
+
+// Note возвращает заметку редакции для продукта
+func (s Set) Note(key string) string {
+	return s[key].EditorialNote
+}
+
+// Pinned - закреплен ли продукт
+func (s Set) Pinned(key string) bool {
+	return s[key].Pinned
+}
+
+// Corrections разбирает JSON исправлений правки
+func Corrections(o models.ProductOverride) map[string]string {
+	if strings.TrimSpace(o.Corrections) == "" {
+		return nil
+	}
+	var fields map[string]string
+	if err := json.Unmarshal([]byte(o.Corrections), &fields); err != nil {
+		log.Printf("[EDITORIAL] bad corrections for %s: %v", o.ProductKey, err)
+		return nil
+	}
+	return fields
+}
+
+// cacheTTL - как долго держим правки в памяти (изменения в админке сбрасывают кэш сразу)
+const cacheTTL = time.Minute
+
+// Store - хранилище правок с кэшем в памяти
+type Store struct {
+	db       *gorm.DB
+	mu       sync.RWMutex
+	byDir    map[string]Set
+	loadedAt time.Time
+}
+
+var (
+	defaultStore     *Store
+	defaultStoreOnce sync.Once
+)
+
+// DefaultStore возвращает глобальное хранилище (БД берется из utils.GetDB)
+func DefaultStore() *Store {
+	defaultStoreOnce.Do(func() {
+		defaultStore = NewStore(utils.GetDB())
+	})
+	return defaultStore
+}
+
+// NewStore создает хранилище правок
+func NewStore(db *gorm.DB) *Store {
+	return &Store{db: db}
+}
+
+// Invalidate сбрасывает кэш правок
+func (s *Store) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loadedAt = time.Time{}
+}
+
+// ForDirection возвращает правки направления
+func (s *Store) ForDirection(direction string) Set {
+	s.mu.RLock()
+	fresh := !s.loadedAt.IsZero() && time.Since(s.loadedAt) < cacheTTL
+	s.mu.RUnlock()
+
+	if !fresh && s.db != nil {
+		var overrides []models.ProductOverride
+		if err := s.db.Find(&overrides).Error; err != nil {
+			log.Printf("[EDITORIAL] failed to load product overrides: %v", err)
+		}
+		byDir := make(map[string]Set)
+		for _, o := range overrides {
+			if byDir[o.Direction] == nil {
+				byDir[o.Direction] = make(Set)
+			}
+			byDir[o.Direction][o.ProductKey] = o
+		}
+		s.mu.Lock()
+		s.byDir = byDir
+		s.loadedAt = time.Now()
+		s.mu.Unlock()
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.byDir[direction]
+}
+
+// Apply исправляет поля и убирает скрытые продукты. Вызывается до фильтров, чтобы они работали по исправленным данным.
+func Apply[T any](set Set, items []T, keyOf func(T) string) []T {
+	if len(set) == 0 {
+		return items
+	}
+	result := make([]T, 0, len(items))
+	for _, it := range items {
+		o, ok := set[keyOf(it)]
+		if !ok {
+			result = append(result, it)
+			continue
+		}
+		if o.Hidden {
+			continue
+		}
+		if fields := Corrections(o); len(fields) > 0 {
+			CorrectFields(&it, fields)
+		}
+		result = append(result, it)
+	}
+	return result
+}
+
+// Pin поднимает закрепленные продукты в начало списка (по pin_position), остальной порядок не меняется.
+// Вызывается после сортировки/ранжирования.
+func Pin[T any](set Set, items []T, keyOf func(T) string) []T {
+	if len(set) == 0 {
+		return items
+	}
+	var pinned, rest []T
+	for _, it := range items {
+		if set.Pinned(keyOf(it)) {
+			pinned = append(pinned, it)
+		} else {
+			rest = append(rest, it)
+		}
+	}
+	if len(pinned) == 0 {
+		return items
+	}
+	sort.SliceStable(pinned, func(i, j int) bool {
+		return set[keyOf(pinned[i])].PinPosition < set[keyOf(pinned[j])].PinPosition
+	})
+	return append(pinned, rest...)
+}
+
+// CorrectFields записывает значения в строковые поля структуры по их json-именам (идентифицирующие поля не трогаются)
+func CorrectFields(ptr interface{}, fields map[string]string) {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return
+	}
+	v = v.Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := jsonName(t.Field(i))
+		value, ok := fields[name]
+		if !ok || identityFields[name] {
+			continue
+		}
+		f := v.Field(i)
+		switch {
+		case f.Kind() == reflect.String:
+			f.SetString(value)
+		case f.Kind() == reflect.Ptr && f.Type().Elem().Kind() == reflect.String:
+			val := value
+			f.Set(reflect.ValueOf(&val))
+		}
+	}
+}
+
+func jsonName(f reflect.StructField) string {
+	tag := f.Tag.Get("json")
+	if tag == "" || tag == "-" {
+		return ""
+	}
+	return strings.Split(tag, ",")[0]
+}
+
+func isStringField(t reflect.Type) bool {
+	return t.Kind() == reflect.String || (t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.String)
+}
@@ -0,0 +1,365 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"kliro/models"
+	"kliro/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Статусы эксперимента
+const (
+	StatusDraft     = "draft"
+	StatusRunning   = "running"
+	StatusPaused    = "paused"
+	StatusCompleted = "completed"
+)
+
+// ValidStatuses допустимые статусы
+var ValidStatuses = map[string]bool{StatusDraft: true, StatusRunning: true, StatusPaused: true, StatusCompleted: true}
+
+// События
+const (
+	EventExposure   = "exposure"
+	EventConversion = "conversion"
+)
+
+// contextKey - ключ сессии экспериментов в gin.Context
+const contextKey = "experiments"
+
+// buckets - точность распределения (0.01%)
+const buckets = 10000
+
+// Variant - вариант эксперимента; Config отдается приложениям и контроллерам как есть
+type Variant struct {
+	Key    string                 `json:"key"`
+	Weight int                    `json:"weight"`
+	Config map[string]interface{} `json:"config,omitempty"`
+}
+
+// Assignment - вариант, назначенный пользователю
+type Assignment struct {
+	Experiment string                 `json:"experiment"`
+	Variant    string                 `json:"variant"`
+	Config     map[string]interface{} `json:"config,omitempty"`
+}
+
+// ParseVariants разбирает и проверяет JSON вариантов (минимум два, уникальные ключи, положительные веса)
+func ParseVariants(raw string) ([]Variant, error) {
+	var variants []Variant
+	if err := json.Unmarshal([]byte(raw), &variants); err != nil {
+		return nil, errors.New("variants должен быть JSON-массивом")
+	}
+	if len(variants) < 2 {
+		return nil, errors.New("нужно минимум два варианта")
+	}
+	seen := map[string]bool{}
+	for _, v := range variants {
+		if v.Key == "" || seen[v.Key] {
+			return nil, errors.New("ключи вариантов должны быть непустыми и уникальными")
+		}
+		if v.Weight <= 0 {
+			return nil, errors.New("вес варианта должен быть больше 0")
+		}
+		seen[v.Key] = true
+	}
+	return variants, nil
+}
+
+// bucket детерминированно переводит (соль, эксперимент, субъект) в число 0..buckets-1
+func bucket(salt, experiment, subject string) int {
+	sum := sha256.Sum256([]byte(salt + ":" + experiment + ":" + subject))
+	return int(binary.BigEndian.Uint32(sum[:4]) % buckets)
+}
+
+type compiled struct {
+	exp      models.Experiment
+	variants []Variant
+	total    int
+}
+
+// assign - вариант для субъекта или false, если субъект вне доли трафика
+func (ce compiled) assign(subject string) (Assignment, bool) {
+	if subject == "" || ce.total == 0 {
+		return Assignment{}, false
+	}
+	// Попадание в трафик и выбор варианта считаются независимыми хешами,
+	// чтобы изменение traffic_percent не перетасовывало уже назначенные варианты
+	if bucket("traffic", ce.exp.Key, subject) >= ce.exp.TrafficPercent*buckets/100 {
+		return Assignment{}, false
+	}
+	point := bucket("variant", ce.exp.Key, subject) * ce.total / buckets
+	for _, v := range ce.variants {
+		if point < v.Weight {
+			return Assignment{Experiment: ce.exp.Key, Variant: v.Key, Config: v.Config}, true
+		}
+		point -= v.Weight
+	}
+	last := ce.variants[len(ce.variants)-1]
+	return Assignment{Experiment: ce.exp.Key, Variant: last.Key, Config: last.Config}, true
+}
+
+// cacheTTL - как долго держим эксперименты в памяти
+const cacheTTL = time.Minute
+
+// Store - запущенные эксперименты с кэшем
+type Store struct {
+	db       *gorm.DB
+	mu       sync.RWMutex
+	running  map[string]compiled
+	loadedAt time.Time
+}
+
+var (
+	defaultStore     *Store
+	defaultStoreOnce sync.Once
+)
+
+// DefaultStore возвращает глобальное хранилище (БД берется из utils.GetDB)
+func DefaultStore() *Store {
+	defaultStoreOnce.Do(func() {
+		defaultStore = NewStore(utils.GetDB())
+	})
+	return defaultStore
+}
+
+// NewStore создает хранилище экспериментов
+func NewStore(db *gorm.DB) *Store {
+	return &Store{db: db}
+}
+
+// Invalidate сбрасывает кэш (после изменений в админке)
+func (s *Store) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loadedAt = time.Time{}
+}
+
+func (s *Store) load() map[string]compiled {
+	s.mu.RLock()
+	fresh := !s.loadedAt.IsZero() && time.Since(s.loadedAt) < cacheTTL
+	running := s.running
+	s.mu.RUnlock()
+	if fresh || s.db == nil {
+		return running
+	}
+
+	var experiments []models.Experiment
+	if err := s.db.Where("status = ?", StatusRunning).Find(&experiments).Error; err != nil {
+		log.Printf("[EXPERIMENTS] failed to load experiments: %v", err)
+	}
+	running = make(map[string]compiled, len(experiments))
+	for _, e := range experiments {
+		variants, err := ParseVariants(e.Variants)
+		if err != nil {
+			log.Printf("[EXPERIMENTS] experiment %s skipped: %v", e.Key, err)
+			continue
+		}
+		ce := compiled{exp: e, variants: variants}
+		for _, v := range variants {
+			ce.total += v.Weight
+		}
+		running[e.Key] = ce
+	}
+
+	s.mu.Lock()
+	s.running = running
+	s.loadedAt = time.Now()
+	s.mu.Unlock()
+	return running
+}
+
+// active - запущенные эксперименты, у которых сейчас окно проведения
+func (s *Store) active() []compiled {
+	now := time.Now()
+	var out []compiled
+	for _, ce := range s.load() {
+		if (ce.exp.StartsAt != nil && now.Before(*ce.exp.StartsAt)) || (ce.exp.EndsAt != nil && !now.Before(*ce.exp.EndsAt)) {
+			continue
+		}
+		out = append(out, ce)
+	}
+	return out
+}
+
+// Assign вариант субъекта в эксперименте key
+func (s *Store) Assign(key, subject string) (Assignment, bool) {
+	for _, ce := range s.active() {
+		if ce.exp.Key == key {
+			return ce.assign(subject)
+		}
+	}
+	return Assignment{}, false
+}
+
+// AssignAll варианты субъекта во всех активных экспериментах
+func (s *Store) AssignAll(subject string) []Assignment {
+	out := []Assignment{}
+	for _, ce := range s.active() {
+		if a, ok := ce.assign(subject); ok {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// Session - эксперименты текущего запроса (кладется в gin.Context middleware)
+type Session struct {
+	c     *gin.Context
+	store *Store
+}
+
+// NewSession создает сессию экспериментов для запроса
+func NewSession(c *gin.Context, store *Store) *Session {
+	return &Session{c: c, store: store}
+}
+
+// Subject - идентификатор для распределения: user:<id> для авторизованных, иначе device:<X-Device-ID>.
+// Определяется в момент вызова, т.к. user_id выставляют JWT middleware групп уже после глобальных.
+func (s *Session) Subject() string {
+	if userID := s.c.GetInt("user_id"); userID > 0 {
+		return "user:" + strconv.Itoa(userID)
+	}
+	device := strings.TrimSpace(s.c.GetHeader("X-Device-ID"))
+	if device == "" {
+		device = strings.TrimSpace(s.c.Query("device_id"))
+	}
+	if device == "" || len(device) > 100 {
+		return ""
+	}
+	return "device:" + device
+}
+
+// Variant назначает вариант и записывает показ
+func (s *Session) Variant(key string) (Assignment, bool) {
+	subject := s.Subject()
+	a, ok := s.store.Assign(key, subject)
+	if ok {
+		LogExposure(a, subject)
+	}
+	return a, ok
+}
+
+// Convert записывает целевое действие во всех экспериментах, где участвует субъект
+// (в отчете учитываются только субъекты, которым вариант был показан)
+func (s *Session) Convert(goal string, value float64) {
+	subject := s.Subject()
+	if subject == "" {
+		return
+	}
+	for _, a := range s.store.AssignAll(subject) {
+		logEvent(models.ExperimentEvent{ExperimentKey: a.Experiment, Variant: a.Variant, SubjectID: subject, Event: EventConversion, Goal: goal, Value: value})
+	}
+}
+
+// FromContext сессия экспериментов запроса (если middleware не подключен — создается на лету)
+func FromContext(c *gin.Context) *Session {
+	if v, ok := c.Get(contextKey); ok {
+		if s, ok := v.(*Session); ok {
+			return s
+		}
+	}
+	s := NewSession(c, DefaultStore())
+	c.Set(contextKey, s)
+	return s
+}
+
+// LogExposure записывает показ варианта; повторные показы одному субъекту за сутки не пишутся (дедупликация в Redis)
+func LogExposure(a Assignment, subject string) {
+	if subject == "" {
+		return
+	}
+	if rdb := utils.GetRedis(); rdb != nil {
+		ok, err := rdb.SetNX(utils.RedisCtx(), "exp:exposed:"+a.Experiment+":"+subject, a.Variant, 24*time.Hour).Result()
+		if err == nil && !ok {
+			return
+		}
+	}
+	logEvent(models.ExperimentEvent{ExperimentKey: a.Experiment, Variant: a.Variant, SubjectID: subject, Event: EventExposure})
+}
+
+func logEvent(event models.ExperimentEvent) {
+	go func() {
+		db := utils.GetDB()
+		if db == nil {
+			return
+		}
+		if err := db.Create(&event).Error; err != nil {
+			utils.LogError(err, "experiments: save event")
+		}
+	}()
+}
+
+// VariantResult - итоги варианта
+type VariantResult struct {
+	Variant        string  `json:"variant"`
+	Exposed        int     `json:"exposed"`         // субъекты, которым показан вариант
+	Converted      int     `json:"converted"`       // из них совершили целевое действие
+	ConversionRate float64 `json:"conversion_rate"` // converted / exposed
+	Conversions    int     `json:"conversions"`     // всего целевых действий
+	Value          float64 `json:"value"`           // сумма value целевых действий
+}
+
+// Results считает итоги эксперимента по вариантам (goal — фильтр по цели, пусто — все цели)
+func Results(db *gorm.DB, key, goal string) ([]VariantResult, error) {
+	var exposures []struct {
+		Variant string
+		Exposed int
+	}
+	if err := db.Model(&models.ExperimentEvent{}).
+		Select("variant, COUNT(DISTINCT subject_id) AS exposed").
+		Where("experiment_key = ? AND event = ?", key, EventExposure).
+		Group("variant").Scan(&exposures).Error; err != nil {
+		return nil, err
+	}
+
+	conv := db.Table("experiment_events AS cv").
+		Select("cv.variant, COUNT(DISTINCT cv.subject_id) AS converted, COUNT(*) AS conversions, COALESCE(SUM(cv.value), 0) AS value").
+		Where("cv.experiment_key = ? AND cv.event = ?", key, EventConversion).
+		Where("EXISTS (SELECT 1 FROM experiment_events ex WHERE ex.experiment_key = cv.experiment_key AND ex.subject_id = cv.subject_id AND ex.variant = cv.variant AND ex.event = ? AND ex.created_at <= cv.created_at)", EventExposure)
+	if goal != "" {
+		conv = conv.Where("cv.goal = ?", goal)
+	}
+	var conversions []struct {
+		Variant     string
+		Converted   int
+		Conversions int
+		Value       float64
+	}
+	if err := conv.Group("cv.variant").Scan(&conversions).Error; err != nil {
+		return nil, err
+	}
+
+	byVariant := map[string]*VariantResult{}
+	results := []VariantResult{}
+	for _, e := range exposures {
+		results = append(results, VariantResult{Variant: e.Variant, Exposed: e.Exposed})
+	}
+	for i := range results {
+		byVariant[results[i].Variant] = &results[i]
+	}
+	for _, cv := range conversions {
+		r, ok := byVariant[cv.Variant]
+		if !ok {
+			continue
+		}
+		r.Converted = cv.Converted
+		r.Conversions = cv.Conversions
+		r.Value = cv.Value
+		if r.Exposed > 0 {
+			r.ConversionRate = float64(int(float64(r.Converted)/float64(r.Exposed)*10000+0.5)) / 10000
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+
+	"kliro/models"
+)
+
+func TestBucketStable(t *testing.T) {
+	tests := []struct {
+		salt, experiment, subject string
+	}{
+		{"variant", "ranking_deposit", "user:42"},
+		{"traffic", "ranking_deposit", "user:42"},
+		{"variant", "ranking_deposit", "device:abc"},
+		{"variant", "osago_layout", "user:42"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.salt+"/"+tt.experiment+"/"+tt.subject, func(t *testing.T) {
+			b := bucket(tt.salt, tt.experiment, tt.subject)
+			if b < 0 || b >= buckets {
+				t.Fatalf("bucket = %d, want 0..%d", b, buckets-1)
+			}
+			for i := 0; i < 5; i++ {
+				if again := bucket(tt.salt, tt.experiment, tt.subject); again != b {
+					t.Fatalf("bucket changed: %d then %d", b, again)
+				}
+			}
+		})
+	}
+}
+
+func TestAssign(t *testing.T) {
+	variants := []Variant{{Key: "control", Weight: 50}, {Key: "rate_first", Weight: 50}}
+	experiment := func(traffic int) compiled {
+		return compiled{exp: models.Experiment{Key: "ranking_deposit", TrafficPercent: traffic}, variants: variants, total: 100}
+	}
+
+	tests := []struct {
+		name     string
+		traffic  int
+		wantIn   bool
+		minShare float64 // минимальная доля каждого варианта среди 2000 субъектов
+	}{
+		{"full traffic", 100, true, 0.45},
+		{"no traffic", 0, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ce := experiment(tt.traffic)
+			if _, ok := ce.assign(""); ok {
+				t.Fatal("empty subject must not be assigned")
+			}
+			counts := map[string]int{}
+			for i := 0; i < 2000; i++ {
+				subject := fmt.Sprintf("user:%d", i)
+				a, ok := ce.assign(subject)
+				if ok != tt.wantIn {
+					t.Fatalf("assign(%s) ok = %v, want %v", subject, ok, tt.wantIn)
+				}
+				if !ok {
+					continue
+				}
+				if again, _ := ce.assign(subject); again.Variant != a.Variant {
+					t.Fatalf("assign(%s) not stable: %s then %s", subject, a.Variant, again.Variant)
+				}
+				counts[a.Variant]++
+			}
+			for _, v := range variants {
+				if tt.wantIn && float64(counts[v.Key])/2000 < tt.minShare {
+					t.Errorf("variant %s share = %d/2000, want >= %.2f", v.Key, counts[v.Key], tt.minShare)
+				}
+			}
+		})
+	}
+}
+
+func TestAssignTrafficKeepsVariant(t *testing.T) {
+	// Увеличение traffic_percent не меняет вариант тех, кто уже был в эксперименте
+	variants := []Variant{{Key: "control", Weight: 1}, {Key: "b", Weight: 1}}
+	small := compiled{exp: models.Experiment{Key: "exp", TrafficPercent: 20}, variants: variants, total: 2}
+	full := compiled{exp: models.Experiment{Key: "exp", TrafficPercent: 100}, variants: variants, total: 2}
+	for i := 0; i < 1000; i++ {
+		subject := fmt.Sprintf("device:%d", i)
+		a, ok := small.assign(subject)
+		if !ok {
+			continue
+		}
+		if b, _ := full.assign(subject); b.Variant != a.Variant {
+			t.Fatalf("%s: variant %s at 20%% traffic, %s at 100%%", subject, a.Variant, b.Variant)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Dataset - набор данных выгрузки: колонки CSV и функция, пишущая строки за день
+type Dataset struct {
+	Name    string
+	Columns []string
+	write   func(db *gorm.DB, start, end time.Time, anon *Anonymizer, emit func([]string) error) error
+}
+
+// Datasets - все наборы ночной выгрузки. Персональные данные (телефоны, email, паспорта, ПИНФЛ, номера машин)
+// не выгружаются, user_id и session_id заменяются псевдонимами.
+var Datasets = []Dataset{
+	{
+		Name:    "clicks",
+		Columns: []string{"created_at", "key", "direction", "bank_slug", "user", "platform", "lang", "source"},
+		write: func(db *gorm.DB, start, end time.Time, anon *Anonymizer, emit func([]string) error) error {
+			return eachRow(db.Raw(`SELECT created_at, key, direction, COALESCE(bank_slug, ''), user_id, COALESCE(platform, ''), COALESCE(lang, ''), COALESCE(source, '')
+				FROM click_events WHERE created_at >= ? AND created_at < ? ORDER BY id`, start, end),
+				func(rows *sql.Rows) error {
+					var createdAt time.Time
+					var key, direction, bank, platform, lang, source string
+					var userID sql.NullInt64
+					if err := rows.Scan(&createdAt, &key, &direction, &bank, &userID, &platform, &lang, &source); err != nil {
+						return err
+					}
+					return emit([]string{ts(createdAt), key, direction, bank, anon.User(userID), platform, lang, source})
+				})
+		},
+	},
+	{
+		Name:    "search_histories",
+		Columns: []string{"created_at", "kind", "user", "details"},
+		write:   writeSearchHistories,
+	},
+	{
+		Name:    "orders",
+		Columns: []string{"created_at", "updated_at", "id", "user", "category", "company_name", "status"},
+		write: func(db *gorm.DB, start, end time.Time, anon *Anonymizer, emit func([]string) error) error {
+			return eachRow(db.Raw(`SELECT created_at, updated_at, id, user_id, category, company_name, COALESCE(status, '')
+				FROM orders WHERE deleted_at IS NULL AND created_at >= ? AND created_at < ? ORDER BY id`, start, end),
+				func(rows *sql.Rows) error {
+					var createdAt, updatedAt time.Time
+					var id int64
+					var userID sql.NullInt64
+					var category, company, status string
+					if err := rows.Scan(&createdAt, &updatedAt, &id, &userID, &category, &company, &status); err != nil {
+						return err
+					}
+					return emit([]string{ts(createdAt), ts(updatedAt), strconv.FormatInt(id, 10), anon.User(userID), category, company, status})
+				})
+		},
+	},
+	{
+		Name: "quotes",
+		Columns: []string{"created_at", "product", "product_type", "batch_id", "session", "provider", "success", "premium", "rank",
+			"gap_to_cheapest", "gap_percent", "vehicle_type", "region", "owner_type", "period_id", "driver_restriction", "vehicle_year", "picked"},
+		write: func(db *gorm.DB, start, end time.Time, anon *Anonymizer, emit func([]string) error) error {
+			return eachRow(db.Raw(`SELECT created_at, product, COALESCE(product_type, ''), batch_id, COALESCE(session_id, ''), provider, success,
+					premium, rank, gap_to_cheapest, gap_percent, COALESCE(vehicle_type, ''), COALESCE(region, ''), COALESCE(owner_type, ''),
+					COALESCE(period_id, 0), COALESCE(driver_restriction, FALSE), vehicle_year, picked
+				FROM insurance_quotes WHERE created_at >= ? AND created_at < ? ORDER BY id`, start, end),
+				func(rows *sql.Rows) error {
+					var createdAt time.Time
+					var product, productType, batchID, sessionID, provider, vehicleType, region, ownerType string
+					var success, driverRestriction, picked bool
+					var premium, gap int64
+					var rank, periodID int
+					var gapPercent float64
+					var vehicleYear sql.NullInt64
+					if err := rows.Scan(&createdAt, &product, &productType, &batchID, &sessionID, &provider, &success, &premium, &rank,
+						&gap, &gapPercent, &vehicleType, &region, &ownerType, &periodID, &driverRestriction, &vehicleYear, &picked); err != nil {
+						return err
+					}
+					year := ""
+					if vehicleYear.Valid {
+						year = strconv.FormatInt(vehicleYear.Int64, 10)
+					}
+					return emit([]string{ts(createdAt), product, productType, batchID, anon.Value("session", sessionID), provider, strconv.FormatBool(success),
+						strconv.FormatInt(premium, 10), strconv.Itoa(rank), strconv.FormatInt(gap, 10), strconv.FormatFloat(gapPercent, 'f', 2, 64),
+						vehicleType, region, ownerType, strconv.Itoa(periodID), strconv.FormatBool(driverRestriction), year, strconv.FormatBool(picked)})
+				})
+		},
+	},
+	{
+		Name:    "signups",
+		Columns: []string{"date", "signups", "confirmed", "with_email", "with_phone", "with_google"},
+		write: func(db *gorm.DB, start, end time.Time, anon *Anonymizer, emit func([]string) error) error {
+			var row struct {
+				Signups, Confirmed, WithEmail, WithPhone, WithGoogle int
+			}
+			if err := db.Raw(`SELECT COUNT(*) AS signups,
+					COUNT(*) FILTER (WHERE confirmed) AS confirmed,
+					COUNT(email) AS with_email,
+					COUNT(phone) AS with_phone,
+					COUNT(google_id) AS with_google
+				FROM users WHERE created_at >= ? AND created_at < ?`, start, end).Scan(&row).Error; err != nil {
+				return err
+			}
+			return emit([]string{start.Format("2006-01-02"), strconv.Itoa(row.Signups), strconv.Itoa(row.Confirmed),
+				strconv.Itoa(row.WithEmail), strconv.Itoa(row.WithPhone), strconv.Itoa(row.WithGoogle)})
+		},
+	},
+}
+
+// writeSearchHistories - поиски авиа, отелей и страховок; из страховых поисков выгружается только то, какие поля были заполнены
+func writeSearchHistories(db *gorm.DB, start, end time.Time, anon *Anonymizer, emit func([]string) error) error {
+	details := func(v map[string]interface{}) string {
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+
+	err := eachRow(db.Raw(`SELECT created_at, user_id, adults, children, infants, infants_with_seat, service_class, directions
+		FROM avia_search_histories WHERE deleted_at IS NULL AND created_at >= ? AND created_at < ? ORDER BY id`, start, end),
+		func(rows *sql.Rows) error {
+			var createdAt time.Time
+			var userID sql.NullInt64
+			var adults, children, infants, infantsWithSeat int
+			var serviceClass string
+			var directions []byte
+			if err := rows.Scan(&createdAt, &userID, &adults, &children, &infants, &infantsWithSeat, &serviceClass, &directions); err != nil {
+				return err
+			}
+			return emit([]string{ts(createdAt), "avia", anon.User(userID), details(map[string]interface{}{
+				"adults": adults, "children": children, "infants": infants, "infants_with_seat": infantsWithSeat,
+				"service_class": serviceClass, "directions": json.RawMessage(jsonOrNull(directions)),
+			})})
+		})
+	if err != nil {
+		return err
+	}
+
+	err = eachRow(db.Raw(`SELECT created_at, user_id, city_id, check_in, check_out, is_resident, currency, occupancies, meal_plans
+		FROM hotel_search_histories WHERE deleted_at IS NULL AND created_at >= ? AND created_at < ? ORDER BY id`, start, end),
+		func(rows *sql.Rows) error {
+			var createdAt time.Time
+			var userID sql.NullInt64
+			var cityID int
+			var checkIn, checkOut, currency string
+			var isResident bool
+			var occupancies, mealPlans []byte
+			if err := rows.Scan(&createdAt, &userID, &cityID, &checkIn, &checkOut, &isResident, &currency, &occupancies, &mealPlans); err != nil {
+				return err
+			}
+			return emit([]string{ts(createdAt), "hotel", anon.User(userID), details(map[string]interface{}{
+				"city_id": cityID, "check_in": checkIn, "check_out": checkOut, "is_resident": isResident, "currency": currency,
+				"occupancies": json.RawMessage(jsonOrNull(occupancies)), "meal_plans": json.RawMessage(jsonOrNull(mealPlans)),
+			})})
+		})
+	if err != nil {
+		return err
+	}
+
+	return eachRow(db.Raw(`SELECT created_at, user_id,
+			COALESCE(pinfl, '') <> '', COALESCE(car_number, '') <> '', COALESCE(tech_passport_number, '') <> '', COALESCE(passport_number, '') <> ''
+		FROM insurance_search_histories WHERE deleted_at IS NULL AND created_at >= ? AND created_at < ? ORDER BY id`, start, end),
+		func(rows *sql.Rows) error {
+			var createdAt time.Time
+			var userID sql.NullInt64
+			var hasPinfl, hasCar, hasTechPassport, hasPassport bool
+			if err := rows.Scan(&createdAt, &userID, &hasPinfl, &hasCar, &hasTechPassport, &hasPassport); err != nil {
+				return err
+			}
+			return emit([]string{ts(createdAt), "insurance", anon.User(userID), details(map[string]interface{}{
+				"has_pinfl": hasPinfl, "has_car_number": hasCar, "has_tech_passport": hasTechPassport, "has_passport": hasPassport,
+			})})
+		})
+}
+
+// eachRow выполняет запрос и вызывает fn для каждой строки
+func eachRow(q *gorm.DB, fn func(*sql.Rows) error) error {
+	rows, err := q.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := fn(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func ts(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func jsonOrNull(data []byte) []byte {
+	if len(data) == 0 || !json.Valid(data) {
+		return []byte("null")
+	}
+	return data
+}
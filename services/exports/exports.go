@@ -0,0 +1,362 @@
+package services
+
+import (
+	"compress/gzip"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"kliro/config"
+	"kliro/utils"
+
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
+	"gorm.io/gorm"
+)
+
+// Format - формат файлов выгрузки
+const Format = "csv.gz"
+
+// manifestsDir - подкаталог с манифестами запусков (по одному на день)
+const manifestsDir = "manifests"
+
+// catchUpDays - сколько прошедших дней без манифеста догружается ночным запуском
+const catchUpDays = 7
+
+// ErrNoSalt - не задана EXPORT_SALT: без отдельной соли псевдонимы можно подобрать, выгрузка не выполняется
+var ErrNoSalt = errors.New("exports: EXPORT_SALT не задана")
+
+// Anonymizer заменяет идентификаторы стабильными псевдонимами (HMAC-SHA256 с солью), чтобы можно было
+// считать уникальных пользователей и связывать наборы данных, не раскрывая исходные id
+type Anonymizer struct {
+	salt []byte
+}
+
+// NewAnonymizer создает псевдонимизатор с солью
+func NewAnonymizer(salt string) *Anonymizer {
+	return &Anonymizer{salt: []byte(salt)}
+}
+
+// Value псевдоним значения в пространстве kind ("user", "session" ...); пустое значение остается пустым
+func (a *Anonymizer) Value(kind, value string) string {
+	if value == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, a.salt)
+	mac.Write([]byte(kind + ":" + value))
+	return hex.EncodeToString(mac.Sum(nil)[:8])
+}
+
+// User псевдоним пользователя (NULL — анонимный, пустая строка)
+func (a *Anonymizer) User(id sql.NullInt64) string {
+	if !id.Valid || id.Int64 == 0 {
+		return ""
+	}
+	return a.Value("user", strconv.FormatInt(id.Int64, 10))
+}
+
+// FileInfo - файл набора данных в манифесте
+type FileInfo struct {
+	Dataset string   `json:"dataset"`
+	Path    string   `json:"path"` // относительно каталога выгрузок
+	Rows    int      `json:"rows"`
+	Bytes   int64    `json:"bytes"`
+	SHA256  string   `json:"sha256"`
+	Columns []string `json:"columns"`
+}
+
+// DatasetError - набор, который не удалось выгрузить
+type DatasetError struct {
+	Dataset string `json:"dataset"`
+	Error   string `json:"error"`
+}
+
+// Manifest - описание одного запуска выгрузки за день
+type Manifest struct {
+	RunID       string         `json:"run_id"`
+	Date        string         `json:"date"` // день данных (время Ташкента)
+	Format      string         `json:"format"`
+	GeneratedAt time.Time      `json:"generated_at"`
+	DurationMs  int64          `json:"duration_ms"`
+	Files       []FileInfo     `json:"files"`
+	Errors      []DatasetError `json:"errors,omitempty"`
+}
+
+// Exporter выгружает наборы данных в каталог: <dir>/<dataset>/dt=YYYY-MM-DD/<dataset>.csv.gz и <dir>/manifests/YYYY-MM-DD.json
+type Exporter struct {
+	db            *gorm.DB
+	dir           string
+	retentionDays int
+	anon          *Anonymizer
+	mu            sync.Mutex // один запуск за раз
+}
+
+var (
+	defaultExporter     *Exporter
+	defaultExporterOnce sync.Once
+)
+
+// DefaultExporter возвращает глобальный экспортер (настройки из config, БД из utils.GetDB)
+func DefaultExporter() *Exporter {
+	defaultExporterOnce.Do(func() {
+		cfg := config.LoadConfig()
+		defaultExporter = NewExporter(utils.GetDB(), cfg.AnalyticsExportDir, cfg.AnalyticsExportRetentionDays, cfg.AnalyticsExportSalt)
+	})
+	return defaultExporter
+}
+
+// NewExporter создает экспортер
+func NewExporter(db *gorm.DB, dir string, retentionDays int, salt string) *Exporter {
+	return &Exporter{db: db, dir: dir, retentionDays: retentionDays, anon: NewAnonymizer(salt)}
+}
+
+// Dir - каталог выгрузок
+func (e *Exporter) Dir() string { return e.dir }
+
+// Configured - задана ли соль для псевдонимов
+func (e *Exporter) Configured() bool { return len(e.anon.salt) > 0 }
+
+// Run выгружает все наборы за день day (дата по времени Ташкента) и пишет манифест.
+// Ошибка одного набора не останавливает остальные — она попадает в манифест.
+func (e *Exporter) Run(day time.Time) (*Manifest, error) {
+	if !e.Configured() {
+		return nil, ErrNoSalt
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	loc := utils.GetUzbekLocation()
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc)
+	end := start.AddDate(0, 0, 1)
+	date := start.Format("2006-01-02")
+	began := time.Now()
+
+	m := &Manifest{RunID: uuid.New().String(), Date: date, Format: Format, Files: []FileInfo{}}
+	for _, ds := range Datasets {
+		info, err := e.writeDataset(ds, date, start, end)
+		if err != nil {
+			utils.LogError(err, "exports: dataset "+ds.Name)
+			m.Errors = append(m.Errors, DatasetError{Dataset: ds.Name, Error: err.Error()})
+			continue
+		}
+		m.Files = append(m.Files, info)
+	}
+	m.GeneratedAt = time.Now()
+	m.DurationMs = time.Since(began).Milliseconds()
+
+	if err := e.writeManifest(m); err != nil {
+		return m, err
+	}
+	return m, nil
+}
+
+func (e *Exporter) writeDataset(ds Dataset, date string, start, end time.Time) (FileInfo, error) {
+	rel := filepath.Join(ds.Name, "dt="+date, ds.Name+"."+Format)
+	path := filepath.Join(e.dir, rel)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return FileInfo{}, err
+	}
+
+	// Пишем во временный файл и переименовываем — читатель не увидит недописанный файл
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+ds.Name+"-*.tmp")
+	if err != nil {
+		return FileInfo{}, err
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	gz := gzip.NewWriter(io.MultiWriter(tmp, hash))
+	w := csv.NewWriter(gz)
+	rows := 0
+	err = w.Write(ds.Columns)
+	if err == nil {
+		err = ds.write(e.db, start, end, e.anon, func(record []string) error {
+			rows++
+			return w.Write(record)
+		})
+	}
+	if err == nil {
+		w.Flush()
+		err = w.Error()
+	}
+	if err == nil {
+		err = gz.Close()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return FileInfo{}, err
+	}
+
+	stat, err := os.Stat(tmp.Name())
+	if err != nil {
+		return FileInfo{}, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return FileInfo{}, err
+	}
+	return FileInfo{Dataset: ds.Name, Path: filepath.ToSlash(rel), Rows: rows, Bytes: stat.Size(), SHA256: hex.EncodeToString(hash.Sum(nil)), Columns: ds.Columns}, nil
+}
+
+func (e *Exporter) writeManifest(m *Manifest) error {
+	dir := filepath.Join(e.dir, manifestsDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, "."+m.Date+".json.tmp")
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, m.Date+".json"))
+}
+
+// Manifests - манифесты всех доступных выгрузок, новые первыми
+func (e *Exporter) Manifests() ([]Manifest, error) {
+	entries, err := os.ReadDir(filepath.Join(e.dir, manifestsDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return []Manifest{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	out := []Manifest{}
+	for _, entry := range entries {
+		date, ok := manifestDate(entry.Name())
+		if !ok {
+			continue
+		}
+		m, err := e.Manifest(date)
+		if err != nil {
+			log.Printf("[EXPORTS] skip manifest %s: %v", entry.Name(), err)
+			continue
+		}
+		out = append(out, *m)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Date > out[j].Date })
+	return out, nil
+}
+
+// Manifest манифест выгрузки за день (YYYY-MM-DD)
+func (e *Exporter) Manifest(date string) (*Manifest, error) {
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		return nil, os.ErrNotExist
+	}
+	data, err := os.ReadFile(filepath.Join(e.dir, manifestsDir, date+".json"))
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// FilePath абсолютный путь файла набора dataset за день date — только для файлов из манифеста
+func (e *Exporter) FilePath(date, dataset string) (string, error) {
+	m, err := e.Manifest(date)
+	if err != nil {
+		return "", err
+	}
+	for _, f := range m.Files {
+		if f.Dataset == dataset {
+			return filepath.Join(e.dir, filepath.FromSlash(f.Path)), nil
+		}
+	}
+	return "", os.ErrNotExist
+}
+
+// Cleanup удаляет выгрузки старше срока хранения
+func (e *Exporter) Cleanup() {
+	if e.retentionDays <= 0 {
+		return
+	}
+	now := utils.UzbekTime()
+	cutoff := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, -e.retentionDays).Format("2006-01-02")
+
+	for _, ds := range Datasets {
+		entries, _ := os.ReadDir(filepath.Join(e.dir, ds.Name))
+		for _, entry := range entries {
+			if len(entry.Name()) == len("dt=2006-01-02") && entry.Name()[3:] < cutoff {
+				os.RemoveAll(filepath.Join(e.dir, ds.Name, entry.Name()))
+			}
+		}
+	}
+	entries, _ := os.ReadDir(filepath.Join(e.dir, manifestsDir))
+	for _, entry := range entries {
+		if date, ok := manifestDate(entry.Name()); ok && date < cutoff {
+			os.Remove(filepath.Join(e.dir, manifestsDir, entry.Name()))
+		}
+	}
+}
+
+// RunNightly выгружает вчерашний день и догружает пропущенные дни за последнюю неделю, затем чистит старые выгрузки
+func (e *Exporter) RunNightly() {
+	now := utils.UzbekTime()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	for i := catchUpDays; i >= 1; i-- {
+		day := today.AddDate(0, 0, -i)
+		if i > 1 {
+			if _, err := e.Manifest(day.Format("2006-01-02")); err == nil {
+				continue
+			}
+		}
+		m, err := e.Run(day)
+		if err != nil {
+			log.Printf("[EXPORTS] export %s failed: %v", day.Format("2006-01-02"), err)
+			continue
+		}
+		log.Printf("[EXPORTS] export %s: %d files, %d errors, %d ms", m.Date, len(m.Files), len(m.Errors), m.DurationMs)
+	}
+	e.Cleanup()
+}
+
+// StartExportCron запускает ночную выгрузку в 03:30 по времени Ташкента; без EXPORT_SALT планировщик не запускается
+func StartExportCron(e *Exporter) {
+	if !e.Configured() {
+		log.Printf("[EXPORTS CRON] EXPORT_SALT не задана — ночная выгрузка аналитики отключена")
+		return
+	}
+	c := cron.New()
+	c.AddFunc("30 3 * * *", e.RunNightly)
+	c.Start()
+	log.Printf("[EXPORTS CRON] Планировщик запущен. Выгрузка аналитики в %s каждую ночь в 03:30", e.dir)
+}
+
+func manifestDate(name string) (string, bool) {
+	if filepath.Ext(name) != ".json" || len(name) != len("2006-01-02.json") {
+		return "", false
+	}
+	date := name[:len("2006-01-02")]
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		return "", false
+	}
+	return date, true
+}
+
+// ParseDate разбирает дату выгрузки YYYY-MM-DD
+func ParseDate(v string) (time.Time, error) {
+	t, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		return t, fmt.Errorf("date должен быть в формате YYYY-MM-DD")
+	}
+	return t, nil
+}
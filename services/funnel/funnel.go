@@ -0,0 +1,221 @@
+package services
+
+import (
+	"strconv"
+	"time"
+
+	"kliro/models"
+	"kliro/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Сценарии
+const (
+	FlowOsago  = "osago"
+	FlowKasko  = "kasko"
+	FlowTravel = "travel"
+)
+
+// Steps - шаги сценариев по порядку
+var Steps = map[string][]string{
+	FlowOsago:  {"find", "calculate", "create"},
+	FlowKasko:  {"start", "calculate"},
+	FlowTravel: {"purpose", "details", "calculate", "save", "check"},
+}
+
+// Track записывает событие шага асинхронно (запись в БД не должна задерживать ответ)
+func Track(c *gin.Context, flow, step, sessionID, provider string, success bool, errMsg string) {
+	if sessionID == "" {
+		return
+	}
+	event := models.FunnelEvent{
+		Flow:      flow,
+		Step:      step,
+		SessionID: sessionID,
+		Provider:  provider,
+		Success:   success,
+		Error:     errMsg,
+	}
+	if c != nil {
+		if userID := uint(c.GetInt("user_id")); userID > 0 {
+			event.UserID = &userID
+		}
+	}
+	go func() {
+		db := utils.GetDB()
+		if db == nil {
+			return
+		}
+		if err := db.Create(&event).Error; err != nil {
+			utils.LogError(err, "funnel: save event")
+		}
+	}()
+}
+
+// TrackResponse записывает шаг по итоговому HTTP-статусу ответа; вызывать через defer после разбора запроса.
+// С provider пишется и событие шага в целом (без провайдера), и событие по провайдеру.
+func TrackResponse(c *gin.Context, flow, step, sessionID, provider string) {
+	status := c.Writer.Status()
+	errMsg := ""
+	if status >= 400 {
+		errMsg = "http " + strconv.Itoa(status)
+	}
+	Track(c, flow, step, sessionID, "", status < 400, errMsg)
+	if provider != "" {
+		Track(c, flow, step, sessionID, provider, status < 400, errMsg)
+	}
+}
+
+// StepStat - прохождение шага: сколько сессий дошли и с какой долей от первого и предыдущего шага
+type StepStat struct {
+	Step             string   `json:"step"`
+	Sessions         int      `json:"sessions"`          // сессии с успешным шагом
+	Attempts         int      `json:"attempts"`          // сессии, пытавшиеся пройти шаг
+	StepSuccessRate  float64  `json:"step_success_rate"` // sessions / attempts
+	FromStartRate    float64  `json:"from_start_rate"`   // sessions / sessions первого шага
+	FromPreviousRate float64  `json:"from_previous_rate"`
+	MedianSecToNext  *float64 `json:"median_seconds_to_next,omitempty"` // медиана времени до следующего шага
+}
+
+// ProviderStat - шаг в разрезе страховой компании
+type ProviderStat struct {
+	Provider    string  `json:"provider"`
+	Step        string  `json:"step"`
+	Attempts    int     `json:"attempts"`
+	Sessions    int     `json:"sessions"`
+	SuccessRate float64 `json:"success_rate"`
+}
+
+// DayStat - сессии, прошедшие шаги, по дням (время Ташкента)
+type DayStat struct {
+	Date  string         `json:"date"`
+	Steps map[string]int `json:"steps"`
+}
+
+// Report - воронка сценария за период
+type Report struct {
+	Flow      string         `json:"flow"`
+	DateFrom  string         `json:"date_from"`
+	DateTo    string         `json:"date_to"`
+	Steps     []StepStat     `json:"steps"`
+	Providers []ProviderStat `json:"providers"`
+	Days      []DayStat      `json:"days"`
+}
+
+// BuildReport считает воронку сценария flow по событиям за [from, to] (даты по времени Ташкента, включительно)
+func BuildReport(db *gorm.DB, flow string, from, to time.Time) (*Report, error) {
+	steps := Steps[flow]
+	loc := utils.GetUzbekLocation()
+	start := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc)
+	end := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, loc).AddDate(0, 0, 1)
+
+	report := &Report{Flow: flow, DateFrom: from.Format("2006-01-02"), DateTo: to.Format("2006-01-02"), Providers: []ProviderStat{}, Days: []DayStat{}}
+	base := func() *gorm.DB {
+		return db.Model(&models.FunnelEvent{}).Where("flow = ? AND created_at >= ? AND created_at < ?", flow, start, end)
+	}
+
+	// По шагам: сессии с попыткой и с успехом — только события шага в целом; события по провайдерам
+	// (provider <> '') считаются ниже, иначе отказ одной страховой засчитывался бы как попытка шага
+	var stepRows []struct {
+		Step     string
+		Attempts int
+		Sessions int
+	}
+	if err := base().Where("provider = ''").
+		Select("step, COUNT(DISTINCT session_id) AS attempts, COUNT(DISTINCT CASE WHEN success THEN session_id END) AS sessions").
+		Group("step").Scan(&stepRows).Error; err != nil {
+		return nil, err
+	}
+	byStep := make(map[string]int, len(stepRows))
+	attempts := make(map[string]int, len(stepRows))
+	for _, r := range stepRows {
+		byStep[r.Step] = r.Sessions
+		attempts[r.Step] = r.Attempts
+	}
+
+	for i, step := range steps {
+		stat := StepStat{Step: step, Sessions: byStep[step], Attempts: attempts[step]}
+		stat.StepSuccessRate = rate(stat.Sessions, stat.Attempts)
+		stat.FromStartRate = rate(stat.Sessions, byStep[steps[0]])
+		if i > 0 {
+			stat.FromPreviousRate = rate(stat.Sessions, byStep[steps[i-1]])
+		} else if stat.Sessions > 0 {
+			stat.FromPreviousRate = 1
+		}
+		if i+1 < len(steps) {
+			stat.MedianSecToNext = medianBetween(db, flow, step, steps[i+1], start, end)
+		}
+		report.Steps = append(report.Steps, stat)
+	}
+
+	// По провайдерам
+	var providerRows []struct {
+		Provider string
+		Step     string
+		Attempts int
+		Sessions int
+	}
+	if err := base().Where("provider <> ''").
+		Select("provider, step, COUNT(DISTINCT session_id) AS attempts, COUNT(DISTINCT CASE WHEN success THEN session_id END) AS sessions").
+		Group("provider, step").Order("provider, step").Scan(&providerRows).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range providerRows {
+		report.Providers = append(report.Providers, ProviderStat{Provider: r.Provider, Step: r.Step, Attempts: r.Attempts, Sessions: r.Sessions, SuccessRate: rate(r.Sessions, r.Attempts)})
+	}
+
+	// По дням
+	var dayRows []struct {
+		Day      string
+		Step     string
+		Sessions int
+	}
+	if err := base().Where("success").
+		Select("to_char(created_at AT TIME ZONE 'Asia/Tashkent', 'YYYY-MM-DD') AS day, step, COUNT(DISTINCT session_id) AS sessions").
+		Group("day, step").Order("day").Scan(&dayRows).Error; err != nil {
+		return nil, err
+	}
+	dayIndex := map[string]int{}
+	for _, r := range dayRows {
+		i, ok := dayIndex[r.Day]
+		if !ok {
+			i = len(report.Days)
+			dayIndex[r.Day] = i
+			report.Days = append(report.Days, DayStat{Date: r.Day, Steps: map[string]int{}})
+		}
+		report.Days[i].Steps[r.Step] = r.Sessions
+	}
+
+	return report, nil
+}
+
+// medianBetween медиана секунд между первым успешным шагом from и первым успешным шагом to в одной сессии
+func medianBetween(db *gorm.DB, flow, fromStep, toStep string, start, end time.Time) *float64 {
+	var row struct{ Median *float64 }
+	err := db.Raw(`
+		WITH firsts AS (
+			SELECT session_id, step, MIN(created_at) AS t
+			FROM funnel_events
+			WHERE flow = ? AND success AND created_at >= ? AND created_at < ? AND step IN (?, ?)
+			GROUP BY session_id, step
+		)
+		SELECT percentile_cont(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM b.t - a.t)) AS median
+		FROM firsts a
+		JOIN firsts b ON a.session_id = b.session_id AND b.t >= a.t
+		WHERE a.step = ? AND b.step = ?
+	`, flow, start, end, fromStep, toStep, fromStep, toStep).Scan(&row).Error
+	if err != nil {
+		utils.LogError(err, "funnel: median between steps")
+		return nil
+	}
+	return row.Median
+}
+
+func rate(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(int(float64(part)/float64(total)*10000+0.5)) / 10000
+}
@@ -0,0 +1,397 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"kliro/config"
+	"kliro/models"
+	"kliro/utils"
+
+	"github.com/robfig/cron/v3"
+	"gopkg.in/gomail.v2"
+	"gorm.io/gorm"
+)
+
+// Статусы заявки
+const (
+	StatusNew            = "new"             // создана, еще не доставлена
+	StatusForwarded      = "forwarded"       // доставлена банку
+	StatusDeliveryFailed = "delivery_failed" // доставка не удалась (будет повтор)
+	StatusContacted      = "contacted"       // банк связался с клиентом
+	StatusApproved       = "approved"
+	StatusRejected       = "rejected"
+	StatusClosed         = "closed"
+)
+
+// ValidStatuses - статусы, которые может выставить админ
+var ValidStatuses = map[string]bool{
+	StatusNew: true, StatusForwarded: true, StatusDeliveryFailed: true,
+	StatusContacted: true, StatusApproved: true, StatusRejected: true, StatusClosed: true,
+}
+
+// PartnerStatuses - статусы, которые может выставить банк-партнер
+var PartnerStatuses = map[string]bool{StatusContacted: true, StatusApproved: true, StatusRejected: true, StatusClosed: true}
+
+// Directions - направления, по которым принимаются заявки
+var Directions = map[string]bool{"microcredit": true, "mortgage": true, "autocredit": true, "deposit": true}
+
+// Deliverer - канал доставки заявки банку
+type Deliverer interface {
+	Channel() string
+	Deliver(ctx context.Context, lead models.Lead, partner *models.BankPartner) error
+}
+
+// EmailDeliverer отправляет заявку письмом через SMTP
+type EmailDeliverer struct {
+	host, user, pass string
+	port             int
+	fallbackTo       string
+}
+
+// NewEmailDeliverer создает email-доставку (порт по умолчанию 587)
+func NewEmailDeliverer(host, port, user, pass, fallbackTo string) *EmailDeliverer {
+	p, err := strconv.Atoi(port)
+	if err != nil || p == 0 {
+		p = 587
+	}
+	return &EmailDeliverer{host: host, port: p, user: user, pass: pass, fallbackTo: fallbackTo}
+}
+
+func (d *EmailDeliverer) Channel() string { return "email" }
+
+func (d *EmailDeliverer) Deliver(ctx context.Context, lead models.Lead, partner *models.BankPartner) error {
+	to := d.fallbackTo
+	if partner != nil && partner.LeadEmail != "" {
+		to = partner.LeadEmail
+	}
+	if to == "" {
+		return errors.New("no lead email configured")
+	}
+	if d.host == "" {
+		return errors.New("smtp is not configured")
+	}
+
+	m := gomail.NewMessage()
+	m.SetHeader("From", d.user)
+	m.SetHeader("To", to)
+	m.SetHeader("Subject", fmt.Sprintf("Kliro: новая заявка #%d (%s, %s)", lead.ID, lead.Direction, lead.BankName))
+	m.SetBody("text/plain", leadEmailBody(lead))
+
+	// gomail не принимает context — ограничиваем ожидание вручную
+	done := make(chan error, 1)
+	go func() {
+		done <- gomail.NewDialer(d.host, d.port, d.user, d.pass).DialAndSend(m)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func leadEmailBody(lead models.Lead) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Заявка #%d от %s\n\n", lead.ID, lead.CreatedAt.Format("2006-01-02 15:04"))
+	fmt.Fprintf(&b, "Продукт: %s — %s (%s)\n", lead.BankName, lead.ProductName, lead.Direction)
+	fmt.Fprintf(&b, "Клиент: %s\nТелефон: %s\n", lead.FullName, lead.Phone)
+	if lead.Email != "" {
+		fmt.Fprintf(&b, "Email: %s\n", lead.Email)
+	}
+	if lead.Amount > 0 {
+		fmt.Fprintf(&b, "Сумма: %d so'm\n", lead.Amount)
+	}
+	if lead.TermMonths > 0 {
+		fmt.Fprintf(&b, "Срок: %d мес.\n", lead.TermMonths)
+	}
+	if lead.Params != "" {
+		fmt.Fprintf(&b, "Параметры: %s\n", lead.Params)
+	}
+	fmt.Fprintf(&b, "\nСогласие на обработку данных получено %s\n", lead.ConsentAt.Format("2006-01-02 15:04"))
+	return b.String()
+}
+
+// WebhookDeliverer отправляет заявку POST-запросом с JSON и HMAC-подписью тела
+type WebhookDeliverer struct {
+	client *http.Client
+}
+
+// NewWebhookDeliverer создает webhook-доставку
+func NewWebhookDeliverer() *WebhookDeliverer {
+	return &WebhookDeliverer{client: &http.Client{}}
+}
+
+func (d *WebhookDeliverer) Channel() string { return "webhook" }
+
+// WebhookPayload - тело webhook-запроса банку
+type WebhookPayload struct {
+	LeadID      uint            `json:"lead_id"`
+	Direction   string          `json:"direction"`
+	ProductKey  string          `json:"product_key"`
+	BankName    string          `json:"bank_name"`
+	ProductName string          `json:"product_name"`
+	FullName    string          `json:"full_name"`
+	Phone       string          `json:"phone"`
+	Email       string          `json:"email,omitempty"`
+	Amount      int64           `json:"amount,omitempty"`
+	TermMonths  int             `json:"term_months,omitempty"`
+	Params      json.RawMessage `json:"params,omitempty"`
+	Lang        string          `json:"lang,omitempty"`
+	ConsentAt   time.Time       `json:"consent_at"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+func (d *WebhookDeliverer) Deliver(ctx context.Context, lead models.Lead, partner *models.BankPartner) error {
+	if partner == nil || partner.LeadWebhookURL == "" {
+		return errors.New("no webhook url configured")
+	}
+	payload := WebhookPayload{
+		LeadID: lead.ID, Direction: lead.Direction, ProductKey: lead.ProductKey, BankName: lead.BankName,
+		ProductName: lead.ProductName, FullName: lead.FullName, Phone: lead.Phone, Email: lead.Email,
+		Amount: lead.Amount, TermMonths: lead.TermMonths, Lang: lead.Lang, ConsentAt: lead.ConsentAt, CreatedAt: lead.CreatedAt,
+	}
+	if lead.Params != "" && json.Valid([]byte(lead.Params)) {
+		payload.Params = json.RawMessage(lead.Params)
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", partner.LeadWebhookURL, bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if partner.LeadWebhookKey != "" {
+		req.Header.Set("X-Kliro-Signature", Sign(partner.LeadWebhookKey, body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("webhook returned %d: %s", resp.StatusCode, string(respBody))
+	}
+	return nil
+}
+
+// Sign - HMAC-SHA256 подпись тела webhook (hex)
+func Sign(key string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Service - создание, доставка и смена статусов заявок
+type Service struct {
+	db          *gorm.DB
+	deliverers  map[string]Deliverer
+	timeout     time.Duration
+	maxAttempts int
+}
+
+var (
+	defaultService     *Service
+	defaultServiceOnce sync.Once
+)
+
+// DefaultService возвращает глобальный сервис заявок (настройки из config, БД из utils.GetDB)
+func DefaultService() *Service {
+	defaultServiceOnce.Do(func() {
+		defaultService = NewService(utils.GetDB(), config.LoadConfig())
+	})
+	return defaultService
+}
+
+// NewService создает сервис заявок с email- и webhook-доставкой
+func NewService(db *gorm.DB, cfg *config.Config) *Service {
+	return NewServiceWithDeliverers(db, cfg.LeadDeliveryTimeout, cfg.LeadMaxAttempts,
+		NewEmailDeliverer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUser, cfg.SMTPPass, cfg.LeadFallbackEmail),
+		NewWebhookDeliverer(),
+	)
+}
+
+// NewServiceWithDeliverers создает сервис с заданными каналами доставки (например, webhook на локальную заглушку)
+func NewServiceWithDeliverers(db *gorm.DB, timeout time.Duration, maxAttempts int, deliverers ...Deliverer) *Service {
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	if maxAttempts <= 0 {
+		maxAttempts = 5
+	}
+	s := &Service{db: db, deliverers: make(map[string]Deliverer), timeout: timeout, maxAttempts: maxAttempts}
+	for _, d := range deliverers {
+		s.deliverers[d.Channel()] = d
+	}
+	return s
+}
+
+// FindPartner ищет активного партнера банка, принимающего заявки по направлению
+func (s *Service) FindPartner(bankName, direction string) *models.BankPartner {
+	var partners []models.BankPartner
+	if err := s.db.Where("is_active = ?", true).Find(&partners).Error; err != nil {
+		return nil
+	}
+	for i := range partners {
+		p := &partners[i]
+		if !utils.BankSlugMatches(bankName, p.BankSlug) {
+			continue
+		}
+		if p.LeadDirections != "" && !containsFold(strings.Split(p.LeadDirections, ","), direction) {
+			continue
+		}
+		return p
+	}
+	return nil
+}
+
+// Create сохраняет заявку и запускает доставку в фоне
+func (s *Service) Create(lead *models.Lead) error {
+	lead.Status = StatusNew
+	if partner := s.FindPartner(lead.BankName, lead.Direction); partner != nil {
+		lead.PartnerID = &partner.ID
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(lead).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.LeadStatusHistory{LeadID: lead.ID, ToStatus: StatusNew, Actor: "system"}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	created := *lead
+	go func() {
+		if err := s.Forward(&created); err != nil {
+			log.Printf("[LEADS] lead %d delivery failed: %v", created.ID, err)
+		}
+	}()
+	return nil
+}
+
+// Forward доставляет заявку банку выбранным каналом и обновляет статус
+func (s *Service) Forward(lead *models.Lead) error {
+	var partner *models.BankPartner
+	if lead.PartnerID != nil {
+		var p models.BankPartner
+		if err := s.db.First(&p, *lead.PartnerID).Error; err == nil && p.IsActive {
+			partner = &p
+		}
+	}
+
+	channel := "email"
+	if partner != nil && partner.LeadChannel != "" {
+		channel = partner.LeadChannel
+	}
+	deliverer, ok := s.deliverers[channel]
+	if !ok {
+		return s.markDelivery(lead, channel, fmt.Errorf("unknown delivery channel: %s", channel))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	return s.markDelivery(lead, channel, deliverer.Deliver(ctx, *lead, partner))
+}
+
+func (s *Service) markDelivery(lead *models.Lead, channel string, deliveryErr error) error {
+	from := lead.Status
+	lead.DeliveryChannel = channel
+	lead.DeliveryAttempts++
+	if deliveryErr != nil {
+		lead.Status = StatusDeliveryFailed
+		lead.DeliveryError = deliveryErr.Error()
+	} else {
+		now := time.Now()
+		lead.Status = StatusForwarded
+		lead.DeliveryError = ""
+		lead.DeliveredAt = &now
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Lead{}).Where("id = ?", lead.ID).Updates(map[string]interface{}{
+			"status":            lead.Status,
+			"delivery_channel":  lead.DeliveryChannel,
+			"delivery_attempts": lead.DeliveryAttempts,
+			"delivery_error":    lead.DeliveryError,
+			"delivered_at":      lead.DeliveredAt,
+		}).Error; err != nil {
+			return err
+		}
+		if from == lead.Status {
+			return nil
+		}
+		return tx.Create(&models.LeadStatusHistory{LeadID: lead.ID, FromStatus: from, ToStatus: lead.Status, Actor: "system", Comment: lead.DeliveryError}).Error
+	})
+	if err != nil {
+		utils.LogError(err, "leads: save delivery result")
+	}
+	return deliveryErr
+}
+
+// ChangeStatus меняет статус заявки и пишет историю
+func (s *Service) ChangeStatus(lead *models.Lead, status, actor string, actorID *uint, comment string) error {
+	from := lead.Status
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(lead).Update("status", status).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.LeadStatusHistory{LeadID: lead.ID, FromStatus: from, ToStatus: status, Actor: actor, ActorID: actorID, Comment: comment}).Error
+	})
+}
+
+// stuckLeadAge - через сколько заявка в статусе new считается недоставленной: первая доставка идет в горутине
+// из Create и теряется при перезапуске сервера
+const stuckLeadAge = 5 * time.Minute
+
+// RetryFailed повторяет доставку заявок со статусом delivery_failed (до maxAttempts попыток)
+// и заявок, оставшихся в new дольше stuckLeadAge
+func (s *Service) RetryFailed() {
+	var leads []models.Lead
+	if err := s.db.Where("((status = ? AND delivery_attempts < ?) OR (status = ? AND created_at < ?))",
+		StatusDeliveryFailed, s.maxAttempts, StatusNew, time.Now().Add(-stuckLeadAge)).
+		Order("id ASC").Limit(100).Find(&leads).Error; err != nil {
+		utils.LogError(err, "leads: load failed leads")
+		return
+	}
+	for i := range leads {
+		if err := s.Forward(&leads[i]); err != nil {
+			log.Printf("[LEADS] retry lead %d (attempt %d) failed: %v", leads[i].ID, leads[i].DeliveryAttempts, err)
+		}
+	}
+}
+
+// StartLeadRetryCron запускает повтор доставки неотправленных заявок каждые 10 минут
+func StartLeadRetryCron(s *Service) {
+	c := cron.New()
+	c.AddFunc("*/10 * * * *", s.RetryFailed)
+	c.Start()
+	log.Printf("[LEADS CRON] Планировщик запущен. Повтор доставки заявок каждые 10 минут")
+}
+
+func containsFold(list []string, value string) bool {
+	for _, v := range list {
+		if strings.EqualFold(strings.TrimSpace(v), value) {
+			return true
+		}
+	}
+	return false
+}
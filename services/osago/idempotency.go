@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"kliro/models"
+	"kliro/utils"
+
+	"gorm.io/gorm"
+)
+
+// createLockTTL - сколько держится блокировка create по сессии (с запасом на таймауты провайдера);
+// createReplayTTL - сколько ответ create повторяется из Redis (дальше — из osago_orders)
+const (
+	createLockTTL   = 2 * time.Minute
+	createReplayTTL = 24 * time.Hour
+)
+
+var (
+	ErrCreateInFlight       = errors.New("оформление по этой сессии уже выполняется, дождитесь ответа")
+	ErrIdempotencyKeyReused = errors.New("Idempotency-Key уже использован с другим телом запроса")
+)
+
+// StoredCreate - сохраненный ответ create, который отдается повторно на тот же ключ
+type StoredCreate struct {
+	RequestHash string          `json:"request_hash,omitempty"`
+	Status      int             `json:"status"`
+	Body        json.RawMessage `json:"body"`
+}
+
+// CreateKey - ключ идемпотентности create: заголовок Idempotency-Key в рамках сессии (одинаковые ключи
+// разных клиентов не пересекаются), а без него — сессия и провайдер (повторное нажатие «Купить» по той же
+// сессии не создает вторую заявку)
+func CreateKey(header, sessionID, provider string) string {
+	if header = strings.TrimSpace(header); header != "" {
+		return "key:" + sessionID + ":" + header
+	}
+	return "session:" + sessionID + ":" + provider
+}
+
+// RequestHash - хэш тела create: тот же Idempotency-Key с другим телом — ошибка клиента
+func RequestHash(req interface{}) string {
+	b, _ := json.Marshal(req)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// replayStatuses - статусы заказа, ответ create по которому еще можно повторять: после failed/expired
+// ссылка оплаты недействительна и create должен выполниться заново
+var replayStatuses = []string{StatusCreated, StatusPaid, StatusIssued}
+
+// LookupCreate ищет ответ на ранее выполненный create: сначала в Redis, затем среди действующих заказов в osago_orders.
+// hash проверяется, только если он передан (явный Idempotency-Key).
+func LookupCreate(db *gorm.DB, key, hash string) (*StoredCreate, error) {
+	var stored *StoredCreate
+	if rdb := utils.GetRedis(); rdb != nil {
+		if val, err := rdb.Get(context.Background(), createReplayKey(key)).Bytes(); err == nil {
+			var s StoredCreate
+			if json.Unmarshal(val, &s) == nil && replayable(db, s.Body) {
+				stored = &s
+			}
+		}
+	}
+	if stored == nil {
+		var order models.OsagoOrder
+		err := db.Where("idempotency_key = ? AND status IN ?", key, replayStatuses).Order("id DESC").First(&order).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		stored = &StoredCreate{RequestHash: order.RequestHash, Status: http.StatusOK, Body: orderCreateBody(&order)}
+	}
+	if hash != "" && stored.RequestHash != "" && stored.RequestHash != hash {
+		return nil, ErrIdempotencyKeyReused
+	}
+	return stored, nil
+}
+
+// StoreCreate сохраняет ответ create в Redis для повтора
+func StoreCreate(key string, s StoredCreate) {
+	rdb := utils.GetRedis()
+	if rdb == nil {
+		return
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		return
+	}
+	if err := rdb.Set(context.Background(), createReplayKey(key), b, createReplayTTL).Err(); err != nil {
+		utils.LogError(err, "osago: store create response")
+	}
+}
+
+// DropCreate удаляет сохраненный ответ create (заказ истек или отклонен — повторять его нельзя)
+func DropCreate(key string) {
+	rdb := utils.GetRedis()
+	if rdb == nil || key == "" {
+		return
+	}
+	if err := rdb.Del(context.Background(), createReplayKey(key)).Err(); err != nil {
+		utils.LogError(err, "osago: drop create response")
+	}
+}
+
+// replayable - заказ из сохраненного ответа еще действует (ответ без order_id повторяется как есть)
+func replayable(db *gorm.DB, body json.RawMessage) bool {
+	var ref struct {
+		OrderID uint `json:"order_id"`
+	}
+	if json.Unmarshal(body, &ref) != nil || ref.OrderID == 0 {
+		return true
+	}
+	var n int64
+	if err := db.Model(&models.OsagoOrder{}).Where("id = ? AND status IN ?", ref.OrderID, replayStatuses).Count(&n).Error; err != nil {
+		return true
+	}
+	return n > 0
+}
+
+// LockCreate занимает create по сессии: параллельный запрос получает ErrCreateInFlight.
+// Возвращает функцию освобождения; без Redis (или при его ошибке) блокировка не выполняется.
+func LockCreate(sessionID string) (func(), error) {
+	rdb := utils.GetRedis()
+	if rdb == nil {
+		return func() {}, nil
+	}
+	ctx := context.Background()
+	token := utils.GenerateSessionID()
+	ok, err := rdb.SetNX(ctx, createLockKey(sessionID), token, createLockTTL).Result()
+	if err != nil {
+		utils.LogError(err, "osago: lock create")
+		return func() {}, nil // Redis недоступен — не блокируем оформление
+	}
+	if !ok {
+		return nil, ErrCreateInFlight
+	}
+	return func() {
+		// Снимаем только свою блокировку (чужую, взятую после истечения TTL, не трогаем)
+		if v, _ := rdb.Get(ctx, createLockKey(sessionID)).Result(); v == token {
+			rdb.Del(ctx, createLockKey(sessionID))
+		}
+	}, nil
+}
+
+// orderCreateBody восстанавливает ответ create по заказу: {"<provider>": ответ провайдера, "order_id": id}
+func orderCreateBody(order *models.OsagoOrder) json.RawMessage {
+	body := map[string]interface{}{"order_id": order.ID}
+	if order.ProviderResponse != "" {
+		body[order.Provider] = json.RawMessage(order.ProviderResponse)
+	}
+	b, _ := json.Marshal(body)
+	return b
+}
+
+func createReplayKey(key string) string     { return "osago_create:replay:" + key }
+func createLockKey(sessionID string) string { return "osago_create:lock:" + sessionID }
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"kliro/config"
+	"kliro/models"
+	osagoProviders "kliro/services/osago/providers"
+	"kliro/utils"
+
+	"gorm.io/gorm"
+)
+
+var errNoContact = errors.New("нет email и телефона для уведомления")
+
+// Contacts - куда отправлять уведомление по заказу
+type Contacts struct {
+	Email string
+	Phone string // только цифры
+}
+
+// UserContacts - email и телефон аккаунта (пусто, если пользователя нет)
+func UserContacts(db *gorm.DB, userID *uint) Contacts {
+	var out Contacts
+	if userID == nil {
+		return out
+	}
+	var user models.User
+	if err := db.First(&user, *userID).Error; err != nil {
+		return out
+	}
+	if user.Email != nil {
+		out.Email = *user.Email
+	}
+	if user.Phone != nil {
+		out.Phone = digits(*user.Phone)
+	}
+	return out
+}
+
+// Notify отправляет сообщение на email, иначе SMS через Eskiz; возвращает использованный канал
+func Notify(cfg *config.Config, to Contacts, subject, msg string) (string, error) {
+	switch {
+	case to.Email != "":
+		return "email", utils.SendEmail(to.Email, subject, msg, cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUser, cfg.SMTPPass)
+	case to.Phone != "":
+		token, err := utils.GetEskizToken(cfg.EskizEmail, cfg.EskizPassword)
+		if err != nil {
+			return "sms", err
+		}
+		return "sms", utils.SendEskizSMS(token, to.Phone, msg)
+	}
+	return "", errNoContact
+}
+
+// OrderInputs - данные заявки в inputs заказа: только то, что читают уведомления и продление
+// (телефон из create, ТС и идентификаторы владельца для /osago-all/find), без сырых ответов Find
+type OrderInputs struct {
+	PhoneNumber string      `json:"phone_number,omitempty"`
+	Prefill     FindPrefill `json:"prefill"`
+}
+
+// NewOrderInputs собирает inputs заказа из запроса create и сессии
+func NewOrderInputs(req *osagoProviders.CreateRequest, session *osagoProviders.Session) OrderInputs {
+	return OrderInputs{PhoneNumber: req.PhoneNumber, Prefill: PrefillFromSession(session)}
+}
+
+// parseInputs читает inputs заказа; заказы до сокращения inputs хранили {"request", "session"} целиком
+func parseInputs(inputs string) OrderInputs {
+	var in struct {
+		OrderInputs
+		Request struct {
+			PhoneNumber string `json:"phone_number"`
+		} `json:"request"`
+		Session *osagoProviders.Session `json:"session"`
+	}
+	_ = json.Unmarshal([]byte(inputs), &in)
+	out := in.OrderInputs
+	if out.PhoneNumber == "" {
+		out.PhoneNumber = in.Request.PhoneNumber
+	}
+	if in.Session != nil && out.Prefill == (FindPrefill{}) {
+		out.Prefill = PrefillFromSession(in.Session)
+	}
+	return out
+}
+
+func digits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+}
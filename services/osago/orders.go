@@ -0,0 +1,191 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"kliro/models"
+	osagoProviders "kliro/services/osago/providers"
+
+	"gorm.io/gorm"
+)
+
+// Статусы заказа ОСАГО (переходы после create — по опросу провайдера, см. StatusTracker)
+const (
+	StatusCreated = osagoProviders.StateCreated // заявка создана у провайдера, ожидает оплаты
+	StatusPaid    = osagoProviders.StatePaid    // оплачен, полис еще не выпущен
+	StatusIssued  = osagoProviders.StateIssued  // полис выпущен
+	StatusFailed  = osagoProviders.StateFailed  // провайдер отклонил create или аннулировал полис
+	StatusExpired = osagoProviders.StateExpired // не оплачен за OSAGO_PAYMENT_TTL
+)
+
+// Attempt - одна попытка create у провайдера
+type Attempt struct {
+	UserID    uint // 0 — без авторизации
+	Provider  string
+	SessionID string
+	Inputs    OrderInputs
+	AmountUZS int64
+	PeriodID  int
+	GosNumber string
+	BeginDate string // YYYY-MM-DD
+	EndDate   string
+	Response  interface{} // ответ провайдера (с click_url/payme_url)
+	Err       error
+
+	IdempotencyKey string // см. CreateKey
+	RequestHash    string
+}
+
+// Ключи, по которым в ответах провайдеров ищутся идентификаторы и ссылки (форматы у всех разные)
+var (
+	orderIDKeys      = []string{"order_id", "orderId", "anketa_id", "contractId", "contract_id", "application_id"}
+	policyIDKeys     = []string{"policy_id", "policyId", "uuid", "policy_uuid"}
+	policyNumberKeys = []string{"policy_number", "policyNumber", "polis_number", "polisNumber"}
+	pdfURLKeys       = []string{"policyUrl", "policy_url", "pdf_url", "pdfUrl", "url_pdf"}
+)
+
+// RecordAttempt сохраняет попытку оформления. Ошибка записи не должна ломать ответ клиенту —
+// полис у провайдера уже создан, поэтому вызывающий только логирует ее.
+func RecordAttempt(db *gorm.DB, a Attempt) (*models.OsagoOrder, error) {
+	order := models.OsagoOrder{
+		Provider:  a.Provider,
+		SessionID: a.SessionID,
+		Status:    StatusCreated,
+		PeriodID:  a.PeriodID,
+		Inputs:    toJSON(a.Inputs),
+
+		IdempotencyKey: a.IdempotencyKey,
+		RequestHash:    a.RequestHash,
+	}
+	if a.UserID != 0 {
+		uid := a.UserID
+		order.UserID = &uid
+	}
+	if a.AmountUZS > 0 {
+		amount := a.AmountUZS
+		order.AmountUZS = &amount
+	}
+	order.GosNumber = optional(a.GosNumber)
+	order.BeginDate = optional(a.BeginDate)
+	order.EndDate = optional(a.EndDate)
+
+	if a.Err != nil {
+		order.Status = StatusFailed
+		order.Error = a.Err.Error()
+	} else {
+		order.ProviderResponse = toJSON(a.Response)
+		order.ProviderOrderID = findString(a.Response, orderIDKeys)
+		order.ProviderPolicyID = findString(a.Response, policyIDKeys)
+		order.PaymentURL = findString(a.Response, []string{"click_url"})
+		order.PaymeURL = findString(a.Response, []string{"payme_url"})
+		order.PolicyNumber = optional(findString(a.Response, policyNumberKeys))
+		order.PdfURL = optional(findString(a.Response, pdfURLKeys))
+		if n, err := strconv.ParseInt(order.ProviderOrderID, 10, 64); err == nil {
+			order.ExternalOrderID = n
+		}
+		if order.PaymentURL == "" {
+			order.PaymentURL, order.PaymeURL = order.PaymeURL, ""
+		}
+		next := NextStatusCheck(time.Now(), 0)
+		order.NextStatusCheckAt = &next
+	}
+
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&order).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.OsagoOrderStatusHistory{OrderID: order.ID, ToStatus: order.Status, Comment: order.Error}).Error
+	}); err != nil {
+		return nil, fmt.Errorf("save osago order: %w", err)
+	}
+	return &order, nil
+}
+
+// LogAttempt - RecordAttempt с логированием ошибки (для вызова из create)
+func LogAttempt(db *gorm.DB, a Attempt) *models.OsagoOrder {
+	order, err := RecordAttempt(db, a)
+	if err != nil {
+		log.Printf("[OSAGO] %s: %v", a.Provider, err)
+		return nil
+	}
+	return order
+}
+
+// findString ищет первое непустое значение по ключам (в порядке приоритета) в ответе провайдера
+func findString(raw interface{}, keys []string) string {
+	for _, key := range keys {
+		if v := findKey(raw, key); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// findKey ищет ключ в ширину: ближайший к корню уровень, внутри уровня — ключи объектов по алфавиту,
+// чтобы при одинаковом ключе на разной глубине (uuid, order_id) результат не зависел от порядка обхода map
+func findKey(raw interface{}, key string) string {
+	level := []interface{}{raw}
+	for len(level) > 0 {
+		var next []interface{}
+		for _, node := range level {
+			switch v := node.(type) {
+			case map[string]interface{}:
+				if val, ok := v[key]; ok {
+					if s := scalarString(val); s != "" {
+						return s
+					}
+				}
+				names := make([]string, 0, len(v))
+				for name := range v {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				for _, name := range names {
+					next = append(next, v[name])
+				}
+			case []interface{}:
+				next = append(next, v...)
+			}
+		}
+		level = next
+	}
+	return ""
+}
+
+func scalarString(v interface{}) string {
+	switch x := v.(type) {
+	case string:
+		return strings.TrimSpace(x)
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case int:
+		return strconv.Itoa(x)
+	case int64:
+		return strconv.FormatInt(x, 10)
+	}
+	return ""
+}
+
+func toJSON(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
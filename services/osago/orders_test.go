@@ -0,0 +1,35 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFindString(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		keys []string
+		want string
+	}{
+		{"top level", `{"order_id": 3355, "response": {"order_id": 1}}`, orderIDKeys, "3355"},
+		{"shallowest wins", `{"a": {"b": {"uuid": "deep"}}, "z": {"uuid": "shallow"}}`, policyIDKeys, "shallow"},
+		{"same depth by key order", `{"b": {"uuid": "from-b"}, "a": {"uuid": "from-a"}}`, policyIDKeys, "from-a"},
+		{"key priority", `{"response": {"policy_id": "p1"}, "uuid": "u1"}`, policyIDKeys, "p1"},
+		{"inside array", `{"data": [{"contractId": "c-7"}]}`, orderIDKeys, "c-7"},
+		{"missing", `{"data": {"status": "ok"}}`, orderIDKeys, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var raw interface{}
+			if err := json.Unmarshal([]byte(tt.raw), &raw); err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 20; i++ { // порядок обхода map в Go случаен — результат не должен меняться
+				if got := findString(raw, tt.keys); got != tt.want {
+					t.Fatalf("findString() = %q, want %q", got, tt.want)
+				}
+			}
+		})
+	}
+}
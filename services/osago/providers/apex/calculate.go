@@ -0,0 +1,213 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	osagoProviders "kliro/services/osago/providers"
+)
+
+// Calculate - расчет для Apex Insurance.
+func (pr *Provider) Calculate(session *osagoProviders.Session, req *osagoProviders.CalculateRequest) (interface{}, error) {
+	vehicleData := session.Vehicle
+
+	// Owner
+	ownerType := osagoProviders.ExtractString(vehicleData, "data", "owner", "type")
+	var owner map[string]interface{}
+	if ownerType == "person" {
+		pinfl := osagoProviders.ExtractString(vehicleData, "data", "owner", "person", "pinfls", "0")
+		if pinfl == "" {
+			pinfl = osagoProviders.ExtractString(vehicleData, "data", "owner", "person", "external_id")
+		}
+		ownerPersonObj := osagoProviders.ExtractValue(vehicleData, "data", "owner", "person")
+		// Используем активный документ из documents[] (ID-карта), а не устаревшее поле passport
+		passportSeries, passportNumber := osagoProviders.ActiveDocument(ownerPersonObj)
+		if passportSeries == "" || passportNumber == "" {
+			// fallback: поле passport
+			passportSeries = osagoProviders.ExtractString(vehicleData, "data", "owner", "person", "passport", "series")
+			passportNumber = osagoProviders.ExtractString(vehicleData, "data", "owner", "person", "passport", "number")
+		}
+
+		owner = map[string]interface{}{
+			"person": map[string]interface{}{
+				"passportData": map[string]interface{}{
+					"pinfl":  pinfl,
+					"seria":  passportSeries,
+					"number": passportNumber,
+				},
+			},
+		}
+	} else {
+		// Юрлицо: Apex в calc ждёт owner.person.passportData.
+		// Берём представителя из drivers[0] (передайте хотя бы 1 водителя для юрлица).
+		if len(req.Drivers) == 0 {
+			return nil, fmt.Errorf("для Apex (юрлицо) укажите drivers[] (минимум 1 водитель) с паспортом и датой рождения")
+		}
+		rep := req.Drivers[0]
+		repPinfl := osagoProviders.SessionPinfl(session, rep.PassportSeries, rep.PassportNumber)
+		if repPinfl == "" && rep.Birthdate != "" {
+			repPinfl, _ = osagoProviders.LookupPinflByPassport(pr.cfg, osagoProviders.FormatDateYYYYMMDD(rep.Birthdate), rep.PassportSeries, rep.PassportNumber)
+		}
+		if repPinfl == "" {
+			return nil, fmt.Errorf("для Apex (юрлицо) не удалось получить PINFL представителя (drivers[0]); укажите паспорт и дату рождения и выполните find по человеку")
+		}
+
+		inn := osagoProviders.ExtractString(vehicleData, "data", "owner", "organization", "inn")
+		owner = map[string]interface{}{
+			"person": map[string]interface{}{
+				"passportData": map[string]interface{}{
+					"pinfl":  repPinfl,
+					"seria":  rep.PassportSeries,
+					"number": rep.PassportNumber,
+				},
+			},
+			"organization": map[string]interface{}{
+				"inn": inn,
+			},
+		}
+	}
+
+	// Vehicle
+	techSeries := osagoProviders.ExtractString(vehicleData, "data", "tech_passport", "series")
+	techNumber := osagoProviders.ExtractString(vehicleData, "data", "tech_passport", "number")
+	gosNumber := osagoProviders.ExtractString(vehicleData, "data", "license_plate")
+
+	// Территория использования — только из сессии Find (без дефолтов)
+	useTerritoryID := osagoProviders.ExtractString(vehicleData, "data", "use_territory_region", "external_id")
+	if useTerritoryID == "" {
+		useTerritoryID = osagoProviders.ExtractString(vehicleData, "data", "use_territory_region", "id")
+	}
+	if useTerritoryID == "" {
+		return nil, fmt.Errorf("в сессии нет территории использования (use_territory_region); выполните find с полными данными по ТС")
+	}
+	// Логируем что именно извлекли из сессии
+	{
+		_s, _n := osagoProviders.ActiveDocument(osagoProviders.ExtractValue(vehicleData, "data", "owner", "person"))
+		_pf := osagoProviders.ExtractString(vehicleData, "data", "owner", "person", "pinfls", "0")
+		if _pf == "" {
+			_pf = osagoProviders.ExtractString(vehicleData, "data", "owner", "person", "external_id")
+		}
+		log.Printf("[Apex calc] session: gos=%q tech_seria=%q tech_number=%q territory=%q pinfl=%q doc_seria=%q doc_number=%q",
+			gosNumber, techSeries, techNumber, useTerritoryID, _pf, _s, _n)
+	}
+
+	// Период — коды Apex из конфига (period_id от пользователя)
+	var contractTermID, seasonalInsuranceID string
+	switch req.PeriodID {
+	case 1:
+		contractTermID = pr.cfg.ApexContractTerm12
+		seasonalInsuranceID = pr.cfg.ApexSeasonalID12
+	case 2:
+		contractTermID = pr.cfg.ApexContractTerm6
+		seasonalInsuranceID = pr.cfg.ApexSeasonalID6
+	case 3:
+		contractTermID = pr.cfg.ApexContractTerm6
+		seasonalInsuranceID = pr.cfg.ApexSeasonalID20
+	default:
+		contractTermID = pr.cfg.ApexContractTerm12
+		seasonalInsuranceID = pr.cfg.ApexSeasonalID12
+	}
+
+	// Drivers
+	var drivers []map[string]interface{}
+	if req.DriverRestriction {
+		if len(req.Drivers) == 0 {
+			// Использовать владельца (только для физлица)
+			ownerPinfl := osagoProviders.OwnerPinfl(session)
+			ownerPassSeries := osagoProviders.ExtractString(vehicleData, "data", "owner", "person", "passport", "series")
+			ownerPassNumber := osagoProviders.ExtractString(vehicleData, "data", "owner", "person", "passport", "number")
+			drivers = []map[string]interface{}{
+				{
+					"passportData": map[string]interface{}{
+						"pinfl":  ownerPinfl,
+						"seria":  ownerPassSeries,
+						"number": ownerPassNumber,
+					},
+				},
+			}
+		} else {
+			for _, driver := range req.Drivers {
+				// Найти PINFL из session
+				pinfl := osagoProviders.SessionPinfl(session, driver.PassportSeries, driver.PassportNumber)
+				if pinfl == "" && driver.Birthdate != "" {
+					if p, err := osagoProviders.LookupPinflByPassport(pr.cfg, osagoProviders.FormatDateYYYYMMDD(driver.Birthdate), driver.PassportSeries, driver.PassportNumber); err == nil {
+						pinfl = p
+					}
+				}
+				if pinfl == "" {
+					return nil, fmt.Errorf("не удалось получить PINFL водителя (серия %s, номер %s); выполните find по человеку или укажите дату рождения", driver.PassportSeries, driver.PassportNumber)
+				}
+				drivers = append(drivers, map[string]interface{}{
+					"passportData": map[string]interface{}{
+						"pinfl":  pinfl,
+						"seria":  driver.PassportSeries,
+						"number": driver.PassportNumber,
+					},
+				})
+			}
+		}
+	} else {
+		// Неограничено водителей: Apex требует непустой массив; передаём владельца как единственного водителя
+		ownerPersonObj := osagoProviders.ExtractValue(vehicleData, "data", "owner", "person")
+		ownerDocSery, ownerDocNum := osagoProviders.ActiveDocument(ownerPersonObj)
+		if ownerDocSery == "" {
+			ownerDocSery = osagoProviders.ExtractString(vehicleData, "data", "owner", "person", "passport", "series")
+		}
+		if ownerDocNum == "" {
+			ownerDocNum = osagoProviders.ExtractString(vehicleData, "data", "owner", "person", "passport", "number")
+		}
+		ownerPinflFull := osagoProviders.ExtractString(vehicleData, "data", "owner", "person", "pinfls", "0")
+		if ownerPinflFull == "" {
+			ownerPinflFull = osagoProviders.ExtractString(vehicleData, "data", "owner", "person", "external_id")
+		}
+		if ownerDocSery == "" || ownerDocNum == "" || ownerPinflFull == "" {
+			return nil, fmt.Errorf("для Apex (неограниченный полис) в сессии должны быть данные владельца: паспорт и PINFL; выполните find по ТС и владельцу")
+		}
+		drivers = []map[string]interface{}{
+			{
+				"passportData": map[string]interface{}{
+					"pinfl":  ownerPinflFull,
+					"seria":  ownerDocSery,
+					"number": ownerDocNum,
+				},
+			},
+		}
+	}
+
+	// Apex calc: согласно документации — НЕ передавать typeId/vehicleTypeId в calc (только в create)
+	requestBody := map[string]interface{}{
+		"owner": owner,
+		"details": map[string]interface{}{
+			"driverNumberRestriction": req.DriverRestriction,
+		},
+		"cost": map[string]interface{}{
+			"contractTermConclusionId": contractTermID,
+			"useTerritoryId":           useTerritoryID,
+			"seasonalInsuranceId":      seasonalInsuranceID,
+			"foreignVehicleId":         pr.cfg.ApexForeignVehicleID,
+		},
+		"vehicle": map[string]interface{}{
+			"techPassport": map[string]interface{}{
+				"number": techNumber,
+				"seria":  techSeries,
+			},
+			"govNumber": gosNumber,
+		},
+		"drivers": drivers,
+	}
+
+	reqJSON, _ := json.Marshal(requestBody)
+	log.Printf("[Apex calc] → sending: %s", reqJSON)
+
+	url := pr.cfg.ApexBaseURL + "/osago_calculation"
+	result, err := osagoProviders.Request("POST", url, requestBody, pr.cfg.ApexLogin, pr.cfg.ApexPassword, "")
+
+	respJSON, _ := json.Marshal(result)
+	if err != nil {
+		log.Printf("[Apex calc] ← error: %v", err)
+	} else {
+		log.Printf("[Apex calc] ← response: %s", respJSON)
+	}
+	return result, err
+}
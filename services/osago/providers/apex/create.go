@@ -0,0 +1,460 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	osagoProviders "kliro/services/osago/providers"
+)
+
+// create - оформление полиса (сырой ответ провайдера)
+func (pr *Provider) create(session *osagoProviders.Session, req *osagoProviders.CreateRequest) (interface{}, error) {
+	if req.ProviderPayload != nil {
+		userID := pr.cfg.ApexUserID
+		if userID == 0 {
+			userID = 30541
+		}
+		url := pr.cfg.ApexBaseURL + "/osago?user_id=" + strconv.Itoa(userID)
+		return osagoProviders.RequestObject("POST", url, req.ProviderPayload, pr.cfg.ApexLogin, pr.cfg.ApexPassword, "")
+	}
+	// period_id, driver_restriction, amount_uzs, drivers — из сессии (calculate_snapshot), как у Neo/Gross
+	periodID := req.PeriodID
+	driverRestriction := req.DriverRestriction
+	amountUZS := req.AmountUZS
+	driversList := req.Drivers
+	if snap := session.CalculateSnapshot; snap != nil {
+		if snap.PeriodID >= 1 && snap.PeriodID <= 3 {
+			periodID = snap.PeriodID
+		}
+		driverRestriction = snap.DriverRestriction
+		if snap.Premiums != nil && snap.Premiums["apex"] > 0 {
+			amountUZS = snap.Premiums["apex"]
+		}
+		if len(snap.Drivers) > 0 {
+			driversList = snap.Drivers
+		}
+	}
+	// Автозаполнение license_series/license_number из Find API, если переданы только паспорт + дата рождения
+	driversList = osagoProviders.EnrichDrivers(pr.cfg, driversList)
+	if req.StartDate == "" {
+		return nil, fmt.Errorf("start_date обязателен для Apex create")
+	}
+	if amountUZS <= 0 {
+		return nil, fmt.Errorf("amount_uzs обязателен для Apex create (вызовите сначала Calculate по этой сессии или передайте amount_uzs в теле)")
+	}
+	if req.PhoneNumber == "" {
+		return nil, fmt.Errorf("phone_number обязателен для Apex create")
+	}
+	v := session.Vehicle
+	ownerType := osagoProviders.ExtractString(v, "data", "owner", "type")
+	// Представитель / владелец
+	var pinfl, passSery, passNum, birthDate string
+	if ownerType == "organization" {
+		if len(driversList) == 0 {
+			return nil, fmt.Errorf("для Apex (юрлицо) укажите drivers[] (минимум 1 представитель) или вызовите Calculate с водителями")
+		}
+		d := driversList[0]
+		pinfl = osagoProviders.FindPinfl(pr.cfg, session, d.PassportSeries, d.PassportNumber, d.Birthdate)
+		passSery, passNum, birthDate = d.PassportSeries, d.PassportNumber, d.Birthdate
+		if pinfl == "" {
+			pinfl = "00000000000000"
+		}
+	} else {
+		pinfl = osagoProviders.ExtractString(v, "data", "owner", "person", "pinfls", "0")
+		if pinfl == "" {
+			pinfl = osagoProviders.ExtractString(v, "data", "owner", "person", "external_id")
+		}
+		// Серия/номер документа владельца — как Neo/Gross/Trust: сначала ID-карта/documents, потом passport
+		ownerPersonObj := osagoProviders.ExtractValue(v, "data", "owner", "person")
+		if ownerPersonObj != nil {
+			passSery, passNum = osagoProviders.PersonPassport(ownerPersonObj)
+		}
+		if (passSery == "" || passNum == "") && session.Owner != nil && *session.Owner && session.Person != nil {
+			passSery, passNum = osagoProviders.PersonPassport(session.Person)
+		}
+		if passSery == "" || passNum == "" {
+			passSery = osagoProviders.ExtractString(v, "data", "owner", "person", "passport", "series")
+			passNum = osagoProviders.ExtractString(v, "data", "owner", "person", "passport", "number")
+		}
+		birthDate = osagoProviders.ExtractString(v, "data", "owner", "person", "birthdate")
+		if birthDate == "" {
+			birthDate = osagoProviders.ExtractString(session.Person, "data", "birthdate")
+		}
+	}
+	// ФИО и даты из Find: сначала vehicle.owner.person, затем session.Person (как у других провайдеров)
+	var firstName, lastName, middleName string
+	firstName = osagoProviders.ExtractString(v, "data", "owner", "person", "first_name")
+	if firstName == "" {
+		firstName = osagoProviders.ExtractString(session.Person, "data", "first_name")
+	}
+	lastName = osagoProviders.ExtractString(v, "data", "owner", "person", "last_name")
+	if lastName == "" {
+		lastName = osagoProviders.ExtractString(session.Person, "data", "last_name")
+	}
+	middleName = osagoProviders.ExtractString(v, "data", "owner", "person", "middle_name")
+	if middleName == "" {
+		middleName = osagoProviders.ExtractString(session.Person, "data", "middle_name")
+	}
+	if firstName == "" {
+		firstName = "N"
+	}
+	if lastName == "" {
+		lastName = "N"
+	}
+	if middleName == "" {
+		middleName = "N"
+	}
+	issueDate := osagoProviders.ExtractString(v, "data", "owner", "person", "passport", "issued_at")
+	if issueDate == "" {
+		issueDate = osagoProviders.ExtractString(session.Person, "data", "passport", "issued_at")
+	}
+	if issueDate == "" {
+		issueDate = "2022-01-01"
+	}
+	issueDate = strings.TrimSpace(strings.Split(issueDate, "T")[0])
+	// Apex ожидает даты в формате YYYY-MM-DD (без времени)
+	birthDate = osagoProviders.ToYYYYMMDD(birthDate)
+	if birthDate == "" {
+		birthDate = issueDate
+	}
+	ownerIssuedBy := "N"
+	// Обогащаем владельца/заявителя из EuroAsia person API (ФИО, паспорт issued_by / issued_at, PINFL)
+	if ownerType != "organization" && passSery != "" && passNum != "" && birthDate != "" {
+		if personData, err := osagoProviders.LookupPersonByPassport(pr.cfg, birthDate, passSery, passNum); err == nil && personData != nil {
+			if fn := osagoProviders.ExtractString(personData, "first_name"); fn != "" {
+				firstName = fn
+			}
+			if ln := osagoProviders.ExtractString(personData, "last_name"); ln != "" {
+				lastName = ln
+			}
+			if mn := osagoProviders.ExtractString(personData, "middle_name"); mn != "" {
+				middleName = mn
+			}
+			if iss := osagoProviders.ExtractString(personData, "passport", "issued_at"); iss != "" {
+				issueDate = osagoProviders.ToYYYYMMDD(iss)
+			}
+			if by := osagoProviders.ExtractString(personData, "passport", "issued_by"); by != "" {
+				ownerIssuedBy = by
+			}
+			if p := osagoProviders.ExtractString(personData, "pinfls", "0"); p != "" {
+				pinfl = p
+			} else if p := osagoProviders.ExtractString(personData, "external_id"); p != "" {
+				pinfl = p
+			}
+		}
+	}
+
+	// endDate от start_date + период; Apex ожидает дату окончания на 1 день раньше (все периоды)
+	endDate := req.StartDate
+	if periodID == 1 {
+		endDate = osagoProviders.AddMonthsThenSubDays(req.StartDate, 12, 1)
+	} else if periodID == 2 {
+		endDate = osagoProviders.AddMonthsThenSubDays(req.StartDate, 6, 1)
+	} else {
+		// период 3: 20 дней — Apex ожидает start + 19 дней (на 1 день раньше)
+		endDate = osagoProviders.AddDays(req.StartDate, 19)
+	}
+
+	contractTermID := "1"
+	seasonalID := 0 // для годового (periodID=1) Apex требует пустой seasonalInsuranceId
+	switch periodID {
+	case 1:
+		contractTermID = "1"
+		seasonalID = 0 // Годовой — seasonalInsuranceId не передаём
+	case 2:
+		contractTermID = "2"
+		seasonalID = 1
+	case 3:
+		contractTermID = "2"
+		seasonalID = 8
+	}
+	useTerritoryID := osagoProviders.ExtractString(v, "data", "use_territory_region", "external_id")
+	if useTerritoryID == "" {
+		useTerritoryID = "1"
+	}
+	vehicleTypeID := osagoProviders.ExtractInt(v, "data", "vehicle_type", "external_id")
+	if vehicleTypeID == 0 {
+		vehicleTypeID = 1
+	}
+	// Apex принимает только id из своего справочника /api/references/vehicle-types-osago (1=легковые, 6=грузовые, 9=автобусы, 15=трамваи/мото)
+	apexVehicleTypeID := osagoProviders.VehicleTypeID(vehicleTypeID)
+	issueYear := osagoProviders.ExtractInt(v, "data", "manufacture_year")
+	if issueYear == 0 {
+		issueYear = 2020
+	}
+
+	applicant := map[string]interface{}{
+		"person": map[string]interface{}{
+			"passportData": map[string]interface{}{
+				"pinfl":     pinfl,
+				"seria":     passSery,
+				"number":    passNum,
+				"issuedBy":  ownerIssuedBy,
+				"issueDate": issueDate,
+			},
+			"fullName": map[string]interface{}{
+				"firstname":  firstName,
+				"lastname":   lastName,
+				"middlename": middleName,
+			},
+			"phoneNumber": req.PhoneNumber,
+			"gender":      "m",
+			"birthDate":   birthDate,
+			"regionId":    10,
+			"districtId":  1005,
+		},
+		"address":       "N",
+		"residentOfUzb": 1,
+		"citizenshipId": 210,
+	}
+	applicantIsOwner := true
+	// Заявитель из тела запроса (applicant_passport_*, applicant_birthdate) — не владелец
+	if aS := strings.TrimSpace(req.ApplicantPassportSeries); aS != "" {
+		if aN := strings.TrimSpace(req.ApplicantPassportNumber); aN != "" {
+			applicantBirthNorm := osagoProviders.ToYYYYMMDD(strings.TrimSpace(req.ApplicantBirthdate))
+			if applicantBirthNorm == "" {
+				applicantBirthNorm = "2000-01-01"
+			}
+			applicantPinfl := osagoProviders.FindPinfl(pr.cfg, session, aS, aN, applicantBirthNorm)
+			applicantIssueDate := "2022-01-01"
+			applicantIssuedBy := "N"
+			afirst, alast, amiddle := "N", "N", "N"
+			if personData, err := osagoProviders.LookupPersonByPassport(pr.cfg, applicantBirthNorm, aS, aN); err == nil && personData != nil {
+				afirst = osagoProviders.ExtractString(personData, "first_name")
+				alast = osagoProviders.ExtractString(personData, "last_name")
+				amiddle = osagoProviders.ExtractString(personData, "middle_name")
+				if afirst == "" {
+					afirst = "N"
+				}
+				if alast == "" {
+					alast = "N"
+				}
+				if amiddle == "" {
+					amiddle = "N"
+				}
+				if iss := osagoProviders.ExtractString(personData, "passport", "issued_at"); iss != "" {
+					applicantIssueDate = osagoProviders.ToYYYYMMDD(iss)
+				}
+				if by := osagoProviders.ExtractString(personData, "passport", "issued_by"); by != "" {
+					applicantIssuedBy = by
+				}
+				if p := osagoProviders.ExtractString(personData, "pinfls", "0"); p != "" {
+					applicantPinfl = p
+				} else if p := osagoProviders.ExtractString(personData, "external_id"); p != "" {
+					applicantPinfl = p
+				}
+			}
+			if applicantPinfl == "" {
+				applicantPinfl = "00000000000000"
+			}
+			applicant = map[string]interface{}{
+				"person": map[string]interface{}{
+					"passportData": map[string]interface{}{
+						"pinfl":     applicantPinfl,
+						"seria":     aS,
+						"number":    aN,
+						"issuedBy":  applicantIssuedBy,
+						"issueDate": applicantIssueDate,
+					},
+					"fullName": map[string]interface{}{
+						"firstname":  afirst,
+						"lastname":   alast,
+						"middlename": amiddle,
+					},
+					"phoneNumber": req.PhoneNumber,
+					"gender":      "m",
+					"birthDate":   applicantBirthNorm,
+					"regionId":    10,
+					"districtId":  1005,
+				},
+				"address":       "N",
+				"residentOfUzb": 1,
+				"citizenshipId": 210,
+			}
+			applicantIsOwner = false
+		}
+	}
+	var owner map[string]interface{}
+	if ownerType == "organization" {
+		// Apex: нельзя передавать и Person, и Organization в owner; только organization с name (обязательно) и inn
+		inn := osagoProviders.ExtractString(v, "data", "owner", "organization", "inn")
+		if inn == "" {
+			inn = osagoProviders.ExtractString(session.Organization, "data", "inn")
+		}
+		orgName := osagoProviders.ExtractString(v, "data", "owner", "organization", "name_short")
+		if orgName == "" {
+			orgName = osagoProviders.ExtractString(v, "data", "owner", "organization", "name")
+		}
+		if orgName == "" {
+			orgName = osagoProviders.ExtractString(session.Organization, "data", "name")
+		}
+		if orgName == "" {
+			orgName = "N"
+		}
+		owner = map[string]interface{}{
+			"organization": map[string]interface{}{
+				"inn":  inn,
+				"name": orgName,
+			},
+			"applicantIsOwner": applicantIsOwner,
+		}
+	} else {
+		owner = map[string]interface{}{
+			"person": map[string]interface{}{
+				"passportData": map[string]interface{}{
+					"pinfl":     pinfl,
+					"seria":     passSery,
+					"number":    passNum,
+					"issuedBy":  ownerIssuedBy,
+					"issueDate": issueDate + "T00:00:00",
+				},
+				"fullName": map[string]interface{}{
+					"firstname":  firstName,
+					"lastname":   lastName,
+					"middlename": middleName,
+				},
+			},
+			"applicantIsOwner": applicantIsOwner,
+		}
+	}
+	techSery := osagoProviders.ExtractString(v, "data", "tech_passport", "series")
+	techNum := osagoProviders.ExtractString(v, "data", "tech_passport", "number")
+	gosNumber := osagoProviders.ExtractString(v, "data", "license_plate")
+	modelName := osagoProviders.ExtractString(v, "data", "model")
+	if modelName == "" {
+		modelName = "N"
+	}
+	bodyNumber := osagoProviders.ExtractString(v, "data", "body_number")
+	if bodyNumber == "" {
+		bodyNumber = "N"
+	}
+	engineNumber := osagoProviders.ExtractString(v, "data", "engine_number")
+	if engineNumber == "" {
+		engineNumber = "N"
+	}
+
+	var apexDriversList []map[string]interface{}
+	if driverRestriction && len(driversList) > 0 {
+		for _, d := range driversList {
+			dpinfl := osagoProviders.FindPinfl(pr.cfg, session, d.PassportSeries, d.PassportNumber, d.Birthdate)
+			if dpinfl == "" {
+				dpinfl = pinfl
+			}
+			driverBirth := osagoProviders.ToYYYYMMDD(d.Birthdate)
+			driverIssueDate := osagoProviders.ToYYYYMMDD(d.LicenseIssueDate)
+			if driverIssueDate == "" {
+				driverIssueDate = "2020-01-01"
+			}
+			dfirst, dlast, dmiddle := "N", "N", "N"
+			dissuedBy := "N"
+			// EuroAsia person API — подставляем реальные ФИО и дату выдачи паспорта
+			if personData, err := osagoProviders.LookupPersonByPassport(pr.cfg, driverBirth, d.PassportSeries, d.PassportNumber); err == nil && personData != nil {
+				dfirst = osagoProviders.ExtractString(personData, "first_name")
+				dlast = osagoProviders.ExtractString(personData, "last_name")
+				dmiddle = osagoProviders.ExtractString(personData, "middle_name")
+				if dfirst == "" {
+					dfirst = "N"
+				}
+				if dlast == "" {
+					dlast = "N"
+				}
+				if dmiddle == "" {
+					dmiddle = "N"
+				}
+				if iss := osagoProviders.ExtractString(personData, "passport", "issued_at"); iss != "" {
+					driverIssueDate = osagoProviders.ToYYYYMMDD(iss)
+				}
+				if by := osagoProviders.ExtractString(personData, "passport", "issued_by"); by != "" {
+					dissuedBy = by
+				}
+				if p := osagoProviders.ExtractString(personData, "pinfls", "0"); p != "" {
+					dpinfl = p
+				} else if p := osagoProviders.ExtractString(personData, "external_id"); p != "" {
+					dpinfl = p
+				}
+			}
+			apexDriversList = append(apexDriversList, map[string]interface{}{
+				"passportData": map[string]interface{}{
+					"pinfl":     dpinfl,
+					"seria":     d.PassportSeries,
+					"number":    d.PassportNumber,
+					"issuedBy":  dissuedBy,
+					"issueDate": driverIssueDate,
+				},
+				"fullName": map[string]interface{}{
+					"firstname":  dfirst,
+					"lastname":   dlast,
+					"middlename": dmiddle,
+				},
+				"licenseNumber":    d.LicenseNumber,
+				"licenseSeria":     d.LicenseSeries,
+				"relative":         relative(d.Relative),
+				"birthDate":        driverBirth,
+				"licenseIssueDate": driverIssueDate,
+				"residentOfUzb":    1,
+			})
+		}
+	} else {
+		// Без ограничения водителей (unlimited): Apex запрещает отправлять drivers — только пустой массив
+		apexDriversList = []map[string]interface{}{}
+	}
+
+	// Apex требует уникальный transactionId в каждом запросе (иначе "Ошибка в поле transactionid - уникальное значение")
+	transactionID := time.Now().UnixNano() / 1000000
+	body := map[string]interface{}{
+		"applicant": applicant,
+		"owner":     owner,
+		"details": map[string]interface{}{
+			"startDate":               req.StartDate,
+			"issueDate":               req.StartDate,
+			"endDate":                 endDate,
+			"driverNumberRestriction": driverRestriction,
+			"transactionId":           transactionID,
+		},
+		"cost": func() map[string]interface{} {
+			cost := map[string]interface{}{
+				"discountId":                    1,
+				"discountSum":                   "0",
+				"insurancePremium":              amountUZS,
+				"sumInsured":                    "80000000",
+				"contractTermConclusionId":      contractTermID,
+				"useTerritoryId":                useTerritoryID,
+				"commission":                    "0",
+				"insurancePremiumPaidToInsurer": amountUZS,
+			}
+			// Для годового (ID=1) seasonalInsuranceId должен быть пустым
+			if seasonalID != 0 {
+				cost["seasonalInsuranceId"] = seasonalID
+			}
+			return cost
+		}(),
+		"vehicle": map[string]interface{}{
+			"techPassport": map[string]interface{}{
+				"seria":  techSery,
+				"number": techNum,
+			},
+			"modelCustomName": modelName,
+			"engineNumber":    engineNumber,
+			"typeId":          apexVehicleTypeID,
+			"issueYear":       issueYear,
+			"govNumber":       gosNumber,
+			"bodyNumber":      bodyNumber,
+			"regionId":        10,
+		},
+		"drivers": apexDriversList,
+	}
+
+	userID := pr.cfg.ApexUserID
+	if userID == 0 {
+		userID = 30541
+	}
+	url := pr.cfg.ApexBaseURL + "/osago?user_id=" + strconv.Itoa(userID)
+	return osagoProviders.RequestObject("POST", url, body, pr.cfg.ApexLogin, pr.cfg.ApexPassword, "")
+}
+
+// relative конвертирует наше значение relative в формат Apex (0-10, без изменений)
+func relative(rel int) int {
+	return rel
+}
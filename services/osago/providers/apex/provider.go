@@ -0,0 +1,62 @@
+package services
+
+import (
+	"strings"
+
+	"kliro/config"
+	osagoProviders "kliro/services/osago/providers"
+)
+
+// Provider - Apex Insurance: физлица и юрлица, все периоды включая 20 дней
+type Provider struct {
+	cfg *config.Config
+}
+
+func New(cfg *config.Config) *Provider {
+	return &Provider{cfg: cfg}
+}
+
+func (pr *Provider) Name() string { return "apex" }
+
+func (pr *Provider) Periods() []int { return []int{1, 2, 3} }
+
+func (pr *Provider) OwnerTypes() []string {
+	return []string{osagoProviders.OwnerPerson, osagoProviders.OwnerOrganization}
+}
+
+// Create - оформление полиса; ссылки оплаты Apex: click_link, payme_link
+func (pr *Provider) Create(session *osagoProviders.Session, req *osagoProviders.CreateRequest) (interface{}, error) {
+	r, err := pr.create(session, req)
+	if err != nil {
+		return nil, err
+	}
+	return osagoProviders.WithPaymentURLs(r, osagoProviders.ExtractString(r, "click_link"), osagoProviders.ExtractString(r, "payme_link")), nil
+}
+
+func (pr *Provider) SupportsStatus() bool { return false }
+
+func (pr *Provider) CheckStatus(osagoProviders.StatusRef) (*osagoProviders.Status, error) {
+	return nil, osagoProviders.ErrStatusUnsupported
+}
+
+// Quote - премия в insurance_premium в виде "384 000,00 UZS", страховую сумму не возвращает
+func (pr *Provider) Quote(raw interface{}) osagoProviders.Quote {
+	return osagoProviders.Quote{Premium: premium(raw)}
+}
+
+func premium(raw interface{}) int {
+	s, _ := osagoProviders.ExtractValue(raw, "insurance_premium").(string)
+	if s == "" {
+		return -1
+	}
+	s = strings.TrimSpace(s)
+	if i := strings.Index(s, " UZS"); i >= 0 {
+		s = s[:i]
+	}
+	s = strings.ReplaceAll(s, " ", "")
+	s = strings.ReplaceAll(s, "\u00a0", "")
+	if idx := strings.Index(s, ","); idx >= 0 {
+		s = s[:idx]
+	}
+	return osagoProviders.ToInt(s)
+}
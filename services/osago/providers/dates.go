@@ -0,0 +1,66 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// FormatDateDDMMYYYY - конвертация даты из ISO8601 / YYYY-MM-DD в DD.MM.YYYY
+func FormatDateDDMMYYYY(isoDate string) string {
+	if isoDate == "" {
+		return ""
+	}
+	// Убрать время если есть
+	p := strings.Split(strings.Split(isoDate, "T")[0], "-")
+	if len(p) == 3 {
+		return fmt.Sprintf("%s.%s.%s", p[2], p[1], p[0])
+	}
+	return isoDate
+}
+
+// FormatDateYYYYMMDD - конвертация даты из ISO8601 в YYYY-MM-DD
+func FormatDateYYYYMMDD(isoDate string) string {
+	if isoDate == "" {
+		return ""
+	}
+	return strings.Split(isoDate, "T")[0]
+}
+
+// ToYYYYMMDD приводит ISO8601 и DD.MM.YYYY к YYYY-MM-DD
+func ToYYYYMMDD(s string) string {
+	s = strings.TrimSpace(strings.Split(s, "T")[0])
+	if len(s) == 10 && s[4] == '-' {
+		return s
+	}
+	// DD.MM.YYYY -> YYYY-MM-DD
+	parts := strings.Split(s, ".")
+	if len(parts) == 3 {
+		return fmt.Sprintf("%s-%s-%s", parts[2], parts[1], parts[0])
+	}
+	return s
+}
+
+// AddMonths - дата + months месяцев (YYYY-MM-DD)
+func AddMonths(ymd string, months int) string {
+	return shiftDate(ymd, months, 0)
+}
+
+// AddMonthsThenSubDays — для годового периода: endDate = start + 12 мес - 1 день
+func AddMonthsThenSubDays(ymd string, months, subDays int) string {
+	return shiftDate(ymd, months, -subDays)
+}
+
+// AddDays - дата + days дней (YYYY-MM-DD)
+func AddDays(ymd string, days int) string {
+	return shiftDate(ymd, 0, days)
+}
+
+func shiftDate(ymd string, months, days int) string {
+	ymd = ToYYYYMMDD(ymd)
+	t, err := time.Parse("2006-01-02", ymd)
+	if err != nil {
+		return ymd
+	}
+	return t.AddDate(0, months, 0).AddDate(0, 0, days).Format("2006-01-02")
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"fmt"
+
+	osagoProviders "kliro/services/osago/providers"
+)
+
+// Calculate - расчет для EuroAsia Insurance
+func (pr *Provider) Calculate(session *osagoProviders.Session, req *osagoProviders.CalculateRequest) (interface{}, error) {
+	vehicleData := session.Vehicle
+
+	// Проверка UUID
+	useTerritoryID := osagoProviders.ExtractString(vehicleData, "data", "use_territory_region", "id")
+	vehicleGroupID := osagoProviders.ExtractString(vehicleData, "data", "vehicle_type", "vehicle_group")
+
+	if useTerritoryID == "" || vehicleGroupID == "" {
+		return nil, fmt.Errorf("недостаточно UUID данных для EuroAsia")
+	}
+
+	// Период — из конфига (UUID сезонности EuroAsia)
+	var seasonalInsuranceID string
+	switch req.PeriodID {
+	case 1:
+		seasonalInsuranceID = pr.cfg.EuroasiaSeasonalID12
+	case 2:
+		seasonalInsuranceID = pr.cfg.EuroasiaSeasonalID6
+	case 3:
+		seasonalInsuranceID = pr.cfg.EuroasiaSeasonalID20
+	default:
+		seasonalInsuranceID = pr.cfg.EuroasiaSeasonalID12
+	}
+
+	// Drivers
+	var drivers []map[string]interface{}
+	if req.DriverRestriction {
+		if len(req.Drivers) == 0 {
+			// Использовать владельца
+			ownerBirthdate := osagoProviders.ExtractString(vehicleData, "data", "owner", "person", "birthdate")
+			ownerPassSeries := osagoProviders.ExtractString(vehicleData, "data", "owner", "person", "passport", "series")
+			ownerPassNumber := osagoProviders.ExtractString(vehicleData, "data", "owner", "person", "passport", "number")
+			drivers = []map[string]interface{}{
+				{
+					"passport_birthdate": osagoProviders.FormatDateYYYYMMDD(ownerBirthdate),
+					"passport_number":    ownerPassNumber,
+					"passport_series":    ownerPassSeries,
+				},
+			}
+		} else {
+			for _, driver := range req.Drivers {
+				drivers = append(drivers, map[string]interface{}{
+					"passport_birthdate": osagoProviders.FormatDateYYYYMMDD(driver.Birthdate),
+					"passport_number":    driver.PassportNumber,
+					"passport_series":    driver.PassportSeries,
+				})
+			}
+		}
+	}
+
+	requestBody := map[string]interface{}{
+		"driver_restriction":      req.DriverRestriction,
+		"drivers":                 drivers,
+		"seasonal_insurance_id":   seasonalInsuranceID,
+		"use_territory_region_id": useTerritoryID,
+		"vehicle_group_id":        vehicleGroupID,
+	}
+
+	url := pr.cfg.EuroasiaAllBaseURL + "/api/v1/insurance/osago/calculate"
+	return osagoProviders.Request("POST", url, requestBody, "", "", pr.cfg.EuroasiaAllAPIKey)
+}
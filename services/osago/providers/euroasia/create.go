@@ -0,0 +1,247 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	osagoProviders "kliro/services/osago/providers"
+)
+
+// create - оформление полиса (сырой ответ провайдера)
+func (pr *Provider) create(session *osagoProviders.Session, req *osagoProviders.CreateRequest) (interface{}, error) {
+	if req.StartDate == "" {
+		return nil, fmt.Errorf("start_date обязателен для EuroAsia create")
+	}
+	if req.PhoneNumber == "" {
+		return nil, fmt.Errorf("phone_number обязателен для EuroAsia create")
+	}
+
+	v := session.Vehicle
+	ownerType := osagoProviders.ExtractString(v, "data", "owner", "type")
+	// district_id: из запроса, затем из session (person или organization), иначе дефолт для юрлица
+	districtID := strings.TrimSpace(req.EuroasiaDistrictID)
+	if districtID == "" && session.Person != nil {
+		districtID = osagoProviders.ExtractString(session.Person, "data", "district", "id")
+	}
+	if districtID == "" && session.Organization != nil {
+		districtID = osagoProviders.ExtractString(session.Organization, "data", "district", "id")
+		if districtID == "" {
+			districtID = osagoProviders.ExtractString(session.Organization, "data", "region", "id")
+		}
+	}
+	// EuroAsia принимает district_id только в формате UUID; для юрлица без района в сессии — дефолт (Ташкент)
+	if districtID == "" && ownerType == "organization" {
+		districtID = "00000000-0000-0000-0000-000000000001"
+	}
+	if districtID == "" {
+		return nil, fmt.Errorf("euroasia_district_id обязателен для EuroAsia create (или получите session через find с паспортом/пинфл физлица — тогда район подставится из ответа автоматически)")
+	}
+
+	license := osagoProviders.ExtractString(v, "data", "license_plate")
+	techS := osagoProviders.ExtractString(v, "data", "tech_passport", "series")
+	techN := osagoProviders.ExtractString(v, "data", "tech_passport", "number")
+	if license == "" || techS == "" || techN == "" {
+		return nil, fmt.Errorf("недостаточно данных о машине")
+	}
+
+	// period_id из сессии (calculate_snapshot), иначе из тела; по умолчанию 1 (чтобы seasonal_insurance_id не был пустым UUID)
+	periodID := req.PeriodID
+	if snap := session.CalculateSnapshot; snap != nil && snap.PeriodID >= 1 && snap.PeriodID <= 3 {
+		periodID = snap.PeriodID
+	}
+	if periodID == 0 {
+		periodID = 1
+	}
+	driverRestriction := req.DriverRestriction
+	if snap := session.CalculateSnapshot; snap != nil {
+		driverRestriction = snap.DriverRestriction
+	}
+	// period -> seasonal_insurance_id UUID (никогда не пустой)
+	var seasonalInsuranceID string
+	switch periodID {
+	case 1:
+		seasonalInsuranceID = "8465a831-850f-4445-a995-ef71195094ab" // 365
+	case 2:
+		seasonalInsuranceID = "9848096e-cc12-4dbd-893b-41f2cdfc9a0e" // 180
+	case 3:
+		seasonalInsuranceID = "0d546748-0ba6-43bc-9ce2-1b977ad9e494" // 20
+	default:
+		seasonalInsuranceID = "8465a831-850f-4445-a995-ef71195094ab"
+	}
+
+	// drivers — из сессии (calculate_snapshot) или из тела
+	driversList := req.Drivers
+	if snap := session.CalculateSnapshot; snap != nil && len(snap.Drivers) > 0 {
+		driversList = snap.Drivers
+	}
+	// Автозаполнение license_series/license_number из Find API, если переданы только паспорт + дата рождения
+	driversList = osagoProviders.EnrichDrivers(pr.cfg, driversList)
+	detailsDrivers := []map[string]interface{}{}
+	if driverRestriction {
+		if len(driversList) == 0 {
+			return nil, fmt.Errorf("drivers[] обязателен если driver_restriction=true для EuroAsia create (вызовите Calculate с водителями или передайте drivers в теле)")
+		}
+		for _, d := range driversList {
+			detailsDrivers = append(detailsDrivers, map[string]interface{}{
+				"passport_birthdate": d.Birthdate,
+				"passport_number":    d.PassportNumber,
+				"passport_series":    d.PassportSeries,
+				"relative_id":        relativeUUID(d.Relative),
+			})
+		}
+	}
+
+	// insurant
+	insType := strings.TrimSpace(req.EuroasiaInsurantType)
+	if insType == "" {
+		insType = ownerType
+		if insType == "" {
+			insType = "person"
+		}
+	}
+	insurant := map[string]interface{}{
+		"district_id":  districtID,
+		"phone_number": req.PhoneNumber,
+		"type":         insType,
+	}
+	if insType == "organization" {
+		inn := osagoProviders.ExtractString(v, "data", "owner", "organization", "inn")
+		if inn == "" {
+			inn = osagoProviders.ExtractString(session.Organization, "data", "inn")
+		}
+		insurant["organization"] = map[string]interface{}{"inn": inn}
+	} else {
+		// person insurant: из тела (applicant_*) или из drivers[0], иначе session.person
+		ps := strings.TrimSpace(req.ApplicantPassportSeries)
+		pn := strings.TrimSpace(req.ApplicantPassportNumber)
+		bd := strings.TrimSpace(req.ApplicantBirthdate)
+		if (ps == "" || pn == "") && len(driversList) > 0 {
+			ps, pn, bd = driversList[0].PassportSeries, driversList[0].PassportNumber, driversList[0].Birthdate
+		}
+		if (ps == "" || pn == "") && session.Person != nil {
+			ps, pn = osagoProviders.PersonPassport(session.Person)
+		}
+		if ps == "" || pn == "" {
+			ps = osagoProviders.ExtractString(session.Person, "data", "passport", "series")
+			pn = osagoProviders.ExtractString(session.Person, "data", "passport", "number")
+		}
+		if bd == "" {
+			bd = osagoProviders.ExtractString(session.Person, "data", "birthdate")
+		}
+		if ps == "" || pn == "" || bd == "" {
+			return nil, fmt.Errorf("не хватает данных insurant.person (passport/birthdate); укажите applicant_* в теле или вызовите Calculate с водителями)")
+		}
+		insurant["person"] = map[string]interface{}{
+			"passport_birthdate": bd,
+			"passport_number":    pn,
+			"passport_series":    ps,
+		}
+	}
+
+	// owner
+	ownerIsIns := true
+	if req.EuroasiaOwnerIsInsurant != nil {
+		ownerIsIns = *req.EuroasiaOwnerIsInsurant
+	}
+	owner := map[string]interface{}{
+		"is_insurant": ownerIsIns,
+		"type":        ownerType,
+	}
+	if ownerType == "organization" {
+		inn := osagoProviders.ExtractString(v, "data", "owner", "organization", "inn")
+		if inn == "" {
+			inn = osagoProviders.ExtractString(session.Organization, "data", "inn")
+		}
+		owner["organization"] = map[string]interface{}{"inn": inn}
+		// добавить представителя как person (если передан)
+		if len(req.Drivers) > 0 {
+			owner["person"] = map[string]interface{}{
+				"passport_series": req.Drivers[0].PassportSeries,
+				"passport_number": req.Drivers[0].PassportNumber,
+			}
+		}
+	} else {
+		// owner person — из сессии Find (documents/passport), как для Neo
+		ownerPersonObj := osagoProviders.ExtractValue(v, "data", "owner", "person")
+		ps, pn := "", ""
+		if ownerPersonObj != nil {
+			ps, pn = osagoProviders.PersonPassport(ownerPersonObj)
+		}
+		if (ps == "" || pn == "") && session.Owner != nil && *session.Owner && session.Person != nil {
+			ps, pn = osagoProviders.PersonPassport(session.Person)
+		}
+		if ps == "" || pn == "" {
+			ps = osagoProviders.ExtractString(v, "data", "owner", "person", "passport", "series")
+			pn = osagoProviders.ExtractString(v, "data", "owner", "person", "passport", "number")
+		}
+		if ps == "" || pn == "" {
+			ps = osagoProviders.ExtractString(session.Person, "data", "passport", "series")
+			pn = osagoProviders.ExtractString(session.Person, "data", "passport", "number")
+		}
+		owner["person"] = map[string]interface{}{
+			"passport_series": ps,
+			"passport_number": pn,
+		}
+	}
+
+	body := map[string]interface{}{
+		"details": map[string]interface{}{
+			"driver_restriction":    driverRestriction,
+			"drivers":               detailsDrivers,
+			"seasonal_insurance_id": seasonalInsuranceID,
+			"start_at":              req.StartDate,
+		},
+		"insurant": insurant,
+		"owner":    owner,
+		"vehicle": map[string]interface{}{
+			"license_number":       license,
+			"tech_passport_number": techN,
+			"tech_passport_series": techS,
+		},
+	}
+
+	url := pr.cfg.EuroasiaAllBaseURL + "/api/v1/insurance/osago/create"
+	return osagoProviders.RequestObject("POST", url, body, "", "", pr.cfg.EuroasiaAllAPIKey)
+}
+
+// payment вызывает API оплаты EuroAsia (click или payme); promocode статический PROMO2024
+func (pr *Provider) payment(policyID, gateway string) (interface{}, error) {
+	if policyID == "" {
+		return nil, fmt.Errorf("policy_id пустой")
+	}
+	url := pr.cfg.EuroasiaAllBaseURL + "/api/v1/insurance/policies/" + policyID + "/payments"
+	body := map[string]string{
+		"gateway":   gateway,
+		"promocode": "PROMO2024",
+	}
+	return osagoProviders.RequestObject("POST", url, body, "", "", pr.cfg.EuroasiaAllAPIKey)
+}
+
+// relativeUUID конвертирует наше значение relative в UUID формат EuroAsia
+func relativeUUID(rel int) string {
+	// from euroAsiaFlow.txt (relatives list)
+	switch rel {
+	case 1:
+		return "903da482-1fd9-4e90-a384-9e4a52b6545c" // Father
+	case 2:
+		return "df286690-0d72-4cce-95e0-f27c30624174" // Mother
+	case 3:
+		return "94531b36-f72d-43b6-9e21-a63b251e0858" // Husband
+	case 4:
+		return "07147dd2-1c8f-424a-86e1-f79a38a5465e" // Wife
+	case 5:
+		return "6f3cb0a3-463c-498f-a0ef-09543a7c36c8" // Son
+	case 6:
+		return "ce1ddb40-e938-40cb-8653-9881817ba5a7" // Daughter
+	case 7:
+		return "44da9d2a-dee9-49b8-ad49-66f8c51d5cc1" // Older Brother
+	case 8:
+		return "10b0ac96-1004-4c71-99a1-82b2ff10847d" // Younger Brother
+	case 9:
+		return "cee50656-1d4f-4b47-aa78-ffa259bf1776" // Older Sister
+	case 10:
+		return "3e29b1ea-e10e-45dd-a73c-2e77c6e62052" // Younger Sister
+	default:
+		return "ab3391d9-a5df-4b7d-ae85-79479e9ad10b" // Not relative
+	}
+}
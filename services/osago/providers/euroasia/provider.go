@@ -0,0 +1,91 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+
+	"kliro/config"
+	osagoProviders "kliro/services/osago/providers"
+)
+
+// Provider - EuroAsia Insurance (erp.eai.uz): физлица и юрлица, все периоды включая 20 дней
+type Provider struct {
+	cfg *config.Config
+}
+
+func New(cfg *config.Config) *Provider {
+	return &Provider{cfg: cfg}
+}
+
+func (pr *Provider) Name() string { return "euroasia" }
+
+func (pr *Provider) Periods() []int { return []int{1, 2, 3} }
+
+func (pr *Provider) OwnerTypes() []string {
+	return []string{osagoProviders.OwnerPerson, osagoProviders.OwnerOrganization}
+}
+
+// Create - оформление полиса. Ссылки оплаты EuroAsia выдает отдельными запросами: параллельно
+// запрашиваем click и payme, ответ — create + payment_click + payment_payme.
+func (pr *Provider) Create(session *osagoProviders.Session, req *osagoProviders.CreateRequest) (interface{}, error) {
+	r, err := pr.create(session, req)
+	if err != nil {
+		return nil, err
+	}
+	policyID := osagoProviders.ExtractString(r, "data", "policy_id")
+	if policyID == "" {
+		return osagoProviders.WithPaymentURLs(r, "", ""), nil
+	}
+	var clickResp, paymeResp interface{}
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		clickResp, _ = pr.payment(policyID, "click")
+		wg.Done()
+	}()
+	go func() {
+		paymeResp, _ = pr.payment(policyID, "payme")
+		wg.Done()
+	}()
+	wg.Wait()
+	out := map[string]interface{}{
+		"create":        r,
+		"payment_click": clickResp,
+		"payment_payme": paymeResp,
+	}
+	return osagoProviders.WithPaymentURLs(out,
+		osagoProviders.ExtractString(out, "payment_click", "data", "payment_link"),
+		osagoProviders.ExtractString(out, "payment_payme", "data", "payment_link")), nil
+}
+
+func (pr *Provider) SupportsStatus() bool { return true }
+
+// CheckStatus - GET /api/v1/insurance/policies/{policy_id}: data.status, номер и PDF полиса
+func (pr *Provider) CheckStatus(ref osagoProviders.StatusRef) (*osagoProviders.Status, error) {
+	if ref.PolicyID == "" {
+		return nil, fmt.Errorf("нет policy_id для проверки статуса EuroAsia")
+	}
+	url := pr.cfg.EuroasiaAllBaseURL + "/api/v1/insurance/policies/" + ref.PolicyID
+	raw, err := osagoProviders.Request("GET", url, nil, "", "", pr.cfg.EuroasiaAllAPIKey)
+	if err != nil {
+		return nil, err
+	}
+	st := &osagoProviders.Status{
+		Raw:          raw,
+		State:        osagoProviders.NormalizeState(osagoProviders.ExtractString(raw, "data", "status")),
+		PolicyNumber: osagoProviders.ExtractString(raw, "data", "policy_number"),
+		PdfURL:       osagoProviders.ExtractString(raw, "data", "pdf_url"),
+	}
+	if st.PdfURL != "" && (st.State == "" || st.State == osagoProviders.StatePaid) {
+		st.State = osagoProviders.StateIssued
+	}
+	return st, nil
+}
+
+// Quote - data.premium.amount, страховая сумма в data.sum.amount
+func (pr *Provider) Quote(raw interface{}) osagoProviders.Quote {
+	return osagoProviders.Quote{
+		Premium:  osagoProviders.ToInt(osagoProviders.ExtractValue(raw, "data", "premium", "amount")),
+		Coverage: int64(osagoProviders.ExtractInt(raw, "data", "sum", "amount")),
+	}
+}
@@ -0,0 +1,254 @@
+package services
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// AsString converts common JSON-unmarshaled types to string (string/float64/json.Number/int/etc).
+// Useful when external APIs sometimes return numeric IDs that we need as strings (e.g., PINFL).
+func AsString(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(t)
+	case float64:
+		// JSON numbers become float64 when unmarshaled into interface{}
+		// PINFL is an integer-like identifier, so we keep 0 decimals.
+		return strings.TrimSpace(strconv.FormatFloat(t, 'f', 0, 64))
+	case int:
+		return strconv.Itoa(t)
+	case int64:
+		return strconv.FormatInt(t, 10)
+	case json.Number:
+		return strings.TrimSpace(t.String())
+	case bool:
+		return strconv.FormatBool(t)
+	default:
+		return ""
+	}
+}
+
+// ExtractValue - безопасное извлечение interface{} из вложенной структуры (индексы массивов — "0", "1", ...)
+func ExtractValue(data interface{}, path ...string) interface{} {
+	current := data
+	for _, key := range path {
+		if current == nil {
+			return nil
+		}
+		switch v := current.(type) {
+		case map[string]interface{}:
+			current = v[key]
+		case []interface{}:
+			idx, err := strconv.Atoi(key)
+			if err != nil || idx < 0 || idx >= len(v) {
+				return nil
+			}
+			current = v[idx]
+		default:
+			return nil
+		}
+	}
+	return current
+}
+
+// ExtractString - безопасное извлечение строки из вложенной структуры
+func ExtractString(data interface{}, path ...string) string {
+	return AsString(ExtractValue(data, path...))
+}
+
+// ExtractInt - безопасное извлечение int из вложенной структуры
+func ExtractInt(data interface{}, path ...string) int {
+	switch v := ExtractValue(data, path...).(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	case string:
+		if i, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+			return i
+		}
+	}
+	return 0
+}
+
+// ToInt конвертирует число из JSON (float64, int, string) в int; -1 для nil и неизвестных типов
+func ToInt(v interface{}) int {
+	if v == nil {
+		return -1
+	}
+	switch x := v.(type) {
+	case float64:
+		return int(x)
+	case int:
+		return x
+	case int64:
+		return int(x)
+	case string:
+		s := strings.TrimSpace(strings.ReplaceAll(x, " ", ""))
+		n, _ := strconv.Atoi(s)
+		return n
+	}
+	return -1
+}
+
+// ActiveDocument — возвращает серию и номер активного документа владельца из documents[]:
+// приоритет IDMS_RECV_MVD_IDCARD_CITIZEN (биометрический ID), затем IDMS_RECV_CITIZ_DOCUMENTS,
+// затем любой другой документ; если ничего нет — из поля passport.
+// Именно этот документ ожидают Apex и Neo (не поле passport, которое содержит старый паспорт).
+func ActiveDocument(personData interface{}) (series, number string) {
+	pm, ok := personData.(map[string]interface{})
+	if !ok {
+		return "", ""
+	}
+	docs, _ := pm["documents"].([]interface{})
+	for _, docType := range []string{"IDMS_RECV_MVD_IDCARD_CITIZEN", "IDMS_RECV_CITIZ_DOCUMENTS", ""} {
+		if s, n := documentOfType(docs, docType); s != "" {
+			return s, n
+		}
+	}
+	if passport, ok := pm["passport"].(map[string]interface{}); ok {
+		s := AsString(passport["series"])
+		n := AsString(passport["number"])
+		if s != "" && n != "" {
+			return s, n
+		}
+	}
+	return "", ""
+}
+
+// PersonPassport извлекает серию и номер паспорта из person из ответа Find:
+// поддерживает и session.Person (data.passport / data.documents), и vehicle.data.owner.person (без обёртки data).
+// В отличие от ActiveDocument, ID-карта и гражданский паспорт равноправны (берется первый из documents).
+func PersonPassport(person interface{}) (series, number string) {
+	pm, ok := person.(map[string]interface{})
+	if !ok {
+		return "", ""
+	}
+	data, _ := pm["data"].(map[string]interface{})
+	if data == nil {
+		data = pm
+	}
+	docs, _ := data["documents"].([]interface{})
+	for _, d := range docs {
+		doc, _ := d.(map[string]interface{})
+		if doc == nil {
+			continue
+		}
+		dt := AsString(doc["document_type"])
+		if dt != "IDMS_RECV_MVD_IDCARD_CITIZEN" && dt != "IDMS_RECV_CITIZ_DOCUMENTS" {
+			continue
+		}
+		s := AsString(doc["series"])
+		n := AsString(doc["number"])
+		if s != "" && n != "" {
+			return s, n
+		}
+	}
+	if s, n := documentOfType(docs, ""); s != "" {
+		return s, n
+	}
+	if passport, ok := data["passport"].(map[string]interface{}); ok {
+		series = AsString(passport["series"])
+		number = AsString(passport["number"])
+		if series != "" && number != "" {
+			return series, number
+		}
+	}
+	return "", ""
+}
+
+// documentOfType - серия и номер первого документа нужного типа ("" — любого) с заполненными полями
+func documentOfType(docs []interface{}, docType string) (series, number string) {
+	for _, d := range docs {
+		doc, _ := d.(map[string]interface{})
+		if doc == nil {
+			continue
+		}
+		if docType != "" && AsString(doc["document_type"]) != docType {
+			continue
+		}
+		s := AsString(doc["series"])
+		n := AsString(doc["number"])
+		if s != "" && n != "" {
+			return s, n
+		}
+	}
+	return "", ""
+}
+
+// OwnerPinfl - PINFL владельца-физлица из сессии
+func OwnerPinfl(session *Session) string {
+	vehicleData := session.Vehicle
+	if vehicleData == nil {
+		return ""
+	}
+	if ExtractString(vehicleData, "data", "owner", "type") != OwnerPerson {
+		return ""
+	}
+	pinfl := ExtractString(vehicleData, "data", "owner", "person", "pinfls", "0")
+	if pinfl == "" {
+		// Попробовать через external_id
+		pinfl = ExtractString(vehicleData, "data", "owner", "person", "external_id")
+	}
+	return pinfl
+}
+
+// SessionPinfl возвращает PINFL персоны из сессии Find (session.Person или владелец ТС), если паспорт совпадает
+func SessionPinfl(session *Session, series, number string) string {
+	series = strings.TrimSpace(series)
+	number = strings.TrimSpace(number)
+	if session.Person != nil {
+		personSeries, personNumber := PersonPassport(session.Person)
+		if personSeries == "" {
+			personSeries = ExtractString(session.Person, "data", "passport", "series")
+			personNumber = ExtractString(session.Person, "data", "passport", "number")
+		}
+		if personSeries == series && personNumber == number {
+			pinfl := ExtractString(session.Person, "data", "pinfls", "0")
+			if pinfl == "" {
+				pinfl = ExtractString(session.Person, "data", "external_id")
+			}
+			if pinfl != "" {
+				return pinfl
+			}
+		}
+	}
+	if session.Vehicle != nil {
+		v := session.Vehicle
+		ownerSeries := ExtractString(v, "data", "owner", "person", "passport", "series")
+		ownerNumber := ExtractString(v, "data", "owner", "person", "passport", "number")
+		if ownerSeries == "" || ownerNumber == "" {
+			ownerSeries, ownerNumber = PersonPassport(ExtractValue(v, "data", "owner", "person"))
+		}
+		if ownerSeries == series && ownerNumber == number {
+			pinfl := ExtractString(v, "data", "owner", "person", "pinfls", "0")
+			if pinfl == "" {
+				pinfl = ExtractString(v, "data", "owner", "person", "external_id")
+			}
+			if pinfl != "" {
+				return pinfl
+			}
+		}
+	}
+	return ""
+}
+
+// VehicleTypeID переводит external_id типа ТС из Find в id справочников Apex/Trust/Inson.
+// Apex: 1=Легковые, 6=Грузовые, 9=Автобусы, 15=Трамваи/мото. Find: 2=Легковые, 6=Грузовые и т.д.
+func VehicleTypeID(findExternalID int) int {
+	switch findExternalID {
+	case 2:
+		return 1 // Легковые автомобили
+	case 6:
+		return 6 // Грузовые
+	case 9:
+		return 9 // Автобусы
+	case 15:
+		return 15 // Трамваи, мотоциклы и т.д.
+	default:
+		return 1 // по умолчанию легковые
+	}
+}
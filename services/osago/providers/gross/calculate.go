@@ -0,0 +1,63 @@
+package services
+
+import (
+	"fmt"
+
+	osagoProviders "kliro/services/osago/providers"
+)
+
+// Calculate - расчет для Gross Insurance
+func (pr *Provider) Calculate(session *osagoProviders.Session, req *osagoProviders.CalculateRequest) (interface{}, error) {
+	if req.PeriodID == 3 {
+		return nil, fmt.Errorf("Gross Insurance не поддерживает период 20 дней")
+	}
+	vehicleData := session.Vehicle
+
+	gosNumber := osagoProviders.ExtractString(vehicleData, "data", "license_plate")
+	techSeries := osagoProviders.ExtractString(vehicleData, "data", "tech_passport", "series")
+	techNumber := osagoProviders.ExtractString(vehicleData, "data", "tech_passport", "number")
+
+	if gosNumber == "" || techSeries == "" || techNumber == "" {
+		return nil, fmt.Errorf("недостаточно данных о машине")
+	}
+
+	// Тип ТС — только из сессии Find (маппинг external_id → Gross: 2→1, 6→2, 9→3, 15→4)
+	vehicleTypeExternalID := osagoProviders.ExtractInt(vehicleData, "data", "vehicle_type", "external_id")
+	if vehicleTypeExternalID == 0 {
+		vehicleTypeExternalID = osagoProviders.ExtractInt(vehicleData, "data", "vehicle_type", "id")
+	}
+	if vehicleTypeExternalID == 0 {
+		return nil, fmt.Errorf("в сессии нет типа ТС (vehicle_type); выполните find с полными данными по ТС")
+	}
+	var vehicleTypeID int
+	switch vehicleTypeExternalID {
+	case 2:
+		vehicleTypeID = 1 // легковые
+	case 6:
+		vehicleTypeID = 2 // грузовые
+	case 9:
+		vehicleTypeID = 3 // автобусы
+	case 15:
+		vehicleTypeID = 4 // мотоциклы
+	default:
+		return nil, fmt.Errorf("тип ТС из сессии (vehicle_type.external_id=%d) не поддерживается Gross; ожидается 2, 6, 9 или 15", vehicleTypeExternalID)
+	}
+
+	// Driver restriction: true→4, false→1
+	numberDriversID := 1
+	if req.DriverRestriction {
+		numberDriversID = 4
+	}
+
+	requestBody := map[string]interface{}{
+		"gos_number":        gosNumber,
+		"tech_sery":         techSeries,
+		"tech_number":       techNumber,
+		"period_id":         req.PeriodID,
+		"number_drivers_id": numberDriversID,
+		"vehicleTypeId":     vehicleTypeID,
+	}
+
+	url := pr.cfg.GrossBaseURL + "/osago-gross/get-calc-osago"
+	return osagoProviders.Request("POST", url, requestBody, pr.cfg.GrossLogin, pr.cfg.GrossPassword, "")
+}
@@ -0,0 +1,269 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	osagoProviders "kliro/services/osago/providers"
+)
+
+// create - оформление полиса (сырой ответ провайдера)
+func (pr *Provider) create(session *osagoProviders.Session, req *osagoProviders.CreateRequest) (interface{}, error) {
+	// period_id, driver_restriction, amount_uzs — из сессии (calculate_snapshot), если был вызван Calculate; иначе из тела запроса
+	periodID := req.PeriodID
+	driverRestriction := req.DriverRestriction
+	amountUZS := req.AmountUZS
+	if snap := session.CalculateSnapshot; snap != nil {
+		if snap.PeriodID >= 1 && snap.PeriodID <= 3 {
+			periodID = snap.PeriodID
+		}
+		driverRestriction = snap.DriverRestriction
+		if snap.Premiums != nil && snap.Premiums["gross"] > 0 {
+			amountUZS = snap.Premiums["gross"]
+		}
+	}
+	if periodID == 3 {
+		return nil, fmt.Errorf("Gross Insurance не поддерживает период 20 дней")
+	}
+	if req.StartDate == "" {
+		return nil, fmt.Errorf("start_date обязателен для Gross create")
+	}
+	if req.PhoneNumber == "" {
+		return nil, fmt.Errorf("phone_number обязателен для Gross create")
+	}
+	if amountUZS <= 0 {
+		return nil, fmt.Errorf("amount_uzs обязателен для Gross create (вызовите сначала Calculate по этой сессии или передайте amount_uzs в теле)")
+	}
+	// drivers — из сессии (calculate_snapshot), т.к. уже спрашиваются в Calculate; иначе из тела
+	driversList := req.Drivers
+	if snap := session.CalculateSnapshot; snap != nil && len(snap.Drivers) > 0 {
+		driversList = snap.Drivers
+	}
+	// Автозаполнение license_series/license_number из Find API, если переданы только паспорт + дата рождения
+	driversList = osagoProviders.EnrichDrivers(pr.cfg, driversList)
+	if len(driversList) == 0 {
+		return nil, fmt.Errorf("drivers[] обязателен для Gross create (вызовите сначала Calculate с водителями по этой сессии или передайте drivers в теле)")
+	}
+
+	v := session.Vehicle
+	gos := osagoProviders.ExtractString(v, "data", "license_plate")
+	techS := osagoProviders.ExtractString(v, "data", "tech_passport", "series")
+	techN := osagoProviders.ExtractString(v, "data", "tech_passport", "number")
+	if gos == "" || techS == "" || techN == "" {
+		return nil, fmt.Errorf("недостаточно данных о машине")
+	}
+
+	ownerType := osagoProviders.ExtractString(v, "data", "owner", "type")
+
+	numberDriversID := 1
+	if driverRestriction {
+		numberDriversID = 4
+	}
+
+	rep := driversList[0]
+	repPinfl := osagoProviders.ExtractString(session.Person, "data", "pinfls", "0")
+	if repPinfl == "" {
+		repPinfl = osagoProviders.ExtractString(session.Person, "data", "external_id")
+	}
+	if repPinfl == "" && rep.Birthdate != "" {
+		if p, err := osagoProviders.LookupPinflByPassport(pr.cfg, rep.Birthdate, rep.PassportSeries, rep.PassportNumber); err == nil {
+			repPinfl = p
+		}
+	}
+	if repPinfl == "" {
+		repPinfl = "00000000000000"
+	}
+
+	// owner — из сессии Find (vehicle owner, documents/passport), как для Neo/EuroAsia
+	ownerPassS, ownerPassN := "", ""
+	ownerPersonObj := osagoProviders.ExtractValue(v, "data", "owner", "person")
+	if ownerPersonObj != nil {
+		ownerPassS, ownerPassN = osagoProviders.PersonPassport(ownerPersonObj)
+	}
+	if (ownerPassS == "" || ownerPassN == "") && session.Owner != nil && *session.Owner && session.Person != nil {
+		ownerPassS, ownerPassN = osagoProviders.PersonPassport(session.Person)
+	}
+	if ownerPassS == "" || ownerPassN == "" {
+		ownerPassS = osagoProviders.ExtractString(v, "data", "owner", "person", "passport", "series")
+		ownerPassN = osagoProviders.ExtractString(v, "data", "owner", "person", "passport", "number")
+	}
+	if ownerPassS == "" {
+		ownerPassS = rep.PassportSeries
+	}
+	if ownerPassN == "" {
+		ownerPassN = rep.PassportNumber
+	}
+	ownerPinfl := repPinfl
+	if ownerPassS != rep.PassportSeries || ownerPassN != rep.PassportNumber {
+		ownerPinfl = osagoProviders.ExtractString(session.Person, "data", "pinfls", "0")
+		if ownerPinfl == "" {
+			ownerPinfl = osagoProviders.ExtractString(session.Person, "data", "external_id")
+		}
+		if ownerPinfl == "" {
+			ownerPinfl = "00000000000000"
+		}
+	}
+
+	ownerObj := map[string]interface{}{}
+	if ownerType == "organization" {
+		inn := osagoProviders.ExtractString(v, "data", "owner", "organization", "inn")
+		name := osagoProviders.ExtractString(v, "data", "owner", "organization", "name_short")
+		if name == "" {
+			name = osagoProviders.ExtractString(v, "data", "owner", "organization", "name")
+		}
+		ownerObj["organization"] = map[string]interface{}{
+			"inn":  osagoProviders.ToInt(inn),
+			"name": name,
+		}
+	}
+	ownerObj["person"] = map[string]interface{}{
+		"pass_seria":  ownerPassS,
+		"pass_number": ownerPassN,
+		"pinfl":       ownerPinfl,
+	}
+
+	// applicant — из тела (applicant_*) или первый водитель (rep); PINFL должен соответствовать паспорту заявителя (Gross проверяет)
+	applicantPassS, applicantPassN := strings.TrimSpace(req.ApplicantPassportSeries), strings.TrimSpace(req.ApplicantPassportNumber)
+	if applicantPassS == "" {
+		applicantPassS = rep.PassportSeries
+	}
+	if applicantPassN == "" {
+		applicantPassN = rep.PassportNumber
+	}
+	applicantPinfl := repPinfl
+	if applicantPassS != rep.PassportSeries || applicantPassN != rep.PassportNumber {
+		// Заявитель — другой человек: ищем его PINFL по паспорту (session.Person или lookup по EuroAsia)
+		applicantPinfl = ""
+		if session.Person != nil {
+			ps, pn := osagoProviders.PersonPassport(session.Person)
+			if ps == "" {
+				ps = osagoProviders.ExtractString(session.Person, "data", "passport", "series")
+				pn = osagoProviders.ExtractString(session.Person, "data", "passport", "number")
+			}
+			if ps == applicantPassS && pn == applicantPassN {
+				applicantPinfl = osagoProviders.ExtractString(session.Person, "data", "pinfls", "0")
+				if applicantPinfl == "" {
+					applicantPinfl = osagoProviders.ExtractString(session.Person, "data", "external_id")
+				}
+			}
+		}
+		if applicantPinfl == "" && req.ApplicantBirthdate != "" {
+			if p, err := osagoProviders.LookupPinflByPassport(pr.cfg, req.ApplicantBirthdate, applicantPassS, applicantPassN); err == nil {
+				applicantPinfl = p
+			}
+		}
+		if applicantPinfl == "" {
+			applicantPinfl = "00000000000000"
+		}
+	}
+	applicantBirthdate := strings.TrimSpace(req.ApplicantBirthdate)
+	if applicantBirthdate == "" {
+		applicantBirthdate = rep.Birthdate
+	}
+	// Gross API: заявитель должен указать либо ПИНФЛ, либо дату рождения (только одно из них)
+	applicantBirthdateGross := applicantBirthdate
+	if idx := strings.Index(applicantBirthdate, "T"); idx > 0 {
+		applicantBirthdateGross = strings.TrimSpace(applicantBirthdate[:idx])
+	}
+	applicantObj := map[string]interface{}{
+		"pass_seria":       applicantPassS,
+		"pass_number":      applicantPassN,
+		"is_driver":        req.ApplicantIsDriver != nil && *req.ApplicantIsDriver,
+		"licenseSeria":     rep.LicenseSeries,
+		"licenseNumber":    rep.LicenseNumber,
+		"licenseIssueDate": osagoProviders.FormatDateDDMMYYYY(rep.LicenseIssueDate),
+		"relative":         relative(rep.Relative),
+	}
+	if applicantPinfl != "" && applicantPinfl != "00000000000000" {
+		applicantObj["pinfl"] = applicantPinfl
+	} else {
+		applicantObj["birthdate"] = applicantBirthdateGross
+	}
+
+	var drivers []map[string]interface{}
+	if driverRestriction {
+		for _, d := range driversList {
+			licenseIssue := d.LicenseIssueDate
+			if licenseIssue == "" {
+				licenseIssue = "2020-01-01"
+			}
+			drivers = append(drivers, map[string]interface{}{
+				"passport__seria":  d.PassportSeries,
+				"passport__number": d.PassportNumber,
+				"driver_birthday":  osagoProviders.FormatDateDDMMYYYY(d.Birthdate),
+				"licenseSeria":     d.LicenseSeries,
+				"licenseNumber":    d.LicenseNumber,
+				"licenseIssueDate": osagoProviders.FormatDateDDMMYYYY(licenseIssue),
+				"relative":         relative(d.Relative),
+			})
+		}
+	} else {
+		licenseIssue := rep.LicenseIssueDate
+		if licenseIssue == "" {
+			licenseIssue = "2020-01-01"
+		}
+		drivers = []map[string]interface{}{
+			{
+				"passport__seria":  rep.PassportSeries,
+				"passport__number": rep.PassportNumber,
+				"driver_birthday":  osagoProviders.FormatDateDDMMYYYY(rep.Birthdate),
+				"licenseSeria":     rep.LicenseSeries,
+				"licenseNumber":    rep.LicenseNumber,
+				"licenseIssueDate": osagoProviders.FormatDateDDMMYYYY(licenseIssue),
+				"relative":         relative(rep.Relative),
+			},
+		}
+	}
+
+	body := map[string]interface{}{
+		"details": map[string]interface{}{
+			"start_date":        osagoProviders.FormatDateDDMMYYYY(req.StartDate),
+			"period_id":         periodID,
+			"number_drivers_id": numberDriversID,
+			"phone_number":      req.PhoneNumber,
+			"amount_uzs":        amountUZS,
+		},
+		"techPassport": map[string]interface{}{
+			"govNumber":   gos,
+			"tech_sery":   techS,
+			"tech_number": techN,
+		},
+		"owner":     ownerObj,
+		"applicant": applicantObj,
+		"drivers":   drivers,
+	}
+
+	url := pr.cfg.GrossBaseURL + "/osago-gross/save-policy-manual"
+	return osagoProviders.RequestObject("POST", url, body, pr.cfg.GrossLogin, pr.cfg.GrossPassword, "")
+}
+
+// relative конвертирует наше значение relative в формат Gross.
+// Gross порядок: 1=Отец, 2=Старший брат, 3=Младший брат, 4=Жена, 5=Мать, 6=Муж, 7=Сын, 8=Дочь, 9=Старшая сестра, 10=Младшая сестра
+func relative(rel int) int {
+	switch rel {
+	case 0:
+		return 0 // не родственник
+	case 1:
+		return 1 // Отец
+	case 2:
+		return 5 // Мать
+	case 3:
+		return 6 // Муж
+	case 4:
+		return 4 // Жена
+	case 5:
+		return 7 // Сын
+	case 6:
+		return 8 // Дочь
+	case 7:
+		return 2 // Старший брат
+	case 8:
+		return 3 // Младший брат
+	case 9:
+		return 9 // Старшая сестра
+	case 10:
+		return 10 // Младшая сестра
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"kliro/config"
+	osagoProviders "kliro/services/osago/providers"
+)
+
+// Provider - Gross Insurance: только физлица, периоды 12 и 6 месяцев
+type Provider struct {
+	cfg *config.Config
+}
+
+func New(cfg *config.Config) *Provider {
+	return &Provider{cfg: cfg}
+}
+
+func (pr *Provider) Name() string { return "gross" }
+
+func (pr *Provider) Periods() []int { return []int{1, 2} }
+
+func (pr *Provider) OwnerTypes() []string { return []string{osagoProviders.OwnerPerson} }
+
+// Create - оформление полиса; ссылки оплаты Gross: response.click.url, response.payme.url
+func (pr *Provider) Create(session *osagoProviders.Session, req *osagoProviders.CreateRequest) (interface{}, error) {
+	r, err := pr.create(session, req)
+	if err != nil {
+		return nil, err
+	}
+	resp := osagoProviders.ExtractValue(r, "response")
+	return osagoProviders.WithPaymentURLs(r, osagoProviders.ExtractString(resp, "click", "url"), osagoProviders.ExtractString(resp, "payme", "url")), nil
+}
+
+func (pr *Provider) SupportsStatus() bool { return false }
+
+func (pr *Provider) CheckStatus(osagoProviders.StatusRef) (*osagoProviders.Status, error) {
+	return nil, osagoProviders.ErrStatusUnsupported
+}
+
+// Quote - премия в response.amount_uzs, страховую сумму не возвращает
+func (pr *Provider) Quote(raw interface{}) osagoProviders.Quote {
+	return osagoProviders.Quote{Premium: osagoProviders.ToInt(osagoProviders.ExtractValue(raw, "response", "amount_uzs"))}
+}
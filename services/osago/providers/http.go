@@ -0,0 +1,202 @@
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"kliro/config"
+)
+
+var (
+	calcClient   = &http.Client{Timeout: 30 * time.Second}
+	createClient = &http.Client{Timeout: 40 * time.Second}
+)
+
+// Request - запрос расчета к провайдеру (Basic или Authorization token).
+// Ответ может быть не объектом (массив, строка) — разбирает адаптер.
+func Request(method, url string, bodyData interface{}, login, password, apiKey string) (interface{}, error) {
+	respBody, err := do(calcClient, method, url, bodyData, login, password, apiKey, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var result interface{}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		// HTML или не-JSON ответ — не возвращаем сырой HTML в теле
+		if len(respBody) > 0 && (respBody[0] == '<' || !json.Valid(respBody)) {
+			return nil, fmt.Errorf("ожидался JSON, получен не-JSON ответ (возможно HTML)")
+		}
+		return string(respBody), nil
+	}
+	return result, nil
+}
+
+// RequestObject - запрос оформления к провайдеру: тело логируется, ответ обязан быть JSON-объектом
+func RequestObject(method, url string, bodyData interface{}, login, password, apiKey string) (map[string]interface{}, error) {
+	if bodyData != nil {
+		// Логируем фактический запрос create для отладки (URL + body)
+		log.Printf("[OSAGO_CREATE] %s %s", method, url)
+		bodyForLog, _ := json.MarshalIndent(bodyData, "", "  ")
+		log.Printf("[OSAGO_CREATE] Request body:\n%s", string(bodyForLog))
+	}
+	respBody, err := do(createClient, method, url, bodyData, login, password, apiKey, true)
+	if err != nil {
+		return nil, err
+	}
+	var out map[string]interface{}
+	if err := json.Unmarshal(respBody, &out); err != nil {
+		return nil, fmt.Errorf("expected JSON, got: %s", strings.TrimSpace(string(respBody)))
+	}
+	return out, nil
+}
+
+func do(cl *http.Client, method, url string, bodyData interface{}, login, password, apiKey string, ru bool) ([]byte, error) {
+	var body io.Reader
+	if bodyData != nil {
+		b, err := json.Marshal(bodyData)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewBuffer(b)
+	}
+
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	if ru {
+		req.Header.Set("Accept-Language", "ru")
+	}
+	// Авторизация
+	if apiKey != "" {
+		req.Header.Set("Authorization", apiKey)
+	} else if login != "" && password != "" {
+		auth := base64.StdEncoding.EncodeToString([]byte(login + ":" + password))
+		req.Header.Set("Authorization", "Basic "+auth)
+	}
+
+	resp, err := cl.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return respBody, nil
+}
+
+// LookupPersonByPassport возвращает объект data персоны из EuroAsia API (find-by-birthdate):
+// ФИО, паспорт, документы — для провайдеров, которым нужны данные не из сессии
+func LookupPersonByPassport(cfg *config.Config, birthdateYYYYMMDD, passportSeries, passportNumber string) (map[string]interface{}, error) {
+	url := cfg.EuroasiaAllBaseURL + "/api/v1/insurance/persons/find-by-birthdate"
+	reqBody := map[string]string{
+		"birthdate":       strings.TrimSpace(strings.Split(birthdateYYYYMMDD, "T")[0]),
+		"passport_series": strings.TrimSpace(passportSeries),
+		"passport_number": strings.TrimSpace(passportNumber),
+	}
+	resp, err := RequestObject("POST", url, reqBody, "", "", cfg.EuroasiaAllAPIKey)
+	if err != nil {
+		return nil, err
+	}
+	data, _ := ExtractValue(resp, "data").(map[string]interface{})
+	return data, nil
+}
+
+// LookupPinflByPassport получает PINFL по паспорту+дате рождения через EuroAsia Find API.
+// Нужно там, где провайдер требует PINFL, а в сессии (например, юрлица) его нет.
+func LookupPinflByPassport(cfg *config.Config, birthdateYYYYMMDD, passportSeries, passportNumber string) (string, error) {
+	data, err := LookupPersonByPassport(cfg, birthdateYYYYMMDD, passportSeries, passportNumber)
+	if err != nil {
+		return "", err
+	}
+	pinfl := ExtractString(data, "pinfls", "0")
+	if pinfl == "" {
+		pinfl = ExtractString(data, "external_id")
+	}
+	return pinfl, nil
+}
+
+// FindPinfl возвращает PINFL персоны: из сессии при совпадении паспорта, иначе — запрос в EuroAsia
+func FindPinfl(cfg *config.Config, session *Session, series, number, birthdate string) string {
+	if pinfl := SessionPinfl(session, series, number); pinfl != "" {
+		return pinfl
+	}
+	if birthdate != "" {
+		if p, err := LookupPinflByPassport(cfg, ToYYYYMMDD(birthdate), strings.TrimSpace(series), strings.TrimSpace(number)); err == nil && p != "" {
+			return p
+		}
+	}
+	return ""
+}
+
+// EnrichDrivers автоматически заполняет license_series и license_number для водителей из Find API,
+// если переданы только паспорт (серия, номер) и дата рождения, но нет данных водительского удостоверения.
+// Использует EuroAsia person API (find-by-birthdate) и извлекает IDMS_RECV_DRIVERS_LICENSE из documents.
+func EnrichDrivers(cfg *config.Config, drivers []Driver) []Driver {
+	if len(drivers) == 0 {
+		return drivers
+	}
+	enriched := make([]Driver, len(drivers))
+	for i, d := range drivers {
+		enriched[i] = d
+		if d.PassportSeries == "" || d.PassportNumber == "" || d.Birthdate == "" {
+			continue
+		}
+		if d.LicenseSeries != "" && d.LicenseNumber != "" {
+			continue
+		}
+		personData, err := LookupPersonByPassport(cfg, ToYYYYMMDD(d.Birthdate), d.PassportSeries, d.PassportNumber)
+		if err != nil || personData == nil {
+			continue
+		}
+		docs, _ := ExtractValue(personData, "documents").([]interface{})
+		for _, doc := range docs {
+			docMap, _ := doc.(map[string]interface{})
+			if docMap == nil || ExtractString(docMap, "document_type") != "IDMS_RECV_DRIVERS_LICENSE" {
+				continue
+			}
+			licenseSeries := ExtractString(docMap, "series")
+			licenseNumber := ExtractString(docMap, "number")
+			if licenseSeries != "" && licenseNumber != "" {
+				enriched[i].LicenseSeries = licenseSeries
+				enriched[i].LicenseNumber = licenseNumber
+				if issueDate := ExtractString(docMap, "issue_date"); issueDate != "" && enriched[i].LicenseIssueDate == "" {
+					enriched[i].LicenseIssueDate = ToYYYYMMDD(issueDate)
+				}
+				break
+			}
+		}
+	}
+	return enriched
+}
+
+// WithPaymentURLs добавляет в ответ create единые поля click_url и payme_url для фронта.
+// Сырой ответ не меняется — копируется и дополняется; не-объект возвращается как есть.
+func WithPaymentURLs(raw interface{}, clickURL, paymeURL string) interface{} {
+	m, ok := raw.(map[string]interface{})
+	if !ok {
+		return raw
+	}
+	out := make(map[string]interface{}, len(m)+2)
+	for k, v := range m {
+		out[k] = v
+	}
+	out["click_url"] = clickURL
+	out["payme_url"] = paymeURL
+	return out
+}
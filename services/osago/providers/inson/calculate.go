@@ -0,0 +1,110 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	osagoProviders "kliro/services/osago/providers"
+)
+
+// Calculate - расчет ОСАГО для Inson Insurance.
+// Inson принимает vehicleTypeId, period (6/12), governmentNumber, drivers (массив PINFL) и owner (для физлиц).
+// Auth: HTTP Basic.
+func (pr *Provider) Calculate(session *osagoProviders.Session, req *osagoProviders.CalculateRequest) (interface{}, error) {
+	if req.PeriodID == 3 {
+		return nil, fmt.Errorf("Inson не поддерживает период 20 дней")
+	}
+
+	vehicleData := session.Vehicle
+	gosNumber := osagoProviders.ExtractString(vehicleData, "data", "license_plate")
+	if gosNumber == "" {
+		return nil, fmt.Errorf("не найден госномер автомобиля")
+	}
+
+	// period: 1→12 мес, 2→6 мес (в месяцах для API)
+	period := 12
+	if req.PeriodID == 2 {
+		period = 6
+	}
+
+	// Тип ТС — только из сессии Find (Inson: 1=легковой, 6, 9, 15; в Find легковые часто external_id=2)
+	vehicleTypeExternalID := osagoProviders.ExtractInt(vehicleData, "data", "vehicle_type", "external_id")
+	if vehicleTypeExternalID == 0 {
+		vehicleTypeExternalID = osagoProviders.ExtractInt(vehicleData, "data", "vehicle_type", "id")
+	}
+	if vehicleTypeExternalID == 0 {
+		return nil, fmt.Errorf("в сессии нет типа ТС (vehicle_type); выполните find с полными данными по ТС")
+	}
+	vehicleTypeID := vehicleTypeExternalID
+	if vehicleTypeExternalID == 2 {
+		vehicleTypeID = 1 // легковые
+	}
+	if vehicleTypeID != 1 && vehicleTypeID != 6 && vehicleTypeID != 9 && vehicleTypeID != 15 {
+		return nil, fmt.Errorf("тип ТС из сессии (vehicle_type=%d) не поддерживается Inson; ожидается 1, 6, 9 или 15", vehicleTypeID)
+	}
+
+	ownerType := osagoProviders.ExtractString(vehicleData, "data", "owner", "type")
+
+	log.Printf("[Inson calc] extracted from session: gos_number=%q owner_type=%q period_id=%d period_sent=%d driver_restriction=%v", gosNumber, ownerType, req.PeriodID, period, req.DriverRestriction)
+
+	// Список водителей (PINFLs). По документации Inson: при driverNumberRestricted=false массив drivers должен быть пустым [].
+	var driverPinfls []string
+	if req.DriverRestriction {
+		for _, driver := range req.Drivers {
+			pinfl := osagoProviders.SessionPinfl(session, driver.PassportSeries, driver.PassportNumber)
+			if pinfl == "" && driver.Birthdate != "" {
+				if p, err := osagoProviders.LookupPinflByPassport(pr.cfg, osagoProviders.FormatDateYYYYMMDD(driver.Birthdate), driver.PassportSeries, driver.PassportNumber); err == nil {
+					pinfl = p
+				}
+			}
+			if pinfl != "" {
+				driverPinfls = append(driverPinfls, pinfl)
+			}
+		}
+	}
+	// Иначе driverPinfls остаётся nil → в JSON "null"; Inson принимает и [], и отсутствие. Явно передаём [] для соответствия доке.
+	if driverPinfls == nil {
+		driverPinfls = []string{}
+	}
+
+	requestBody := map[string]interface{}{
+		"vehicleTypeId":          vehicleTypeID,
+		"period":                 period,
+		"governmentNumber":       gosNumber,
+		"drivers":                driverPinfls,
+		"driverNumberRestricted": req.DriverRestriction,
+	}
+
+	// owner: только для физлиц; для юрлиц не передаётся
+	if ownerType == "person" {
+		ownerPinfl := osagoProviders.OwnerPinfl(session)
+		ownerPersonObj := osagoProviders.ExtractValue(vehicleData, "data", "owner", "person")
+		passportSeries, passportNumber := osagoProviders.ActiveDocument(ownerPersonObj)
+		if passportSeries == "" {
+			passportSeries = osagoProviders.ExtractString(vehicleData, "data", "owner", "person", "passport", "series")
+			passportNumber = osagoProviders.ExtractString(vehicleData, "data", "owner", "person", "passport", "number")
+		}
+		if ownerPinfl != "" && passportSeries != "" && passportNumber != "" {
+			requestBody["owner"] = map[string]interface{}{
+				"pinfl":          ownerPinfl,
+				"passportSeries": passportSeries,
+				"passportNumber": passportNumber,
+			}
+		}
+	}
+
+	insonBodyRaw, _ := json.Marshal(requestBody)
+	log.Printf("[Inson calc] → request body (raw JSON): %s", insonBodyRaw)
+
+	url := pr.cfg.InsonBaseURL + "/api/v2/osago/calculator"
+	result, err := osagoProviders.Request("POST", url, requestBody, pr.cfg.InsonLogin, pr.cfg.InsonPassword, "")
+
+	if err != nil {
+		log.Printf("[Inson calc] ← error: %v", err)
+	} else {
+		respJSON, _ := json.Marshal(result)
+		log.Printf("[Inson calc] ← response: %s", respJSON)
+	}
+	return result, err
+}
@@ -0,0 +1,340 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	osagoProviders "kliro/services/osago/providers"
+)
+
+// create — оформление ОСАГО через Inson Insurance (двухшаговый процесс).
+// Шаг 1: POST /api/v2/osago/contract → contractId
+// Шаг 2: PUT  /api/v1/osago/contract/payment → policyUrl
+// Auth: HTTP Basic. Для резидентов РУз достаточно паспортных данных — ФИО и PINFL система подтягивает сама.
+func (pr *Provider) create(session *osagoProviders.Session, req *osagoProviders.CreateRequest) (interface{}, error) {
+	if req.PeriodID == 3 {
+		return nil, fmt.Errorf("Inson не поддерживает период 20 дней")
+	}
+	if req.StartDate == "" {
+		return nil, fmt.Errorf("start_date обязателен для Inson create")
+	}
+	if req.PhoneNumber == "" {
+		return nil, fmt.Errorf("phone_number обязателен для Inson create")
+	}
+
+	// startDate должен быть сегодня или в будущем (Inson 422)
+	startDate := osagoProviders.ToYYYYMMDD(req.StartDate)
+	if startDate != "" {
+		if t, err := time.Parse("2006-01-02", startDate); err == nil && t.Before(time.Now().Truncate(24*time.Hour)) {
+			startDate = time.Now().Format("2006-01-02")
+		}
+	} else {
+		startDate = time.Now().Format("2006-01-02")
+	}
+
+	v := session.Vehicle
+	ownerType := osagoProviders.ExtractString(v, "data", "owner", "type")
+
+	// period: 1→12 мес, 2→6 мес
+	period := 12
+	if req.PeriodID == 2 {
+		period = 6
+	}
+	// Если period_id не передан — берём из сессии (calculate_snapshot)
+	if req.PeriodID == 0 {
+		if snap := session.CalculateSnapshot; snap != nil && snap.PeriodID >= 1 && snap.PeriodID <= 2 {
+			if snap.PeriodID == 2 {
+				period = 6
+			}
+		}
+	}
+
+	gosNumber := osagoProviders.ExtractString(v, "data", "license_plate")
+	techSeries := osagoProviders.ExtractString(v, "data", "tech_passport", "series")
+	techNumber := osagoProviders.ExtractString(v, "data", "tech_passport", "number")
+	if gosNumber == "" {
+		return nil, fmt.Errorf("не найден госномер автомобиля")
+	}
+
+	// Drivers list: те же водители, что и в Calculate. Приоритет — список с большим числом водителей (из тела create или из snapshot после calculate).
+	driverRestriction := req.DriverRestriction
+	driversList := req.Drivers
+	if snap := session.CalculateSnapshot; snap != nil {
+		driverRestriction = snap.DriverRestriction
+		if len(snap.Drivers) > 0 {
+			if len(snap.Drivers) >= len(req.Drivers) {
+				driversList = snap.Drivers
+			}
+			// иначе оставляем req.Drivers (в теле передали явно, например 2 водителя)
+		}
+	}
+	driversList = osagoProviders.EnrichDrivers(pr.cfg, driversList)
+
+	// Строим applicant (страхователь)
+	var applicant map[string]interface{}
+	if ownerType == "organization" {
+		inn := osagoProviders.ExtractString(v, "data", "owner", "organization", "inn")
+		if inn == "" {
+			inn = osagoProviders.ExtractString(session.Organization, "data", "inn")
+		}
+		orgName := osagoProviders.ExtractString(v, "data", "owner", "organization", "name")
+		if orgName == "" {
+			orgName = osagoProviders.ExtractString(session.Organization, "data", "name")
+		}
+		if orgName == "" {
+			orgName = "N"
+		}
+		applicant = map[string]interface{}{
+			"organization": map[string]interface{}{
+				"inn":  inn,
+				"name": orgName,
+			},
+			"residentType":  1,
+			"citizenshipId": 1,
+			"phoneNumber":   req.PhoneNumber,
+			"isOwner":       true,
+		}
+	} else {
+		// Физлицо: паспорт, PINFL, birthDate и телефон (Inson требует для applicant.person)
+		ownerPersonObj := osagoProviders.ExtractValue(v, "data", "owner", "person")
+		passSeries, passNumber := osagoProviders.PersonPassport(ownerPersonObj)
+		if passSeries == "" {
+			passSeries = osagoProviders.ExtractString(v, "data", "owner", "person", "passport", "series")
+			passNumber = osagoProviders.ExtractString(v, "data", "owner", "person", "passport", "number")
+		}
+		if passSeries == "" && session.Person != nil {
+			passSeries, passNumber = osagoProviders.PersonPassport(session.Person)
+		}
+		ownerBirth := osagoProviders.ExtractString(v, "data", "owner", "person", "birthdate")
+		if ownerBirth == "" && session.Person != nil {
+			ownerBirth = osagoProviders.ExtractString(session.Person, "data", "birthdate")
+		}
+		ownerBirth = osagoProviders.ToYYYYMMDD(ownerBirth)
+		if ownerBirth == "" {
+			ownerBirth = strings.TrimSpace(req.ApplicantBirthdate)
+			ownerBirth = osagoProviders.ToYYYYMMDD(ownerBirth)
+		}
+		ownerPinfl := osagoProviders.ExtractString(v, "data", "owner", "person", "pinfls", "0")
+		if ownerPinfl == "" {
+			ownerPinfl = osagoProviders.ExtractString(v, "data", "owner", "person", "external_id")
+		}
+		ownerIssuedBy, ownerIssueDate := "ИИБ", "2020-01-01"
+		ownerFirst, ownerLast, ownerMiddle := "N", "N", "N"
+		ownerAddress := ""
+		ownerRegionID, ownerDistrictID := 0, 0
+		if ownerPinfl == "" && passSeries != "" && passNumber != "" && ownerBirth != "" {
+			ownerPinfl, _ = osagoProviders.LookupPinflByPassport(pr.cfg, ownerBirth, passSeries, passNumber)
+		}
+		if passSeries != "" && passNumber != "" && ownerBirth != "" {
+			if personData, err := osagoProviders.LookupPersonByPassport(pr.cfg, ownerBirth, passSeries, passNumber); err == nil && personData != nil {
+				if by := osagoProviders.ExtractString(personData, "passport", "issued_by"); by != "" {
+					ownerIssuedBy = by
+				}
+				if iss := osagoProviders.ExtractString(personData, "passport", "issued_at"); iss != "" {
+					ownerIssueDate = osagoProviders.ToYYYYMMDD(iss)
+				}
+				if fn := osagoProviders.ExtractString(personData, "first_name"); fn != "" {
+					ownerFirst = fn
+				}
+				if ln := osagoProviders.ExtractString(personData, "last_name"); ln != "" {
+					ownerLast = ln
+				}
+				if mn := osagoProviders.ExtractString(personData, "middle_name"); mn != "" {
+					ownerMiddle = mn
+				}
+				if addr := osagoProviders.ExtractString(personData, "address"); addr != "" {
+					ownerAddress = addr
+				}
+				if rid := osagoProviders.ExtractInt(v, "data", "owner", "person", "region", "external_id"); rid > 0 {
+					ownerRegionID = rid
+				}
+				if did := osagoProviders.ExtractInt(v, "data", "owner", "person", "district", "external_id"); did > 0 {
+					ownerDistrictID = did
+				}
+			}
+		}
+		if ownerAddress == "" {
+			ownerAddress = "N"
+		}
+		passportData := map[string]interface{}{
+			"series":    passSeries,
+			"number":    passNumber,
+			"issuedBy":  ownerIssuedBy,
+			"issueDate": ownerIssueDate,
+		}
+		if ownerPinfl != "" {
+			passportData["pinfl"] = ownerPinfl
+		}
+		applicantPerson := map[string]interface{}{
+			"passportData": passportData,
+			"fullName": map[string]interface{}{
+				"firstname":  ownerFirst,
+				"lastname":   ownerLast,
+				"middlename": ownerMiddle,
+			},
+			"gender": 1,
+		}
+		if ownerBirth != "" {
+			applicantPerson["birthDate"] = ownerBirth
+		}
+		if ownerAddress != "" {
+			applicantPerson["address"] = ownerAddress
+		}
+		applicantPerson["regionId"] = ownerRegionID
+		applicantPerson["districtId"] = ownerDistrictID
+		phoneNum := strings.TrimSpace(req.PhoneNumber)
+		phoneNum = strings.TrimPrefix(phoneNum, "+")
+		phoneNum = strings.ReplaceAll(phoneNum, " ", "")
+		phoneNum = strings.ReplaceAll(phoneNum, "-", "")
+		if phoneNum != "" && len(phoneNum) < 12 && !strings.HasPrefix(phoneNum, "998") {
+			phoneNum = "998" + strings.TrimLeft(phoneNum, "8")
+		}
+		applicant = map[string]interface{}{
+			"person":        applicantPerson,
+			"residentType":  1,
+			"citizenshipId": 1,
+			"phoneNumber":   phoneNum,
+			"isOwner":       true,
+		}
+	}
+
+	// Обрабатываем drivers (только при ограниченном полисе). Inson требует: birthDate, passportData.pinfl, licenseSeries (2–5 символов), licenseNumber (6–10 символов).
+	var insonDrivers []map[string]interface{}
+	if driverRestriction && len(driversList) > 0 {
+		for _, d := range driversList {
+			birthNorm := osagoProviders.ToYYYYMMDD(d.Birthdate)
+			if birthNorm == "" {
+				return nil, fmt.Errorf("для Inson у каждого водителя обязательна дата рождения (birthdate); водитель: %s %s", d.PassportSeries, d.PassportNumber)
+			}
+			pinfl := osagoProviders.FindPinfl(pr.cfg, session, d.PassportSeries, d.PassportNumber, d.Birthdate)
+			if pinfl == "" && birthNorm != "" {
+				pinfl, _ = osagoProviders.LookupPinflByPassport(pr.cfg, birthNorm, d.PassportSeries, d.PassportNumber)
+			}
+			if pinfl == "" {
+				return nil, fmt.Errorf("для Inson не удалось получить PINFL водителя (серия %s, номер %s); выполните find по человеку", d.PassportSeries, d.PassportNumber)
+			}
+			licSeries := strings.TrimSpace(d.LicenseSeries)
+			licNumber := strings.TrimSpace(d.LicenseNumber)
+			// Inson: licenseSeries 2–5 символов [A-Za-z0-9], licenseNumber 6–10 символов
+			if len(licSeries) < 2 || len(licSeries) > 5 {
+				licSeries = "AA"
+			}
+			if len(licNumber) < 6 || len(licNumber) > 10 {
+				licNumber = "000000"
+			}
+			licenseIssueDate := osagoProviders.ToYYYYMMDD(d.LicenseIssueDate)
+			dIssuedBy, dIssueDate := "Toshkent shahar IIB", "2020-01-01"
+			dFirst, dLast, dMiddle := "N", "N", "N"
+			if personData, err := osagoProviders.LookupPersonByPassport(pr.cfg, birthNorm, d.PassportSeries, d.PassportNumber); err == nil && personData != nil {
+				if by := osagoProviders.ExtractString(personData, "passport", "issued_by"); by != "" {
+					dIssuedBy = by
+				}
+				if iss := osagoProviders.ExtractString(personData, "passport", "issued_at"); iss != "" {
+					dIssueDate = osagoProviders.ToYYYYMMDD(iss)
+				}
+				if fn := osagoProviders.ExtractString(personData, "first_name"); fn != "" {
+					dFirst = fn
+				}
+				if ln := osagoProviders.ExtractString(personData, "last_name"); ln != "" {
+					dLast = ln
+				}
+				if mn := osagoProviders.ExtractString(personData, "middle_name"); mn != "" {
+					dMiddle = mn
+				}
+			}
+			driverObj := map[string]interface{}{
+				"residentType": 1,
+				"birthDate":    birthNorm,
+				"relative":     d.Relative,
+				"passportData": map[string]interface{}{
+					"pinfl":     pinfl,
+					"series":    d.PassportSeries,
+					"number":    d.PassportNumber,
+					"issuedBy":  dIssuedBy,
+					"issueDate": dIssueDate,
+				},
+				"fullName": map[string]interface{}{
+					"firstname":  dFirst,
+					"lastname":   dLast,
+					"middlename": dMiddle,
+				},
+				"licenseSeries":    licSeries,
+				"licenseNumber":    licNumber,
+				"licenseIssueDate": licenseIssueDate,
+			}
+			if licenseIssueDate == "" {
+				driverObj["licenseIssueDate"] = "2020-01-01"
+			}
+			insonDrivers = append(insonDrivers, driverObj)
+		}
+	}
+	if insonDrivers == nil {
+		insonDrivers = []map[string]interface{}{}
+	}
+
+	// Рабочая форма Inson: applicant, details, vehicle (techPassport только series+number), drivers; owner не передаём
+	contractBody := map[string]interface{}{
+		"applicant": applicant,
+		"details": map[string]interface{}{
+			"startDate":              startDate,
+			"period":                 period,
+			"driverNumberRestricted": driverRestriction,
+		},
+		"vehicle": map[string]interface{}{
+			"governmentNumber": gosNumber,
+			"techPassport": map[string]interface{}{
+				"series": techSeries,
+				"number": techNumber,
+			},
+		},
+		"drivers": insonDrivers,
+	}
+
+	contractURL := pr.cfg.InsonBaseURL + "/api/v2/osago/contract"
+	contractResp, err := osagoProviders.RequestObject("POST", contractURL, contractBody, pr.cfg.InsonLogin, pr.cfg.InsonPassword, "")
+	if err != nil {
+		return nil, fmt.Errorf("Inson contract create failed: %w", err)
+	}
+
+	// Извлекаем contractId из ответа {"data": {"contractId": 123456, ...}}
+	contractIDRaw := osagoProviders.ExtractValue(contractResp, "data", "contractId")
+	if contractIDRaw == nil {
+		// contractId может быть на верхнем уровне
+		contractIDRaw = contractResp["contractId"]
+	}
+	if contractIDRaw == nil {
+		// Контракт создан, но ID не получен — возвращаем ответ как есть
+		return contractResp, nil
+	}
+
+	var contractIDInt int
+	switch cid := contractIDRaw.(type) {
+	case float64:
+		contractIDInt = int(cid)
+	case int:
+		contractIDInt = cid
+	}
+
+	// Шаг 2: финализируем (выпускаем) полис
+	paymentBody := map[string]interface{}{
+		"contractId": contractIDInt,
+	}
+	paymentURL := pr.cfg.InsonBaseURL + "/api/v1/osago/contract/payment"
+	paymentResp, err := osagoProviders.RequestObject("PUT", paymentURL, paymentBody, pr.cfg.InsonLogin, pr.cfg.InsonPassword, "")
+	if err != nil {
+		// Если финализация не удалась — возвращаем то что есть с contractId
+		log.Printf("[Inson create] payment step failed: %v", err)
+		return map[string]interface{}{
+			"contract": contractResp,
+			"payment":  nil,
+			"error":    "payment step failed: " + err.Error(),
+		}, nil
+	}
+
+	return map[string]interface{}{
+		"contract": contractResp,
+		"payment":  paymentResp,
+	}, nil
+}
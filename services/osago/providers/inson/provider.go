@@ -0,0 +1,61 @@
+package services
+
+import (
+	"fmt"
+	neturl "net/url"
+	"strings"
+
+	"kliro/config"
+	osagoProviders "kliro/services/osago/providers"
+)
+
+// Provider - Inson Insurance: физлица и юрлица, периоды 12 и 6 месяцев
+type Provider struct {
+	cfg *config.Config
+}
+
+func New(cfg *config.Config) *Provider {
+	return &Provider{cfg: cfg}
+}
+
+func (pr *Provider) Name() string { return "inson" }
+
+func (pr *Provider) Periods() []int { return []int{1, 2} }
+
+func (pr *Provider) OwnerTypes() []string {
+	return []string{osagoProviders.OwnerPerson, osagoProviders.OwnerOrganization}
+}
+
+// Create - оформление полиса (contract + payment); ссылок оплаты Inson не выдает, ответ как есть
+func (pr *Provider) Create(session *osagoProviders.Session, req *osagoProviders.CreateRequest) (interface{}, error) {
+	return pr.create(session, req)
+}
+
+func (pr *Provider) SupportsStatus() bool { return true }
+
+// CheckStatus - GET /api/v1/osago/contract/policy-check?contractId=: полис выпущен, если есть policyUrl
+func (pr *Provider) CheckStatus(ref osagoProviders.StatusRef) (*osagoProviders.Status, error) {
+	if ref.OrderID == "" {
+		return nil, fmt.Errorf("нет contractId для проверки статуса Inson")
+	}
+	url := pr.cfg.InsonBaseURL + "/api/v1/osago/contract/policy-check?contractId=" + neturl.QueryEscape(ref.OrderID)
+	raw, err := osagoProviders.Request("GET", url, nil, pr.cfg.InsonLogin, pr.cfg.InsonPassword, "")
+	if err != nil {
+		return nil, err
+	}
+	st := &osagoProviders.Status{Raw: raw, PdfURL: osagoProviders.ExtractString(raw, "data", "policyUrl")}
+	if st.PdfURL != "" {
+		st.State = osagoProviders.StateIssued
+		st.PolicyNumber = strings.TrimSpace(osagoProviders.ExtractString(raw, "data", "policySeries") + " " +
+			osagoProviders.ExtractString(raw, "data", "policyNumber"))
+	}
+	return st, nil
+}
+
+// Quote - data.insurancePremium, страховая сумма в data.liability (если есть)
+func (pr *Provider) Quote(raw interface{}) osagoProviders.Quote {
+	return osagoProviders.Quote{
+		Premium:  osagoProviders.ToInt(osagoProviders.ExtractValue(raw, "data", "insurancePremium")),
+		Coverage: int64(osagoProviders.ExtractInt(raw, "data", "liability")),
+	}
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+
+	osagoProviders "kliro/services/osago/providers"
+)
+
+// Calculate - расчет для Neo Insurance.
+func (pr *Provider) Calculate(session *osagoProviders.Session, req *osagoProviders.CalculateRequest) (interface{}, error) {
+	ownerType := osagoProviders.ExtractString(session.Vehicle, "data", "owner", "type")
+	if ownerType == "organization" {
+		return nil, fmt.Errorf("Neo Insurance не поддерживает юридические лица")
+	}
+	if req.PeriodID == 3 {
+		return nil, fmt.Errorf("Neo Insurance не поддерживает период 20 дней")
+	}
+
+	vehicleData := session.Vehicle
+	gosNumber := osagoProviders.ExtractString(vehicleData, "data", "license_plate")
+	techSeries := osagoProviders.ExtractString(vehicleData, "data", "tech_passport", "series")
+	techNumber := osagoProviders.ExtractString(vehicleData, "data", "tech_passport", "number")
+
+	// Логируем что именно извлекли из сессии
+	log.Printf("[Neo calc] extracted from session: gos_number=%q tech_sery=%q tech_number=%q", gosNumber, techSeries, techNumber)
+
+	if gosNumber == "" || techSeries == "" || techNumber == "" {
+		return nil, fmt.Errorf("недостаточно данных о машине")
+	}
+
+	periodIDStr := strconv.Itoa(req.PeriodID)
+	numberDriversID := "1"
+	if req.DriverRestriction {
+		numberDriversID = "4"
+	}
+	requestBody := map[string]interface{}{
+		"gos_number":        gosNumber,
+		"tech_sery":         techSeries,
+		"tech_number":       techNumber,
+		"period_id":         periodIDStr,
+		"number_drivers_id": numberDriversID,
+	}
+
+	reqJSON, _ := json.Marshal(requestBody)
+	log.Printf("[Neo calc] → sending: %s", reqJSON)
+
+	url := pr.cfg.NeoBaseURL + "/api/osago-neo/get-calc-osago"
+	result, err := osagoProviders.Request("POST", url, requestBody, pr.cfg.NeoLogin, pr.cfg.NeoPassword, "")
+
+	respJSON, _ := json.Marshal(result)
+	if err != nil {
+		log.Printf("[Neo calc] ← error: %v", err)
+	} else {
+		log.Printf("[Neo calc] ← response: %s", respJSON)
+	}
+	return result, err
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	osagoProviders "kliro/services/osago/providers"
+)
+
+// create - оформление полиса (сырой ответ провайдера)
+func (pr *Provider) create(session *osagoProviders.Session, req *osagoProviders.CreateRequest) (interface{}, error) {
+	ownerType := osagoProviders.ExtractString(session.Vehicle, "data", "owner", "type")
+	if ownerType == "organization" {
+		return nil, fmt.Errorf("Neo Insurance не поддерживает юридические лица")
+	}
+	// period_id, driver_restriction, amount_uzs — из сессии (calculate_snapshot), если был вызван Calculate; иначе из тела запроса
+	periodID := req.PeriodID
+	driverRestriction := req.DriverRestriction
+	amountUZS := req.AmountUZS
+	if snap := session.CalculateSnapshot; snap != nil {
+		if snap.PeriodID >= 1 && snap.PeriodID <= 3 {
+			periodID = snap.PeriodID
+		}
+		driverRestriction = snap.DriverRestriction
+		if snap.Premiums != nil && snap.Premiums["neo"] > 0 {
+			amountUZS = snap.Premiums["neo"]
+		}
+	}
+	if periodID != 1 && periodID != 2 {
+		return nil, fmt.Errorf("Neo Insurance требует period_id 1 или 2 (вызовите сначала Calculate с period_id 1/2 по этой сессии или передайте period_id в теле)")
+	}
+	if req.PhoneNumber == "" {
+		return nil, fmt.Errorf("phone_number обязателен для Neo create")
+	}
+	if amountUZS <= 0 {
+		return nil, fmt.Errorf("amount_uzs обязателен для Neo create (вызовите сначала Calculate по этой сессии или передайте amount_uzs в теле)")
+	}
+
+	v := session.Vehicle
+	gos := strings.TrimSpace(strings.ToUpper(osagoProviders.ExtractString(v, "data", "license_plate")))
+	techS := strings.TrimSpace(osagoProviders.ExtractString(v, "data", "tech_passport", "series"))
+	techN := strings.TrimSpace(osagoProviders.ExtractString(v, "data", "tech_passport", "number"))
+	if gos == "" || techS == "" || techN == "" {
+		return nil, fmt.Errorf("недостаточно данных о машине")
+	}
+
+	// Владелец ТС — только из сессии Find: number/series из documents[] (IDMS_RECV_MVD_IDCARD_CITIZEN), как в neoFlow 553-562
+	ownerPersonObj := osagoProviders.ExtractValue(v, "data", "owner", "person")
+	ownerPassS, ownerPassN := "", ""
+	if ownerPersonObj != nil {
+		ownerPassS, ownerPassN = osagoProviders.PersonPassport(ownerPersonObj)
+	}
+	if (ownerPassS == "" || ownerPassN == "") && session.Owner != nil && *session.Owner && session.Person != nil {
+		ownerPassS, ownerPassN = osagoProviders.PersonPassport(session.Person)
+	}
+	if ownerPassS == "" || ownerPassN == "" {
+		ownerPassS = osagoProviders.ExtractString(v, "data", "owner", "person", "passport", "series")
+		ownerPassN = osagoProviders.ExtractString(v, "data", "owner", "person", "passport", "number")
+	}
+	ownerBirth := osagoProviders.ExtractString(v, "data", "owner", "person", "birthdate")
+	if ownerBirth == "" {
+		ownerBirth = osagoProviders.ExtractString(session.Person, "data", "birthdate")
+	}
+	if ownerPassS == "" || ownerPassN == "" || ownerBirth == "" {
+		return nil, fmt.Errorf("не хватает данных владельца (passport/birthdate) в сессии Find — проверьте vehicle.data.owner.person или person.data (passport/documents)")
+	}
+
+	// Заявитель: из тела запроса, иначе = владелец ТС (по neoFlow applicant задаётся в запросе)
+	applicantPassS := strings.TrimSpace(req.ApplicantPassportSeries)
+	applicantPassN := strings.TrimSpace(req.ApplicantPassportNumber)
+	applicantBirth := strings.TrimSpace(req.ApplicantBirthdate)
+	if applicantPassS == "" {
+		applicantPassS = ownerPassS
+	}
+	if applicantPassN == "" {
+		applicantPassN = ownerPassN
+	}
+	if applicantBirth == "" {
+		applicantBirth = ownerBirth
+	}
+
+	// drivers — из сессии (calculate_snapshot), т.к. уже спрашиваются в Calculate; иначе из тела
+	driversList := req.Drivers
+	if snap := session.CalculateSnapshot; snap != nil && len(snap.Drivers) > 0 {
+		driversList = snap.Drivers
+	}
+	// Автозаполнение license_series/license_number из Find API, если переданы только паспорт + дата рождения
+	driversList = osagoProviders.EnrichDrivers(pr.cfg, driversList)
+
+	numberDriversID := 1
+	if driverRestriction {
+		numberDriversID = 4
+	}
+
+	var drivers []map[string]interface{}
+	if driverRestriction {
+		for _, d := range driversList {
+			licenseIssue := d.LicenseIssueDate
+			if licenseIssue == "" {
+				licenseIssue = "2020-01-01"
+			}
+			drivers = append(drivers, map[string]interface{}{
+				"passport__seria":  d.PassportSeries,
+				"passport__number": d.PassportNumber,
+				"driver_birthday":  osagoProviders.FormatDateDDMMYYYY(d.Birthdate),
+				"licenseNumber":    d.LicenseNumber,
+				"licenseSeria":     d.LicenseSeries,
+				"licenseIssueDate": osagoProviders.FormatDateDDMMYYYY(licenseIssue),
+				"relative":         relative(d.Relative),
+			})
+		}
+	}
+
+	// applicant_is_driver: из тела запроса (Neo); если не передан — по паспорту заявителя и drivers
+	applicantIsDriver := false
+	if req.ApplicantIsDriver != nil {
+		applicantIsDriver = *req.ApplicantIsDriver
+	} else {
+		for _, d := range driversList {
+			if strings.TrimSpace(d.PassportSeries) == applicantPassS && strings.TrimSpace(d.PassportNumber) == applicantPassN {
+				applicantIsDriver = true
+				break
+			}
+		}
+	}
+
+	body := map[string]interface{}{
+		"gos_number":             gos,
+		"tech_sery":              techS,
+		"tech_number":            techN,
+		"period_id":              periodID,
+		"number_drivers_id":      numberDriversID,
+		"owner__pass_seria":      ownerPassS,
+		"owner__pass_number":     ownerPassN,
+		"owner_birthday":         osagoProviders.FormatDateDDMMYYYY(ownerBirth),
+		"applicant__pass_seria":  applicantPassS,
+		"applicant__pass_number": applicantPassN,
+		"applicant__birthday":    osagoProviders.FormatDateDDMMYYYY(applicantBirth),
+		"applicant_is_driver":    applicantIsDriver,
+		"phone_number":           req.PhoneNumber,
+		"drivers":                drivers,
+		"amount_uzs":             amountUZS,
+	}
+	if req.StartDate != "" {
+		body["startDate"] = osagoProviders.FormatDateDDMMYYYY(req.StartDate)
+	}
+
+	url := pr.cfg.NeoBaseURL + "/api/osago-neo/save-policy/v2"
+	res, err := osagoProviders.RequestObject("POST", url, body, pr.cfg.NeoLogin, pr.cfg.NeoPassword, "")
+	if err != nil && strings.Contains(err.Error(), "Vehicle not found") {
+		return nil, fmt.Errorf("%w (Neo ищет ТС в своей базе: убедитесь, что по этому госномеру/техпаспорту уже делали расчёт Neo, или что ТС есть в базе Neo)", err)
+	}
+	return res, err
+}
+
+// relative конвертирует наше значение relative в формат Neo (0-10, без изменений)
+func relative(rel int) int {
+	return rel
+}
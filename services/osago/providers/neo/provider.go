@@ -0,0 +1,81 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+
+	"kliro/config"
+	osagoProviders "kliro/services/osago/providers"
+)
+
+// Provider - Neo Insurance: только физлица, периоды 12 и 6 месяцев
+type Provider struct {
+	cfg *config.Config
+}
+
+func New(cfg *config.Config) *Provider {
+	return &Provider{cfg: cfg}
+}
+
+func (pr *Provider) Name() string { return "neo" }
+
+func (pr *Provider) Periods() []int { return []int{1, 2} }
+
+func (pr *Provider) OwnerTypes() []string { return []string{osagoProviders.OwnerPerson} }
+
+// Create - оформление полиса; ссылки оплаты Neo: response.url (click), response.payme_url (payme)
+func (pr *Provider) Create(session *osagoProviders.Session, req *osagoProviders.CreateRequest) (interface{}, error) {
+	r, err := pr.create(session, req)
+	if err != nil {
+		return nil, err
+	}
+	resp := osagoProviders.ExtractValue(r, "response")
+	return osagoProviders.WithPaymentURLs(r, osagoProviders.ExtractString(resp, "url"), osagoProviders.ExtractString(resp, "payme_url")), nil
+}
+
+func (pr *Provider) SupportsStatus() bool { return true }
+
+// CheckStatus - POST /api/osago-neo/confirm-check {"order_id"}: статус оплаты в response.status,
+// номер и PDF выпущенного полиса (url в ответе — ссылка оплаты click, не полис)
+func (pr *Provider) CheckStatus(ref osagoProviders.StatusRef) (*osagoProviders.Status, error) {
+	if ref.OrderID == "" {
+		return nil, fmt.Errorf("нет order_id для проверки статуса Neo")
+	}
+	var orderID interface{} = ref.OrderID
+	if n, err := strconv.ParseInt(ref.OrderID, 10, 64); err == nil {
+		orderID = n
+	}
+	url := pr.cfg.NeoBaseURL + "/api/osago-neo/confirm-check"
+	raw, err := osagoProviders.Request("POST", url, map[string]interface{}{"order_id": orderID}, pr.cfg.NeoLogin, pr.cfg.NeoPassword, "")
+	if err != nil {
+		return nil, err
+	}
+	resp := osagoProviders.ExtractValue(raw, "response")
+	if resp == nil {
+		resp = raw
+	}
+	st := &osagoProviders.Status{
+		Raw:          raw,
+		State:        osagoProviders.NormalizeState(firstString(resp, "status", "state", "payment_status")),
+		PolicyNumber: firstString(resp, "polis_number", "policy_number"),
+		PdfURL:       firstString(resp, "url_pdf", "pdf_url", "policy_url"),
+	}
+	if (st.PdfURL != "" || st.PolicyNumber != "") && (st.State == "" || st.State == osagoProviders.StatePaid) {
+		st.State = osagoProviders.StateIssued
+	}
+	return st, nil
+}
+
+func firstString(data interface{}, keys ...string) string {
+	for _, k := range keys {
+		if v := osagoProviders.ExtractString(data, k); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// Quote - премия в response.amount_uzs, страховую сумму не возвращает
+func (pr *Provider) Quote(raw interface{}) osagoProviders.Quote {
+	return osagoProviders.Quote{Premium: osagoProviders.ToInt(osagoProviders.ExtractValue(raw, "response", "amount_uzs"))}
+}
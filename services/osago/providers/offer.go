@@ -0,0 +1,105 @@
+package services
+
+import (
+	"sort"
+	"time"
+)
+
+// StatutoryCoverageUZS - страховая сумма ОСАГО по закону; подставляется, если провайдер ее не вернул
+const StatutoryCoverageUZS = 80000000
+
+// CurrencyUZS - все страховые считают ОСАГО в сумах
+const CurrencyUZS = "UZS"
+
+// Quote - данные, которые адаптер извлекает из своего ответа Calculate
+type Quote struct {
+	Premium  int   `json:"premium"`  // UZS; -1 — не удалось извлечь
+	Coverage int64 `json:"coverage"` // страховая сумма UZS; 0 — провайдер не указал
+}
+
+// Offer - нормализованное предложение страховой в ответе /osago-all/calculate.
+// Для провайдера, не давшего расчет, заполнены только Provider и Error.
+type Offer struct {
+	Provider          string      `json:"provider"`
+	Premium           int64       `json:"premium,omitempty"` // UZS
+	Currency          string      `json:"currency,omitempty"`
+	PeriodID          int         `json:"period_id,omitempty"`
+	Period            string      `json:"period,omitempty"` // ISO 8601: P12M, P6M, P20D
+	DriverRestriction bool        `json:"driver_restriction"`
+	CoverageLimit     int64       `json:"coverage_limit,omitempty"`          // страховая сумма UZS
+	ValidUntil        *time.Time  `json:"valid_until,omitempty"`             // до какого момента расчет можно оформить (время жизни сессии)
+	ProviderRef       string      `json:"provider_ref,omitempty"`            // идентификатор расчета у провайдера, если он его выдает
+	StatutoryPremium  int64       `json:"statutory_premium,omitempty"`       // премия по регулируемому тарифу (локальный расчет)
+	DeviationPercent  float64     `json:"deviation_percent,omitempty"`       // отклонение премии от тарифа, %
+	Deviates          bool        `json:"deviates_from_statutory,omitempty"` // отклонение больше допустимого
+	Raw               interface{} `json:"raw,omitempty"`                     // сырой ответ провайдера — только для отладки (?debug=1)
+	Error             string      `json:"error,omitempty"`
+}
+
+// periodDurations - period_id -> длительность полиса
+var periodDurations = map[int]string{1: "P12M", 2: "P6M", 3: "P20D"}
+
+// referenceKeys - ключи, под которыми провайдеры возвращают идентификатор расчета
+var referenceKeys = []string{"uuid", "calc_id", "calculation_id", "quote_id"}
+
+// NewOffer собирает предложение из ответа Calculate; ответ без распознаваемой премии считается ошибкой
+func NewOffer(p Provider, req *CalculateRequest, raw interface{}, validUntil time.Time) Offer {
+	offer := OfferFromQuote(p.Name(), req, p.Quote(raw), validUntil)
+	if offer.Error == "" {
+		offer.ProviderRef = reference(raw)
+		offer.Raw = raw
+	}
+	return offer
+}
+
+// OfferFromQuote собирает предложение только из премии и страховой суммы (котировка из кэша):
+// без сырого ответа и идентификатора расчета провайдера
+func OfferFromQuote(provider string, req *CalculateRequest, q Quote, validUntil time.Time) Offer {
+	if q.Premium <= 0 {
+		return FailedOffer(provider, "премия не найдена в ответе провайдера")
+	}
+	coverage := q.Coverage
+	if coverage <= 0 {
+		coverage = StatutoryCoverageUZS
+	}
+	return Offer{
+		Provider:          provider,
+		Premium:           int64(q.Premium),
+		Currency:          CurrencyUZS,
+		PeriodID:          req.PeriodID,
+		Period:            periodDurations[req.PeriodID],
+		DriverRestriction: req.DriverRestriction,
+		CoverageLimit:     coverage,
+		ValidUntil:        &validUntil,
+	}
+}
+
+// FailedOffer - запись об ошибке провайдера в списке предложений
+func FailedOffer(provider, errText string) Offer {
+	return Offer{Provider: provider, Error: errText}
+}
+
+// SortOffers: сначала предложения по возрастанию премии, затем ошибки (по имени провайдера)
+func SortOffers(offers []Offer) {
+	sort.SliceStable(offers, func(i, j int) bool {
+		a, b := offers[i], offers[j]
+		if (a.Error == "") != (b.Error == "") {
+			return a.Error == ""
+		}
+		if a.Premium != b.Premium {
+			return a.Premium < b.Premium
+		}
+		return a.Provider < b.Provider
+	})
+}
+
+func reference(raw interface{}) string {
+	for _, prefix := range [][]string{nil, {"data"}, {"response"}} {
+		for _, key := range referenceKeys {
+			if s := ExtractString(raw, append(prefix, key)...); s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
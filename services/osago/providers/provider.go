@@ -0,0 +1,129 @@
+package services
+
+import (
+	"errors"
+)
+
+// Типы владельца ТС (vehicle.data.owner.type из Find)
+const (
+	OwnerPerson       = "person"
+	OwnerOrganization = "organization"
+)
+
+// ErrStatusUnsupported - у провайдера нет API проверки статуса полиса
+var ErrStatusUnsupported = errors.New("провайдер не поддерживает проверку статуса")
+
+// Provider - адаптер страховой ОСАГО. Контроллеры работают только через этот интерфейс и реестр,
+// форматы запросов и ответов конкретной страховой живут в ее пакете.
+type Provider interface {
+	Name() string
+	Periods() []int       // поддерживаемые period_id (1 = 12 мес, 2 = 6 мес, 3 = 20 дней)
+	OwnerTypes() []string // OwnerPerson / OwnerOrganization
+	Calculate(session *Session, req *CalculateRequest) (interface{}, error)
+	// Create оформляет полис; если провайдер выдает ссылки оплаты, в ответ добавляются единые поля click_url и payme_url
+	Create(session *Session, req *CreateRequest) (interface{}, error)
+	// SupportsStatus - есть ли у провайдера API проверки статуса полиса
+	SupportsStatus() bool
+	// CheckStatus запрашивает статус оформленного полиса; ErrStatusUnsupported, если API нет
+	CheckStatus(ref StatusRef) (*Status, error)
+	// Quote извлекает премию и страховую сумму из ответа Calculate
+	Quote(raw interface{}) Quote
+}
+
+// CalculateRequest - единый запрос на расчет для всех провайдеров
+type CalculateRequest struct {
+	SessionID         string   `json:"session_id" binding:"required"`
+	PeriodID          int      `json:"period_id" binding:"required"` // 1 = 12 месяцев, 2 = 6 месяцев, 3 = 20 дней (только EuroAsia, Apex)
+	DriverRestriction bool     `json:"driver_restriction"`           // true = ограничено, false = неограничено
+	Drivers           []Driver `json:"drivers,omitempty"`            // опционально, если driver_restriction = true
+}
+
+// CreateRequest - единый body для create у любого провайдера: backend сам маппит на API провайдера
+type CreateRequest struct {
+	SessionID         string   `json:"session_id" binding:"required"`
+	Provider          string   `json:"provider" binding:"required"` // имя из реестра: neo|gross|euroasia|trust|apex|inson
+	PeriodID          int      `json:"period_id,omitempty"`         // 1=12мес, 2=6мес, 3=20дней; 0 — возьмётся из сессии (после Calculate)
+	DriverRestriction bool     `json:"driver_restriction"`
+	Drivers           []Driver `json:"drivers,omitempty"`
+
+	StartDate   string `json:"start_date,omitempty"`   // YYYY-MM-DD (обязательно для gross, euroasia, trust)
+	PhoneNumber string `json:"phone_number,omitempty"` // обязательно для neo, gross, euroasia
+	AmountUZS   int    `json:"amount_uzs,omitempty"`   // обязательно для neo, gross
+
+	// Заявитель (для Neo и др.): если не передан — берётся владелец ТС из сессии
+	ApplicantPassportSeries string `json:"applicant_passport_series,omitempty"`
+	ApplicantPassportNumber string `json:"applicant_passport_number,omitempty"`
+	ApplicantBirthdate      string `json:"applicant_birthdate,omitempty"` // YYYY-MM-DD
+	ApplicantIsDriver       *bool  `json:"applicant_is_driver,omitempty"` // для Neo: заявитель в списке водителей (передаётся в теле)
+
+	// Только для provider=euroasia
+	EuroasiaDistrictID      string `json:"euroasia_district_id,omitempty"`
+	EuroasiaInsurantType    string `json:"euroasia_insurant_type,omitempty"`
+	EuroasiaOwnerIsInsurant *bool  `json:"euroasia_owner_is_insurant,omitempty"`
+
+	// Опционально: если передан — подменяет собранный payload только для trust/apex (для обратной совместимости)
+	ProviderPayload map[string]interface{} `json:"provider_payload,omitempty"`
+}
+
+// Driver - данные водителя
+type Driver struct {
+	PassportSeries string `json:"passport_series"`
+	PassportNumber string `json:"passport_number"`
+	Birthdate      string `json:"birthdate"` // YYYY-MM-DD
+
+	LicenseSeries    string `json:"license_series,omitempty"`
+	LicenseNumber    string `json:"license_number,omitempty"`
+	LicenseIssueDate string `json:"license_issue_date,omitempty"` // YYYY-MM-DD
+	Relative         int    `json:"relative,omitempty"`           // 0-10, по умолчанию 0
+}
+
+// StatusRef - идентификаторы заказа у провайдера (из osago_orders)
+type StatusRef struct {
+	OrderID  string
+	PolicyID string
+}
+
+// Status - статус полиса у провайдера, приведенный к общему виду
+type Status struct {
+	State        string // State* ; пусто — статус не изменился или не распознан
+	PolicyNumber string
+	PdfURL       string
+	Raw          interface{}
+}
+
+// Supports проверяет, работает ли провайдер с периодом и типом владельца (0 / "" — без проверки)
+func Supports(p Provider, periodID int, ownerType string) bool {
+	if periodID != 0 && !containsInt(p.Periods(), periodID) {
+		return false
+	}
+	if ownerType != "" && !containsString(p.OwnerTypes(), ownerType) {
+		return false
+	}
+	return true
+}
+
+// OwnerTypeLabel - название типа владельца для сообщений об ошибках
+func OwnerTypeLabel(ownerType string) string {
+	if ownerType == OwnerOrganization {
+		return "юридического лица"
+	}
+	return "физического лица"
+}
+
+func containsInt(list []int, v int) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(list []string, v string) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
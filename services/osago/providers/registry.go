@@ -0,0 +1,92 @@
+package services
+
+import (
+	"strings"
+)
+
+// Registry - подключенные страховые ОСАГО в порядке опроса.
+// Отключенные (OSAGO_DISABLED_PROVIDERS) не участвуют ни в calculate, ни в create.
+type Registry struct {
+	providers []Provider
+	disabled  map[string]bool
+	required  map[string]string // тип владельца -> провайдер, без ответа которого расчет не считается успешным
+}
+
+// NewRegistry собирает реестр; disabled — имена отключенных провайдеров
+func NewRegistry(disabled []string, providers ...Provider) *Registry {
+	r := &Registry{
+		providers: providers,
+		disabled:  make(map[string]bool, len(disabled)),
+		required:  map[string]string{},
+	}
+	for _, name := range disabled {
+		r.disabled[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+	return r
+}
+
+// Require делает ответ провайдера обязательным для типа владельца (например, Trust для юрлиц)
+func (r *Registry) Require(ownerType, provider string) *Registry {
+	r.required[ownerType] = provider
+	return r
+}
+
+// Required - обязательный провайдер для типа владельца ("" — нет), только если он включен
+func (r *Registry) Required(ownerType string) string {
+	name := r.required[ownerType]
+	if _, ok := r.Get(name); !ok {
+		return ""
+	}
+	return name
+}
+
+// All - включенные провайдеры
+func (r *Registry) All() []Provider {
+	out := make([]Provider, 0, len(r.providers))
+	for _, p := range r.providers {
+		if !r.disabled[p.Name()] {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// Get - включенный провайдер по имени
+func (r *Registry) Get(name string) (Provider, bool) {
+	for _, p := range r.All() {
+		if p.Name() == name {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// Find - провайдер по имени, в том числе отключенный: статус уже оформленных полисов проверяется всегда
+func (r *Registry) Find(name string) (Provider, bool) {
+	for _, p := range r.providers {
+		if p.Name() == name {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// For - включенные провайдеры, работающие с периодом и типом владельца
+func (r *Registry) For(periodID int, ownerType string) []Provider {
+	var out []Provider
+	for _, p := range r.All() {
+		if Supports(p, periodID, ownerType) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// Names - имена провайдеров списка
+func Names(providers []Provider) []string {
+	out := make([]string, 0, len(providers))
+	for _, p := range providers {
+		out = append(out, p.Name())
+	}
+	return out
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"kliro/utils"
+)
+
+// SessionTTL - время жизни сессии Find/Calculate: после него расчет надо повторить
+const SessionTTL = 30 * time.Minute
+
+// SessionKey - ключ сессии Find/Calculate в Redis
+func SessionKey(sessionID string) string {
+	return "osago_all:session:" + sessionID
+}
+
+// CalculateSnapshot — параметры и результаты calculate, сохраняются в сессию при вызове Calculate
+type CalculateSnapshot struct {
+	PeriodID          int            `json:"period_id"`
+	DriverRestriction bool           `json:"driver_restriction"`
+	Drivers           []Driver       `json:"drivers"`
+	Premiums          map[string]int `json:"premiums"` // neo, gross, ... -> amount_uzs
+}
+
+// Session - данные Find из Redis (сырые ответы EuroAsia) и снимок calculate
+type Session struct {
+	Vehicle           interface{}        `json:"vehicle"`
+	Person            interface{}        `json:"person"`
+	Organization      interface{}        `json:"organization"`
+	Owner             *bool              `json:"owner"`
+	CalculateSnapshot *CalculateSnapshot `json:"calculate_snapshot,omitempty"`
+}
+
+// LoadSession - получение данных из Redis по session_id
+func LoadSession(sessionID string) (*Session, error) {
+	rdb := utils.GetRedis()
+	if rdb == nil {
+		return nil, fmt.Errorf("redis not available")
+	}
+	val, err := rdb.Get(context.Background(), SessionKey(sessionID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("session not found: %v", err)
+	}
+	var s Session
+	if err := json.Unmarshal([]byte(val), &s); err != nil {
+		return nil, fmt.Errorf("failed to parse session data: %v", err)
+	}
+	return &s, nil
+}
+
+// OwnerType - тип владельца ТС из сессии (person / organization)
+func (s *Session) OwnerType() string {
+	return ExtractString(s.Vehicle, "data", "owner", "type")
+}
@@ -0,0 +1,26 @@
+package services
+
+import "strings"
+
+// Состояния полиса, которые возвращает CheckStatus (совпадают со статусами osago_orders)
+const (
+	StateCreated = "created" // заявка создана, оплаты еще нет
+	StatePaid    = "paid"    // оплачен, полис еще не выпущен
+	StateIssued  = "issued"  // полис выпущен
+	StateFailed  = "failed"  // отклонен / аннулирован провайдером
+	StateExpired = "expired" // не оплачен вовремя
+)
+
+// stateAliases - статусы из ответов провайдеров -> общее состояние
+var stateAliases = map[string]string{
+	"new": StateCreated, "draft": StateCreated, "created": StateCreated, "pending": StateCreated, "waiting": StateCreated,
+	"paid": StatePaid, "payed": StatePaid, "success": StatePaid,
+	"issued": StateIssued, "active": StateIssued, "signed": StateIssued, "completed": StateIssued,
+	"failed": StateFailed, "error": StateFailed, "rejected": StateFailed, "cancelled": StateFailed, "canceled": StateFailed,
+	"expired": StateExpired,
+}
+
+// NormalizeState приводит статус провайдера к State*; пусто — статус не распознан
+func NormalizeState(raw string) string {
+	return stateAliases[strings.ToLower(strings.TrimSpace(raw))]
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	osagoProviders "kliro/services/osago/providers"
+)
+
+// Calculate - расчет для Trust Insurance
+func (pr *Provider) Calculate(session *osagoProviders.Session, req *osagoProviders.CalculateRequest) (interface{}, error) {
+	vehicleData := session.Vehicle
+	ownerType := osagoProviders.ExtractString(vehicleData, "data", "owner", "type")
+
+	gosNumber := osagoProviders.ExtractString(vehicleData, "data", "license_plate")
+
+	// Тип ТС — только из сессии Find (маппинг external_id → Trust: 2→1, 6→6, 9→9, 15→15)
+	vehicleTypeExternalID := osagoProviders.ExtractInt(vehicleData, "data", "vehicle_type", "external_id")
+	if vehicleTypeExternalID == 0 {
+		vehicleTypeExternalID = osagoProviders.ExtractInt(vehicleData, "data", "vehicle_type", "id")
+	}
+	if vehicleTypeExternalID == 0 {
+		return nil, fmt.Errorf("в сессии нет типа ТС (vehicle_type); выполните find с полными данными по ТС")
+	}
+	vehicleTypeID := vehicleTypeExternalID
+	if vehicleTypeExternalID == 2 {
+		vehicleTypeID = 1 // легковые в Find = external_id 2, в Trust = 1
+	}
+
+	// Территория: Find возвращает 1–3 категории (1=Ташкент и обл., 2=Другие регионы, 3=Для иностранцев),
+	// Trust ожидает 1–14 регионов (1=город Ташкент, 2=Ташкентская обл., 3–14=остальные). Маппинг через конфиг.
+	useTerritoryExternalID := osagoProviders.ExtractInt(vehicleData, "data", "use_territory_region", "external_id")
+	if useTerritoryExternalID == 0 {
+		useTerritoryExternalID = osagoProviders.ExtractInt(vehicleData, "data", "use_territory_region", "id")
+	}
+	if useTerritoryExternalID <= 0 {
+		return nil, fmt.Errorf("в сессии нет территории использования (use_territory_region); выполните find с полными данными по ТС")
+	}
+	var useTerritoryID int
+	switch useTerritoryExternalID {
+	case 1:
+		useTerritoryID = pr.cfg.TrustTerritoryFind1 // Ташкент и обл. → по умолчанию 1 (город Ташкент)
+	case 2:
+		useTerritoryID = pr.cfg.TrustTerritoryFind2 // Другие регионы → по умолчанию 10 (Самаркандская)
+	case 3:
+		useTerritoryID = pr.cfg.TrustTerritoryFind3 // Для иностранцев
+	default:
+		if useTerritoryExternalID >= 4 && useTerritoryExternalID <= 14 {
+			useTerritoryID = useTerritoryExternalID
+		} else {
+			return nil, fmt.Errorf("территория из сессии (use_territory_region.external_id=%d) не поддерживается Trust; ожидается 1–3 (Find) или 4–14", useTerritoryExternalID)
+		}
+	}
+
+	// Trust: 1=6мес, 2=12мес, 3=2мес, 4=15 и 20 дней
+	var periodID int
+	switch req.PeriodID {
+	case 1:
+		periodID = 2 // 12 месяцев
+	case 2:
+		periodID = 1 // 6 месяцев
+	case 3:
+		periodID = 4 // 15 и 20 дней
+	default:
+		periodID = 2
+	}
+
+	// Driver limit: true→1, false→0
+	driverLimit := 0
+	if req.DriverRestriction {
+		driverLimit = 1
+	}
+
+	// Owner data
+	var ownerPinfl string
+	var ownerInn string
+	if ownerType == "person" {
+		ownerPinfl = osagoProviders.OwnerPinfl(session)
+		if ownerPinfl == "" {
+			return nil, fmt.Errorf("не найден PINFL владельца")
+		}
+	} else {
+		ownerInn = osagoProviders.ExtractString(vehicleData, "data", "owner", "organization", "inn")
+		if ownerInn == "" {
+			ownerInn = osagoProviders.ExtractString(session.Organization, "data", "inn")
+		}
+		// Для Trust (юрлицо) нужен хотя бы один водитель, чтобы получить PINFL и подставить его в расчёт.
+		if len(req.Drivers) == 0 {
+			return nil, fmt.Errorf("для Trust (юрлицо) укажите drivers[] (минимум 1 водитель) с паспортом и датой рождения")
+		}
+		// owner_pinfl используем как PINFL представителя (первого водителя)
+		first := req.Drivers[0]
+		ownerPinfl = osagoProviders.SessionPinfl(session, first.PassportSeries, first.PassportNumber)
+		if ownerPinfl == "" && first.Birthdate != "" {
+			if p, err := osagoProviders.LookupPinflByPassport(pr.cfg, osagoProviders.FormatDateYYYYMMDD(first.Birthdate), first.PassportSeries, first.PassportNumber); err == nil {
+				ownerPinfl = p
+			}
+		}
+		if ownerPinfl == "" {
+			return nil, fmt.Errorf("не удалось получить PINFL представителя (drivers[0]) для Trust")
+		}
+	}
+
+	// Drivers
+	var drivers []map[string]interface{}
+	if req.DriverRestriction {
+		if len(req.Drivers) == 0 {
+			drivers = []map[string]interface{}{
+				{
+					"pinfl":       ownerPinfl,
+					"coefficient": 1,
+				},
+			}
+		} else {
+			for _, driver := range req.Drivers {
+				pinfl := osagoProviders.SessionPinfl(session, driver.PassportSeries, driver.PassportNumber)
+				if pinfl == "" && driver.Birthdate != "" {
+					if p, err := osagoProviders.LookupPinflByPassport(pr.cfg, osagoProviders.FormatDateYYYYMMDD(driver.Birthdate), driver.PassportSeries, driver.PassportNumber); err == nil {
+						pinfl = p
+					}
+				}
+				if pinfl == "" {
+					pinfl = ownerPinfl // fallback на представителя
+				}
+				drivers = append(drivers, map[string]interface{}{
+					"pinfl":       pinfl,
+					"coefficient": 1,
+				})
+			}
+		}
+	} else {
+		// Неограничено (driver_limit=0): пустой массив, как в Trust Create — иначе Trust возвращает завышенную премию
+		drivers = []map[string]interface{}{}
+	}
+
+	requestBody := map[string]interface{}{
+		"vehicle": map[string]interface{}{
+			"vehicle":        vehicleTypeID,
+			"renumber":       gosNumber,
+			"foreignVehicle": false,
+		},
+		"period":        periodID,
+		"use_territory": useTerritoryID,
+		"driver_limit":  driverLimit,
+		"discount":      pr.cfg.TrustDefaultDiscountID,
+		"owner": map[string]interface{}{
+			"owner_pinfl": ownerPinfl,
+		},
+		"drivers": drivers,
+	}
+	if ownerType != "person" && ownerInn != "" {
+		// пробуем передать ИНН юрлица (Trust может использовать для юрлиц)
+		if o, ok := requestBody["owner"].(map[string]interface{}); ok {
+			o["owner_inn"] = ownerInn
+			o["owner_fy"] = 1
+		}
+	}
+
+	trustBodyRaw, _ := json.Marshal(requestBody)
+	log.Printf("[Trust calc] → request body (raw JSON): %s", trustBodyRaw)
+
+	url := pr.cfg.TrustBaseURL + "/api/osgo/v2/calc-prem"
+	return osagoProviders.Request("POST", url, requestBody, pr.cfg.TrustLogin, pr.cfg.TrustPassword, "")
+}
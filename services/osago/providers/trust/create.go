@@ -0,0 +1,354 @@
+package services
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+
+	osagoProviders "kliro/services/osago/providers"
+)
+
+// create - оформление полиса (сырой ответ провайдера)
+func (pr *Provider) create(session *osagoProviders.Session, req *osagoProviders.CreateRequest) (interface{}, error) {
+	if req.ProviderPayload != nil {
+		url := pr.cfg.TrustBaseURL + "/api/osgo/create"
+		return osagoProviders.RequestObject("POST", url, req.ProviderPayload, pr.cfg.TrustLogin, pr.cfg.TrustPassword, "")
+	}
+	// period_id, driver_restriction, drivers — из сессии (calculate_snapshot), как у Neo/Gross/Apex
+	periodID := req.PeriodID
+	driverRestriction := req.DriverRestriction
+	trustDriversList := req.Drivers
+	if snap := session.CalculateSnapshot; snap != nil {
+		if snap.PeriodID >= 1 && snap.PeriodID <= 3 {
+			periodID = snap.PeriodID
+		}
+		driverRestriction = snap.DriverRestriction
+		if len(snap.Drivers) > 0 {
+			trustDriversList = snap.Drivers
+		}
+	}
+	// Автозаполнение license_series/license_number из Find API, если переданы только паспорт + дата рождения
+	trustDriversList = osagoProviders.EnrichDrivers(pr.cfg, trustDriversList)
+	if req.StartDate == "" {
+		return nil, fmt.Errorf("start_date обязателен для Trust create")
+	}
+	// Trust API принимает только period 1 (6 мес) или 2 (12 мес); период 20 дней не поддерживается
+	if periodID == 3 {
+		return nil, fmt.Errorf("Trust поддерживает только period_id 1 (12 мес) или 2 (6 мес); для периода 20 дней выберите другого провайдера (например Apex)")
+	}
+	v := session.Vehicle
+	renumber := osagoProviders.ExtractString(v, "data", "license_plate")
+	texpsery := osagoProviders.ExtractString(v, "data", "tech_passport", "series")
+	texpnumber := osagoProviders.ExtractString(v, "data", "tech_passport", "number")
+	vmodel := osagoProviders.ExtractString(v, "data", "model")
+	year := osagoProviders.ExtractInt(v, "data", "manufacture_year")
+	kuzov := osagoProviders.ExtractString(v, "data", "body_number")
+	dvigatel := osagoProviders.ExtractString(v, "data", "engine_number")
+	if renumber == "" || texpsery == "" || texpnumber == "" {
+		return nil, fmt.Errorf("недостаточно данных о машине для Trust")
+	}
+	vehicleTypeID := osagoProviders.ExtractInt(v, "data", "vehicle_type", "external_id")
+	if vehicleTypeID == 0 {
+		vehicleTypeID = 1
+	}
+	// Trust принимает type/regionId/districtId из своих справочников; берём из Find и маппим
+	trustVehicleTypeID := osagoProviders.VehicleTypeID(vehicleTypeID)
+	ownerRegion := osagoProviders.ExtractInt(v, "data", "owner", "person", "region", "external_id")
+	if ownerRegion == 0 {
+		ownerRegion = 10
+	}
+	ownerDistrict := osagoProviders.ExtractInt(v, "data", "owner", "person", "district", "external_id")
+	if ownerDistrict == 0 {
+		ownerDistrict = 1001
+	}
+	trustDistrict := mapFindDistrict(ownerRegion, ownerDistrict)
+	useTerritory := osagoProviders.ExtractInt(v, "data", "use_territory_region", "external_id")
+	if useTerritory <= 0 || useTerritory > 14 {
+		useTerritory = 1
+	}
+	texpdate := osagoProviders.FormatDateDDMMYYYY(osagoProviders.ExtractString(v, "data", "tech_passport", "issue_date"))
+	if texpdate == "" {
+		texpdate = "01.01.2020"
+	}
+	if year == 0 {
+		year = 2020
+	}
+	if vmodel == "" {
+		vmodel = "N"
+	}
+	if kuzov == "" {
+		kuzov = "N"
+	}
+	if dvigatel == "" {
+		dvigatel = "N"
+	}
+
+	ownerType := osagoProviders.ExtractString(v, "data", "owner", "type")
+	ownerFy := 0
+	ownerPinfl := osagoProviders.ExtractString(v, "data", "owner", "person", "pinfls", "0")
+	if ownerPinfl == "" {
+		ownerPinfl = osagoProviders.ExtractString(v, "data", "owner", "person", "external_id")
+	}
+	ownerBirthdate := osagoProviders.FormatDateDDMMYYYY(osagoProviders.ExtractString(v, "data", "owner", "person", "birthdate"))
+	if ownerBirthdate == "" {
+		ownerBirthdate = osagoProviders.FormatDateDDMMYYYY(osagoProviders.ExtractString(session.Person, "data", "birthdate"))
+	}
+	// Серия/номер документа владельца — как в Neo/Gross: extractPersonPassportFromFindSession (сначала ID-карта/documents, потом passport)
+	ownerPersonObj := osagoProviders.ExtractValue(v, "data", "owner", "person")
+	ownerPaspSery, ownerPaspNum := "", ""
+	if ownerPersonObj != nil {
+		ownerPaspSery, ownerPaspNum = osagoProviders.PersonPassport(ownerPersonObj)
+	}
+	if (ownerPaspSery == "" || ownerPaspNum == "") && session.Owner != nil && *session.Owner && session.Person != nil {
+		ownerPaspSery, ownerPaspNum = osagoProviders.PersonPassport(session.Person)
+	}
+	if ownerPaspSery == "" || ownerPaspNum == "" {
+		ownerPaspSery = osagoProviders.ExtractString(v, "data", "owner", "person", "passport", "series")
+		ownerPaspNum = osagoProviders.ExtractString(v, "data", "owner", "person", "passport", "number")
+	}
+	// ФИО владельца — сначала vehicle.owner.person, затем session.Person (как Neo/Gross)
+	var ownerSurname, ownerName, ownerPatronym string
+	ownerSurname = osagoProviders.ExtractString(v, "data", "owner", "person", "last_name")
+	if ownerSurname == "" {
+		ownerSurname = osagoProviders.ExtractString(session.Person, "data", "last_name")
+	}
+	ownerName = osagoProviders.ExtractString(v, "data", "owner", "person", "first_name")
+	if ownerName == "" {
+		ownerName = osagoProviders.ExtractString(session.Person, "data", "first_name")
+	}
+	ownerPatronym = osagoProviders.ExtractString(v, "data", "owner", "person", "middle_name")
+	if ownerPatronym == "" {
+		ownerPatronym = osagoProviders.ExtractString(session.Person, "data", "middle_name")
+	}
+	if ownerSurname == "" {
+		ownerSurname = "N"
+	}
+	if ownerName == "" {
+		ownerName = "N"
+	}
+	if ownerPatronym == "" {
+		ownerPatronym = "N"
+	}
+	ownerInn := ""
+	ownerOrgname := ""
+	if ownerType == "organization" {
+		ownerFy = 1
+		ownerInn = osagoProviders.ExtractString(v, "data", "owner", "organization", "inn")
+		if ownerInn == "" {
+			ownerInn = osagoProviders.ExtractString(session.Organization, "data", "inn")
+		}
+		ownerOrgname = osagoProviders.ExtractString(v, "data", "owner", "organization", "name_short")
+		if ownerOrgname == "" {
+			ownerOrgname = osagoProviders.ExtractString(v, "data", "owner", "organization", "name")
+		}
+		if len(trustDriversList) == 0 {
+			return nil, fmt.Errorf("для Trust (юрлицо) укажите drivers[] (минимум 1 представитель) или вызовите Calculate с водителями")
+		}
+		rep := trustDriversList[0]
+		ownerPinfl = osagoProviders.FindPinfl(pr.cfg, session, rep.PassportSeries, rep.PassportNumber, rep.Birthdate)
+		if ownerPinfl == "" {
+			ownerPinfl = "00000000000000"
+		}
+		ownerBirthdate = osagoProviders.FormatDateDDMMYYYY(rep.Birthdate)
+		ownerPaspSery = rep.PassportSeries
+		ownerPaspNum = rep.PassportNumber
+		ownerSurname = "N"
+		ownerName = "N"
+		ownerPatronym = "N"
+	}
+	ownerPhone := req.PhoneNumber
+	if ownerPhone == "" {
+		ownerPhone = "998900000000"
+	}
+
+	// Trust: 1 -> 6 мес, 2 -> 12 мес (period_id 3 уже отсечён выше)
+	periodTrust := 2
+	switch periodID {
+	case 1:
+		periodTrust = 2 // 12 мес
+	case 2:
+		periodTrust = 1 // 6 мес
+	default:
+		periodTrust = 2
+	}
+	driverLimit := 0
+	if driverRestriction {
+		driverLimit = 1
+	}
+	contractBegin := osagoProviders.FormatDateDDMMYYYY(req.StartDate)
+	if contractBegin == "" {
+		contractBegin = req.StartDate
+	}
+
+	var driversList []map[string]interface{}
+	if driverRestriction && len(trustDriversList) > 0 {
+		for _, d := range trustDriversList {
+			pinfl := osagoProviders.FindPinfl(pr.cfg, session, d.PassportSeries, d.PassportNumber, d.Birthdate)
+			if pinfl == "" {
+				pinfl = ownerPinfl
+			}
+			licdate := osagoProviders.FormatDateDDMMYYYY(d.LicenseIssueDate)
+			if licdate == "" {
+				licdate = "01.01.0001"
+			}
+			driversList = append(driversList, map[string]interface{}{
+				"datebirth":  osagoProviders.FormatDateDDMMYYYY(d.Birthdate),
+				"paspsery":   d.PassportSeries,
+				"paspnumber": d.PassportNumber,
+				"pinfl":      pinfl,
+				"surname":    "N",
+				"name":       "N",
+				"patronym":   "N",
+				"licnumber":  d.LicenseNumber,
+				"licsery":    d.LicenseSeries,
+				"licdate":    licdate,
+				"relative":   relative(d.Relative),
+				"resident":   1,
+			})
+		}
+	} else {
+		// Trust: при неограниченном полисе (driver_limit=0) не отправлять водителей
+		driversList = []map[string]interface{}{}
+	}
+
+	body := map[string]interface{}{
+		"renumber":          renumber,
+		"texpsery":          texpsery,
+		"texpnumber":        texpnumber,
+		"vmodel":            vmodel,
+		"type":              trustVehicleTypeID,
+		"texpdate":          texpdate,
+		"year":              year,
+		"kuzov":             kuzov,
+		"dvigatel":          dvigatel,
+		"use_territory":     useTerritory,
+		"owner_fy":          ownerFy,
+		"owner_pinfl":       ownerPinfl,
+		"owner_birthdate":   ownerBirthdate,
+		"owner_pasp_sery":   ownerPaspSery,
+		"owner_pasp_num":    ownerPaspNum,
+		"owner_surname":     ownerSurname,
+		"owner_name":        ownerName,
+		"owner_patronym":    ownerPatronym,
+		"owner_isdriver":    1,
+		"owner_oblast":      ownerRegion,
+		"owner_rayon":       trustDistrict,
+		"has_benefit":       1,
+		"owner_phone":       ownerPhone,
+		"applicant_isowner": 1,
+		"driver_limit":      driverLimit,
+		"contract_begin":    contractBegin,
+		"period":            periodTrust,
+		"drivers":           driversList,
+	}
+	if ownerFy == 1 {
+		body["owner_inn"] = osagoProviders.ToInt(ownerInn)
+		body["owner_orgname"] = ownerOrgname
+	}
+
+	url := pr.cfg.TrustBaseURL + "/api/osgo/create"
+	return osagoProviders.RequestObject("POST", url, body, pr.cfg.TrustLogin, pr.cfg.TrustPassword, "")
+}
+
+// withPaymentLinks обогащает ответ Trust create платёжными ссылками Click и Payme при успехе (error=0).
+func (pr *Provider) withPaymentLinks(trustResp interface{}) interface{} {
+	m, ok := trustResp.(map[string]interface{})
+	if !ok {
+		return trustResp
+	}
+	// Проверяем успешность: error должен быть 0 (число или отсутствовать)
+	errVal := m["error"]
+	if errVal != nil {
+		if errNum, ok := errVal.(float64); ok && errNum != 0 {
+			return trustResp
+		}
+		if errNum, ok := errVal.(int); ok && errNum != 0 {
+			return trustResp
+		}
+	}
+	anketaID := ""
+	switch v := m["anketa_id"].(type) {
+	case float64:
+		anketaID = strconv.Itoa(int(v))
+	case int:
+		anketaID = strconv.Itoa(v)
+	case string:
+		anketaID = v
+	}
+	uuidStr, _ := m["uuid"].(string)
+	amountStr := ""
+	switch v := m["insurance_premium"].(type) {
+	case string:
+		amountStr = v
+	case float64:
+		amountStr = strconv.Itoa(int(v))
+	case int:
+		amountStr = strconv.Itoa(v)
+	}
+	amountSum := 0
+	if amountStr != "" {
+		amountSum, _ = strconv.Atoi(amountStr)
+	}
+	if anketaID == "" || amountSum <= 0 {
+		return trustResp
+	}
+	links := pr.paymentLinks(anketaID, uuidStr, amountSum)
+	out := make(map[string]interface{})
+	for k, v := range m {
+		out[k] = v
+	}
+	out["click_url"] = links["click_url"]
+	out["payme_url"] = links["payme_url"]
+	return out
+}
+
+// paymentLinks генерирует только простые URL для Click и Payme (anketa_id как transaction_param/order_id).
+// amountSum — сумма в сумах; для Payme конвертируется в тийины (×100).
+func (pr *Provider) paymentLinks(anketaID, uuid string, amountSum int) map[string]interface{} {
+	serviceID := pr.cfg.ClickServiceID
+	if serviceID == "" {
+		serviceID = "23572"
+	}
+	merchantIDClick := pr.cfg.ClickMerchantID
+	if merchantIDClick == "" {
+		merchantIDClick = "14417"
+	}
+	merchantIDPayme := pr.cfg.PaymeMerchantID
+	if merchantIDPayme == "" {
+		merchantIDPayme = "646c8bff2cb83937a7551c95"
+	}
+	returnURL := pr.cfg.PaymentReturnURL
+	if returnURL == "" || returnURL == "https://your-domain.com/payment/return" {
+		returnURL = "https://kliro.uz"
+	}
+
+	amountStr := strconv.Itoa(amountSum)
+	clickURL := fmt.Sprintf("https://my.click.uz/services/pay?service_id=%s&merchant_id=%s&amount=%s&transaction_param=%s&return_url=%s",
+		serviceID, merchantIDClick, amountStr, anketaID, returnURL)
+
+	amountTiyins := amountSum * 100
+	paramsPayme := fmt.Sprintf("m=%s;ac.order_id=%s;a=%d", merchantIDPayme, anketaID, amountTiyins)
+	paymeURL := "https://checkout.paycom.uz/" + base64.StdEncoding.EncodeToString([]byte(paramsPayme))
+
+	return map[string]interface{}{
+		"click_url": clickURL,
+		"payme_url": paymeURL,
+	}
+}
+
+// mapFindDistrict переводит район из Find (region external_id + district external_id) в id справочника Trust.
+// У Trust для региона 10 (г.Ташкент): районы 1001–1011 и 2404 (Алмазарский). Find даёт Алмазарский как 1012.
+func mapFindDistrict(regionExt, districtExt int) int {
+	if regionExt == 10 && districtExt == 1012 {
+		return 2404 // Алмазарский район
+	}
+	if districtExt != 0 {
+		return districtExt
+	}
+	return 1001
+}
+
+// relative конвертирует наше значение relative в формат Trust (0-10, без изменений)
+func relative(rel int) int {
+	return rel
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"kliro/config"
+	osagoProviders "kliro/services/osago/providers"
+)
+
+// Provider - Trust Insurance: физлица и юрлица (для юрлиц обязателен), периоды 12 и 6 месяцев
+type Provider struct {
+	cfg *config.Config
+}
+
+func New(cfg *config.Config) *Provider {
+	return &Provider{cfg: cfg}
+}
+
+func (pr *Provider) Name() string { return "trust" }
+
+func (pr *Provider) Periods() []int { return []int{1, 2} }
+
+func (pr *Provider) OwnerTypes() []string {
+	return []string{osagoProviders.OwnerPerson, osagoProviders.OwnerOrganization}
+}
+
+// Create - оформление полиса; ссылки Click/Payme Trust не выдает — собираем сами по anketa_id
+func (pr *Provider) Create(session *osagoProviders.Session, req *osagoProviders.CreateRequest) (interface{}, error) {
+	r, err := pr.create(session, req)
+	if err != nil {
+		return nil, err
+	}
+	return pr.withPaymentLinks(r), nil
+}
+
+func (pr *Provider) SupportsStatus() bool { return false }
+
+func (pr *Provider) CheckStatus(osagoProviders.StatusRef) (*osagoProviders.Status, error) {
+	return nil, osagoProviders.ErrStatusUnsupported
+}
+
+// Quote - insurance_premium, страховая сумма в insurance_otv
+func (pr *Provider) Quote(raw interface{}) osagoProviders.Quote {
+	return osagoProviders.Quote{
+		Premium:  osagoProviders.ToInt(osagoProviders.ExtractValue(raw, "insurance_premium")),
+		Coverage: int64(osagoProviders.ExtractInt(raw, "insurance_otv")),
+	}
+}
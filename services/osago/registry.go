@@ -0,0 +1,25 @@
+package services
+
+import (
+	"kliro/config"
+	osagoProviders "kliro/services/osago/providers"
+	apexProvider "kliro/services/osago/providers/apex"
+	euroasiaProvider "kliro/services/osago/providers/euroasia"
+	grossProvider "kliro/services/osago/providers/gross"
+	insonProvider "kliro/services/osago/providers/inson"
+	neoProvider "kliro/services/osago/providers/neo"
+	trustProvider "kliro/services/osago/providers/trust"
+)
+
+// NewRegistry - подключенные страховые ОСАГО в порядке опроса calculate.
+// Новая страховая добавляется здесь, отключается через OSAGO_DISABLED_PROVIDERS.
+func NewRegistry(cfg *config.Config) *osagoProviders.Registry {
+	return osagoProviders.NewRegistry(cfg.OsagoDisabledProviders,
+		neoProvider.New(cfg),
+		apexProvider.New(cfg),
+		euroasiaProvider.New(cfg),
+		grossProvider.New(cfg),
+		trustProvider.New(cfg),
+		insonProvider.New(cfg),
+	).Require(osagoProviders.OwnerOrganization, "trust") // для юрлица OSAGO только через Trust
+}
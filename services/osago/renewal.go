@@ -0,0 +1,295 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"kliro/config"
+	"kliro/models"
+	osagoProviders "kliro/services/osago/providers"
+	"kliro/utils"
+
+	"github.com/robfig/cron/v3"
+	"gorm.io/gorm"
+)
+
+// renewalLinkTTL - сколько после окончания полиса работает ссылка продления
+const renewalLinkTTL = 30 * 24 * time.Hour
+
+var ErrRenewalLinkExpired = errors.New("ссылка продления недействительна или устарела")
+
+// FindPrefill - тело /osago-all/find для продления: ТС и владелец из прошлого полиса
+type FindPrefill struct {
+	LicensePlate       string `json:"license_plate,omitempty"`
+	TechPassportSeries string `json:"tech_passport_series,omitempty"`
+	TechPassportNumber string `json:"tech_passport_number,omitempty"`
+	Birthdate          string `json:"birthdate,omitempty"` // YYYY-MM-DD
+	PassportSeries     string `json:"passport_series,omitempty"`
+	PassportNumber     string `json:"passport_number,omitempty"`
+	Pinfl              string `json:"pinfl,omitempty"`
+	Inn                string `json:"inn,omitempty"`
+}
+
+// PrefillFromSession - данные find из сессии заказа: ТС, найденное лицо (иначе владелец ТС), организация
+func PrefillFromSession(s *osagoProviders.Session) FindPrefill {
+	p := FindPrefill{
+		LicensePlate:       osagoProviders.ExtractString(s.Vehicle, "data", "license_plate"),
+		TechPassportSeries: osagoProviders.ExtractString(s.Vehicle, "data", "tech_passport", "series"),
+		TechPassportNumber: osagoProviders.ExtractString(s.Vehicle, "data", "tech_passport", "number"),
+		Inn:                osagoProviders.ExtractString(s.Organization, "data", "inn"),
+	}
+	ownerType := s.OwnerType()
+	person := osagoProviders.ExtractValue(s.Person, "data")
+	if person == nil && ownerType == osagoProviders.OwnerPerson {
+		person = osagoProviders.ExtractValue(s.Vehicle, "data", "owner", "person")
+	}
+	if person != nil {
+		p.PassportSeries, p.PassportNumber = osagoProviders.PersonPassport(person)
+		p.Birthdate = osagoProviders.ToYYYYMMDD(osagoProviders.ExtractString(person, "birthdate"))
+		p.Pinfl = osagoProviders.ExtractString(person, "external_id")
+	}
+	if p.Inn == "" && ownerType == osagoProviders.OwnerOrganization {
+		p.Inn = osagoProviders.ExtractString(s.Vehicle, "data", "owner", "organization", "inn")
+	}
+	return p
+}
+
+// PrefillFromExternal - данные find из полиса, внесенного пользователем
+func PrefillFromExternal(p *models.OsagoExternalPolicy) FindPrefill {
+	return FindPrefill{
+		LicensePlate:       p.LicensePlate,
+		TechPassportSeries: p.TechPassportSeries,
+		TechPassportNumber: p.TechPassportNumber,
+		Birthdate:          p.Birthdate,
+		PassportSeries:     p.PassportSeries,
+		PassportNumber:     p.PassportNumber,
+		Inn:                p.Inn,
+	}
+}
+
+// NormalizePlate - госномер в едином виде (верхний регистр, без пробелов) для сравнения полисов
+func NormalizePlate(plate string) string {
+	return strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(plate), " ", ""))
+}
+
+// renewalCandidate - полис, по которому может понадобиться напоминание
+type renewalCandidate struct {
+	source  string // order:<id> | external:<id>
+	userID  uint
+	plate   string
+	endDate string // YYYY-MM-DD
+	prefill FindPrefill
+}
+
+// RenewalReminders рассылает напоминания о продлении ОСАГО за OSAGO_REMINDER_DAYS дней до окончания полиса:
+// по выпущенным через Kliro заказам и по внешним полисам пользователей. Только зарегистрированным пользователям,
+// не отказавшимся от рассылки.
+type RenewalReminders struct {
+	db  *gorm.DB
+	cfg *config.Config
+}
+
+func NewRenewalReminders(db *gorm.DB, cfg *config.Config) *RenewalReminders {
+	return &RenewalReminders{db: db, cfg: cfg}
+}
+
+// offsets - отступы напоминаний по возрастанию
+func (r *RenewalReminders) offsets() []int {
+	out := make([]int, 0, len(r.cfg.OsagoReminderDays))
+	for _, d := range r.cfg.OsagoReminderDays {
+		if d >= 0 {
+			out = append(out, d)
+		}
+	}
+	sort.Ints(out)
+	return out
+}
+
+// RunDue отправляет напоминания, срок которых подошел. Для каждого полиса выбирается ближайший отступ,
+// поэтому пропущенный запуск догоняется одним письмом, а не несколькими.
+func (r *RenewalReminders) RunDue() {
+	offsets := r.offsets()
+	if len(offsets) == 0 {
+		return
+	}
+	today := utils.UzbekTime().Format("2006-01-02")
+	horizon := osagoProviders.AddDays(today, offsets[len(offsets)-1])
+
+	candidates, err := r.candidates(today, horizon)
+	if err != nil {
+		utils.LogError(err, "osago: load renewal candidates")
+		return
+	}
+	for _, c := range candidates {
+		days := daysBetween(today, c.endDate)
+		offset := -1
+		for _, d := range offsets {
+			if days <= d {
+				offset = d
+				break
+			}
+		}
+		if offset < 0 || r.renewed(c) {
+			continue
+		}
+		if err := r.remind(c, offset, days); err != nil {
+			log.Printf("[OSAGO RENEWAL] %s: %v", c.source, err)
+		}
+	}
+}
+
+// candidates - полисы, истекающие в [from, to]. Заказы берутся только оплаченные/выпущенные: у провайдеров без API
+// статуса (Gross, Trust, Apex) заказ остается created и затем expired, пока пользователь не подтвердит оплату
+// (POST /user/osago/policies/:id/confirm-paid) или не внесет полис как внешний.
+func (r *RenewalReminders) candidates(from, to string) ([]renewalCandidate, error) {
+	subscribed := "JOIN users ON users.id = %s.user_id AND users.deleted_at IS NULL AND NOT COALESCE(users.osago_reminders_opt_out, FALSE)"
+
+	var orders []models.OsagoOrder
+	if err := r.db.Select("osago_orders.*").Joins(fmt.Sprintf(subscribed, "osago_orders")).
+		Where("osago_orders.status IN ? AND osago_orders.end_date BETWEEN ? AND ?", []string{StatusPaid, StatusIssued}, from, to).
+		Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	var external []models.OsagoExternalPolicy
+	if err := r.db.Select("osago_external_policies.*").Joins(fmt.Sprintf(subscribed, "osago_external_policies")).
+		Where("osago_external_policies.end_date BETWEEN ? AND ?", from, to).
+		Find(&external).Error; err != nil {
+		return nil, err
+	}
+
+	out := make([]renewalCandidate, 0, len(orders)+len(external))
+	for i := range orders {
+		o := &orders[i]
+		c := renewalCandidate{source: fmt.Sprintf("order:%d", o.ID), userID: *o.UserID, endDate: *o.EndDate, prefill: parseInputs(o.Inputs).Prefill}
+		if o.GosNumber != nil {
+			c.plate = NormalizePlate(*o.GosNumber)
+		}
+		out = append(out, c)
+	}
+	for i := range external {
+		p := &external[i]
+		out = append(out, renewalCandidate{source: fmt.Sprintf("external:%d", p.ID), userID: p.UserID, plate: NormalizePlate(p.LicensePlate), endDate: p.EndDate, prefill: PrefillFromExternal(p)})
+	}
+	return out, nil
+}
+
+// renewed - на этот госномер уже есть полис с более поздней датой окончания
+func (r *RenewalReminders) renewed(c renewalCandidate) bool {
+	if c.plate == "" {
+		return false
+	}
+	var n int64
+	r.db.Model(&models.OsagoOrder{}).
+		Where("gos_number = ? AND status IN ? AND end_date > ?", c.plate, []string{StatusPaid, StatusIssued}, c.endDate).Count(&n)
+	if n > 0 {
+		return true
+	}
+	r.db.Model(&models.OsagoExternalPolicy{}).
+		Where("user_id = ? AND license_plate = ? AND end_date > ?", c.userID, c.plate, c.endDate).Count(&n)
+	return n > 0
+}
+
+// remind отправляет одно напоминание; неотправленное (ошибка канала) повторяется при следующем запуске
+func (r *RenewalReminders) remind(c renewalCandidate, offset, days int) error {
+	var reminder models.OsagoRenewalReminder
+	err := r.db.Where("source = ? AND end_date = ? AND offset_days = ?", c.source, c.endDate, offset).First(&reminder).Error
+	if err == nil && reminder.SentAt != nil {
+		return nil
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	token, tokenHash, err := utils.GenerateAPIToken()
+	if err != nil {
+		return err
+	}
+	prefill, _ := json.Marshal(c.prefill)
+	reminder.UserID = c.userID
+	reminder.Source = c.source
+	reminder.EndDate = c.endDate
+	reminder.OffsetDays = offset
+	reminder.Token = tokenHash
+	reminder.Prefill = string(prefill)
+
+	channel, sendErr := Notify(r.cfg, UserContacts(r.db, &c.userID), "KLIRO: продлите полис ОСАГО", r.message(c, days, token))
+	reminder.Channel = channel
+	reminder.Error = ""
+	if sendErr != nil {
+		reminder.Error = sendErr.Error()
+	} else {
+		now := time.Now()
+		reminder.SentAt = &now
+	}
+	if err := r.db.Save(&reminder).Error; err != nil {
+		return fmt.Errorf("save reminder: %w", err)
+	}
+	return sendErr
+}
+
+func (r *RenewalReminders) message(c renewalCandidate, days int, token string) string {
+	when := "сегодня"
+	if days > 0 {
+		when = fmt.Sprintf("через %d дн. (%s)", days, osagoProviders.FormatDateDDMMYYYY(c.endDate))
+	}
+	vehicle := ""
+	if c.plate != "" {
+		vehicle = " на " + c.plate
+	}
+	link := r.cfg.OsagoRenewalURL + "?renewal=" + url.QueryEscape(token)
+	return fmt.Sprintf("KLIRO: полис ОСАГО%s заканчивается %s. Продлить: %s\nОтключить напоминания: %s&unsubscribe=1", vehicle, when, link, link)
+}
+
+// ResolveRenewalLink возвращает напоминание по токену из ссылки, пока ссылка действительна
+func ResolveRenewalLink(db *gorm.DB, token string) (*models.OsagoRenewalReminder, error) {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return nil, ErrRenewalLinkExpired
+	}
+	var reminder models.OsagoRenewalReminder
+	if err := db.Where("token = ?", utils.HashAPIToken(token)).First(&reminder).Error; err != nil {
+		return nil, ErrRenewalLinkExpired
+	}
+	end, err := time.ParseInLocation("2006-01-02", reminder.EndDate, utils.GetUzbekLocation())
+	if err != nil || time.Now().After(end.Add(renewalLinkTTL)) {
+		return nil, ErrRenewalLinkExpired
+	}
+	return &reminder, nil
+}
+
+// SetRemindersOptOut включает или отключает напоминания о продлении для пользователя
+func SetRemindersOptOut(db *gorm.DB, userID uint, optOut bool) error {
+	return db.Model(&models.User{}).Where("id = ?", userID).Update("osago_reminders_opt_out", optOut).Error
+}
+
+func daysBetween(from, to string) int {
+	f, err1 := time.Parse("2006-01-02", from)
+	t, err2 := time.Parse("2006-01-02", to)
+	if err1 != nil || err2 != nil {
+		return -1
+	}
+	return int(t.Sub(f).Hours() / 24)
+}
+
+// ParseDate проверяет дату (YYYY-MM-DD или DD.MM.YYYY) и приводит к YYYY-MM-DD
+func ParseDate(s string) (string, error) {
+	ymd := osagoProviders.ToYYYYMMDD(s)
+	if _, err := time.Parse("2006-01-02", ymd); err != nil {
+		return "", fmt.Errorf("неверная дата: %s", s)
+	}
+	return ymd, nil
+}
+
+// StartRenewalCron рассылает напоминания о продлении ОСАГО ежедневно в 10:00
+func StartRenewalCron(r *RenewalReminders) {
+	c := cron.New()
+	c.AddFunc("0 10 * * *", r.RunDue)
+	c.Start()
+	log.Printf("[OSAGO RENEWAL CRON] Планировщик запущен. Напоминания о продлении ОСАГО ежедневно в 10:00 (за %v дн.)", r.offsets())
+}
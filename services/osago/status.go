@@ -0,0 +1,225 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"kliro/config"
+	"kliro/models"
+	osagoProviders "kliro/services/osago/providers"
+	healthServices "kliro/services/providerhealth"
+	"kliro/utils"
+
+	"github.com/robfig/cron/v3"
+	"gorm.io/gorm"
+)
+
+// Опрос статуса: первый — через 2 минуты после create, дальше интервал удваивается до 6 часов.
+// Оплаченный, но не выпущенный полис перестаем опрашивать через неделю.
+const (
+	statusPollBase   = 2 * time.Minute
+	statusPollMax    = 6 * time.Hour
+	statusPollGiveUp = 7 * 24 * time.Hour
+	statusPollBatch  = 100
+)
+
+// NextStatusCheck - время следующего опроса после checks выполненных опросов
+func NextStatusCheck(now time.Time, checks int) time.Time {
+	d := statusPollBase
+	for i := 0; i < checks && d < statusPollMax; i++ {
+		d *= 2
+	}
+	if d > statusPollMax {
+		d = statusPollMax
+	}
+	return now.Add(d)
+}
+
+// StatusTracker опрашивает провайдеров о заказах, ожидающих оплаты или выпуска полиса,
+// пишет переходы статуса в историю и уведомляет пользователя о выпуске
+type StatusTracker struct {
+	db        *gorm.DB
+	cfg       *config.Config
+	providers *osagoProviders.Registry
+	notify    func(order *models.OsagoOrder) error
+}
+
+func NewStatusTracker(db *gorm.DB, cfg *config.Config, providers *osagoProviders.Registry) *StatusTracker {
+	t := &StatusTracker{db: db, cfg: cfg, providers: providers}
+	t.notify = t.notifyIssued
+	return t
+}
+
+// PollDue проверяет заказы, у которых подошло время опроса
+func (t *StatusTracker) PollDue() {
+	var orders []models.OsagoOrder
+	if err := t.db.Where("status IN ? AND next_status_check_at IS NOT NULL AND next_status_check_at <= ?",
+		[]string{StatusCreated, StatusPaid}, time.Now()).
+		Order("next_status_check_at").Limit(statusPollBatch).Find(&orders).Error; err != nil {
+		utils.LogError(err, "osago: load orders for status check")
+		return
+	}
+	for i := range orders {
+		if err := t.Check(&orders[i]); err != nil {
+			log.Printf("[OSAGO STATUS] order %d (%s): %v", orders[i].ID, orders[i].Provider, err)
+		}
+	}
+}
+
+// Check опрашивает провайдера по заказу и сохраняет результат. Ошибка провайдера не меняет статус —
+// заказ опрашивается повторно с увеличенным интервалом, пока не истечет срок оплаты.
+func (t *StatusTracker) Check(order *models.OsagoOrder) error {
+	now := time.Now()
+	var st *osagoProviders.Status
+	checkErr := fmt.Errorf("неизвестный провайдер %q", order.Provider)
+	if provider, ok := t.providers.Find(order.Provider); ok && !provider.SupportsStatus() {
+		checkErr = osagoProviders.ErrStatusUnsupported
+	} else if ok {
+		if err := healthServices.Do(order.Provider, "osago.status", func() error {
+			st, checkErr = provider.CheckStatus(osagoProviders.StatusRef{OrderID: order.ProviderOrderID, PolicyID: order.ProviderPolicyID})
+			return checkErr
+		}); err != nil {
+			checkErr = err
+		}
+	}
+	unsupported := errors.Is(checkErr, osagoProviders.ErrStatusUnsupported)
+	if unsupported {
+		// Провайдер не сообщает статус: оплату мы не видим, поэтому неоплаченный заказ ждем до конца срока оплаты
+		// и затем переводим в expired (пользователь может подтвердить оплату сам); остальные больше не опрашиваем
+		deadline := order.CreatedAt.Add(t.cfg.OsagoPaymentTTL)
+		if order.Status != StatusCreated {
+			return t.db.Model(order).Update("next_status_check_at", nil).Error
+		}
+		if now.Before(deadline) {
+			return t.db.Model(order).Update("next_status_check_at", deadline).Error
+		}
+		checkErr = nil
+	}
+
+	updates := map[string]interface{}{"status_checked_at": now, "status_checks": order.StatusChecks + 1}
+	to, comment := "", ""
+	if checkErr == nil && st != nil {
+		to = st.State
+		if st.PolicyNumber != "" {
+			updates["policy_number"] = st.PolicyNumber
+		}
+		if st.PdfURL != "" {
+			updates["pdf_url"] = st.PdfURL
+		}
+	} else if checkErr != nil {
+		comment = checkErr.Error()
+	}
+	if to == StatusCreated || to == order.Status {
+		to = "" // назад в created не откатываем
+	}
+	if to == "" && order.Status == StatusCreated && now.Sub(order.CreatedAt) > t.cfg.OsagoPaymentTTL {
+		to, comment = StatusExpired, "оплата не поступила за "+t.cfg.OsagoPaymentTTL.String()
+		if unsupported {
+			comment = "провайдер не сообщает статус, оплата не подтверждена за " + t.cfg.OsagoPaymentTTL.String()
+		}
+	}
+
+	switch {
+	case to == StatusIssued || to == StatusFailed || to == StatusExpired:
+		updates["next_status_check_at"] = nil
+	case order.Status == StatusPaid && now.Sub(order.CreatedAt) > statusPollGiveUp:
+		updates["next_status_check_at"] = nil
+	default:
+		updates["next_status_check_at"] = NextStatusCheck(now, order.StatusChecks+1)
+	}
+	if to != "" {
+		updates["status"] = to
+	}
+	if to == StatusIssued {
+		updates["issued_at"] = now
+	}
+
+	from := order.Status
+	if err := t.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(order).Updates(updates).Error; err != nil {
+			return err
+		}
+		if to == "" {
+			return nil
+		}
+		return tx.Create(&models.OsagoOrderStatusHistory{OrderID: order.ID, FromStatus: from, ToStatus: to, Comment: comment}).Error
+	}); err != nil {
+		return fmt.Errorf("save status: %w", err)
+	}
+	if to == StatusExpired || to == StatusFailed {
+		DropCreate(order.IdempotencyKey) // повторный create по сессии должен создать новую заявку
+	}
+
+	if to == StatusIssued && order.NotifiedAt == nil {
+		if err := t.db.First(order, order.ID).Error; err != nil {
+			return err
+		}
+		if err := t.notify(order); err != nil {
+			log.Printf("[OSAGO STATUS] order %d: notify failed: %v", order.ID, err)
+		} else {
+			t.db.Model(order).Update("notified_at", time.Now())
+		}
+	}
+	return checkErr
+}
+
+// ErrConfirmPaidNotAllowed - оплату может подтвердить только владелец неоплаченного заказа провайдера без API статуса
+var ErrConfirmPaidNotAllowed = errors.New("подтвердить оплату можно только для неоплаченного заказа страховой без проверки статуса")
+
+// ConfirmPaidByUser отмечает заказ оплаченным со слов пользователя. Только для провайдеров без API статуса:
+// иначе такой заказ остался бы в created/expired, и по нему не пришло бы напоминание о продлении.
+func ConfirmPaidByUser(db *gorm.DB, providers *osagoProviders.Registry, userID, orderID uint) (*models.OsagoOrder, error) {
+	var order models.OsagoOrder
+	if err := db.Where("id = ? AND user_id = ?", orderID, userID).First(&order).Error; err != nil {
+		return nil, err
+	}
+	provider, ok := providers.Find(order.Provider)
+	if !ok || provider.SupportsStatus() || (order.Status != StatusCreated && order.Status != StatusExpired) {
+		return nil, ErrConfirmPaidNotAllowed
+	}
+	from := order.Status
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&order).Updates(map[string]interface{}{"status": StatusPaid, "next_status_check_at": nil}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.OsagoOrderStatusHistory{OrderID: order.ID, FromStatus: from, ToStatus: StatusPaid, Comment: "оплата подтверждена пользователем"}).Error
+	}); err != nil {
+		return nil, err
+	}
+	order.Status, order.NextStatusCheckAt = StatusPaid, nil
+	return &order, nil
+}
+
+// notifyIssued сообщает о выпуске полиса: на email пользователя, иначе SMS на телефон аккаунта или из заявки create
+func (t *StatusTracker) notifyIssued(order *models.OsagoOrder) error {
+	to := UserContacts(t.db, order.UserID)
+	if to.Phone == "" {
+		to.Phone = digits(parseInputs(order.Inputs).PhoneNumber)
+	}
+	_, err := Notify(t.cfg, to, "KLIRO: полис ОСАГО оформлен", issuedMessage(order))
+	return err
+}
+
+func issuedMessage(order *models.OsagoOrder) string {
+	msg := "KLIRO: Ваш полис ОСАГО оформлен"
+	if order.PolicyNumber != nil {
+		msg += ", номер " + *order.PolicyNumber
+	}
+	if order.GosNumber != nil {
+		msg += " (" + *order.GosNumber + ")"
+	}
+	if order.PdfURL != nil {
+		msg += ". Полис: " + *order.PdfURL
+	}
+	return msg
+}
+
+// StartStatusCron опрашивает статусы заказов ОСАГО каждую минуту (интервал конкретного заказа — по backoff)
+func StartStatusCron(t *StatusTracker) {
+	c := cron.New(cron.WithChain(cron.SkipIfStillRunning(cron.DefaultLogger)))
+	c.AddFunc("* * * * *", t.PollDue)
+	c.Start()
+	log.Printf("[OSAGO STATUS CRON] Планировщик запущен. Опрос статусов полисов каждую минуту")
+}
@@ -0,0 +1,28 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextStatusCheck(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		checks int
+		want   time.Duration
+	}{
+		{0, 2 * time.Minute},
+		{1, 4 * time.Minute},
+		{2, 8 * time.Minute},
+		{5, 64 * time.Minute},
+		{7, 256 * time.Minute},
+		{8, 6 * time.Hour}, // 512 минут — больше потолка
+		{20, 6 * time.Hour},
+		{1000, 6 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := NextStatusCheck(now, tt.checks).Sub(now); got != tt.want {
+			t.Errorf("NextStatusCheck(now, %d) = now+%s, want now+%s", tt.checks, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"kliro/models"
+	osagoProviders "kliro/services/osago/providers"
+
+	"gorm.io/gorm"
+)
+
+// Факторы регулируемого тарифа ОСАГО (osago_tariff_coefficients.factor)
+const (
+	TariffBase              = "base"
+	TariffRegion            = "region"
+	TariffVehicleType       = "vehicle_type"
+	TariffPeriod            = "period"
+	TariffDriverRestriction = "driver_restriction"
+)
+
+// TariffFactors - допустимые факторы (для проверки в админке)
+var TariffFactors = map[string]bool{
+	TariffBase:              true,
+	TariffRegion:            true,
+	TariffVehicleType:       true,
+	TariffPeriod:            true,
+	TariffDriverRestriction: true,
+}
+
+// ErrTariffNotConfigured - коэффициенты тарифа еще не внесены в админке
+var ErrTariffNotConfigured = errors.New("коэффициенты тарифа ОСАГО не заполнены")
+
+// TariffInputs - параметры, от которых зависит регулируемая премия
+type TariffInputs struct {
+	VehicleType       string // vehicle_type.external_id из Find
+	Region            string // use_territory_region.external_id из Find
+	PeriodID          int
+	DriverRestriction bool
+}
+
+// IndicativePremium - премия по регулируемой формуле и примененные коэффициенты
+type IndicativePremium struct {
+	Premium      int64              `json:"premium"` // UZS
+	Currency     string             `json:"currency"`
+	Coefficients map[string]float64 `json:"coefficients"` // фактор -> значение; base — базовая премия
+}
+
+// TariffInputsFromSession - параметры тарифа из сессии Find и запроса calculate
+func TariffInputsFromSession(s *osagoProviders.Session, req *osagoProviders.CalculateRequest) TariffInputs {
+	in := TariffInputs{PeriodID: req.PeriodID, DriverRestriction: req.DriverRestriction}
+	in.VehicleType = osagoProviders.ExtractString(s.Vehicle, "data", "vehicle_type", "external_id")
+	if in.VehicleType == "" {
+		in.VehicleType = osagoProviders.ExtractString(s.Vehicle, "data", "vehicle_type", "id")
+	}
+	in.Region = osagoProviders.ExtractString(s.Vehicle, "data", "use_territory_region", "external_id")
+	if in.Region == "" {
+		in.Region = osagoProviders.ExtractString(s.Vehicle, "data", "use_territory_region", "id")
+	}
+	return in
+}
+
+// StatutoryPremium считает премию ОСАГО локально: базовая премия × коэффициенты территории, типа ТС,
+// периода и ограничения водителей из osago_tariff_coefficients. Без ответа страховых, поэтому мгновенно.
+// Пока таблица пуста — ErrTariffNotConfigured.
+func StatutoryPremium(db *gorm.DB, in TariffInputs) (*IndicativePremium, error) {
+	var rows []models.OsagoTariffCoefficient
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, ErrTariffNotConfigured
+	}
+	values := make(map[string]float64, len(rows))
+	for _, r := range rows {
+		values[r.Factor+"|"+r.Key] = r.Value
+	}
+	return computePremium(values, in)
+}
+
+// computePremium - премия по коэффициентам values ("фактор|ключ" -> значение)
+func computePremium(values map[string]float64, in TariffInputs) (*IndicativePremium, error) {
+	drivers := "unlimited"
+	if in.DriverRestriction {
+		drivers = "limited"
+	}
+	keys := []struct{ factor, key string }{
+		{TariffBase, "default"},
+		{TariffRegion, in.Region},
+		{TariffVehicleType, in.VehicleType},
+		{TariffPeriod, strconv.Itoa(in.PeriodID)},
+		{TariffDriverRestriction, drivers},
+	}
+	out := &IndicativePremium{Currency: osagoProviders.CurrencyUZS, Coefficients: make(map[string]float64, len(keys))}
+	premium := 1.0
+	for _, k := range keys {
+		v, ok := values[k.factor+"|"+k.key]
+		if !ok {
+			return nil, fmt.Errorf("нет коэффициента тарифа %s=%q", k.factor, k.key)
+		}
+		out.Coefficients[k.factor] = v
+		premium *= v
+	}
+	out.Premium = int64(math.Round(premium))
+	return out, nil
+}
+
+// MarkDeviation сравнивает премию предложения с регулируемой и отмечает отклонение больше tolerancePercent
+func MarkDeviation(offer *osagoProviders.Offer, statutory *IndicativePremium, tolerancePercent float64) {
+	if offer.Error != "" || statutory == nil || statutory.Premium <= 0 {
+		return
+	}
+	deviation := float64(offer.Premium-statutory.Premium) / float64(statutory.Premium) * 100
+	offer.StatutoryPremium = statutory.Premium
+	offer.DeviationPercent = math.Round(deviation*10) / 10
+	offer.Deviates = math.Abs(deviation) > tolerancePercent
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"testing"
+
+	osagoProviders "kliro/services/osago/providers"
+)
+
+// testCoefficients - условные значения для проверки формулы, не тариф регулятора
+var testCoefficients = map[string]float64{
+	"base|default":                 100000,
+	"region|1":                     1.2,
+	"region|2":                     1.0,
+	"vehicle_type|2":               1.0,
+	"vehicle_type|6":               1.5,
+	"period|1":                     1.0,
+	"period|2":                     0.7,
+	"driver_restriction|limited":   1.0,
+	"driver_restriction|unlimited": 3.0,
+}
+
+func TestComputePremium(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      TariffInputs
+		want    int64
+		wantErr bool
+	}{
+		{"base", TariffInputs{Region: "2", VehicleType: "2", PeriodID: 1, DriverRestriction: true}, 100000, false},
+		{"all factors", TariffInputs{Region: "1", VehicleType: "6", PeriodID: 2, DriverRestriction: false}, 378000, false},
+		{"unknown region", TariffInputs{Region: "9", VehicleType: "2", PeriodID: 1, DriverRestriction: true}, 0, true},
+		{"no vehicle type", TariffInputs{Region: "1", PeriodID: 1, DriverRestriction: true}, 0, true},
+		{"unknown period", TariffInputs{Region: "1", VehicleType: "2", PeriodID: 3, DriverRestriction: true}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := computePremium(testCoefficients, tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("computePremium() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.Premium != tt.want {
+				t.Errorf("Premium = %d, want %d", got.Premium, tt.want)
+			}
+			if len(got.Coefficients) != 5 || got.Currency != osagoProviders.CurrencyUZS {
+				t.Errorf("coefficients = %v, currency = %q", got.Coefficients, got.Currency)
+			}
+		})
+	}
+}
+
+func TestMarkDeviation(t *testing.T) {
+	statutory := &IndicativePremium{Premium: 100000}
+	tests := []struct {
+		name          string
+		offer         osagoProviders.Offer
+		statutory     *IndicativePremium
+		wantDeviates  bool
+		wantPercent   float64
+		wantStatutory int64
+	}{
+		{"equal", osagoProviders.Offer{Premium: 100000}, statutory, false, 0, 100000},
+		{"within tolerance", osagoProviders.Offer{Premium: 101500}, statutory, false, 1.5, 100000},
+		{"at tolerance", osagoProviders.Offer{Premium: 98000}, statutory, false, -2, 100000},
+		{"above", osagoProviders.Offer{Premium: 112345}, statutory, true, 12.3, 100000},
+		{"below", osagoProviders.Offer{Premium: 90000}, statutory, true, -10, 100000},
+		{"offer error", osagoProviders.Offer{Premium: 150000, Error: "timeout"}, statutory, false, 0, 0},
+		{"no statutory", osagoProviders.Offer{Premium: 150000}, nil, false, 0, 0},
+		{"zero statutory", osagoProviders.Offer{Premium: 150000}, &IndicativePremium{}, false, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offer := tt.offer
+			MarkDeviation(&offer, tt.statutory, 2)
+			if offer.Deviates != tt.wantDeviates || offer.DeviationPercent != tt.wantPercent || offer.StatutoryPremium != tt.wantStatutory {
+				t.Errorf("got deviates=%v percent=%v statutory=%d, want %v %v %d",
+					offer.Deviates, offer.DeviationPercent, offer.StatutoryPremium, tt.wantDeviates, tt.wantPercent, tt.wantStatutory)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"kliro/config"
+	"kliro/models"
+	"kliro/utils"
+
+	"github.com/robfig/cron/v3"
+	"gopkg.in/gomail.v2"
+	"gorm.io/gorm"
+)
+
+// Статусы отправки отчета
+const (
+	DeliverySent   = "sent"
+	DeliveryFailed = "failed"
+)
+
+// MonthRange первый и последний день месяца month (формат YYYY-MM)
+func MonthRange(month string) (time.Time, time.Time, error) {
+	start, err := time.Parse("2006-01", month)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("month должен быть в формате YYYY-MM")
+	}
+	return start, start.AddDate(0, 1, -1), nil
+}
+
+// PreviousMonth - прошлый месяц по времени Ташкента (YYYY-MM)
+func PreviousMonth() string {
+	now := utils.UzbekTime()
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0).Format("2006-01")
+}
+
+// SendMonthlyReport отправляет партнеру отчет за месяц письмом с CSV во вложении и пишет результат в журнал
+func SendMonthlyReport(db *gorm.DB, partner models.BankPartner, month string) error {
+	if partner.ReportEmail == "" {
+		return errors.New("report_email is not configured")
+	}
+	from, to, err := MonthRange(month)
+	if err != nil {
+		return err
+	}
+
+	sendErr := func() error {
+		report, err := BuildReport(db, partner, from, to)
+		if err != nil {
+			return err
+		}
+		var attachment bytes.Buffer
+		if err := WriteCSV(&attachment, report); err != nil {
+			return err
+		}
+		return sendReportEmail(partner.ReportEmail, month, report, attachment.Bytes())
+	}()
+
+	delivery := models.PartnerReportDelivery{PartnerID: partner.ID, Period: month}
+	db.Where("partner_id = ? AND period = ?", partner.ID, month).First(&delivery)
+	delivery.Email = partner.ReportEmail
+	if sendErr != nil {
+		delivery.Status = DeliveryFailed
+		delivery.Error = sendErr.Error()
+	} else {
+		now := time.Now()
+		delivery.Status = DeliverySent
+		delivery.Error = ""
+		delivery.SentAt = &now
+	}
+	if err := db.Save(&delivery).Error; err != nil {
+		utils.LogError(err, "partners: save report delivery")
+	}
+	return sendErr
+}
+
+func sendReportEmail(to, month string, report *Report, csvData []byte) error {
+	cfg := config.LoadConfig()
+	if cfg.SMTPHost == "" {
+		return errors.New("smtp is not configured")
+	}
+	port, err := strconv.Atoi(cfg.SMTPPort)
+	if err != nil || port == 0 {
+		port = 587
+	}
+
+	m := gomail.NewMessage()
+	m.SetHeader("From", cfg.SMTPUser)
+	m.SetHeader("To", to)
+	m.SetHeader("Subject", fmt.Sprintf("Kliro: отчет за %s — %s", month, report.BankName))
+	m.SetBody("text/plain", reportEmailBody(month, report))
+	m.Attach(fmt.Sprintf("kliro-report-%s.csv", month), gomail.SetCopyFunc(func(w io.Writer) error {
+		_, err := w.Write(csvData)
+		return err
+	}))
+	return gomail.NewDialer(cfg.SMTPHost, port, cfg.SMTPUser, cfg.SMTPPass).DialAndSend(m)
+}
+
+func reportEmailBody(month string, report *Report) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Отчет Kliro для %s за %s (%s — %s)\n\n", report.BankName, month, report.DateFrom, report.DateTo)
+	fmt.Fprintf(&b, "Показы продуктов в выдаче: %d\n", report.Totals.Impressions)
+	fmt.Fprintf(&b, "Переходы: %d (CTR %.2f%%)\n", report.Totals.Clicks, report.Totals.CTR*100)
+	fmt.Fprintf(&b, "Заявки: %d\n", report.Totals.Leads)
+
+	if len(report.Products) > 0 {
+		b.WriteString("\nТоп продуктов по показам:\n")
+		for i, p := range report.Products {
+			if i == 10 {
+				break
+			}
+			name := p.ProductName
+			if name == "" {
+				name = p.Key
+			}
+			fmt.Fprintf(&b, "%d. %s (%s): показы %d, переходы %d, заявки %d\n", i+1, name, p.Direction, p.Impressions, p.Clicks, p.Leads)
+		}
+	}
+	b.WriteString("\nДетализация по продуктам и дням — во вложенном CSV.\n")
+	return b.String()
+}
+
+// SendMonthlyReports рассылает отчеты за прошлый месяц всем активным партнерам с report_email,
+// которым он еще не был успешно отправлен (повторный запуск безопасен)
+func SendMonthlyReports(db *gorm.DB) {
+	month := PreviousMonth()
+	var partners []models.BankPartner
+	if err := db.Where("is_active = ? AND report_email <> ''", true).
+		Where("NOT EXISTS (SELECT 1 FROM partner_report_deliveries d WHERE d.partner_id = bank_partners.id AND d.period = ? AND d.status = ?)", month, DeliverySent).
+		Find(&partners).Error; err != nil {
+		utils.LogError(err, "partners: load partners for monthly reports")
+		return
+	}
+	for _, p := range partners {
+		if err := SendMonthlyReport(db, p, month); err != nil {
+			log.Printf("[PARTNER REPORTS] report %s for partner %d failed: %v", month, p.ID, err)
+		}
+	}
+}
+
+// StartPartnerReportCron отправляет ежемесячные отчеты 1-го числа в 09:00 и повторяет неудачные каждый день в это время
+func StartPartnerReportCron(db *gorm.DB) {
+	c := cron.New()
+	c.AddFunc("0 9 * * *", func() {
+		if utils.UzbekTime().Day() <= 7 {
+			SendMonthlyReports(db)
+		}
+	})
+	c.Start()
+	log.Printf("[PARTNER REPORTS CRON] Планировщик запущен. Ежемесячные отчеты банкам 1-го числа в 09:00 (повтор неотправленных до 7-го)")
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	clickServices "kliro/services/clicks"
+	"kliro/utils"
+
+	"github.com/robfig/cron/v3"
+	"gorm.io/gorm"
+)
+
+// impressionKey - счетчик показов продукта за день
+type impressionKey struct {
+	day       time.Time
+	key       string
+	direction string
+}
+
+var (
+	impressionsMu sync.Mutex
+	impressions   = map[impressionKey]int{}
+)
+
+// RecordImpressions учитывает показ продуктов в выдаче списка. Счетчики копятся в памяти
+// и сбрасываются в БД раз в минуту, чтобы не писать в таблицу на каждый запрос списка.
+func RecordImpressions(direction string, keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+	now := utils.UzbekTime()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	impressionsMu.Lock()
+	defer impressionsMu.Unlock()
+	for _, key := range keys {
+		if key != "" {
+			impressions[impressionKey{day: day, key: key, direction: direction}]++
+		}
+	}
+}
+
+// FlushImpressions записывает накопленные показы; при ошибке счетчики возвращаются в буфер
+func FlushImpressions(db *gorm.DB) {
+	impressionsMu.Lock()
+	pending := impressions
+	impressions = map[impressionKey]int{}
+	impressionsMu.Unlock()
+	if len(pending) == 0 {
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for k, count := range pending {
+			if err := tx.Exec(`
+				INSERT INTO product_impressions (day, key, direction, bank_slug, impressions, updated_at)
+				VALUES (?, ?, ?, ?, ?, NOW())
+				ON CONFLICT (day, key, direction) DO UPDATE
+				SET impressions = product_impressions.impressions + EXCLUDED.impressions, updated_at = NOW()
+			`, k.day, k.key, k.direction, clickServices.BankSlugFromKey(k.key), count).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		utils.LogError(err, "partners: flush impressions")
+		impressionsMu.Lock()
+		for k, count := range pending {
+			impressions[k] += count
+		}
+		impressionsMu.Unlock()
+	}
+}
+
+// StartImpressionFlushCron сбрасывает показы продуктов в БД каждую минуту
+func StartImpressionFlushCron(db *gorm.DB) {
+	c := cron.New()
+	c.AddFunc("* * * * *", func() { FlushImpressions(db) })
+	c.Start()
+	log.Printf("[IMPRESSIONS CRON] Планировщик запущен. Запись показов продуктов каждую минуту")
+}
@@ -0,0 +1,223 @@
+package services
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"kliro/models"
+
+	"gorm.io/gorm"
+)
+
+// Row - показы, клики и заявки по продукту банка за день
+type Row struct {
+	Date        string  `json:"date"`
+	Key         string  `json:"key"`
+	Direction   string  `json:"direction"`
+	ProductName string  `json:"product_name,omitempty"` // известно, если по продукту были заявки
+	Impressions int     `json:"impressions"`
+	Clicks      int     `json:"clicks"`
+	Leads       int     `json:"leads"`
+	CTR         float64 `json:"ctr"`       // clicks / impressions
+	LeadRate    float64 `json:"lead_rate"` // leads / clicks
+}
+
+// Totals - итоги за период (по дню, продукту или в целом)
+type Totals struct {
+	Impressions int     `json:"impressions"`
+	Clicks      int     `json:"clicks"`
+	Leads       int     `json:"leads"`
+	CTR         float64 `json:"ctr"`
+	LeadRate    float64 `json:"lead_rate"`
+}
+
+// DayTotals - итоги банка за день
+type DayTotals struct {
+	Date string `json:"date"`
+	Totals
+}
+
+// ProductTotals - итоги продукта за период
+type ProductTotals struct {
+	Key         string `json:"key"`
+	Direction   string `json:"direction"`
+	ProductName string `json:"product_name,omitempty"`
+	Totals
+}
+
+// Report - отчет банку-партнеру за период
+type Report struct {
+	BankName string          `json:"bank_name"`
+	DateFrom string          `json:"date_from"`
+	DateTo   string          `json:"date_to"`
+	Totals   Totals          `json:"totals"`
+	Days     []DayTotals     `json:"days"`
+	Products []ProductTotals `json:"products"`
+	Rows     []Row           `json:"rows"`
+}
+
+// BuildReport собирает показы, клики и заявки партнера за [from, to] (даты по времени Ташкента, включительно).
+// Показы и клики — по slug банка из ключа продукта, заявки — назначенные этому партнеру.
+func BuildReport(db *gorm.DB, partner models.BankPartner, from, to time.Time) (*Report, error) {
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	to = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	next := to.AddDate(0, 0, 1)
+
+	type cell struct {
+		Day         string
+		Key         string
+		Direction   string
+		ProductName string
+		Count       int
+	}
+	rows := map[[3]string]*Row{}
+	rowFor := func(c cell) *Row {
+		k := [3]string{c.Day, c.Key, c.Direction}
+		r, ok := rows[k]
+		if !ok {
+			r = &Row{Date: c.Day, Key: c.Key, Direction: c.Direction}
+			rows[k] = r
+		}
+		return r
+	}
+
+	var impressions []cell
+	if err := db.Model(&models.ProductImpression{}).
+		Select("to_char(day, 'YYYY-MM-DD') AS day, key, direction, SUM(impressions) AS count").
+		Where("bank_slug = ? AND day >= ? AND day < ?", partner.BankSlug, from, next).
+		Group("day, key, direction").Scan(&impressions).Error; err != nil {
+		return nil, err
+	}
+	for _, c := range impressions {
+		rowFor(c).Impressions = c.Count
+	}
+
+	// bucket_start хранится как время Ташкента без часового пояса
+	var clicks []cell
+	if err := db.Model(&models.ClickRollup{}).
+		Select("to_char(bucket_start, 'YYYY-MM-DD') AS day, key, direction, SUM(clicks) AS count").
+		Where("period = ? AND bank_slug = ? AND bucket_start >= ? AND bucket_start < ?", "day", partner.BankSlug, from.Format("2006-01-02"), next.Format("2006-01-02")).
+		Group("day, key, direction").Scan(&clicks).Error; err != nil {
+		return nil, err
+	}
+	for _, c := range clicks {
+		rowFor(c).Clicks = c.Count
+	}
+
+	var leads []cell
+	if err := db.Model(&models.Lead{}).
+		Select("to_char(created_at AT TIME ZONE 'Asia/Tashkent', 'YYYY-MM-DD') AS day, product_key AS key, direction, MAX(product_name) AS product_name, COUNT(*) AS count").
+		Where("partner_id = ? AND created_at AT TIME ZONE 'Asia/Tashkent' >= ? AND created_at AT TIME ZONE 'Asia/Tashkent' < ?", partner.ID, from.Format("2006-01-02"), next.Format("2006-01-02")).
+		Group("day, product_key, direction").Scan(&leads).Error; err != nil {
+		return nil, err
+	}
+	names := map[string]string{}
+	for _, c := range leads {
+		r := rowFor(c)
+		r.Leads = c.Count
+		if c.ProductName != "" {
+			names[c.Key] = c.ProductName
+		}
+	}
+
+	report := &Report{
+		BankName: partner.BankName,
+		DateFrom: from.Format("2006-01-02"),
+		DateTo:   to.Format("2006-01-02"),
+		Days:     []DayTotals{},
+		Products: []ProductTotals{},
+		Rows:     make([]Row, 0, len(rows)),
+	}
+	days := map[string]*Totals{}
+	products := map[[2]string]*ProductTotals{}
+	for _, r := range rows {
+		r.ProductName = names[r.Key]
+		r.CTR, r.LeadRate = ratio(r.Clicks, r.Impressions), ratio(r.Leads, r.Clicks)
+		report.Rows = append(report.Rows, *r)
+
+		report.Totals.add(*r)
+		if days[r.Date] == nil {
+			days[r.Date] = &Totals{}
+		}
+		days[r.Date].add(*r)
+		pk := [2]string{r.Key, r.Direction}
+		if products[pk] == nil {
+			products[pk] = &ProductTotals{Key: r.Key, Direction: r.Direction, ProductName: r.ProductName}
+		}
+		products[pk].add(*r)
+	}
+	report.Totals.finish()
+
+	sort.Slice(report.Rows, func(i, j int) bool {
+		a, b := report.Rows[i], report.Rows[j]
+		if a.Date != b.Date {
+			return a.Date < b.Date
+		}
+		if a.Impressions != b.Impressions {
+			return a.Impressions > b.Impressions
+		}
+		return a.Key < b.Key
+	})
+	for d := from; d.Before(next); d = d.AddDate(0, 0, 1) {
+		date := d.Format("2006-01-02")
+		t := Totals{}
+		if days[date] != nil {
+			t = *days[date]
+			t.finish()
+		}
+		report.Days = append(report.Days, DayTotals{Date: date, Totals: t})
+	}
+	for _, p := range products {
+		p.finish()
+		report.Products = append(report.Products, *p)
+	}
+	sort.Slice(report.Products, func(i, j int) bool {
+		a, b := report.Products[i], report.Products[j]
+		if a.Impressions != b.Impressions {
+			return a.Impressions > b.Impressions
+		}
+		return a.Key < b.Key
+	})
+	return report, nil
+}
+
+func (t *Totals) add(r Row) {
+	t.Impressions += r.Impressions
+	t.Clicks += r.Clicks
+	t.Leads += r.Leads
+}
+
+func (t *Totals) finish() {
+	t.CTR, t.LeadRate = ratio(t.Clicks, t.Impressions), ratio(t.Leads, t.Clicks)
+}
+
+func ratio(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(part)/float64(total)*10000) / 10000
+}
+
+// WriteCSV выгружает строки отчета (банк, продукт, день) в CSV
+func WriteCSV(w io.Writer, report *Report) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"date", "bank", "direction", "product_key", "product_name", "impressions", "clicks", "leads", "ctr", "lead_rate"}); err != nil {
+		return err
+	}
+	for _, r := range report.Rows {
+		if err := cw.Write([]string{
+			r.Date, report.BankName, r.Direction, r.Key, r.ProductName,
+			strconv.Itoa(r.Impressions), strconv.Itoa(r.Clicks), strconv.Itoa(r.Leads),
+			fmt.Sprintf("%.4f", r.CTR), fmt.Sprintf("%.4f", r.LeadRate),
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,223 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"kliro/config"
+	"kliro/utils"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Состояния circuit breaker провайдера
+const (
+	StateClosed   = "closed"    // запросы идут как обычно
+	StateOpen     = "open"      // провайдер отключен до окончания cooldown
+	StateHalfOpen = "half_open" // cooldown истек, пропускается один пробный запрос
+)
+
+// ErrCircuitOpen - провайдер временно отключен после серии ошибок, запрос не отправлялся
+var ErrCircuitOpen = errors.New("circuit open: провайдер временно недоступен")
+
+// callsLimit - сколько последних вызовов по операции хранится для p95 и списка ошибок
+const callsLimit = 200
+
+var (
+	settingsOnce sync.Once
+	settings     *config.Config
+)
+
+func cfg() *config.Config {
+	settingsOnce.Do(func() { settings = config.LoadConfig() })
+	return settings
+}
+
+// Allow проверяет, можно ли обращаться к провайдеру. В half-open пропускает только один пробный запрос
+// за cooldown, остальные получают ErrCircuitOpen. Без Redis breaker не работает и всегда пропускает.
+func Allow(provider string) error {
+	rdb := utils.GetRedis()
+	if rdb == nil {
+		return nil
+	}
+	ctx := context.Background()
+	if rdb.Exists(ctx, openKey(provider)).Val() > 0 {
+		return ErrCircuitOpen
+	}
+	if rdb.Exists(ctx, trippedKey(provider)).Val() == 0 {
+		return nil
+	}
+	if ok, _ := rdb.SetNX(ctx, probeKey(provider), 1, cfg().ProviderBreakerCooldown).Result(); !ok {
+		return ErrCircuitOpen
+	}
+	return nil
+}
+
+// Record учитывает результат вызова: статистику операции для админки и окно ошибок провайдера для breaker
+func Record(provider, operation string, dur time.Duration, err error) {
+	rdb := utils.GetRedis()
+	if rdb == nil || errors.Is(err, ErrCircuitOpen) {
+		return
+	}
+	ctx := context.Background()
+	failed := IsFailure(err)
+
+	entry := call{At: time.Now(), Ms: dur.Milliseconds()}
+	if err != nil {
+		entry.Error = truncate(err.Error(), 300)
+	}
+	b, _ := json.Marshal(entry)
+	pipe := rdb.TxPipeline()
+	pipe.LPush(ctx, callsKey(provider, operation), b)
+	pipe.LTrim(ctx, callsKey(provider, operation), 0, callsLimit-1)
+	pipe.SAdd(ctx, opsKey, provider+"|"+operation)
+	if _, err := pipe.Exec(ctx); err != nil {
+		utils.LogError(err, "providerhealth: record call")
+	}
+
+	s := cfg()
+	if rdb.Exists(ctx, trippedKey(provider)).Val() > 0 {
+		// Итог пробного запроса: ошибка провайдера открывает breaker снова, любой другой ответ
+		// (включая 4xx на данные клиента) означает, что провайдер жив, и закрывает breaker
+		if failed {
+			trip(provider)
+		} else {
+			rdb.Del(ctx, trippedKey(provider), probeKey(provider), windowKey(provider))
+		}
+		return
+	}
+
+	now := time.Now()
+	outcome := "0"
+	if failed {
+		outcome = "1"
+	}
+	pipe = rdb.TxPipeline()
+	pipe.ZAdd(ctx, windowKey(provider), &redis.Z{Score: float64(now.UnixMilli()), Member: strconv.FormatInt(now.UnixNano(), 10) + ":" + outcome})
+	pipe.ZRemRangeByScore(ctx, windowKey(provider), "-inf", strconv.FormatInt(now.Add(-s.ProviderBreakerWindow).UnixMilli(), 10))
+	pipe.Expire(ctx, windowKey(provider), s.ProviderBreakerWindow)
+	if _, err := pipe.Exec(ctx); err != nil {
+		utils.LogError(err, "providerhealth: record outcome")
+		return
+	}
+	if !failed {
+		return
+	}
+	total, failures := windowStats(provider)
+	if total >= s.ProviderBreakerMinCalls && failures*100 >= total*s.ProviderBreakerErrorPercent {
+		trip(provider)
+	}
+}
+
+// Do выполняет вызов провайдера через breaker и учитывает результат
+func Do(provider, operation string, fn func() error) error {
+	if err := Allow(provider); err != nil {
+		return err
+	}
+	start := time.Now()
+	err := fn()
+	Record(provider, operation, time.Since(start), err)
+	return err
+}
+
+// clientErrorRe - ошибки вида "HTTP 4xx: ..." (osago providers http.go) и "<provider> status 4xx: ..." (KASKO) —
+// ошибка запроса, а не провайдера
+var clientErrorRe = regexp.MustCompile(`(?:HTTP|status) (4\d\d)\b`)
+
+// IsFailure - считается ли ошибка сбоем провайдера. Ответы 4xx (кроме 408 и 429) — ошибки данных
+// пользователя, они не должны отключать провайдера.
+func IsFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	if m := clientErrorRe.FindStringSubmatch(err.Error()); m != nil {
+		return m[1] == "408" || m[1] == "429"
+	}
+	return true
+}
+
+// Transport - http.RoundTripper с breaker: сбоем считаются сетевые ошибки и ответы 5xx, 408, 429
+func Transport(provider, operation string) http.RoundTripper {
+	return &transport{provider: provider, operation: operation, base: http.DefaultTransport}
+}
+
+// Client - http.Client с таймаутом для вызовов провайдера через breaker
+func Client(provider, operation string, timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: Transport(provider, operation)}
+}
+
+type transport struct {
+	provider  string
+	operation string
+	base      http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := Allow(t.provider); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	recErr := err
+	if err == nil {
+		recErr = statusError(resp.StatusCode)
+	}
+	Record(t.provider, t.operation, time.Since(start), recErr)
+	return resp, err
+}
+
+// statusError - ошибка для учета в breaker по HTTP-статусу ответа: nil для 2xx/3xx и 4xx (кроме 408 и 429)
+func statusError(code int) error {
+	if code >= 500 || code == http.StatusRequestTimeout || code == http.StatusTooManyRequests {
+		return fmt.Errorf("HTTP %d", code)
+	}
+	return nil
+}
+
+// trip открывает breaker провайдера на cooldown; после него провайдер в half-open до успешной пробы
+func trip(provider string) {
+	rdb := utils.GetRedis()
+	ctx := context.Background()
+	s := cfg()
+	pipe := rdb.TxPipeline()
+	pipe.Set(ctx, openKey(provider), time.Now().Format(time.RFC3339), s.ProviderBreakerCooldown)
+	pipe.Set(ctx, trippedKey(provider), time.Now().Format(time.RFC3339), 24*time.Hour)
+	pipe.Del(ctx, probeKey(provider), windowKey(provider))
+	if _, err := pipe.Exec(ctx); err != nil {
+		utils.LogError(err, "providerhealth: open circuit")
+		return
+	}
+	log.Printf("[PROVIDER HEALTH] %s: circuit open на %s", provider, s.ProviderBreakerCooldown)
+}
+
+// windowStats - число вызовов и сбоев провайдера в скользящем окне
+func windowStats(provider string) (total, failures int) {
+	members, err := utils.GetRedis().ZRange(context.Background(), windowKey(provider), 0, -1).Result()
+	if err != nil {
+		return 0, 0
+	}
+	for _, m := range members {
+		if len(m) > 0 && m[len(m)-1] == '1' {
+			failures++
+		}
+	}
+	return len(members), failures
+}
+
+const opsKey = "provider_health:ops"
+
+func openKey(provider string) string    { return "provider_health:open:" + provider }
+func trippedKey(provider string) string { return "provider_health:tripped:" + provider }
+func probeKey(provider string) string   { return "provider_health:probe:" + provider }
+func windowKey(provider string) string  { return "provider_health:window:" + provider }
+func callsKey(provider, operation string) string {
+	return "provider_health:calls:" + provider + ":" + operation
+}
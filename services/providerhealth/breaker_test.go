@@ -0,0 +1,71 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestIsFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"osago 400", errors.New("HTTP 400: неверный номер техпаспорта"), false},
+		{"osago 404", errors.New("HTTP 404: not found"), false},
+		{"osago 422", fmt.Errorf("create: %w", errors.New("HTTP 422: validation")), false},
+		{"kasko 400", errors.New("neo status 400: bad request"), false},
+		{"timeout 408", errors.New("HTTP 408: timeout"), true},
+		{"rate limit 429", errors.New("HTTP 429: too many requests"), true},
+		{"server 500", errors.New("HTTP 500: internal error"), true},
+		{"server 503", errors.New("neo status 503"), true},
+		{"network", errors.New("dial tcp: connection refused"), true},
+		{"4xx digits in body only", errors.New("HTTP 502: code 4001"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsFailure(tt.err); got != tt.want {
+				t.Errorf("IsFailure(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatusError(t *testing.T) {
+	tests := []struct {
+		code        int
+		wantFailure bool
+	}{
+		{http.StatusOK, false},
+		{http.StatusFound, false},
+		{http.StatusBadRequest, false},
+		{http.StatusUnauthorized, false},
+		{http.StatusNotFound, false},
+		{http.StatusUnprocessableEntity, false},
+		{http.StatusRequestTimeout, true},
+		{http.StatusTooManyRequests, true},
+		{http.StatusInternalServerError, true},
+		{http.StatusBadGateway, true},
+		{http.StatusGatewayTimeout, true},
+	}
+	for _, tt := range tests {
+		err := statusError(tt.code)
+		// Ответ транспорта учитывается в breaker как IsFailure(statusError(code))
+		if got := IsFailure(err); got != tt.wantFailure {
+			t.Errorf("status %d: failure = %v (err %v), want %v", tt.code, got, err, tt.wantFailure)
+		}
+	}
+}
+
+func TestDoWithoutRedis(t *testing.T) {
+	// Без Redis breaker пропускает все вызовы и возвращает ошибку вызова как есть
+	want := errors.New("HTTP 500: down")
+	for i := 0; i < 20; i++ {
+		if err := Do("test", "op", func() error { return want }); err != want {
+			t.Fatalf("call %d: Do() = %v, want %v", i, err, want)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"kliro/utils"
+)
+
+// recentErrorsLimit - сколько последних ошибок операции показывать в админке
+const recentErrorsLimit = 10
+
+// call - один вызов провайдера в статистике операции
+type call struct {
+	At    time.Time `json:"at"`
+	Ms    int64     `json:"ms"`
+	Error string    `json:"error,omitempty"`
+}
+
+// ProviderHealth - состояние провайдера для /admin/providers/health
+type ProviderHealth struct {
+	Provider       string            `json:"provider"`
+	State          string            `json:"state"` // closed | open | half_open
+	OpenUntil      *time.Time        `json:"open_until,omitempty"`
+	WindowCalls    int               `json:"window_calls"`
+	WindowFailures int               `json:"window_failures"`
+	Operations     []OperationHealth `json:"operations"`
+}
+
+// OperationHealth - статистика по последним вызовам операции (до 200)
+type OperationHealth struct {
+	Operation    string      `json:"operation"`
+	Calls        int         `json:"calls"`
+	Errors       int         `json:"errors"`
+	ErrorRate    float64     `json:"error_rate"` // 0..1
+	P95Ms        int64       `json:"p95_ms"`
+	LastCallAt   *time.Time  `json:"last_call_at,omitempty"`
+	RecentErrors []CallError `json:"recent_errors"`
+}
+
+type CallError struct {
+	At    time.Time `json:"at"`
+	Error string    `json:"error"`
+}
+
+// State - текущее состояние breaker провайдера и время окончания cooldown для open
+func State(provider string) (string, *time.Time) {
+	rdb := utils.GetRedis()
+	if rdb == nil {
+		return StateClosed, nil
+	}
+	ctx := context.Background()
+	if ttl, err := rdb.TTL(ctx, openKey(provider)).Result(); err == nil && ttl > 0 {
+		until := time.Now().Add(ttl)
+		return StateOpen, &until
+	}
+	if rdb.Exists(ctx, trippedKey(provider)).Val() > 0 {
+		return StateHalfOpen, nil
+	}
+	return StateClosed, nil
+}
+
+// Report собирает состояние всех провайдеров, по которым есть вызовы, в порядке имени
+func Report() ([]ProviderHealth, error) {
+	rdb := utils.GetRedis()
+	if rdb == nil {
+		return []ProviderHealth{}, nil
+	}
+	ctx := context.Background()
+	ops, err := rdb.SMembers(ctx, opsKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(ops)
+
+	out := []ProviderHealth{}
+	byProvider := map[string]int{}
+	for _, po := range ops {
+		parts := strings.SplitN(po, "|", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		provider, operation := parts[0], parts[1]
+		idx, ok := byProvider[provider]
+		if !ok {
+			state, until := State(provider)
+			total, failures := windowStats(provider)
+			out = append(out, ProviderHealth{Provider: provider, State: state, OpenUntil: until, WindowCalls: total, WindowFailures: failures, Operations: []OperationHealth{}})
+			idx = len(out) - 1
+			byProvider[provider] = idx
+		}
+		raw, err := rdb.LRange(ctx, callsKey(provider, operation), 0, -1).Result()
+		if err != nil {
+			return nil, err
+		}
+		out[idx].Operations = append(out[idx].Operations, operationHealth(operation, raw))
+	}
+	return out, nil
+}
+
+// operationHealth считает ошибки и p95 по вызовам (новые первыми)
+func operationHealth(operation string, raw []string) OperationHealth {
+	h := OperationHealth{Operation: operation, RecentErrors: []CallError{}}
+	durations := make([]int64, 0, len(raw))
+	for _, r := range raw {
+		var c call
+		if json.Unmarshal([]byte(r), &c) != nil {
+			continue
+		}
+		if h.LastCallAt == nil {
+			at := c.At
+			h.LastCallAt = &at
+		}
+		h.Calls++
+		durations = append(durations, c.Ms)
+		if c.Error != "" {
+			h.Errors++
+			if len(h.RecentErrors) < recentErrorsLimit {
+				h.RecentErrors = append(h.RecentErrors, CallError{At: c.At, Error: c.Error})
+			}
+		}
+	}
+	if h.Calls > 0 {
+		h.ErrorRate = float64(h.Errors) / float64(h.Calls)
+		sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+		h.P95Ms = durations[(len(durations)*95+99)/100-1]
+	}
+	return h
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"kliro/config"
+	"kliro/utils"
+)
+
+// Статусы кэша котировки в ответе calculate
+const (
+	CacheHit    = "hit"
+	CacheMiss   = "miss"
+	CacheBypass = "bypass" // кэш пропущен по запросу, свежий ответ записан в кэш
+)
+
+// Cache - кратковременный кэш ответов страховых на расчет (Redis). Ключ — хэш нормализованных
+// входных данных, поэтому повторный calculate с теми же параметрами не опрашивает провайдера заново.
+type Cache struct {
+	ttl  time.Duration            // QUOTE_CACHE_TTL
+	ttls map[string]time.Duration // QUOTE_CACHE_TTL_<PROVIDER>; 0 — не кэшировать провайдера
+}
+
+// CacheMeta - информация о кэше для метаданных ответа
+type CacheMeta struct {
+	Key       string                       `json:"key"`
+	Bypass    bool                         `json:"bypass,omitempty"`
+	Providers map[string]ProviderCacheMeta `json:"providers"`
+}
+
+// ProviderCacheMeta - результат обращения к кэшу по одной страховой
+type ProviderCacheMeta struct {
+	Status   string     `json:"status"` // hit | miss | bypass
+	CachedAt *time.Time `json:"cached_at,omitempty"`
+	TTL      int        `json:"ttl_seconds"`
+}
+
+type cacheEntry struct {
+	CachedAt time.Time       `json:"cached_at"`
+	Value    json.RawMessage `json:"value"`
+}
+
+func NewCache(cfg *config.Config) *Cache {
+	return &Cache{ttl: cfg.QuoteCacheTTL, ttls: cfg.QuoteCacheProviderTTLs}
+}
+
+// CacheKey - хэш входных данных расчета; inputs должен содержать только параметры, влияющие на цену
+func CacheKey(product string, inputs interface{}) string {
+	b, _ := json.Marshal(inputs)
+	sum := sha256.Sum256(append([]byte(product+":"), b...))
+	return hex.EncodeToString(sum[:16])
+}
+
+// NewCacheMeta - метаданные кэша одного расчета
+func NewCacheMeta(key string, bypass bool) *CacheMeta {
+	return &CacheMeta{Key: key, Bypass: bypass, Providers: map[string]ProviderCacheMeta{}}
+}
+
+// TTL - время жизни котировки провайдера
+func (c *Cache) TTL(provider string) time.Duration {
+	if d, ok := c.ttls[provider]; ok {
+		return d
+	}
+	return c.ttl
+}
+
+// Get читает котировку провайдера в dst и отмечает результат в meta. При bypass кэш не читается.
+func (c *Cache) Get(product, provider string, meta *CacheMeta, dst interface{}) bool {
+	ttl := c.TTL(provider)
+	m := ProviderCacheMeta{Status: CacheMiss, TTL: int(ttl.Seconds())}
+	defer func() { meta.Providers[provider] = m }()
+
+	if meta.Bypass {
+		m.Status = CacheBypass
+		return false
+	}
+	rdb := utils.GetRedis()
+	if rdb == nil || ttl <= 0 {
+		return false
+	}
+	val, err := rdb.Get(context.Background(), cacheKey(product, provider, meta.Key)).Bytes()
+	if err != nil {
+		return false
+	}
+	var entry cacheEntry
+	if json.Unmarshal(val, &entry) != nil || json.Unmarshal(entry.Value, dst) != nil {
+		return false
+	}
+	m.Status, m.CachedAt = CacheHit, &entry.CachedAt
+	return true
+}
+
+// Set сохраняет котировку провайдера (только успешные ответы — ошибки не кэшируются)
+func (c *Cache) Set(product, provider string, meta *CacheMeta, value interface{}) {
+	ttl := c.TTL(provider)
+	rdb := utils.GetRedis()
+	if rdb == nil || ttl <= 0 {
+		return
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	b, err := json.Marshal(cacheEntry{CachedAt: time.Now(), Value: raw})
+	if err != nil {
+		return
+	}
+	if err := rdb.Set(context.Background(), cacheKey(product, provider, meta.Key), b, ttl).Err(); err != nil {
+		utils.LogError(err, "quotes: cache set")
+	}
+}
+
+func cacheKey(product, provider, key string) string {
+	return "quote_cache:" + product + ":" + provider + ":" + key
+}
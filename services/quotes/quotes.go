@@ -0,0 +1,255 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"kliro/models"
+	"kliro/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Продукты
+const (
+	ProductOsago = "osago"
+	ProductKasko = "kasko"
+)
+
+// Inputs - обезличенные параметры расчета
+type Inputs struct {
+	ProductType       string
+	VehicleType       string
+	Region            string
+	OwnerType         string
+	PeriodID          int
+	DriverRestriction bool
+	VehicleYear       *int
+}
+
+// Result - итог расчета одной страховой (Premium > 0 — есть цена)
+type Result struct {
+	Provider string
+	Premium  int64
+	Error    string
+}
+
+// Record сохраняет котировки одного расчета: ранжирует цены и считает разрыв с самой низкой.
+// Запись асинхронная — ответ пользователю не ждет БД.
+func Record(product, sessionID string, in Inputs, results []Result) {
+	if sessionID == "" || len(results) == 0 {
+		return
+	}
+	quotes := buildQuotes(product, sessionID, in, results)
+	go func() {
+		db := utils.GetDB()
+		if db == nil {
+			return
+		}
+		if err := db.Create(&quotes).Error; err != nil {
+			utils.LogError(err, "quotes: save")
+		}
+	}()
+}
+
+func buildQuotes(product, sessionID string, in Inputs, results []Result) []models.InsuranceQuote {
+	batchID := uuid.New().String()
+
+	var prices []int64
+	for _, r := range results {
+		if r.Premium > 0 {
+			prices = append(prices, r.Premium)
+		}
+	}
+	sort.Slice(prices, func(i, j int) bool { return prices[i] < prices[j] })
+
+	quotes := make([]models.InsuranceQuote, 0, len(results))
+	for _, r := range results {
+		q := models.InsuranceQuote{
+			Product:           product,
+			ProductType:       in.ProductType,
+			BatchID:           batchID,
+			SessionID:         sessionID,
+			Provider:          r.Provider,
+			Success:           r.Premium > 0,
+			Premium:           r.Premium,
+			Error:             r.Error,
+			VehicleType:       in.VehicleType,
+			Region:            in.Region,
+			OwnerType:         in.OwnerType,
+			PeriodID:          in.PeriodID,
+			DriverRestriction: in.DriverRestriction,
+			VehicleYear:       in.VehicleYear,
+		}
+		if q.Success {
+			// плотный ранг: одинаковые цены делят место
+			q.Rank = 1
+			for i, p := range prices {
+				if p >= r.Premium {
+					break
+				}
+				if i == 0 || p != prices[i-1] {
+					q.Rank++
+				}
+			}
+			q.GapToCheapest = r.Premium - prices[0]
+			q.GapPercent = math.Round(float64(q.GapToCheapest)/float64(prices[0])*10000) / 100
+		} else if q.Error == "" {
+			q.Error = "no premium"
+		}
+		quotes = append(quotes, q)
+	}
+	return quotes
+}
+
+// MarkPicked отмечает страховую, выбранную пользователем, в последнем расчете сессии
+func MarkPicked(product, sessionID, provider string) {
+	go func() {
+		db := utils.GetDB()
+		if db == nil || sessionID == "" {
+			return
+		}
+		var last models.InsuranceQuote
+		if err := db.Where("product = ? AND session_id = ?", product, sessionID).Order("created_at DESC, id DESC").First(&last).Error; err != nil {
+			return
+		}
+		now := time.Now()
+		if err := db.Model(&models.InsuranceQuote{}).
+			Where("batch_id = ? AND provider = ?", last.BatchID, provider).
+			Updates(map[string]interface{}{"picked": true, "picked_at": now}).Error; err != nil {
+			utils.LogError(err, "quotes: mark picked")
+		}
+	}()
+}
+
+// Filter - условия отчета
+type Filter struct {
+	Product     string
+	ProductType string
+	From        time.Time
+	To          time.Time // не включительно
+	PeriodID    int
+	VehicleType string
+	Region      string
+}
+
+// ProviderStats - конкурентность страховой за период
+type ProviderStats struct {
+	Provider        string  `json:"provider"`
+	Quotes          int     `json:"quotes"`
+	Successes       int     `json:"successes"`
+	ErrorRate       float64 `json:"error_rate"`
+	Cheapest        int     `json:"cheapest"`       // сколько раз была самой дешевой
+	CheapestShare   float64 `json:"cheapest_share"` // доля расчетов, где была самой дешевой
+	AvgPremium      float64 `json:"avg_premium"`
+	AvgGap          float64 `json:"avg_gap"` // средний разрыв с самой низкой ценой, когда не самая дешевая
+	AvgGapPercent   float64 `json:"avg_gap_percent"`
+	Picked          int     `json:"picked"`       // сколько раз выбрали на шаге create
+	PickedShare     float64 `json:"picked_share"` // доля выборов
+	PickedWhenCheap int     `json:"picked_when_cheapest"`
+}
+
+// Report - сводка по котировкам
+type Report struct {
+	Batches        int             `json:"batches"`              // расчеты хотя бы с одной ценой
+	Picks          int             `json:"picks"`                // расчеты, после которых пользователь выбрал страховую
+	PickedCheapest float64         `json:"picked_cheapest_rate"` // доля выборов самой дешевой
+	Providers      []ProviderStats `json:"providers"`
+}
+
+// BuildReport считает отчет о конкурентности цен страховых
+func BuildReport(db *gorm.DB, f Filter) (*Report, error) {
+	base := func() *gorm.DB {
+		q := db.Model(&models.InsuranceQuote{}).Where("product = ? AND created_at >= ? AND created_at < ?", f.Product, f.From, f.To)
+		if f.ProductType != "" {
+			q = q.Where("product_type = ?", f.ProductType)
+		}
+		if f.PeriodID > 0 {
+			q = q.Where("period_id = ?", f.PeriodID)
+		}
+		if f.VehicleType != "" {
+			q = q.Where("vehicle_type = ?", f.VehicleType)
+		}
+		if f.Region != "" {
+			q = q.Where("region = ?", f.Region)
+		}
+		return q
+	}
+
+	report := &Report{Providers: []ProviderStats{}}
+
+	var totals struct {
+		Batches        int
+		Picks          int
+		PickedCheapest int
+	}
+	if err := base().Select(`
+		COUNT(DISTINCT CASE WHEN success THEN batch_id END) AS batches,
+		COUNT(DISTINCT CASE WHEN picked THEN batch_id END) AS picks,
+		COUNT(DISTINCT CASE WHEN picked AND rank = 1 THEN batch_id END) AS picked_cheapest`).
+		Scan(&totals).Error; err != nil {
+		return nil, err
+	}
+	report.Batches = totals.Batches
+	report.Picks = totals.Picks
+	report.PickedCheapest = share(totals.PickedCheapest, totals.Picks)
+
+	var rows []struct {
+		Provider        string
+		Quotes          int
+		Successes       int
+		Cheapest        int
+		AvgPremium      *float64
+		AvgGap          *float64
+		AvgGapPercent   *float64
+		Picked          int
+		PickedWhenCheap int
+	}
+	if err := base().Select(`
+		provider,
+		COUNT(*) AS quotes,
+		SUM(CASE WHEN success THEN 1 ELSE 0 END) AS successes,
+		SUM(CASE WHEN rank = 1 THEN 1 ELSE 0 END) AS cheapest,
+		AVG(CASE WHEN success THEN premium END) AS avg_premium,
+		AVG(CASE WHEN success AND rank > 1 THEN gap_to_cheapest END) AS avg_gap,
+		AVG(CASE WHEN success AND rank > 1 THEN gap_percent END) AS avg_gap_percent,
+		SUM(CASE WHEN picked THEN 1 ELSE 0 END) AS picked,
+		SUM(CASE WHEN picked AND rank = 1 THEN 1 ELSE 0 END) AS picked_when_cheap`).
+		Group("provider").Order("cheapest DESC, provider").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for _, r := range rows {
+		report.Providers = append(report.Providers, ProviderStats{
+			Provider:        r.Provider,
+			Quotes:          r.Quotes,
+			Successes:       r.Successes,
+			ErrorRate:       share(r.Quotes-r.Successes, r.Quotes),
+			Cheapest:        r.Cheapest,
+			CheapestShare:   share(r.Cheapest, totals.Batches),
+			AvgPremium:      round2(r.AvgPremium),
+			AvgGap:          round2(r.AvgGap),
+			AvgGapPercent:   round2(r.AvgGapPercent),
+			Picked:          r.Picked,
+			PickedShare:     share(r.Picked, totals.Picks),
+			PickedWhenCheap: r.PickedWhenCheap,
+		})
+	}
+	return report, nil
+}
+
+func share(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(part)/float64(total)*10000) / 10000
+}
+
+func round2(v *float64) float64 {
+	if v == nil {
+		return 0
+	}
+	return math.Round(*v*100) / 100
+}
@@ -0,0 +1,398 @@
+package services
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"kliro/models"
+	"kliro/utils"
+
+	"gorm.io/gorm"
+)
+
+// Стратегии основной сортировки
+const (
+	StrategyRateDesc  = "rate_desc"
+	StrategyRateAsc   = "rate_asc"
+	StrategyFreshness = "freshness"
+	StrategyBankName  = "bank_name"
+)
+
+// Правила разрешения равенства
+const (
+	TieBreakerFreshness = "freshness"
+	TieBreakerBankName  = "bank_name"
+	TieBreakerSeeded    = "seeded"
+)
+
+// ValidStrategies / ValidTieBreakers - допустимые значения для админки
+var ValidStrategies = map[string]bool{StrategyRateDesc: true, StrategyRateAsc: true, StrategyFreshness: true, StrategyBankName: true}
+var ValidTieBreakers = map[string]bool{TieBreakerFreshness: true, TieBreakerBankName: true, TieBreakerSeeded: true}
+
+// DefaultConfigs - порядок по умолчанию, если в ranking_configs нет записи для направления
+var DefaultConfigs = map[string]models.RankingConfig{
+	"deposit":     {Direction: "deposit", Strategy: StrategyRateDesc, TieBreaker: TieBreakerFreshness},
+	"microcredit": {Direction: "microcredit", Strategy: StrategyRateAsc, TieBreaker: TieBreakerFreshness, DiversifyBanks: true},
+	"autocredit":  {Direction: "autocredit", Strategy: StrategyRateAsc, TieBreaker: TieBreakerFreshness},
+	"mortgage":    {Direction: "mortgage", Strategy: StrategyRateAsc, TieBreaker: TieBreakerFreshness},
+	"credit":      {Direction: "credit", Strategy: StrategyRateAsc, TieBreaker: TieBreakerFreshness},
+	"card":        {Direction: "card", Strategy: StrategyFreshness, TieBreaker: TieBreakerBankName},
+	"transfer":    {Direction: "transfer", Strategy: StrategyRateAsc, TieBreaker: TieBreakerFreshness},
+}
+
+// Features - признаки продукта, по которым он ранжируется
+type Features struct {
+	Key       string // стабильный ключ продукта (utils.ProductKey)
+	BankName  string
+	Title     string
+	Rate      float64 // ставка или комиссия
+	HasRate   bool    // false — ставка не распознана, продукт уходит в конец
+	CreatedAt time.Time
+}
+
+// Meta - как был отсортирован список (отдается клиенту, чтобы он передавал seed на следующие страницы)
+type Meta struct {
+	Direction  string `json:"direction"`
+	Strategy   string `json:"strategy"`
+	TieBreaker string `json:"tie_breaker"`
+	Seed       string `json:"seed,omitempty"`
+	Promoted   int    `json:"promoted"`
+	Experiment string `json:"experiment,omitempty"` // эксперимент:вариант, если порядок задан A/B экспериментом
+}
+
+// Override - изменение настройки направления для варианта A/B эксперимента (пустые поля — без изменений)
+type Override struct {
+	Experiment       string
+	Strategy         string
+	TieBreaker       string
+	DiversifyBanks   *bool
+	DisableSponsored bool
+}
+
+// Ranked - отсортированный список с пометками спонсорских мест
+type Ranked[T any] struct {
+	Items    []T
+	Promoted []bool
+	Labels   []string
+	Meta     Meta
+	byKey    map[string]string // ключ продукта -> метка спонсорского места
+}
+
+// Promotion возвращает пометку спонсорского места для элемента с индексом i (в полном списке)
+func (r Ranked[T]) Promotion(i int) (bool, string) {
+	if i < 0 || i >= len(r.Promoted) {
+		return false, ""
+	}
+	return r.Promoted[i], r.Labels[i]
+}
+
+// PromotionByKey возвращает пометку спонсорского места по ключу продукта
+// (не зависит от позиции, если список переупорядочен после ранжирования)
+func (r Ranked[T]) PromotionByKey(key string) (bool, string) {
+	label, ok := r.byKey[key]
+	return ok, label
+}
+
+// maxSponsoredSlots - сколько спонсорских мест может быть в одном списке; размещения сверх лимита
+// (с большей позицией) не применяются, чтобы платная выдача не вытесняла органическую
+const maxSponsoredSlots = 3
+
+// configCacheTTL - как долго держим настройки в памяти (админские изменения сбрасывают кэш сразу)
+const configCacheTTL = time.Minute
+
+// Engine - движок ранжирования с кэшем настроек из БД
+type Engine struct {
+	db         *gorm.DB
+	mu         sync.RWMutex
+	configs    map[string]models.RankingConfig
+	placements []models.SponsoredPlacement
+	loadedAt   time.Time
+}
+
+var (
+	defaultEngine     *Engine
+	defaultEngineOnce sync.Once
+)
+
+// DefaultEngine возвращает глобальный движок (БД берется из utils.GetDB)
+func DefaultEngine() *Engine {
+	defaultEngineOnce.Do(func() {
+		defaultEngine = NewEngine(utils.GetDB())
+	})
+	return defaultEngine
+}
+
+// NewEngine создает движок ранжирования
+func NewEngine(db *gorm.DB) *Engine {
+	return &Engine{db: db}
+}
+
+// Invalidate сбрасывает кэш настроек (вызывается после изменений в админке)
+func (e *Engine) Invalidate() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.loadedAt = time.Time{}
+}
+
+// load возвращает настройку направления и активные на момент now размещения
+func (e *Engine) load(direction string, now time.Time) (models.RankingConfig, []models.SponsoredPlacement) {
+	e.mu.RLock()
+	fresh := !e.loadedAt.IsZero() && time.Since(e.loadedAt) < configCacheTTL
+	e.mu.RUnlock()
+
+	if !fresh && e.db != nil {
+		var configs []models.RankingConfig
+		var placements []models.SponsoredPlacement
+		if err := e.db.Find(&configs).Error; err != nil {
+			log.Printf("[RANKING] failed to load ranking configs: %v", err)
+		}
+		if err := e.db.Where("is_active = ?", true).Order("position ASC, id ASC").Find(&placements).Error; err != nil {
+			log.Printf("[RANKING] failed to load sponsored placements: %v", err)
+		}
+		byDirection := make(map[string]models.RankingConfig, len(configs))
+		for _, cfg := range configs {
+			byDirection[cfg.Direction] = cfg
+		}
+		e.mu.Lock()
+		e.configs = byDirection
+		e.placements = placements
+		e.loadedAt = time.Now()
+		e.mu.Unlock()
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	cfg, ok := e.configs[direction]
+	if !ok {
+		cfg = DefaultConfigs[direction]
+	}
+	if cfg.Strategy == "" {
+		cfg.Strategy = StrategyFreshness
+	}
+	if cfg.TieBreaker == "" {
+		cfg.TieBreaker = TieBreakerFreshness
+	}
+	cfg.Direction = direction
+
+	var active []models.SponsoredPlacement
+	for _, p := range e.placements {
+		if p.Direction != direction || now.Before(p.StartsAt) || (p.EndsAt != nil && !now.Before(*p.EndsAt)) {
+			continue
+		}
+		active = append(active, p)
+	}
+	return cfg, active
+}
+
+// Rank сортирует items детерминированно по настройке направления и расставляет спонсорские места.
+// seed задает порядок внутри равных значений (одинаковый seed — одинаковый порядок на всех страницах).
+// overrides (необязательно) — настройка варианта A/B эксперимента поверх настройки направления.
+func Rank[T any](e *Engine, direction, seed string, items []T, features func(T) Features, overrides ...Override) Ranked[T] {
+	cfg, placements := e.load(direction, time.Now())
+	experiment := ""
+	for _, o := range overrides {
+		if ValidStrategies[o.Strategy] {
+			cfg.Strategy = o.Strategy
+		}
+		if ValidTieBreakers[o.TieBreaker] {
+			cfg.TieBreaker = o.TieBreaker
+		}
+		if o.DiversifyBanks != nil {
+			cfg.DiversifyBanks = *o.DiversifyBanks
+		}
+		if o.DisableSponsored {
+			placements = nil
+		}
+		if o.Experiment != "" {
+			experiment = o.Experiment
+		}
+	}
+
+	tieBreaker := cfg.TieBreaker
+	if seed != "" {
+		tieBreaker = TieBreakerSeeded
+	} else if tieBreaker == TieBreakerSeeded {
+		// Без seed от клиента — seed дня: порядок стабилен в течение суток
+		seed = utils.UzbekTime().Format("2006-01-02")
+	}
+
+	type entry struct {
+		item     T
+		f        Features
+		tie      uint64
+		promoted bool
+		label    string
+	}
+	entries := make([]entry, len(items))
+	for i, it := range items {
+		f := features(it)
+		entries[i] = entry{item: it, f: f}
+		if tieBreaker == TieBreakerSeeded {
+			entries[i].tie = seededHash(seed, f.Key)
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i].f, entries[j].f
+		if c := comparePrimary(cfg.Strategy, a, b); c != 0 {
+			return c < 0
+		}
+		switch tieBreaker {
+		case TieBreakerSeeded:
+			if entries[i].tie != entries[j].tie {
+				return entries[i].tie < entries[j].tie
+			}
+		case TieBreakerFreshness:
+			if !a.CreatedAt.Equal(b.CreatedAt) {
+				return a.CreatedAt.After(b.CreatedAt)
+			}
+		}
+		// Последний уровень — всегда детерминированный
+		if a.BankName != b.BankName {
+			return a.BankName < b.BankName
+		}
+		if a.Title != b.Title {
+			return a.Title < b.Title
+		}
+		return a.Key < b.Key
+	})
+
+	if cfg.DiversifyBanks {
+		entries = diversifyByBank(entries, func(en entry) string { return en.f.BankName })
+	}
+
+	// Спонсорские места: переносим найденный продукт на заданную позицию (не больше maxSponsoredSlots)
+	promotedCount := 0
+	for _, p := range placements {
+		if promotedCount >= maxSponsoredSlots {
+			break
+		}
+		src := -1
+		for i, en := range entries {
+			if !en.promoted && placementMatches(p, en.f) {
+				src = i
+				break
+			}
+		}
+		if src < 0 {
+			continue
+		}
+		moved := entries[src]
+		moved.promoted = true
+		moved.label = p.Label
+		if moved.label == "" {
+			moved.label = "promoted"
+		}
+		entries = append(entries[:src], entries[src+1:]...)
+		dst := p.Position - 1
+		if dst < 0 {
+			dst = 0
+		}
+		if dst > len(entries) {
+			dst = len(entries)
+		}
+		entries = append(entries[:dst], append([]entry{moved}, entries[dst:]...)...)
+		promotedCount++
+	}
+
+	result := Ranked[T]{
+		Items:    make([]T, len(entries)),
+		Promoted: make([]bool, len(entries)),
+		Labels:   make([]string, len(entries)),
+		Meta:     Meta{Direction: direction, Strategy: cfg.Strategy, TieBreaker: tieBreaker, Seed: seed, Promoted: promotedCount, Experiment: experiment},
+		byKey:    make(map[string]string, promotedCount),
+	}
+	for i, en := range entries {
+		result.Items[i] = en.item
+		result.Promoted[i] = en.promoted
+		result.Labels[i] = en.label
+		if en.promoted {
+			result.byKey[en.f.Key] = en.label
+		}
+	}
+	return result
+}
+
+// comparePrimary сравнивает по основной стратегии (-1: a выше, 1: b выше, 0: равны)
+func comparePrimary(strategy string, a, b Features) int {
+	switch strategy {
+	case StrategyRateDesc, StrategyRateAsc:
+		// Нераспознанные ставки всегда в конце
+		if a.HasRate != b.HasRate {
+			if a.HasRate {
+				return -1
+			}
+			return 1
+		}
+		if a.Rate == b.Rate {
+			return 0
+		}
+		if (strategy == StrategyRateDesc) == (a.Rate > b.Rate) {
+			return -1
+		}
+		return 1
+	case StrategyFreshness:
+		if a.CreatedAt.Equal(b.CreatedAt) {
+			return 0
+		}
+		if a.CreatedAt.After(b.CreatedAt) {
+			return -1
+		}
+		return 1
+	case StrategyBankName:
+		return strings.Compare(a.BankName, b.BankName)
+	}
+	return 0
+}
+
+// seededHash - детерминированный псевдослучайный порядок для пары (seed, ключ продукта)
+func seededHash(seed, key string) uint64 {
+	sum := md5.Sum([]byte(seed + "|" + key))
+	return binary.BigEndian.Uint64(sum[:8])
+}
+
+// diversifyByBank переставляет элементы так, чтобы один банк не шел подряд,
+// сохраняя порядок внутри банка и порядок первых появлений банков
+func diversifyByBank[E any](entries []E, bankOf func(E) string) []E {
+	if len(entries) <= 1 {
+		return entries
+	}
+	byBank := make(map[string][]E)
+	var banks []string
+	for _, en := range entries {
+		b := bankOf(en)
+		if _, ok := byBank[b]; !ok {
+			banks = append(banks, b)
+		}
+		byBank[b] = append(byBank[b], en)
+	}
+	result := make([]E, 0, len(entries))
+	for len(result) < len(entries) {
+		for _, b := range banks {
+			if len(byBank[b]) == 0 {
+				continue
+			}
+			result = append(result, byBank[b][0])
+			byBank[b] = byBank[b][1:]
+		}
+	}
+	return result
+}
+
+// placementMatches - подходит ли продукт под спонсорское размещение
+func placementMatches(p models.SponsoredPlacement, f Features) bool {
+	if !utils.BankSlugMatches(f.BankName, p.BankName) && !strings.EqualFold(strings.TrimSpace(p.BankName), strings.TrimSpace(f.BankName)) {
+		return false
+	}
+	match := strings.ToLower(strings.TrimSpace(p.ProductMatch))
+	if match == "" {
+		return true
+	}
+	return strings.Contains(strings.ToLower(f.Title), match) || f.Key == p.ProductMatch
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"kliro/models"
+)
+
+type product struct {
+	key  string
+	bank string
+	rate float64
+}
+
+func productFeatures(p product) Features {
+	return Features{Key: p.key, BankName: p.bank, Title: p.key, Rate: p.rate, HasRate: p.rate > 0, CreatedAt: time.Unix(0, 0)}
+}
+
+func keys(items []product) []string {
+	out := make([]string, len(items))
+	for i, p := range items {
+		out[i] = p.key
+	}
+	return out
+}
+
+func TestRankSeededTieBreakStable(t *testing.T) {
+	// Одинаковая ставка у всех — порядок задает только seed
+	items := []product{
+		{"deposit:a:1", "A", 20}, {"deposit:b:2", "B", 20}, {"deposit:c:3", "C", 20},
+		{"deposit:d:4", "D", 20}, {"deposit:e:5", "E", 20}, {"deposit:f:6", "F", 20},
+	}
+	reversed := make([]product, len(items))
+	for i, p := range items {
+		reversed[len(items)-1-i] = p
+	}
+
+	tests := []struct {
+		name  string
+		seed  string
+		input []product
+	}{
+		{"seed s1", "s1", items},
+		{"seed s1, обратный порядок входа", "s1", reversed},
+		{"seed s2", "s2", items},
+		{"seed s2, обратный порядок входа", "s2", reversed},
+	}
+	first := map[string][]string{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEngine(nil)
+			got := Rank(e, "deposit", tt.seed, tt.input, productFeatures)
+			if got.Meta.TieBreaker != TieBreakerSeeded || got.Meta.Seed != tt.seed {
+				t.Fatalf("meta = %+v, want seeded tie-breaker with seed %q", got.Meta, tt.seed)
+			}
+			again := Rank(e, "deposit", tt.seed, tt.input, productFeatures)
+			if !reflect.DeepEqual(keys(got.Items), keys(again.Items)) {
+				t.Fatalf("order changed between calls: %v vs %v", keys(got.Items), keys(again.Items))
+			}
+			if want, ok := first[tt.seed]; ok && !reflect.DeepEqual(keys(got.Items), want) {
+				t.Fatalf("order depends on input order: %v vs %v", keys(got.Items), want)
+			}
+			first[tt.seed] = keys(got.Items)
+		})
+	}
+}
+
+func TestRankSeedKeepsPrimaryOrder(t *testing.T) {
+	items := []product{
+		{"deposit:a:1", "A", 18}, {"deposit:b:2", "B", 22}, {"deposit:c:3", "C", 0}, {"deposit:d:4", "D", 22},
+	}
+	for _, seed := range []string{"s1", "s2", "s3"} {
+		got := keys(Rank(NewEngine(nil), "deposit", seed, items, productFeatures).Items)
+		// deposit — ставка по убыванию: сначала обе 22%, затем 18%, нераспознанная ставка в конце
+		if got[2] != "deposit:a:1" || got[3] != "deposit:c:3" {
+			t.Errorf("seed %s: order = %v, seed must only reorder equal rates", seed, got)
+		}
+	}
+}
+
+// placementEngine - движок с размещениями в кэше, без БД
+func placementEngine(placements ...models.SponsoredPlacement) *Engine {
+	return &Engine{configs: map[string]models.RankingConfig{}, placements: placements, loadedAt: time.Now()}
+}
+
+func TestRankSponsoredPlacements(t *testing.T) {
+	// Органический порядок deposit (ставка по убыванию): a, b, c, d, e, f; у банка C два продукта
+	items := []product{
+		{"deposit:a:1", "A", 25}, {"deposit:b:2", "B", 24}, {"deposit:c:3", "C", 23},
+		{"deposit:c:4", "C", 22}, {"deposit:e:5", "E", 21}, {"deposit:f:6", "F", 20},
+	}
+	now := time.Now()
+	ended := now.Add(-time.Hour)
+	placement := func(bank string, position int) models.SponsoredPlacement {
+		return models.SponsoredPlacement{Direction: "deposit", BankName: bank, Position: position, StartsAt: now.Add(-24 * time.Hour), IsActive: true}
+	}
+	withMatch := func(p models.SponsoredPlacement, match string) models.SponsoredPlacement {
+		p.ProductMatch = match
+		return p
+	}
+	withLabel := func(p models.SponsoredPlacement, label string) models.SponsoredPlacement {
+		p.Label = label
+		return p
+	}
+
+	tests := []struct {
+		name         string
+		placements   []models.SponsoredPlacement
+		want         []string
+		wantPromoted map[string]string // ключ -> метка
+	}{
+		{"no placements", nil,
+			[]string{"deposit:a:1", "deposit:b:2", "deposit:c:3", "deposit:c:4", "deposit:e:5", "deposit:f:6"}, map[string]string{}},
+		{"slot 1", []models.SponsoredPlacement{placement("F", 1)},
+			[]string{"deposit:f:6", "deposit:a:1", "deposit:b:2", "deposit:c:3", "deposit:c:4", "deposit:e:5"}, map[string]string{"deposit:f:6": "promoted"}},
+		{"slot 3 with label", []models.SponsoredPlacement{withLabel(placement("E", 3), "partner")},
+			[]string{"deposit:a:1", "deposit:b:2", "deposit:e:5", "deposit:c:3", "deposit:c:4", "deposit:f:6"}, map[string]string{"deposit:e:5": "partner"}},
+		{"position beyond list", []models.SponsoredPlacement{placement("A", 10)},
+			[]string{"deposit:b:2", "deposit:c:3", "deposit:c:4", "deposit:e:5", "deposit:f:6", "deposit:a:1"}, map[string]string{"deposit:a:1": "promoted"}},
+		{"product match", []models.SponsoredPlacement{withMatch(placement("C", 1), "deposit:c:4")},
+			[]string{"deposit:c:4", "deposit:a:1", "deposit:b:2", "deposit:c:3", "deposit:e:5", "deposit:f:6"}, map[string]string{"deposit:c:4": "promoted"}},
+		{"cap", []models.SponsoredPlacement{placement("F", 1), placement("E", 2), withMatch(placement("C", 3), "deposit:c:4"), placement("B", 4)},
+			[]string{"deposit:f:6", "deposit:e:5", "deposit:c:4", "deposit:a:1", "deposit:b:2", "deposit:c:3"},
+			map[string]string{"deposit:f:6": "promoted", "deposit:e:5": "promoted", "deposit:c:4": "promoted"}},
+		{"same product in two placements", []models.SponsoredPlacement{placement("F", 1), placement("F", 2)},
+			[]string{"deposit:f:6", "deposit:a:1", "deposit:b:2", "deposit:c:3", "deposit:c:4", "deposit:e:5"}, map[string]string{"deposit:f:6": "promoted"}},
+		{"bank placements take next product", []models.SponsoredPlacement{placement("C", 1), placement("C", 2)},
+			[]string{"deposit:c:3", "deposit:c:4", "deposit:a:1", "deposit:b:2", "deposit:e:5", "deposit:f:6"},
+			map[string]string{"deposit:c:3": "promoted", "deposit:c:4": "promoted"}},
+		{"ended placement", []models.SponsoredPlacement{{Direction: "deposit", BankName: "F", Position: 1, StartsAt: now.Add(-48 * time.Hour), EndsAt: &ended, IsActive: true}},
+			[]string{"deposit:a:1", "deposit:b:2", "deposit:c:3", "deposit:c:4", "deposit:e:5", "deposit:f:6"}, map[string]string{}},
+		{"other direction", []models.SponsoredPlacement{{Direction: "card", BankName: "F", Position: 1, StartsAt: now.Add(-time.Hour), IsActive: true}},
+			[]string{"deposit:a:1", "deposit:b:2", "deposit:c:3", "deposit:c:4", "deposit:e:5", "deposit:f:6"}, map[string]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Rank(placementEngine(tt.placements...), "deposit", "", items, productFeatures)
+			if !reflect.DeepEqual(keys(got.Items), tt.want) {
+				t.Fatalf("order = %v, want %v", keys(got.Items), tt.want)
+			}
+			if got.Meta.Promoted != len(tt.wantPromoted) {
+				t.Errorf("Meta.Promoted = %d, want %d", got.Meta.Promoted, len(tt.wantPromoted))
+			}
+			for i, p := range got.Items {
+				promoted, label := got.Promotion(i)
+				wantLabel, want := tt.wantPromoted[p.key]
+				if promoted != want || label != wantLabel {
+					t.Errorf("%s: promoted = %v %q, want %v %q", p.key, promoted, label, want, wantLabel)
+				}
+				if byKey, byKeyLabel := got.PromotionByKey(p.key); byKey != want || byKeyLabel != wantLabel {
+					t.Errorf("%s: PromotionByKey = %v %q, want %v %q", p.key, byKey, byKeyLabel, want, wantLabel)
+				}
+			}
+		})
+	}
+}
+
+func TestRankDisableSponsored(t *testing.T) {
+	items := []product{{"deposit:a:1", "A", 25}, {"deposit:b:2", "B", 20}}
+	e := placementEngine(models.SponsoredPlacement{Direction: "deposit", BankName: "B", Position: 1, StartsAt: time.Now().Add(-time.Hour), IsActive: true})
+	got := Rank(e, "deposit", "", items, productFeatures, Override{DisableSponsored: true})
+	if want := []string{"deposit:a:1", "deposit:b:2"}; !reflect.DeepEqual(keys(got.Items), want) || got.Meta.Promoted != 0 {
+		t.Errorf("order = %v, promoted = %d, want %v without promotions", keys(got.Items), got.Meta.Promoted, want)
+	}
+}
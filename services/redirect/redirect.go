@@ -0,0 +1,206 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"kliro/models"
+	"kliro/utils"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidToken - токен поврежден или подписан другим ключом
+var ErrInvalidToken = errors.New("invalid redirect token")
+
+// ErrNoSecret - ключ подписи не задан: с пустым ключом HMAC токен мог бы подделать кто угодно
+var ErrNoSecret = errors.New("redirect token secret is not configured")
+
+// Payload - что зашито в подписанный токен /go/:token
+type Payload struct {
+	Direction  string `json:"d"`
+	ProductKey string `json:"k"`
+	BankName   string `json:"b"`
+	URL        string `json:"u,omitempty"` // ссылка продукта из парсера (если есть)
+}
+
+// secret - ключ подписи токенов (REDIRECT_TOKEN_SECRET, иначе JWT_SECRET)
+func secret() []byte {
+	if s := os.Getenv("REDIRECT_TOKEN_SECRET"); s != "" {
+		return []byte(s)
+	}
+	return []byte(os.Getenv("JWT_SECRET"))
+}
+
+// CheckSecret пишет в лог при старте, если ключ подписи не задан: ссылки /go/ тогда не выдаются и не принимаются
+func CheckSecret() {
+	if len(secret()) == 0 {
+		log.Printf("[REDIRECT] REDIRECT_TOKEN_SECRET и JWT_SECRET не заданы: партнерские ссылки /go/ отключены")
+	}
+}
+
+// Sign упаковывает payload в токен вида base64url(json).base64url(hmac); "" — ключ подписи не задан
+func Sign(p Payload) string {
+	if len(secret()) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(p)
+	body := base64.RawURLEncoding.EncodeToString(data)
+	return body + "." + signature(body)
+}
+
+// Parse проверяет подпись и распаковывает токен
+func Parse(token string) (Payload, error) {
+	var p Payload
+	if len(secret()) == 0 {
+		return p, ErrNoSecret
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 || !hmac.Equal([]byte(parts[1]), []byte(signature(parts[0]))) {
+		return p, ErrInvalidToken
+	}
+	data, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return p, ErrInvalidToken
+	}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return p, ErrInvalidToken
+	}
+	return p, nil
+}
+
+func signature(body string) string {
+	mac := hmac.New(sha256.New, secret())
+	mac.Write([]byte(body))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// configCacheTTL - как долго держим настройки банков в памяти
+const configCacheTTL = time.Minute
+
+// Resolver - настройки партнерских ссылок банков с кэшем
+type Resolver struct {
+	db       *gorm.DB
+	mu       sync.RWMutex
+	configs  []models.BankRedirectConfig
+	loadedAt time.Time
+}
+
+var (
+	defaultResolver     *Resolver
+	defaultResolverOnce sync.Once
+)
+
+// DefaultResolver возвращает глобальный резолвер (БД берется из utils.GetDB)
+func DefaultResolver() *Resolver {
+	defaultResolverOnce.Do(func() {
+		defaultResolver = NewResolver(utils.GetDB())
+	})
+	return defaultResolver
+}
+
+// NewResolver создает резолвер партнерских ссылок
+func NewResolver(db *gorm.DB) *Resolver {
+	return &Resolver{db: db}
+}
+
+// Invalidate сбрасывает кэш настроек (после изменений в админке)
+func (r *Resolver) Invalidate() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.loadedAt = time.Time{}
+}
+
+// ConfigFor возвращает активную настройку банка
+func (r *Resolver) ConfigFor(bankName string) *models.BankRedirectConfig {
+	r.mu.RLock()
+	fresh := !r.loadedAt.IsZero() && time.Since(r.loadedAt) < configCacheTTL
+	r.mu.RUnlock()
+
+	if !fresh && r.db != nil {
+		var configs []models.BankRedirectConfig
+		if err := r.db.Where("is_active = ?", true).Find(&configs).Error; err != nil {
+			log.Printf("[REDIRECT] failed to load redirect configs: %v", err)
+		}
+		r.mu.Lock()
+		r.configs = configs
+		r.loadedAt = time.Now()
+		r.mu.Unlock()
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for i := range r.configs {
+		if utils.BankSlugMatches(bankName, r.configs[i].BankSlug) {
+			cfg := r.configs[i]
+			return &cfg
+		}
+	}
+	return nil
+}
+
+// Link возвращает путь /go/<token> для продукта или "" если ссылку не на что вести
+func (r *Resolver) Link(direction, productKey, bankName, productURL string) string {
+	if productURL == "" {
+		if cfg := r.ConfigFor(bankName); cfg == nil || cfg.DefaultURL == "" {
+			return ""
+		}
+	}
+	token := Sign(Payload{Direction: direction, ProductKey: productKey, BankName: bankName, URL: productURL})
+	if token == "" {
+		return ""
+	}
+	return "/go/" + token
+}
+
+// Target собирает итоговый URL перехода: ссылка продукта (или банка по умолчанию) + UTM-метки банка
+func (r *Resolver) Target(p Payload) (string, error) {
+	cfg := r.ConfigFor(p.BankName)
+	target := p.URL
+	if target == "" && cfg != nil {
+		target = cfg.DefaultURL
+	}
+	if target == "" {
+		return "", errors.New("no target url")
+	}
+
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", errors.New("bad target url")
+	}
+
+	q := u.Query()
+	source, medium, campaign := "kliro", "referral", p.Direction
+	if cfg != nil {
+		if cfg.UTMSource != "" {
+			source = cfg.UTMSource
+		}
+		if cfg.UTMMedium != "" {
+			medium = cfg.UTMMedium
+		}
+		if cfg.UTMCampaign != "" {
+			campaign = strings.ReplaceAll(cfg.UTMCampaign, "{direction}", p.Direction)
+		}
+		if extra, err := url.ParseQuery(cfg.ExtraParams); err == nil {
+			for k, vs := range extra {
+				for _, v := range vs {
+					q.Set(k, v)
+				}
+			}
+		}
+	}
+	q.Set("utm_source", source)
+	q.Set("utm_medium", medium)
+	q.Set("utm_campaign", campaign)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSignParse(t *testing.T) {
+	t.Setenv("REDIRECT_TOKEN_SECRET", "test-secret")
+	payload := Payload{Direction: "deposit", ProductKey: "deposit:kapital-bank:1a2b3c4d5e6f", BankName: "Kapital Bank", URL: "https://kapitalbank.uz"}
+	token := Sign(payload)
+	body, sig, _ := strings.Cut(token, ".")
+	otherBody, _, _ := strings.Cut(Sign(Payload{Direction: "card", ProductKey: payload.ProductKey}), ".")
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{"valid", token, nil},
+		{"tampered body", otherBody + "." + sig, ErrInvalidToken},
+		{"tampered signature", body + "." + strings.Repeat("A", len(sig)), ErrInvalidToken},
+		{"no signature", body, ErrInvalidToken},
+		{"empty", "", ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Parse() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && got != payload {
+				t.Errorf("Parse() = %+v, want %+v", got, payload)
+			}
+		})
+	}
+}
+
+func TestSignParseOtherSecret(t *testing.T) {
+	t.Setenv("REDIRECT_TOKEN_SECRET", "first")
+	token := Sign(Payload{Direction: "deposit"})
+	t.Setenv("REDIRECT_TOKEN_SECRET", "second")
+	if _, err := Parse(token); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("Parse() with another secret error = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestSignParseWithoutSecret(t *testing.T) {
+	t.Setenv("REDIRECT_TOKEN_SECRET", "")
+	t.Setenv("JWT_SECRET", "")
+	if token := Sign(Payload{Direction: "deposit"}); token != "" {
+		t.Errorf("Sign() without secret = %q, want empty", token)
+	}
+	if _, err := Parse("e30.AAAAAAAAAAAAAAAAAAAAAA"); !errors.Is(err, ErrNoSecret) {
+		t.Errorf("Parse() without secret error = %v, want ErrNoSecret", err)
+	}
+}
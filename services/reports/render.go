@@ -0,0 +1,61 @@
+package services
+
+import (
+	"bytes"
+	"html/template"
+)
+
+var summaryTemplate = template.Must(template.New("summary").Funcs(template.FuncMap{
+	"title": func(kind string) string {
+		if kind == KindWeekly {
+			return "Еженедельная сводка"
+		}
+		return "Ежедневная сводка"
+	},
+	"inc": func(i int) int { return i + 1 },
+}).Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Kliro</title></head>
+<body style="font-family:Arial,sans-serif;color:#222;max-width:720px">
+<h2>Kliro: {{title .Kind}}</h2>
+<p style="color:#666">{{if eq .DateFrom .DateTo}}{{.DateFrom}}{{else}}{{.DateFrom}} — {{.DateTo}}{{end}} (время Ташкента)</p>
+{{with .Users}}
+<h3>Пользователи</h3>
+<table cellpadding="4"><tr><td>Новые</td><td><b>{{.New}}</b></td></tr><tr><td>Из них подтвердили контакт</td><td>{{.Confirmed}}</td></tr><tr><td>Всего пользователей</td><td>{{.Total}}</td></tr></table>
+{{end}}
+{{with .Orders}}
+<h3>Заказы: {{.Total}}</h3>
+{{if .ByCategory}}<table cellpadding="4" border="1" style="border-collapse:collapse"><tr><th>Категория</th><th>Заказы</th></tr>{{range .ByCategory}}<tr><td>{{.Name}}</td><td>{{.Count}}</td></tr>{{end}}</table>{{end}}
+{{if .ByStatus}}<p>{{range $i, $c := .ByStatus}}{{if $i}}, {{end}}{{$c.Name}}: {{$c.Count}}{{end}}</p>{{end}}
+{{end}}
+{{with .Payments}}
+<h3>Платежи: {{.Total}}</h3>
+<p>Успешные: <b>{{.Success}}</b> на {{.SuccessAmount}} сум</p>
+{{if .ByMethod}}<table cellpadding="4" border="1" style="border-collapse:collapse"><tr><th>Способ</th><th>Платежи</th><th>Сумма, сум</th></tr>{{range .ByMethod}}<tr><td>{{.Name}}</td><td>{{.Count}}</td><td>{{.Amount}}</td></tr>{{end}}</table>{{end}}
+{{if .ByStatus}}<p>{{range $i, $c := .ByStatus}}{{if $i}}, {{end}}{{$c.Name}}: {{$c.Count}}{{end}}</p>{{end}}
+{{end}}
+{{with .Insurance}}
+<h3>Страхование</h3>
+<table cellpadding="4">{{range .Quotes}}<tr><td>Расчеты {{.Name}}</td><td>{{.Count}}</td></tr>{{end}}
+<tr><td>Заказы ОСАГО</td><td><b>{{.OsagoOrders}}</b> на {{.OsagoAmountUZS}} сум</td></tr>
+<tr><td>Выпущено полисов ОСАГО</td><td>{{.OsagoIssued}}</td></tr></table>
+{{end}}
+{{if .TopClicks}}
+<h3>Топ продуктов по переходам</h3>
+<table cellpadding="4" border="1" style="border-collapse:collapse"><tr><th>#</th><th>Направление</th><th>Банк</th><th>Продукт</th><th>Клики</th></tr>
+{{range $i, $t := .TopClicks}}<tr><td>{{inc $i}}</td><td>{{$t.Direction}}</td><td>{{$t.BankSlug}}</td><td>{{$t.Key}}</td><td>{{$t.ClickCount}}</td></tr>{{end}}</table>
+{{end}}
+{{if .Parsers}}
+<h3>Парсеры</h3>
+<table cellpadding="4" border="1" style="border-collapse:collapse"><tr><th>Данные</th><th>Записей</th><th>Обновлено</th><th>Статус</th></tr>
+{{range .Parsers}}<tr><td>{{.Name}}</td><td>{{.Rows}}</td><td>{{if .LastUpdate}}{{.AgeHours}} ч назад{{else}}—{{end}}</td><td style="color:{{if .OK}}#2a7{{else}}#c33{{end}}">{{if .OK}}OK{{else}}устарели{{end}}</td></tr>{{end}}</table>
+{{end}}
+</body></html>`))
+
+// RenderHTML рендерит сводку в HTML для письма
+func RenderHTML(s *Summary) (string, error) {
+	var buf bytes.Buffer
+	if err := summaryTemplate.Execute(&buf, s); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"kliro/config"
+	"kliro/models"
+	"kliro/utils"
+
+	"github.com/robfig/cron/v3"
+	"gopkg.in/gomail.v2"
+	"gorm.io/gorm"
+)
+
+// ParseRecipients разбирает и проверяет адреса получателей через запятую
+func ParseRecipients(raw string) ([]string, error) {
+	var out []string
+	for _, r := range strings.Split(raw, ",") {
+		r = strings.TrimSpace(r)
+		if r == "" {
+			continue
+		}
+		if _, err := mail.ParseAddress(r); err != nil {
+			return nil, fmt.Errorf("неверный email: %s", r)
+		}
+		out = append(out, r)
+	}
+	if len(out) == 0 {
+		return nil, errors.New("укажите хотя бы одного получателя")
+	}
+	return out, nil
+}
+
+// Due - пора ли отправлять сводку: наступил час отправки (и день недели для weekly), а сегодня она еще не уходила
+func Due(s models.ReportSchedule, now time.Time) bool {
+	now = now.In(utils.GetUzbekLocation())
+	if !s.IsActive || now.Hour() < s.SendHour {
+		return false
+	}
+	if s.Kind == KindWeekly && int(now.Weekday()) != s.Weekday {
+		return false
+	}
+	if s.LastSentAt == nil {
+		return true
+	}
+	last := s.LastSentAt.In(now.Location())
+	return last.Year() != now.Year() || last.YearDay() != now.YearDay()
+}
+
+// Send собирает сводку расписания за отчетный период, отправляет письмо и запоминает результат
+func Send(db *gorm.DB, s *models.ReportSchedule) error {
+	sendErr := func() error {
+		recipients, err := ParseRecipients(s.Recipients)
+		if err != nil {
+			return err
+		}
+		from, to := Period(s.Kind, time.Now())
+		summary, err := Build(db, s.Kind, from, to, ParseSections(s.Sections))
+		if err != nil {
+			return err
+		}
+		body, err := RenderHTML(summary)
+		if err != nil {
+			return err
+		}
+		subject := fmt.Sprintf("Kliro: %s за %s", s.Name, summary.DateTo)
+		if summary.DateFrom != summary.DateTo {
+			subject = fmt.Sprintf("Kliro: %s за %s — %s", s.Name, summary.DateFrom, summary.DateTo)
+		}
+		return sendHTML(recipients, subject, body)
+	}()
+
+	s.LastError = ""
+	if sendErr != nil {
+		s.LastError = sendErr.Error()
+	} else {
+		now := time.Now()
+		s.LastSentAt = &now
+	}
+	if err := db.Model(&models.ReportSchedule{}).Where("id = ?", s.ID).
+		Updates(map[string]interface{}{"last_error": s.LastError, "last_sent_at": s.LastSentAt}).Error; err != nil {
+		utils.LogError(err, "reports: save schedule result")
+	}
+	return sendErr
+}
+
+func sendHTML(to []string, subject, body string) error {
+	cfg := config.LoadConfig()
+	if cfg.SMTPHost == "" {
+		return errors.New("smtp is not configured")
+	}
+	port, err := strconv.Atoi(cfg.SMTPPort)
+	if err != nil || port == 0 {
+		port = 587
+	}
+
+	m := gomail.NewMessage()
+	m.SetHeader("From", cfg.SMTPUser)
+	m.SetHeader("To", to...)
+	m.SetHeader("Subject", subject)
+	m.SetBody("text/html", body)
+	return gomail.NewDialer(cfg.SMTPHost, port, cfg.SMTPUser, cfg.SMTPPass).DialAndSend(m)
+}
+
+// RunDue отправляет все сводки, время которых подошло (неудачные повторяются в следующий час)
+func RunDue(db *gorm.DB) {
+	var schedules []models.ReportSchedule
+	if err := db.Where("is_active = ?", true).Find(&schedules).Error; err != nil {
+		utils.LogError(err, "reports: load schedules")
+		return
+	}
+	now := time.Now()
+	for i := range schedules {
+		if !Due(schedules[i], now) {
+			continue
+		}
+		if err := Send(db, &schedules[i]); err != nil {
+			log.Printf("[REPORTS] schedule %d (%s) failed: %v", schedules[i].ID, schedules[i].Name, err)
+		}
+	}
+}
+
+// StartReportCron проверяет расписания сводок в начале каждого часа
+func StartReportCron(db *gorm.DB) {
+	c := cron.New()
+	c.AddFunc("0 * * * *", func() { RunDue(db) })
+	c.Start()
+	log.Printf("[REPORTS CRON] Планировщик запущен. Проверка расписаний email-сводок каждый час")
+}
@@ -0,0 +1,270 @@
+package services
+
+import (
+	"strings"
+	"time"
+
+	"kliro/models"
+	clickServices "kliro/services/clicks"
+	osagoServices "kliro/services/osago"
+	"kliro/utils"
+
+	"gorm.io/gorm"
+)
+
+// Виды сводок
+const (
+	KindDaily  = "daily"
+	KindWeekly = "weekly"
+)
+
+// Разделы сводки
+const (
+	SectionUsers     = "users"
+	SectionOrders    = "orders"
+	SectionPayments  = "payments"
+	SectionInsurance = "insurance"
+	SectionClicks    = "clicks"
+	SectionParsers   = "parsers"
+)
+
+// AllSections - разделы по порядку в письме
+var AllSections = []string{SectionUsers, SectionOrders, SectionPayments, SectionInsurance, SectionClicks, SectionParsers}
+
+// parserTables - таблицы, которые заполняют парсеры bank.uz (ключ — название в отчете)
+var parserTables = []struct{ Name, Table string }{
+	{"Вклады", "new_deposit"},
+	{"Микрокредиты", "new_microcredit"},
+	{"Автокредиты", "new_autocredit"},
+	{"Ипотека", "new_mortgage"},
+	{"Карты", "new_card"},
+	{"Кредитные карты", "new_credit_card"},
+	{"Переводы", "new_transfer"},
+	{"Курсы валют", "new_currency"},
+}
+
+// parserStaleAfter - данные старше считаются устаревшими (парсеры обновляют таблицы раз в сутки)
+const parserStaleAfter = 36 * time.Hour
+
+// Count - значение в разрезе (категория, статус, метод оплаты ...)
+type Count struct {
+	Name   string `json:"name"`
+	Count  int    `json:"count"`
+	Amount int64  `json:"amount,omitempty"`
+}
+
+// UsersSection - регистрации
+type UsersSection struct {
+	New       int `json:"new"`
+	Confirmed int `json:"confirmed"` // из новых подтвердили email/телефон
+	Total     int `json:"total"`
+}
+
+// OrdersSection - заказы
+type OrdersSection struct {
+	Total      int     `json:"total"`
+	ByCategory []Count `json:"by_category"`
+	ByStatus   []Count `json:"by_status"`
+}
+
+// PaymentsSection - платежи (суммы в сумах)
+type PaymentsSection struct {
+	Total         int     `json:"total"`
+	Success       int     `json:"success"`
+	SuccessAmount int64   `json:"success_amount"`
+	ByStatus      []Count `json:"by_status"`
+	ByMethod      []Count `json:"by_method"` // успешные по способу оплаты
+}
+
+// InsuranceSection - расчеты и покупки страховок
+type InsuranceSection struct {
+	Quotes         []Count `json:"quotes"` // расчеты с хотя бы одной ценой по продукту
+	OsagoOrders    int     `json:"osago_orders"`
+	OsagoIssued    int     `json:"osago_issued"`
+	OsagoAmountUZS int64   `json:"osago_amount_uzs"`
+}
+
+// ParserHealth - свежесть данных парсера
+type ParserHealth struct {
+	Name       string     `json:"name"`
+	Table      string     `json:"table"`
+	Rows       int        `json:"rows"`
+	LastUpdate *time.Time `json:"last_update"`
+	AgeHours   float64    `json:"age_hours"`
+	OK         bool       `json:"ok"`
+}
+
+// Summary - сводка за период
+type Summary struct {
+	Kind      string                   `json:"kind"`
+	DateFrom  string                   `json:"date_from"`
+	DateTo    string                   `json:"date_to"`
+	Users     *UsersSection            `json:"users,omitempty"`
+	Orders    *OrdersSection           `json:"orders,omitempty"`
+	Payments  *PaymentsSection         `json:"payments,omitempty"`
+	Insurance *InsuranceSection        `json:"insurance,omitempty"`
+	TopClicks []clickServices.KeyTotal `json:"top_clicks,omitempty"`
+	Parsers   []ParserHealth           `json:"parsers,omitempty"`
+}
+
+// Period - отчетный период сводки: daily — вчера, weekly — 7 дней по вчера включительно (даты Ташкента)
+func Period(kind string, now time.Time) (time.Time, time.Time) {
+	now = now.In(utils.GetUzbekLocation())
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, -1)
+	if kind == KindWeekly {
+		return to.AddDate(0, 0, -6), to
+	}
+	return to, to
+}
+
+// ParseSections разбирает список разделов через запятую; пусто — все. Неизвестные разделы отбрасываются.
+func ParseSections(raw string) []string {
+	if strings.TrimSpace(raw) == "" {
+		return AllSections
+	}
+	wanted := map[string]bool{}
+	for _, s := range strings.Split(raw, ",") {
+		wanted[strings.ToLower(strings.TrimSpace(s))] = true
+	}
+	var out []string
+	for _, s := range AllSections {
+		if wanted[s] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// Build собирает сводку за [from, to] (даты по времени Ташкента, включительно)
+func Build(db *gorm.DB, kind string, from, to time.Time, sections []string) (*Summary, error) {
+	loc := utils.GetUzbekLocation()
+	start := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc)
+	end := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, loc).AddDate(0, 0, 1)
+	s := &Summary{Kind: kind, DateFrom: from.Format("2006-01-02"), DateTo: to.Format("2006-01-02")}
+
+	for _, section := range sections {
+		var err error
+		switch section {
+		case SectionUsers:
+			s.Users, err = buildUsers(db, start, end)
+		case SectionOrders:
+			s.Orders, err = buildOrders(db, start, end)
+		case SectionPayments:
+			s.Payments, err = buildPayments(db, start, end)
+		case SectionInsurance:
+			s.Insurance, err = buildInsurance(db, start, end)
+		case SectionClicks:
+			s.TopClicks, err = clickServices.Totals(db, clickServices.Filter{From: from, To: to}, 10)
+		case SectionParsers:
+			s.Parsers = buildParsers(db)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func buildUsers(db *gorm.DB, start, end time.Time) (*UsersSection, error) {
+	var row struct {
+		New       int
+		Confirmed int
+	}
+	if err := db.Model(&models.User{}).
+		Select("COUNT(*) AS new, SUM(CASE WHEN confirmed THEN 1 ELSE 0 END) AS confirmed").
+		Where("created_at >= ? AND created_at < ?", start, end).Scan(&row).Error; err != nil {
+		return nil, err
+	}
+	var total int64
+	if err := db.Model(&models.User{}).Count(&total).Error; err != nil {
+		return nil, err
+	}
+	return &UsersSection{New: row.New, Confirmed: row.Confirmed, Total: int(total)}, nil
+}
+
+func buildOrders(db *gorm.DB, start, end time.Time) (*OrdersSection, error) {
+	section := &OrdersSection{}
+	base := func() *gorm.DB {
+		return db.Model(&models.Order{}).Where("created_at >= ? AND created_at < ?", start, end)
+	}
+	if err := base().Select("category AS name, COUNT(*) AS count").Group("category").Order("count DESC").Scan(&section.ByCategory).Error; err != nil {
+		return nil, err
+	}
+	if err := base().Select("status AS name, COUNT(*) AS count").Group("status").Order("count DESC").Scan(&section.ByStatus).Error; err != nil {
+		return nil, err
+	}
+	for _, c := range section.ByStatus {
+		section.Total += c.Count
+	}
+	return section, nil
+}
+
+func buildPayments(db *gorm.DB, start, end time.Time) (*PaymentsSection, error) {
+	section := &PaymentsSection{}
+	base := func() *gorm.DB {
+		return db.Model(&models.Payment{}).Where("created_at >= ? AND created_at < ?", start, end)
+	}
+	// amount хранится в тийинах
+	if err := base().Select("status AS name, COUNT(*) AS count, COALESCE(SUM(amount), 0) / 100 AS amount").Group("status").Order("count DESC").Scan(&section.ByStatus).Error; err != nil {
+		return nil, err
+	}
+	if err := base().Where("status = ?", "success").Select("payment_method AS name, COUNT(*) AS count, COALESCE(SUM(amount), 0) / 100 AS amount").Group("payment_method").Order("amount DESC").Scan(&section.ByMethod).Error; err != nil {
+		return nil, err
+	}
+	for _, c := range section.ByStatus {
+		section.Total += c.Count
+		if c.Name == "success" {
+			section.Success = c.Count
+			section.SuccessAmount = c.Amount
+		}
+	}
+	return section, nil
+}
+
+func buildInsurance(db *gorm.DB, start, end time.Time) (*InsuranceSection, error) {
+	section := &InsuranceSection{}
+	if err := db.Model(&models.InsuranceQuote{}).
+		Select("product AS name, COUNT(DISTINCT batch_id) AS count").
+		Where("success AND created_at >= ? AND created_at < ?", start, end).
+		Group("product").Order("product").Scan(&section.Quotes).Error; err != nil {
+		return nil, err
+	}
+	var orders struct {
+		Orders int
+		Amount int64
+	}
+	if err := db.Model(&models.OsagoOrder{}).
+		Select("COUNT(*) AS orders, COALESCE(SUM(amount_uzs), 0) AS amount").
+		Where("status <> ? AND created_at >= ? AND created_at < ?", osagoServices.StatusFailed, start, end).Scan(&orders).Error; err != nil {
+		return nil, err
+	}
+	var issued int64
+	if err := db.Model(&models.OsagoOrder{}).Where("issued_at >= ? AND issued_at < ?", start, end).Count(&issued).Error; err != nil {
+		return nil, err
+	}
+	section.OsagoOrders, section.OsagoAmountUZS, section.OsagoIssued = orders.Orders, orders.Amount, int(issued)
+	return section, nil
+}
+
+// buildParsers - здоровье парсеров по свежести таблиц (отдельного журнала запусков нет)
+func buildParsers(db *gorm.DB) []ParserHealth {
+	out := make([]ParserHealth, 0, len(parserTables))
+	for _, p := range parserTables {
+		h := ParserHealth{Name: p.Name, Table: p.Table}
+		var row struct {
+			RowCount int
+			LastAt   *time.Time
+		}
+		if err := db.Table(p.Table).Select("COUNT(*) AS row_count, MAX(created_at) AS last_at").Scan(&row).Error; err != nil {
+			utils.LogError(err, "reports: parser health "+p.Table)
+		}
+		h.Rows, h.LastUpdate = row.RowCount, row.LastAt
+		if row.LastAt != nil {
+			age := time.Since(*row.LastAt)
+			h.AgeHours = float64(int(age.Hours()*10)) / 10
+			h.OK = row.RowCount > 0 && age < parserStaleAfter
+		}
+		out = append(out, h)
+	}
+	return out
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"kliro/models"
+	"kliro/utils"
+
+	"gorm.io/gorm"
+)
+
+// Объекты отзывов
+const (
+	TargetBank    = "bank"
+	TargetProduct = "product"
+)
+
+// Статусы модерации
+const (
+	StatusPending  = "pending"
+	StatusApproved = "approved"
+	StatusRejected = "rejected"
+)
+
+// Aggregate - сводная оценка объекта по одобренным отзывам
+type Aggregate struct {
+	Rating       float64     `json:"rating"`
+	ReviewCount  int         `json:"review_count"`
+	Distribution map[int]int `json:"distribution,omitempty"` // оценка -> количество
+}
+
+// PublicReview - отзыв в публичной выдаче: без id автора и полей модерации
+type PublicReview struct {
+	ID           uint      `json:"id"`
+	Rating       int       `json:"rating"`
+	Title        string    `json:"title"`
+	Body         string    `json:"body"`
+	Author       string    `json:"author"` // имя и первая буква фамилии
+	HelpfulCount int       `json:"helpful_count"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// PublicReviews готовит одобренные отзывы к публичной выдаче (имена авторов — одним запросом)
+func PublicReviews(db *gorm.DB, reviews []models.Review) []PublicReview {
+	userIDs := make([]uint, 0, len(reviews))
+	for _, r := range reviews {
+		userIDs = append(userIDs, r.UserID)
+	}
+	var users []models.User
+	if len(userIDs) > 0 {
+		db.Select("id", "first_name", "last_name").Where("id IN ?", userIDs).Find(&users)
+	}
+	names := make(map[uint]string, len(users))
+	for _, u := range users {
+		names[u.ID] = authorName(u)
+	}
+
+	out := make([]PublicReview, 0, len(reviews))
+	for _, r := range reviews {
+		author := names[r.UserID]
+		if author == "" {
+			author = "Пользователь"
+		}
+		out = append(out, PublicReview{ID: r.ID, Rating: r.Rating, Title: r.Title, Body: r.Body, Author: author, HelpfulCount: r.HelpfulCount, CreatedAt: r.CreatedAt})
+	}
+	return out
+}
+
+// authorName - "Имя Ф." для подписи отзыва; "" — имя не указано
+func authorName(u models.User) string {
+	if u.FirstName == nil || strings.TrimSpace(*u.FirstName) == "" {
+		return ""
+	}
+	name := strings.TrimSpace(*u.FirstName)
+	if u.LastName != nil {
+		if last := []rune(strings.TrimSpace(*u.LastName)); len(last) > 0 {
+			name += " " + string(last[0]) + "."
+		}
+	}
+	return name
+}
+
+// Aggregates - сводные оценки по ключам объектов
+type Aggregates map[string]Aggregate
+
+// Get возвращает оценку и количество отзывов (0, 0 — отзывов нет)
+func (a Aggregates) Get(key string) (float64, int) {
+	agg := a[key]
+	return agg.Rating, agg.ReviewCount
+}
+
+// ProductAggregates считает оценки продуктов по их ключам одним запросом (для списков /bank/*/new)
+func ProductAggregates(keys []string) Aggregates {
+	return loadAggregates(utils.GetDB(), TargetProduct, keys)
+}
+
+// TargetAggregate сводная оценка одного объекта с распределением оценок
+func TargetAggregate(db *gorm.DB, targetType, targetKey string) Aggregate {
+	var rows []struct {
+		Rating int
+		Count  int
+	}
+	db.Model(&models.Review{}).
+		Select("rating, COUNT(*) AS count").
+		Where("target_type = ? AND target_key = ? AND status = ?", targetType, targetKey, StatusApproved).
+		Group("rating").Scan(&rows)
+
+	agg := Aggregate{Distribution: map[int]int{1: 0, 2: 0, 3: 0, 4: 0, 5: 0}}
+	sum := 0
+	for _, r := range rows {
+		agg.Distribution[r.Rating] = r.Count
+		agg.ReviewCount += r.Count
+		sum += r.Rating * r.Count
+	}
+	if agg.ReviewCount > 0 {
+		agg.Rating = roundRating(float64(sum) / float64(agg.ReviewCount))
+	}
+	return agg
+}
+
+func loadAggregates(db *gorm.DB, targetType string, keys []string) Aggregates {
+	result := make(Aggregates, len(keys))
+	if db == nil || len(keys) == 0 {
+		return result
+	}
+	var rows []struct {
+		TargetKey string
+		Avg       float64
+		Count     int
+	}
+	if err := db.Model(&models.Review{}).
+		Select("target_key, AVG(rating) AS avg, COUNT(*) AS count").
+		Where("target_type = ? AND status = ? AND target_key IN ?", targetType, StatusApproved, keys).
+		Group("target_key").Scan(&rows).Error; err != nil {
+		utils.LogError(err, "reviews: load aggregates")
+		return result
+	}
+	for _, r := range rows {
+		result[r.TargetKey] = Aggregate{Rating: roundRating(r.Avg), ReviewCount: r.Count}
+	}
+	return result
+}
+
+// RecountHelpful пересчитывает счетчик голосов "полезно" у отзыва
+func RecountHelpful(db *gorm.DB, reviewID uint) (int, error) {
+	var count int64
+	if err := db.Model(&models.ReviewVote{}).Where("review_id = ?", reviewID).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	err := db.Model(&models.Review{}).Where("id = ?", reviewID).UpdateColumn("helpful_count", count).Error
+	return int(count), err
+}
+
+func roundRating(v float64) float64 {
+	return math.Round(v*10) / 10
+}
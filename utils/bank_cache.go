@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"context"
+	"log"
+)
+
+// bankDataVersionKey - счетчик версии данных парсеров банков.
+// Увеличивается после каждого парсинга; кэши, построенные по new_* таблицам,
+// включают версию в ключ и поэтому живут ровно до следующего парсинга.
+const bankDataVersionKey = "bank:data_version"
+
+// BankDataVersion возвращает текущую версию данных парсеров (0, если Redis недоступен)
+func BankDataVersion() int64 {
+	rdb := GetRedis()
+	if rdb == nil {
+		return 0
+	}
+	v, err := rdb.Get(context.Background(), bankDataVersionKey).Int64()
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+// BumpBankDataVersion увеличивает версию данных (вызывается кронами после парсинга)
+func BumpBankDataVersion() {
+	rdb := GetRedis()
+	if rdb == nil {
+		return
+	}
+	if err := rdb.Incr(context.Background(), bankDataVersionKey).Err(); err != nil {
+		log.Printf("[BANK CACHE] failed to bump data version: %v", err)
+	}
+}
@@ -175,3 +175,31 @@ func (bn *BankNormalizer) IsBankName(bankName string) bool {
 
 	return !mobileApps[normalized]
 }
+
+// BankSlug - строит slug банка для URL (например "Kapital Bank" -> "kapital-bank", "O‘zbekiston Milliy Banki" -> "ozbekiston-milliy-banki")
+func BankSlug(bankName string) string {
+	normalized := GetBankNormalizer().NormalizeBankName(bankName)
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(normalized) {
+		switch {
+		case (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9'):
+			b.WriteRune(r)
+			dash = false
+		case r == '\'' || r == '‘' || r == '’' || r == 'ʻ' || r == '`':
+			// апострофы в узбекской латинице просто убираем
+		default:
+			if !dash && b.Len() > 0 {
+				b.WriteRune('-')
+				dash = true
+			}
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// BankSlugMatches - совпадает ли slug банка с запросом (поддерживает и "kapital-bank", и camelCase "kapitalBank")
+func BankSlugMatches(bankName, query string) bool {
+	compact := func(s string) string { return strings.ReplaceAll(strings.ToLower(s), "-", "") }
+	return compact(BankSlug(bankName)) == compact(BankSlug(query))
+}
@@ -20,6 +20,7 @@ type TranslatedCard struct {
 	En        CardLangData `json:"en"`
 	Oz        CardLangData `json:"oz"`
 	CreatedAt string       `json:"created_at"`
+	ListingMeta
 }
 
 type CreditCardLangData struct {
@@ -37,6 +38,7 @@ type TranslatedCreditCard struct {
 	En        CreditCardLangData `json:"en"`
 	Oz        CreditCardLangData `json:"oz"`
 	CreatedAt string             `json:"created_at"`
+	ListingMeta
 }
 
 type CardTranslator struct {
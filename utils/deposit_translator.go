@@ -21,6 +21,7 @@ type TranslatedDeposit struct {
 	En        DepositLangData `json:"en"`
 	Oz        DepositLangData `json:"oz"`
 	CreatedAt string          `json:"created_at"`
+	ListingMeta
 }
 
 type DepositTranslator struct {
@@ -30,3 +30,52 @@ func MarkOTPSent(rdb *redis.Client, key string) {
 	rdb.Incr(ctx, hourKey)
 	rdb.Expire(ctx, hourKey, time.Hour)
 }
+
+// CanExport ограничивает выгрузки списков продуктов (CSV/XLSX): не более 10 в минуту с одного клиента.
+// При недоступном Redis не блокирует.
+func CanExport(rdb *redis.Client, key string) (bool, string) {
+	if rdb == nil {
+		return true, ""
+	}
+	ctx := context.Background()
+	minuteKey := fmt.Sprintf("export_minute_%s", key)
+	cnt, err := rdb.Incr(ctx, minuteKey).Result()
+	if err != nil {
+		return true, ""
+	}
+	if cnt == 1 {
+		rdb.Expire(ctx, minuteKey, time.Minute)
+	}
+	if cnt > 10 {
+		return false, "Можно выгружать не более 10 раз в минуту"
+	}
+	return true, ""
+}
+
+// CanSubmitLead ограничивает публичную отправку заявок банкам: не более 20 в час с одного IP
+// и 5 в час на один номер телефона. При недоступном Redis не блокирует.
+func CanSubmitLead(rdb *redis.Client, ip, phone string) (bool, string) {
+	if rdb == nil {
+		return true, ""
+	}
+	if hourlyCount(rdb, fmt.Sprintf("lead_hour_ip_%s", ip)) > 20 {
+		return false, "Слишком много заявок, попробуйте позже"
+	}
+	if hourlyCount(rdb, fmt.Sprintf("lead_hour_phone_%s", phone)) > 5 {
+		return false, "По этому номеру уже отправлено много заявок, попробуйте позже"
+	}
+	return true, ""
+}
+
+// hourlyCount увеличивает счетчик с окном в час и возвращает его значение (0 при ошибке Redis)
+func hourlyCount(rdb *redis.Client, key string) int64 {
+	ctx := context.Background()
+	cnt, err := rdb.Incr(ctx, key).Result()
+	if err != nil {
+		return 0
+	}
+	if cnt == 1 {
+		rdb.Expire(ctx, key, time.Hour)
+	}
+	return cnt
+}
@@ -0,0 +1,13 @@
+package utils
+
+// ListingMeta - служебные поля продукта в списках /bank/*/new (встраивается в Translated* структуры)
+type ListingMeta struct {
+	ProductKey     string  `json:"product_key,omitempty"` // стабильный ключ продукта (ProductKey)
+	Promoted       bool    `json:"promoted"`              // спонсорское место
+	PromotionLabel string  `json:"promotion_label,omitempty"`
+	Pinned         bool    `json:"pinned,omitempty"`         // закреплен редакцией
+	EditorialNote  string  `json:"editorial_note,omitempty"` // заметка редакции
+	Rating         float64 `json:"rating"`                   // средняя оценка по одобренным отзывам (0 — отзывов нет)
+	ReviewCount    int     `json:"review_count"`             // количество одобренных отзывов
+	RedirectURL    string  `json:"redirect_url,omitempty"`   // подписанная ссылка /go/<token> на сайт банка
+}
@@ -46,6 +46,7 @@ type TranslatedMicrocredit struct {
 	Oz        MicrocreditLangData `json:"oz"`
 	URL       string              `json:"url"`
 	CreatedAt string              `json:"created_at"`
+	ListingMeta
 }
 
 // TranslatedAutocredit - структура автокредита с переводами (каждый язык отдельным объектом)
@@ -57,6 +58,7 @@ type TranslatedAutocredit struct {
 	En        MicrocreditLangData `json:"en"`
 	Oz        MicrocreditLangData `json:"oz"`
 	CreatedAt string              `json:"created_at"`
+	ListingMeta
 }
 
 // TranslateMicrocredit - переводит микрокредит на 4 языка (каждый язык отдельным объектом)
@@ -0,0 +1,20 @@
+package utils
+
+import (
+	"crypto/md5"
+	"fmt"
+	"strings"
+)
+
+// ProductKey - стабильный идентификатор продукта банка, переживающий перепарсинг.
+// ID в new_* таблицах меняются после каждого TRUNCATE, поэтому продукт определяется
+// направлением, банком и названием: "deposit:kapital-bank:1a2b3c4d5e6f".
+func ProductKey(direction, bankName, name string) string {
+	normalizedName := strings.ToLower(strings.Join(strings.Fields(name), " "))
+	hash := md5.Sum([]byte(normalizedName))
+	bank := BankSlug(bankName)
+	if bank == "" {
+		bank = "-"
+	}
+	return fmt.Sprintf("%s:%s:%x", strings.ToLower(direction), bank, hash[:6])
+}
@@ -0,0 +1,162 @@
+package utils
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// TableWriter - потоковая запись таблицы построчно (CSV/XLSX): строки уходят клиенту по мере записи,
+// весь файл в памяти не собирается
+type TableWriter interface {
+	WriteRow(cells []string) error
+	Close() error
+}
+
+// tableFlushEvery - через сколько строк сбрасывать буферы в ответ
+const tableFlushEvery = 100
+
+// flushResponse отправляет клиенту уже записанные данные, если writer это поддерживает (gin.ResponseWriter)
+func flushResponse(w io.Writer) {
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+type csvTableWriter struct {
+	out  io.Writer
+	w    *csv.Writer
+	rows int
+}
+
+// NewCSVTableWriter пишет CSV в UTF-8 с BOM (чтобы Excel корректно открывал кириллицу)
+func NewCSVTableWriter(out io.Writer) (TableWriter, error) {
+	if _, err := io.WriteString(out, "\ufeff"); err != nil {
+		return nil, err
+	}
+	return &csvTableWriter{out: out, w: csv.NewWriter(out)}, nil
+}
+
+func (t *csvTableWriter) WriteRow(cells []string) error {
+	if err := t.w.Write(cells); err != nil {
+		return err
+	}
+	t.rows++
+	if t.rows%tableFlushEvery == 0 {
+		t.w.Flush()
+		flushResponse(t.out)
+	}
+	return t.w.Error()
+}
+
+func (t *csvTableWriter) Close() error {
+	t.w.Flush()
+	flushResponse(t.out)
+	return t.w.Error()
+}
+
+type xlsxTableWriter struct {
+	out   io.Writer
+	zw    *zip.Writer
+	sheet io.Writer
+	rows  int
+}
+
+// Статические части книги XLSX (SpreadsheetML): один лист, строки записываются как inline strings
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`
+	xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	xlsxSheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxSheetEnd = `</sheetData></worksheet>`
+)
+
+// NewXLSXTableWriter пишет книгу XLSX с одним листом sheetName (до 31 символа, без спецсимволов)
+func NewXLSXTableWriter(out io.Writer, sheetName string) (TableWriter, error) {
+	zw := zip.NewWriter(out)
+	var name strings.Builder
+	xml.EscapeText(&name, []byte(sheetName))
+	parts := []struct{ path, body string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", fmt.Sprintf(xlsxWorkbook, name.String())},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	}
+	for _, p := range parts {
+		f, err := zw.Create(p.path)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, p.body); err != nil {
+			return nil, err
+		}
+	}
+	sheet, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(sheet, xlsxSheetStart); err != nil {
+		return nil, err
+	}
+	return &xlsxTableWriter{out: out, zw: zw, sheet: sheet}, nil
+}
+
+func (t *xlsxTableWriter) WriteRow(cells []string) error {
+	t.rows++
+	row := strconv.Itoa(t.rows)
+	if _, err := io.WriteString(t.sheet, `<row r="`+row+`">`); err != nil {
+		return err
+	}
+	for i, cell := range cells {
+		if _, err := io.WriteString(t.sheet, `<c r="`+xlsxColumn(i)+row+`" t="inlineStr"><is><t xml:space="preserve">`); err != nil {
+			return err
+		}
+		if err := xml.EscapeText(t.sheet, []byte(cell)); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(t.sheet, `</t></is></c>`); err != nil {
+			return err
+		}
+	}
+	if _, err := io.WriteString(t.sheet, `</row>`); err != nil {
+		return err
+	}
+	if t.rows%tableFlushEvery == 0 {
+		if err := t.zw.Flush(); err != nil {
+			return err
+		}
+		flushResponse(t.out)
+	}
+	return nil
+}
+
+func (t *xlsxTableWriter) Close() error {
+	if _, err := io.WriteString(t.sheet, xlsxSheetEnd); err != nil {
+		return err
+	}
+	if err := t.zw.Close(); err != nil {
+		return err
+	}
+	flushResponse(t.out)
+	return nil
+}
+
+// xlsxColumn переводит индекс колонки (с 0) в буквенное обозначение: 0 -> A, 26 -> AA
+func xlsxColumn(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
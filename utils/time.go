@@ -1,6 +1,9 @@
 package utils
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 // UzbekTime возвращает текущее время в часовом поясе Узбекистана
 func UzbekTime() time.Time {
@@ -22,3 +25,33 @@ func GetUzbekLocation() *time.Location {
 	}
 	return uzbekLocation
 }
+
+// ParseDateRange разбирает период отчета: значения параметров fromName/toName (YYYY-MM-DD, включительно),
+// по умолчанию последние 30 дней по времени Ташкента. maxDays > 0 ограничивает длину периода.
+// Возвращает текст ошибки для ответа клиенту ("" — период корректен).
+func ParseDateRange(fromName, fromValue, toName, toValue string, maxDays int) (time.Time, time.Time, string) {
+	now := UzbekTime()
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	from := to.AddDate(0, 0, -29)
+	if fromValue != "" {
+		t, err := time.Parse("2006-01-02", fromValue)
+		if err != nil {
+			return from, to, fromName + " должен быть в формате YYYY-MM-DD"
+		}
+		from = t
+	}
+	if toValue != "" {
+		t, err := time.Parse("2006-01-02", toValue)
+		if err != nil {
+			return from, to, toName + " должен быть в формате YYYY-MM-DD"
+		}
+		to = t
+	}
+	if to.Before(from) {
+		return from, to, toName + " должен быть не раньше " + fromName
+	}
+	if maxDays > 0 && to.Sub(from) > time.Duration(maxDays)*24*time.Hour {
+		return from, to, fmt.Sprintf("Период не может быть больше %d дней", maxDays)
+	}
+	return from, to, ""
+}
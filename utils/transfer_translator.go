@@ -20,6 +20,7 @@ type TranslatedTransfer struct {
 	En        TransferLangData  `json:"en"`
 	Oz        TransferLangData  `json:"oz"`
 	CreatedAt string            `json:"created_at"`
+	ListingMeta
 }
 
 // TransferTranslator - утилита для перевода полей переводов
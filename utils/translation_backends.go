@@ -0,0 +1,273 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"kliro/services"
+)
+
+// LibreTranslateBackend переводит через LibreTranslate (бесплатный, без токенов)
+type LibreTranslateBackend struct {
+	apiURL string
+	client *http.Client
+}
+
+// NewLibreTranslateBackend создает бэкенд LibreTranslate (по умолчанию публичный инстанс)
+func NewLibreTranslateBackend(apiURL string) *LibreTranslateBackend {
+	if apiURL == "" {
+		apiURL = "https://libretranslate.com/translate"
+	}
+	return &LibreTranslateBackend{apiURL: apiURL, client: &http.Client{}}
+}
+
+func (b *LibreTranslateBackend) Name() string { return "libretranslate" }
+
+func (b *LibreTranslateBackend) Translate(ctx context.Context, text, source, target string) (string, error) {
+	requestData := map[string]interface{}{
+		"q":      text,
+		"source": source,
+		"target": target,
+		"format": "text",
+	}
+
+	jsonData, err := json.Marshal(requestData)
+	if err != nil {
+		return text, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", b.apiURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return text, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return text, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return text, fmt.Errorf("libretranslate API error: %s", string(body))
+	}
+
+	var result struct {
+		TranslatedText string `json:"translatedText"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return text, err
+	}
+	if result.TranslatedText == "" {
+		return text, fmt.Errorf("no translation returned")
+	}
+
+	return result.TranslatedText, nil
+}
+
+// MyMemoryBackend переводит через MyMemory API (бесплатный, без токенов, с лимитами)
+type MyMemoryBackend struct {
+	apiURL string
+	client *http.Client
+}
+
+// NewMyMemoryBackend создает бэкенд MyMemory
+func NewMyMemoryBackend() *MyMemoryBackend {
+	return &MyMemoryBackend{apiURL: "https://api.mymemory.translated.net/get", client: &http.Client{}}
+}
+
+func (b *MyMemoryBackend) Name() string { return "mymemory" }
+
+func (b *MyMemoryBackend) Translate(ctx context.Context, text, source, target string) (string, error) {
+	reqURL := fmt.Sprintf("%s?q=%s&langpair=%s|%s", b.apiURL, url.QueryEscape(text), source, target)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return text, err
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return text, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return text, fmt.Errorf("mymemory API error: %s", string(body))
+	}
+
+	var result struct {
+		ResponseData struct {
+			TranslatedText string `json:"translatedText"`
+		} `json:"responseData"`
+		QuotaFinished bool `json:"quotaFinished"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return text, err
+	}
+	if result.QuotaFinished {
+		return text, fmt.Errorf("mymemory quota finished")
+	}
+	if result.ResponseData.TranslatedText == "" {
+		return text, fmt.Errorf("no translation returned")
+	}
+
+	return result.ResponseData.TranslatedText, nil
+}
+
+// LLMTranslateBackend переводит через chat-completion API (DeepSeek / OpenAI-совместимый)
+type LLMTranslateBackend struct {
+	apiURL string
+	apiKey string
+	model  string
+	client *http.Client
+}
+
+// NewLLMTranslateBackend создает LLM-бэкенд (по умолчанию DeepSeek)
+func NewLLMTranslateBackend(apiURL, apiKey, model string) *LLMTranslateBackend {
+	if apiURL == "" {
+		apiURL = "https://api.deepseek.com/chat/completions"
+	}
+	if model == "" {
+		model = "deepseek-chat"
+	}
+	return &LLMTranslateBackend{apiURL: apiURL, apiKey: apiKey, model: model, client: &http.Client{}}
+}
+
+func (b *LLMTranslateBackend) Name() string { return "llm" }
+
+// llmLanguageNames - названия языков для промпта
+var llmLanguageNames = map[string]string{
+	"uz": "Uzbek (Latin script)",
+	"ru": "Russian",
+	"en": "English",
+}
+
+func (b *LLMTranslateBackend) Translate(ctx context.Context, text, source, target string) (string, error) {
+	sourceName, ok := llmLanguageNames[source]
+	if !ok {
+		return text, fmt.Errorf("unsupported source language: %s", source)
+	}
+	targetName, ok := llmLanguageNames[target]
+	if !ok {
+		return text, fmt.Errorf("unsupported target language: %s", target)
+	}
+
+	requestData := services.DeepSeekRequest{
+		Model: b.model,
+		Messages: []services.Message{
+			{
+				Role: "system",
+				Content: fmt.Sprintf("You translate banking product descriptions from %s to %s. "+
+					"Keep numbers, percentages, currencies and bank names unchanged. Reply with the translation only.", sourceName, targetName),
+			},
+			{Role: "user", Content: text},
+		},
+		MaxTokens:   512,
+		Temperature: 0,
+	}
+
+	jsonData, err := json.Marshal(requestData)
+	if err != nil {
+		return text, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", b.apiURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return text, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+b.apiKey)
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return text, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return text, fmt.Errorf("llm API error (%d): %s", resp.StatusCode, string(body))
+	}
+
+	var result services.DeepSeekResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return text, err
+	}
+	if len(result.Choices) == 0 || strings.TrimSpace(result.Choices[0].Message.Content) == "" {
+		return text, fmt.Errorf("no translation returned")
+	}
+
+	return strings.TrimSpace(result.Choices[0].Message.Content), nil
+}
+
+// DictionaryBackend переводит только по словарю целых фраз (без внешних запросов).
+// Формат файла: {"фраза на uz": {"ru": "...", "en": "..."}}
+type DictionaryBackend struct {
+	mu      sync.RWMutex
+	entries map[string]map[string]string
+}
+
+// NewDictionaryBackend создает пустой словарный бэкенд
+func NewDictionaryBackend() *DictionaryBackend {
+	return &DictionaryBackend{entries: make(map[string]map[string]string)}
+}
+
+// LoadDictionaryBackend загружает словарь из JSON-файла.
+// При ошибке возвращается пустой (но рабочий) словарь и ошибка.
+func LoadDictionaryBackend(path string) (*DictionaryBackend, error) {
+	d := NewDictionaryBackend()
+	if path == "" {
+		return d, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return d, err
+	}
+	var entries map[string]map[string]string
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return d, err
+	}
+	for phrase, langs := range entries {
+		for lang, value := range langs {
+			d.Add(phrase, lang, value)
+		}
+	}
+	return d, nil
+}
+
+func (d *DictionaryBackend) Name() string { return "dictionary" }
+
+// Add добавляет перевод фразы на язык lang
+func (d *DictionaryBackend) Add(phrase, lang, value string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	key := dictionaryKey(phrase)
+	if d.entries[key] == nil {
+		d.entries[key] = make(map[string]string)
+	}
+	d.entries[key][lang] = value
+}
+
+func (d *DictionaryBackend) Translate(ctx context.Context, text, source, target string) (string, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if v := d.entries[dictionaryKey(text)][target]; v != "" {
+		return v, nil
+	}
+	return text, ErrTranslationNotFound
+}
+
+func dictionaryKey(phrase string) string {
+	return strings.ToLower(strings.Join(strings.Fields(phrase), " "))
+}
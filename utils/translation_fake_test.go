@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// FakeTranslator - бэкенд для тестов: без сети, детерминированный ответ,
+// с возможностью задать ошибку и задержку. Есть только в тестовой сборке — конфигурацией не выбирается
+type FakeTranslator struct {
+	name      string
+	mu        sync.Mutex
+	responses map[string]string
+	err       error
+	delay     time.Duration
+	calls     int
+}
+
+// NewFakeTranslator создает фейковый бэкенд с именем name
+func NewFakeTranslator(name string) *FakeTranslator {
+	if name == "" {
+		name = "fake"
+	}
+	return &FakeTranslator{name: name, responses: make(map[string]string)}
+}
+
+func (f *FakeTranslator) Name() string { return f.name }
+
+// SetResponse задает ответ на перевод text на язык target
+func (f *FakeTranslator) SetResponse(text, target, translated string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.responses[target+":"+text] = translated
+}
+
+// SetError заставляет бэкенд возвращать err на каждый вызов (nil - сбросить)
+func (f *FakeTranslator) SetError(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.err = err
+}
+
+// SetDelay задает искусственную задержку ответа (для проверки таймаутов)
+func (f *FakeTranslator) SetDelay(delay time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.delay = delay
+}
+
+// Calls возвращает количество вызовов Translate
+func (f *FakeTranslator) Calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+func (f *FakeTranslator) Translate(ctx context.Context, text, source, target string) (string, error) {
+	f.mu.Lock()
+	f.calls++
+	delay, err := f.delay, f.err
+	response, ok := f.responses[target+":"+text]
+	f.mu.Unlock()
+
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return text, ctx.Err()
+		}
+	}
+	if err != nil {
+		return text, err
+	}
+	if ok {
+		return response, nil
+	}
+	return fmt.Sprintf("[%s] %s", target, text), nil
+}
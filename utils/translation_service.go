@@ -1,39 +1,147 @@
 package utils
 
 import (
-	"bytes"
 	"context"
 	"crypto/md5"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
-	"net/url"
+	"strings"
+	"sync"
 	"time"
 
+	"kliro/config"
+
 	"github.com/go-redis/redis/v8"
 )
 
-// TranslationService - сервис для переводов с использованием внешнего API
+// Translator - бэкенд машинного перевода (LibreTranslate, LLM, словарь и т.д.)
+type Translator interface {
+	// Name возвращает имя бэкенда (используется в метриках и логах)
+	Name() string
+	// Translate переводит text с языка source на язык target
+	Translate(ctx context.Context, text, source, target string) (string, error)
+}
+
+// ErrTranslationNotFound возвращается бэкендом, у которого нет перевода для фразы
+var ErrTranslationNotFound = errors.New("translation not found")
+
+// errTranslationRateLimited возвращается, когда бэкенд исчерпал лимит запросов
+var errTranslationRateLimited = errors.New("translation backend rate limited")
+
+// TranslationBackend - бэкенд в цепочке fallback со своим таймаутом и лимитом запросов
+type TranslationBackend struct {
+	Translator    Translator
+	Timeout       time.Duration // 0 = без таймаута
+	RatePerMinute int           // 0 = без ограничений
+}
+
+// translationBackendSlot - бэкенд цепочки вместе с его лимитером
+type translationBackendSlot struct {
+	translator Translator
+	timeout    time.Duration
+	limiter    *translationRateLimiter
+}
+
+// TranslationService - сервис для переводов с цепочкой бэкендов (fallback по порядку)
 type TranslationService struct {
-	apiURL string // URL API (LibreTranslate или MyMemory)
-	client *http.Client
-	redis  *redis.Client
+	backends []*translationBackendSlot
+	redis    *redis.Client
+	metrics  *TranslationMetrics
+}
+
+var globalTranslationService *TranslationService
+
+// SetTranslationService устанавливает глобальный сервис переводов (для админских метрик)
+func SetTranslationService(service *TranslationService) {
+	globalTranslationService = service
+}
+
+// GetTranslationService возвращает глобальный сервис переводов
+func GetTranslationService() *TranslationService {
+	return globalTranslationService
 }
 
-// NewTranslationService создает новый сервис переводов (бесплатный, без токенов)
+// NewTranslationService создает новый сервис переводов (бесплатный, без токенов):
+// LibreTranslate по apiURL, затем MyMemory
 func NewTranslationService(apiURL string) *TranslationService {
 	// Если URL не указан, используем публичный LibreTranslate
 	if apiURL == "" {
 		apiURL = "https://libretranslate.com/translate"
 	}
-	
-	return &TranslationService{
-		apiURL: apiURL,
-		client: &http.Client{Timeout: 10 * time.Second},
-		redis:  GetRedis(),
+
+	return NewTranslationServiceWithBackends(
+		TranslationBackend{Translator: NewLibreTranslateBackend(apiURL), Timeout: 10 * time.Second},
+		TranslationBackend{Translator: NewMyMemoryBackend(), Timeout: 10 * time.Second},
+	)
+}
+
+// NewTranslationServiceWithBackends создает сервис переводов с заданным порядком бэкендов
+func NewTranslationServiceWithBackends(backends ...TranslationBackend) *TranslationService {
+	ts := &TranslationService{
+		redis:   GetRedis(),
+		metrics: newTranslationMetrics(),
+	}
+	for _, b := range backends {
+		if b.Translator == nil {
+			continue
+		}
+		ts.backends = append(ts.backends, &translationBackendSlot{
+			translator: b.Translator,
+			timeout:    b.Timeout,
+			limiter:    newTranslationRateLimiter(b.RatePerMinute),
+		})
+	}
+	return ts
+}
+
+// NewTranslationServiceFromConfig собирает цепочку бэкендов из конфигурации (TRANSLATION_BACKENDS и т.д.)
+func NewTranslationServiceFromConfig(cfg *config.Config) *TranslationService {
+	backends := make([]TranslationBackend, 0, len(cfg.TranslationBackends))
+	for _, name := range cfg.TranslationBackends {
+		var translator Translator
+		switch name {
+		case "libretranslate":
+			translator = NewLibreTranslateBackend(cfg.TranslationAPIURL)
+		case "mymemory":
+			translator = NewMyMemoryBackend()
+		case "llm":
+			if cfg.TranslationLLMAPIKey == "" {
+				log.Printf("[TRANSLATION] backend llm skipped: TRANSLATION_LLM_API_KEY is empty")
+				continue
+			}
+			translator = NewLLMTranslateBackend(cfg.TranslationLLMURL, cfg.TranslationLLMAPIKey, cfg.TranslationLLMModel)
+		case "dictionary":
+			dict, err := LoadDictionaryBackend(cfg.TranslationDictionaryPath)
+			if err != nil {
+				log.Printf("[TRANSLATION] dictionary %s not loaded: %v", cfg.TranslationDictionaryPath, err)
+			}
+			translator = dict
+		default:
+			log.Printf("[TRANSLATION] unknown backend %q skipped", name)
+			continue
+		}
+		backends = append(backends, TranslationBackend{
+			Translator:    translator,
+			Timeout:       cfg.TranslationBackendTimeouts[name],
+			RatePerMinute: cfg.TranslationBackendRPM[name],
+		})
+	}
+	return NewTranslationServiceWithBackends(backends...)
+}
+
+// Backends возвращает имена бэкендов в порядке fallback
+func (ts *TranslationService) Backends() []string {
+	names := make([]string, 0, len(ts.backends))
+	for _, b := range ts.backends {
+		names = append(names, b.translator.Name())
 	}
+	return names
+}
+
+// Metrics возвращает снимок метрик по бэкендам
+func (ts *TranslationService) Metrics() TranslationMetricsSnapshot {
+	return ts.metrics.snapshot(ts.Backends())
 }
 
 // Translate переводит текст с узбекского на указанный язык
@@ -43,6 +151,16 @@ func (ts *TranslationService) Translate(text, targetLang string) (string, error)
 		return "", nil
 	}
 
+	// Маппинг языков
+	langMap := map[string]string{
+		"ru": "ru",
+		"en": "en",
+	}
+	targetLangCode, ok := langMap[targetLang]
+	if !ok {
+		return text, fmt.Errorf("unsupported target language: %s", targetLang)
+	}
+
 	// Генерируем ключ кэша
 	cacheKey := ts.getCacheKey(text, targetLang)
 
@@ -51,167 +169,238 @@ func (ts *TranslationService) Translate(text, targetLang string) (string, error)
 		ctx := context.Background()
 		cached, err := ts.redis.Get(ctx, cacheKey).Result()
 		if err == nil && cached != "" {
+			ts.metrics.recordCacheHit()
 			log.Printf("[TRANSLATION CACHE] HIT: %s -> %s", text[:min(50, len(text))], targetLang)
 			return cached, nil
 		}
 	}
 
-	// Переводим через бесплатный API
-	translated, err := ts.translateFree(text, targetLang)
-
+	// Переводим по цепочке бэкендов
+	translated, backend, err := ts.translateChain(text, "uz", targetLangCode)
 	if err != nil {
 		log.Printf("[TRANSLATION ERROR] Failed to translate: %v", err)
 		return text, err // Возвращаем оригинал при ошибке
 	}
+	ts.metrics.recordPhrase(text, targetLang, backend)
 
 	// Сохраняем в кэш на 30 дней
 	if ts.redis != nil && translated != "" {
 		ctx := context.Background()
 		ts.redis.Set(ctx, cacheKey, translated, 30*24*time.Hour)
-		log.Printf("[TRANSLATION CACHE] MISS: %s -> %s (stored)", text[:min(50, len(text))], targetLang)
+		log.Printf("[TRANSLATION CACHE] MISS: %s -> %s (stored, backend=%s)", text[:min(50, len(text))], targetLang, backend)
 	}
 
 	return translated, nil
 }
 
-// getCacheKey генерирует ключ для кэша
-func (ts *TranslationService) getCacheKey(text, lang string) string {
-	hash := md5.Sum([]byte(text + ":" + lang))
-	return fmt.Sprintf("translation:uz:%s:%x", lang, hash)
-}
-
-// translateFree переводит через бесплатный API (LibreTranslate или MyMemory)
-func (ts *TranslationService) translateFree(text, targetLang string) (string, error) {
-	// Маппинг языков
-	langMap := map[string]string{
-		"ru": "ru",
-		"en": "en",
+// translateChain пробует бэкенды по порядку и возвращает первый успешный перевод и имя бэкенда
+func (ts *TranslationService) translateChain(text, source, target string) (string, string, error) {
+	if len(ts.backends) == 0 {
+		return text, "", fmt.Errorf("no translation backends configured")
 	}
 
-	targetLangCode, ok := langMap[targetLang]
-	if !ok {
-		return text, fmt.Errorf("unsupported target language: %s", targetLang)
-	}
+	errs := make([]string, 0, len(ts.backends))
+	for _, b := range ts.backends {
+		name := b.translator.Name()
+		if !b.limiter.allow() {
+			ts.metrics.recordRateLimited(name)
+			errs = append(errs, fmt.Sprintf("%s: %v", name, errTranslationRateLimited))
+			continue
+		}
 
-	// Пробуем сначала LibreTranslate
-	translated, err := ts.translateLibreTranslate(text, targetLangCode)
-	if err == nil && translated != "" {
-		return translated, nil
-	}
+		ctx := context.Background()
+		var cancel context.CancelFunc
+		if b.timeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, b.timeout)
+		}
+		started := time.Now()
+		translated, err := b.translator.Translate(ctx, text, source, target)
+		elapsed := time.Since(started)
+		if cancel != nil {
+			cancel()
+		}
 
-	// Если LibreTranslate не сработал, пробуем MyMemory
-	translated, err = ts.translateMyMemory(text, targetLangCode)
-	if err == nil && translated != "" {
-		return translated, nil
+		if err == nil && strings.TrimSpace(translated) != "" {
+			ts.metrics.recordServed(name, elapsed)
+			return translated, name, nil
+		}
+		if err == nil {
+			err = fmt.Errorf("no translation returned")
+		}
+		ts.metrics.recordFailed(name, elapsed, errors.Is(err, context.DeadlineExceeded), errors.Is(err, ErrTranslationNotFound))
+		errs = append(errs, fmt.Sprintf("%s: %v", name, err))
 	}
 
-	return text, fmt.Errorf("all translation APIs failed")
+	return text, "", fmt.Errorf("all translation backends failed: %s", strings.Join(errs, "; "))
 }
 
-// translateLibreTranslate переводит через LibreTranslate (бесплатный, без токенов)
-func (ts *TranslationService) translateLibreTranslate(text, targetLang string) (string, error) {
-	// Используем публичный LibreTranslate API
-	apiURL := "https://libretranslate.com/translate"
-	
-	requestData := map[string]interface{}{
-		"q":      text,
-		"source": "uz",
-		"target": targetLang,
-		"format": "text",
-	}
+// getCacheKey генерирует ключ для кэша
+func (ts *TranslationService) getCacheKey(text, lang string) string {
+	hash := md5.Sum([]byte(text + ":" + lang))
+	return fmt.Sprintf("translation:uz:%s:%x", lang, hash)
+}
 
-	jsonData, err := json.Marshal(requestData)
-	if err != nil {
-		return text, err
-	}
+// translationRateLimiter - простой token bucket на минуту для одного бэкенда
+type translationRateLimiter struct {
+	mu       sync.Mutex
+	capacity float64
+	tokens   float64
+	last     time.Time
+}
 
-	req, err := http.NewRequest("POST", apiURL, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return text, err
+func newTranslationRateLimiter(perMinute int) *translationRateLimiter {
+	if perMinute <= 0 {
+		return nil
 	}
+	return &translationRateLimiter{capacity: float64(perMinute), tokens: float64(perMinute), last: time.Now()}
+}
 
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := ts.client.Do(req)
-	if err != nil {
-		return text, err
+// allow забирает токен, если он есть (nil-лимитер пропускает всё)
+func (l *translationRateLimiter) allow() bool {
+	if l == nil {
+		return true
 	}
-	defer resp.Body.Close()
+	l.mu.Lock()
+	defer l.mu.Unlock()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return text, fmt.Errorf("libretranslate API error: %s", string(body))
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Minutes() * l.capacity
+	if l.tokens > l.capacity {
+		l.tokens = l.capacity
 	}
-
-	var result struct {
-		TranslatedText string `json:"translatedText"`
+	l.last = now
+	if l.tokens < 1 {
+		return false
 	}
+	l.tokens--
+	return true
+}
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return text, err
-	}
+// TranslationBackendStats - счетчики одного бэкенда
+type TranslationBackendStats struct {
+	Backend      string  `json:"backend"`
+	Served       int64   `json:"served"`
+	Failed       int64   `json:"failed"`
+	NotFound     int64   `json:"not_found"`
+	Timeouts     int64   `json:"timeouts"`
+	RateLimited  int64   `json:"rate_limited"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+
+	totalLatency time.Duration
+	calls        int64
+}
 
-	if result.TranslatedText == "" {
-		return text, fmt.Errorf("no translation returned")
-	}
+// TranslatedPhrase - какой бэкенд перевел фразу
+type TranslatedPhrase struct {
+	Text      string    `json:"text"`
+	Target    string    `json:"target"`
+	Backend   string    `json:"backend"`
+	CreatedAt time.Time `json:"created_at"`
+}
 
-	return result.TranslatedText, nil
+// TranslationMetricsSnapshot - снимок метрик сервиса переводов
+type TranslationMetricsSnapshot struct {
+	Order     []string                  `json:"order"`
+	CacheHits int64                     `json:"cache_hits"`
+	Backends  []TranslationBackendStats `json:"backends"`
+	Recent    []TranslatedPhrase        `json:"recent"`
 }
 
-// translateMyMemory переводит через MyMemory API (бесплатный, без токенов, с лимитами)
-func (ts *TranslationService) translateMyMemory(text, targetLang string) (string, error) {
-	// MyMemory API - бесплатный, без токенов
-	apiURL := "https://api.mymemory.translated.net/get"
-	
-	// Маппинг языков для MyMemory
-	langMap := map[string]string{
-		"ru": "ru",
-		"en": "en",
-	}
+// maxRecentPhrases - сколько последних переведенных фраз храним в памяти
+const maxRecentPhrases = 100
 
-	myMemoryLang, ok := langMap[targetLang]
+// TranslationMetrics - потокобезопасные метрики по бэкендам
+type TranslationMetrics struct {
+	mu        sync.Mutex
+	cacheHits int64
+	backends  map[string]*TranslationBackendStats
+	recent    []TranslatedPhrase
+}
+
+func newTranslationMetrics() *TranslationMetrics {
+	return &TranslationMetrics{backends: make(map[string]*TranslationBackendStats)}
+}
+
+func (m *TranslationMetrics) stats(name string) *TranslationBackendStats {
+	s, ok := m.backends[name]
 	if !ok {
-		return text, fmt.Errorf("unsupported target language: %s", targetLang)
+		s = &TranslationBackendStats{Backend: name}
+		m.backends[name] = s
 	}
+	return s
+}
 
-	reqURL := fmt.Sprintf("%s?q=%s&langpair=uz|%s", apiURL, url.QueryEscape(text), myMemoryLang)
+func (m *TranslationMetrics) recordCacheHit() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cacheHits++
+}
 
-	req, err := http.NewRequest("GET", reqURL, nil)
-	if err != nil {
-		return text, err
-	}
+func (m *TranslationMetrics) recordServed(name string, elapsed time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.stats(name)
+	s.Served++
+	s.calls++
+	s.totalLatency += elapsed
+}
 
-	resp, err := ts.client.Do(req)
-	if err != nil {
-		return text, err
+func (m *TranslationMetrics) recordFailed(name string, elapsed time.Duration, timeout, notFound bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.stats(name)
+	s.calls++
+	s.totalLatency += elapsed
+	switch {
+	case notFound:
+		s.NotFound++
+	case timeout:
+		s.Timeouts++
+		s.Failed++
+	default:
+		s.Failed++
 	}
-	defer resp.Body.Close()
+}
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return text, fmt.Errorf("mymemory API error: %s", string(body))
-	}
+func (m *TranslationMetrics) recordRateLimited(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stats(name).RateLimited++
+}
 
-	var result struct {
-		ResponseData struct {
-			TranslatedText string `json:"translatedText"`
-		} `json:"responseData"`
-		QuotaFinished bool `json:"quotaFinished"`
+func (m *TranslationMetrics) recordPhrase(text, target, backend string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.recent = append(m.recent, TranslatedPhrase{
+		Text:      text[:min(100, len(text))],
+		Target:    target,
+		Backend:   backend,
+		CreatedAt: time.Now(),
+	})
+	if len(m.recent) > maxRecentPhrases {
+		m.recent = m.recent[len(m.recent)-maxRecentPhrases:]
 	}
+}
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return text, err
-	}
+func (m *TranslationMetrics) snapshot(order []string) TranslationMetricsSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	if result.QuotaFinished {
-		return text, fmt.Errorf("mymemory quota finished")
+	out := TranslationMetricsSnapshot{
+		Order:     order,
+		CacheHits: m.cacheHits,
+		Backends:  make([]TranslationBackendStats, 0, len(order)),
+		Recent:    make([]TranslatedPhrase, len(m.recent)),
 	}
-
-	if result.ResponseData.TranslatedText == "" {
-		return text, fmt.Errorf("no translation returned")
+	for _, name := range order {
+		s := *m.stats(name)
+		if s.calls > 0 {
+			s.AvgLatencyMs = float64(s.totalLatency.Milliseconds()) / float64(s.calls)
+		}
+		out.Backends = append(out.Backends, s)
 	}
-
-	return result.ResponseData.TranslatedText, nil
+	copy(out.Recent, m.recent)
+	return out
 }
 
 // min возвращает минимум из двух чисел
@@ -0,0 +1,138 @@
+package utils
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"kliro/config"
+)
+
+func TestTranslateChainFallbackOrder(t *testing.T) {
+	tests := []struct {
+		name        string
+		setup       func(first, second *FakeTranslator)
+		wantText    string
+		wantBackend string
+		wantErr     bool
+		wantCalls   [2]int
+	}{
+		{"first serves", func(first, second *FakeTranslator) {
+			first.SetResponse("Omonat", "ru", "Вклад")
+		}, "Вклад", "first", false, [2]int{1, 0}},
+		{"first fails", func(first, second *FakeTranslator) {
+			first.SetError(errors.New("HTTP 502"))
+			second.SetResponse("Omonat", "ru", "Депозит")
+		}, "Депозит", "second", false, [2]int{1, 1}},
+		{"first not found", func(first, second *FakeTranslator) {
+			first.SetError(ErrTranslationNotFound)
+		}, "[ru] Omonat", "second", false, [2]int{1, 1}},
+		{"first empty", func(first, second *FakeTranslator) {
+			first.SetResponse("Omonat", "ru", "  ")
+		}, "[ru] Omonat", "second", false, [2]int{1, 1}},
+		{"all fail", func(first, second *FakeTranslator) {
+			first.SetError(errors.New("HTTP 502"))
+			second.SetError(ErrTranslationNotFound)
+		}, "Omonat", "", true, [2]int{1, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, second := NewFakeTranslator("first"), NewFakeTranslator("second")
+			tt.setup(first, second)
+			ts := NewTranslationServiceWithBackends(TranslationBackend{Translator: first}, TranslationBackend{Translator: second})
+
+			got, backend, err := ts.translateChain("Omonat", "uz", "ru")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("translateChain() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.wantText || backend != tt.wantBackend {
+				t.Errorf("translateChain() = %q from %q, want %q from %q", got, backend, tt.wantText, tt.wantBackend)
+			}
+			if calls := [2]int{first.Calls(), second.Calls()}; calls != tt.wantCalls {
+				t.Errorf("calls = %v, want %v", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestTranslateBackendTimeout(t *testing.T) {
+	slow, fallback := NewFakeTranslator("slow"), NewFakeTranslator("fallback")
+	slow.SetDelay(time.Second)
+	ts := NewTranslationServiceWithBackends(
+		TranslationBackend{Translator: slow, Timeout: 20 * time.Millisecond},
+		TranslationBackend{Translator: fallback},
+	)
+
+	started := time.Now()
+	got, err := ts.Translate("Omonat", "en")
+	if err != nil || got != "[en] Omonat" {
+		t.Fatalf("Translate() = %q, %v, want fallback translation", got, err)
+	}
+	if elapsed := time.Since(started); elapsed > 500*time.Millisecond {
+		t.Errorf("Translate() took %s, slow backend was not cut by timeout", elapsed)
+	}
+
+	stats := ts.Metrics().Backends
+	if stats[0].Timeouts != 1 || stats[0].Served != 0 || stats[1].Served != 1 {
+		t.Errorf("metrics = %+v, want one timeout on slow and one served by fallback", stats)
+	}
+}
+
+func TestTranslateRateLimitedBackendSkipped(t *testing.T) {
+	limited, fallback := NewFakeTranslator("limited"), NewFakeTranslator("fallback")
+	ts := NewTranslationServiceWithBackends(
+		TranslationBackend{Translator: limited, RatePerMinute: 2},
+		TranslationBackend{Translator: fallback},
+	)
+
+	var backends []string
+	for i := 0; i < 3; i++ {
+		_, backend, err := ts.translateChain("Omonat", "uz", "ru")
+		if err != nil {
+			t.Fatalf("translateChain() error = %v", err)
+		}
+		backends = append(backends, backend)
+	}
+	if want := []string{"limited", "limited", "fallback"}; !reflect.DeepEqual(backends, want) {
+		t.Errorf("backends = %v, want %v", backends, want)
+	}
+	if limited.Calls() != 2 {
+		t.Errorf("limited backend calls = %d, want 2", limited.Calls())
+	}
+	if stats := ts.Metrics().Backends; stats[0].RateLimited != 1 {
+		t.Errorf("rate_limited = %d, want 1", stats[0].RateLimited)
+	}
+}
+
+func TestTranslationRateLimiterRefill(t *testing.T) {
+	if l := newTranslationRateLimiter(0); !l.allow() {
+		t.Fatal("limiter without RPM must allow everything")
+	}
+
+	l := newTranslationRateLimiter(60)
+	for i := 0; i < 60; i++ {
+		if !l.allow() {
+			t.Fatalf("call %d denied, bucket holds 60", i+1)
+		}
+	}
+	if l.allow() {
+		t.Fatal("61st call allowed, bucket must be empty")
+	}
+
+	// Через 2 секунды при 60 в минуту в ведре снова 2 токена
+	l.last = l.last.Add(-2 * time.Second)
+	if !l.allow() || !l.allow() {
+		t.Fatal("tokens were not refilled")
+	}
+	if l.allow() {
+		t.Fatal("refill gave more tokens than elapsed time allows")
+	}
+}
+
+func TestTranslationServiceFromConfigSkipsFake(t *testing.T) {
+	cfg := &config.Config{TranslationBackends: []string{"fake", "mymemory"}}
+	if got := NewTranslationServiceFromConfig(cfg).Backends(); !reflect.DeepEqual(got, []string{"mymemory"}) {
+		t.Errorf("Backends() = %v, want [mymemory]: fake must not be selectable by config", got)
+	}
+}